@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/accountimportservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/setup/globalsetup"
+	"bitbucket.org/calmisland/go-server-configs/configs"
+)
+
+func main() {
+	filePath := flag.String("file", "", "The CSV file to import, reads from stdin if empty")
+	dryRun := flag.Bool("dryrun", false, "Only validate the rows without creating any account")
+	outputFormat := flag.String("output", "json", "The report format, either json or csv")
+	source := flag.String("source", "", "The product the password hashes are imported from")
+	flag.Parse()
+
+	if *outputFormat != "json" && *outputFormat != "csv" {
+		fmt.Fprintf(os.Stderr, "Unknown output format: %s\n", *outputFormat)
+		os.Exit(2)
+	}
+
+	err := configs.UpdateConfigDirectoryPath(configs.DefaultConfigFolderName)
+	if err != nil {
+		panic(err)
+	}
+
+	globalsetup.Setup()
+
+	var input io.Reader = os.Stdin
+	if len(*filePath) > 0 {
+		file, err := os.Open(*filePath)
+		if err != nil {
+			panic(err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	rows, err := accountimportservice.ParseCSV(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse the CSV data: %v\n", err)
+		os.Exit(1)
+	}
+
+	report := accountimportservice.Import(rows, accountimportservice.Options{
+		DryRun: *dryRun,
+		Source: *source,
+	})
+
+	if *outputFormat == "csv" {
+		err = accountimportservice.WriteCSV(os.Stdout, report)
+	} else {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Total: %d, created: %d, valid: %d, failed: %d\n", report.Total, report.Created, report.Valid, report.Failed)
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/expiryreminders"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/transactionservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/setup/globalsetup"
+	"bitbucket.org/calmisland/go-server-configs/configs"
+	"github.com/guregu/dynamo"
+)
+
+// The job is meant to be scheduled at least daily, as only the reminders due at the time of a run are sent.
+func main() {
+	dryRun := flag.Bool("dryrun", false, "Only report the reminders that would be sent")
+	pageSize := flag.Int64("pagesize", 100, "The number of transactions read per page")
+	flag.Parse()
+
+	if *pageSize <= 0 {
+		fmt.Fprintln(os.Stderr, "The page size must be positive")
+		os.Exit(2)
+	}
+
+	err := configs.UpdateConfigDirectoryPath(configs.DefaultConfigFolderName)
+	if err != nil {
+		panic(err)
+	}
+
+	globalsetup.Setup()
+
+	var config expiryreminders.Config
+	err = configs.ReadEnvConfig(&config)
+	if err != nil {
+		panic(err)
+	}
+
+	windows, err := expiryreminders.ParseWindows(config.WindowsDays)
+	if err != nil {
+		panic(err)
+	}
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	outcomes := map[expiryreminders.Outcome]int{}
+	failed := 0
+	seenAccountIDs := map[string]bool{}
+	var lastKey dynamo.PagingKey
+	for {
+		accountIDs, nextKey, err := transactionservice.ScanAccountIDs(lastKey, *pageSize)
+		if err != nil {
+			panic(err)
+		}
+
+		for _, accountID := range accountIDs {
+			if seenAccountIDs[accountID] {
+				continue
+			}
+			seenAccountIDs[accountID] = true
+
+			accountOutcomes, err := expiryreminders.SendAccountReminders(accountID, windows, now, *dryRun)
+			for _, outcome := range accountOutcomes {
+				outcomes[outcome]++
+				fmt.Printf("%s\t%s\n", outcome, accountID)
+			}
+			if err != nil {
+				// The reminders that failed are not recorded, so they are sent by the next run
+				fmt.Fprintf(os.Stderr, "Failed to send the reminders of [%s]: %v\n", accountID, err)
+				failed++
+			}
+		}
+
+		lastKey = nextKey
+		if lastKey == nil {
+			break
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Dry run: %t, accounts: %d, sent: %d, already sent: %d, would be sent: %d, no recipient: %d, no account: %d, opted out: %d, failed: %d\n",
+		*dryRun,
+		len(seenAccountIDs),
+		outcomes[expiryreminders.OutcomeSent],
+		outcomes[expiryreminders.OutcomeAlreadySent],
+		outcomes[expiryreminders.OutcomeWouldBeSent],
+		outcomes[expiryreminders.OutcomeNoRecipient],
+		outcomes[expiryreminders.OutcomeNoAccount],
+		outcomes[expiryreminders.OutcomeOptedOut],
+		failed)
+}
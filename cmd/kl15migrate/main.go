@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/kl15migrationservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/setup/globalsetup"
+	"bitbucket.org/calmisland/go-server-configs/configs"
+	"golang.org/x/time/rate"
+)
+
+func main() {
+	dryRun := flag.Bool("dryrun", false, "Only report what would be migrated")
+	ratePerSecond := flag.Float64("rate", 10, "The maximum number of records migrated per second")
+	pageSize := flag.Int64("pagesize", 100, "The number of records read per page")
+	checkpointPath := flag.String("checkpoint", "kl15migrate.checkpoint.json", "The file used to resume an interrupted migration")
+	restart := flag.Bool("restart", false, "Start from the beginning, only keeping the failed records of any existing checkpoint")
+	retryFailed := flag.Bool("retryfailed", false, "Only retry the records that failed in the previous runs")
+	flag.Parse()
+
+	if *ratePerSecond <= 0 || *pageSize <= 0 {
+		fmt.Fprintln(os.Stderr, "The rate and the page size must be positive")
+		os.Exit(2)
+	}
+
+	err := configs.UpdateConfigDirectoryPath(configs.DefaultConfigFolderName)
+	if err != nil {
+		panic(err)
+	}
+
+	globalsetup.Setup()
+
+	state := kl15migrationservice.NewCheckpoint()
+	if !*dryRun || *retryFailed {
+		state, err = kl15migrationservice.LoadCheckpoint(*checkpointPath)
+		if err != nil {
+			panic(err)
+		} else if *restart && !*retryFailed {
+			// The failed records of the previous runs stay reachable with -retryfailed
+			state = state.Restart()
+		} else if state.Done && !*retryFailed {
+			fmt.Fprintln(os.Stderr, "The migration was already completed, use -restart to run it again or -retryfailed to retry the failed records")
+			return
+		}
+	}
+
+	ctx := context.Background()
+	limiter := rate.NewLimiter(rate.Limit(*ratePerSecond), 1)
+
+	if *retryFailed {
+		retryFailedRecords(ctx, limiter, state, *dryRun)
+		if !*dryRun {
+			err = state.Save(*checkpointPath)
+			if err != nil {
+				panic(err)
+			}
+		}
+		printSummary(state, *dryRun)
+		return
+	}
+
+	for {
+		records, lastKey, err := kl15migrationservice.ScanPendingRecords(state.LastKey, *pageSize)
+		if err != nil {
+			panic(err)
+		}
+
+		for _, record := range records {
+			migrateRecord(ctx, limiter, state, record, *dryRun)
+		}
+
+		state.LastKey = lastKey
+		state.Done = (lastKey == nil)
+		if !*dryRun {
+			err = state.Save(*checkpointPath)
+			if err != nil {
+				panic(err)
+			}
+		}
+
+		if state.Done {
+			break
+		}
+	}
+
+	printSummary(state, *dryRun)
+	if len(state.FailedKeys) > 0 {
+		fmt.Fprintln(os.Stderr, "Some records failed, use -retryfailed to retry them")
+	}
+}
+
+// migrateRecord migrates a record, keeping it in the checkpoint failed records if it fails.
+func migrateRecord(ctx context.Context, limiter *rate.Limiter, state *kl15migrationservice.Checkpoint, record *models.AccountMigrationKl1dot5, dryRun bool) {
+	err := limiter.Wait(ctx)
+	if err != nil {
+		panic(err)
+	}
+
+	outcome, err := kl15migrationservice.PrecreateAccount(record, dryRun)
+	if err != nil {
+		// The scan moves past the failed records, so they are kept to be retried with -retryfailed
+		fmt.Fprintf(os.Stderr, "Failed to migrate [%s]: %v\n", record.Email, err)
+		state.RecordFailure(record)
+		if !dryRun {
+			kl15migrationservice.RecordFailure(record, kl15migrationservice.FailureReasonInternalError, kl15migrationservice.EventSourceBatch)
+		}
+		return
+	}
+
+	state.RecordOutcome(record, outcome)
+	fmt.Printf("%s\t%s\t%s\n", outcome, record.Email, record.PhoneNumber)
+}
+
+// retryFailedRecords migrates again the records that failed in the previous runs.
+func retryFailedRecords(ctx context.Context, limiter *rate.Limiter, state *kl15migrationservice.Checkpoint, dryRun bool) {
+	failedKeys := append([]string(nil), state.FailedKeys...)
+	for _, key := range failedKeys {
+		record, found, err := kl15migrationservice.GetRecordByKey(key)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read [%s]: %v\n", key, err)
+			continue
+		} else if !found {
+			state.RecordOutcome(&models.AccountMigrationKl1dot5{Email: key}, kl15migrationservice.OutcomeInvalid)
+			continue
+		}
+
+		migrateRecord(ctx, limiter, state, record, dryRun)
+	}
+}
+
+func printSummary(state *kl15migrationservice.Checkpoint, dryRun bool) {
+	fmt.Fprintf(os.Stderr, "Dry run: %t, created: %d, existing accounts: %d, invalid: %d, already migrated: %d, failed: %d\n",
+		dryRun,
+		state.Outcomes[kl15migrationservice.OutcomeCreated],
+		state.Outcomes[kl15migrationservice.OutcomeExistingAccount],
+		state.Outcomes[kl15migrationservice.OutcomeInvalid],
+		state.Outcomes[kl15migrationservice.OutcomeAlreadyMigrated],
+		len(state.FailedKeys))
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/kl15migrationservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/setup/globalsetup"
+	"bitbucket.org/calmisland/go-server-configs/configs"
+)
+
+func main() {
+	fromParam := flag.String("from", "", "The first reported day (YYYY-MM-DD), defaults to 30 days ago")
+	toParam := flag.String("to", "", "The last reported day (YYYY-MM-DD), defaults to today")
+	outputJSON := flag.Bool("json", false, "Print the report as JSON")
+	recount := flag.Bool("recount", false, "Scan the KL1.5 records to reset the status counts first, only needed once")
+	flag.Parse()
+
+	defaultFrom, defaultTo := kl15migrationservice.DefaultReportRange()
+	from, err := kl15migrationservice.ParseReportDay(*fromParam, defaultFrom)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid from day: %v\n", err)
+		os.Exit(2)
+	}
+	to, err := kl15migrationservice.ParseReportDay(*toParam, defaultTo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid to day: %v\n", err)
+		os.Exit(2)
+	}
+	if !kl15migrationservice.IsValidReportRange(from, to) {
+		fmt.Fprintf(os.Stderr, "The range must be ordered and at most %d days long\n", kl15migrationservice.MaxReportDays)
+		os.Exit(2)
+	}
+
+	err = configs.UpdateConfigDirectoryPath(configs.DefaultConfigFolderName)
+	if err != nil {
+		panic(err)
+	}
+
+	globalsetup.Setup()
+
+	if *recount {
+		_, err = kl15migrationservice.RecountStatuses()
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	report, err := kl15migrationservice.BuildReport(from, to)
+	if err != nil {
+		panic(err)
+	}
+
+	if *outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	fmt.Printf("Total: ~%d, pending: ~%d, done: %d, failed: %d\n\n", report.Statuses.Total, report.Statuses.Pending, report.Statuses.Done, report.Statuses.Failed)
+	fmt.Println("day\tnew accounts\tpassword overrides\tfailures\treasons\trejected attempts\treasons")
+	for _, day := range report.Days {
+		fmt.Printf("%s\t%d\t%d\t%d\t%s\t%d\t%s\n", day.Day, day.NewAccounts, day.PasswordOverrides,
+			day.Failures, formatReasons(day.FailureReasons), day.RejectedAttempts, formatReasons(day.RejectionReasons))
+	}
+}
+
+func formatReasons(counts map[string]int) string {
+	reasons := make([]string, 0, len(counts))
+	for reason := range counts {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	for i, reason := range reasons {
+		reasons[i] = fmt.Sprintf("%s=%d", reason, counts[reason])
+	}
+	return strings.Join(reasons, ", ")
+}
@@ -0,0 +1,86 @@
+package v1
+
+import (
+	"net/http"
+	"strings"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/avatarservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/parentalconsentservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
+	"bitbucket.org/calmisland/go-server-logs/logger"
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+	"bitbucket.org/calmisland/go-server-requests/apirequests"
+	"bitbucket.org/calmisland/go-server-utils/emailutils"
+	"github.com/labstack/echo/v4"
+)
+
+type adminAccountChildRequestBody struct {
+	IsChild bool `json:"isChild"`
+	// ParentEmail receives the parental consent request, it's required to flag an account that has no consent yet
+	ParentEmail string `json:"parentEmail"`
+}
+
+// HandleAdminSetAccountChild handles requests to flag an account as a child account.
+// Child accounts can only use preset avatars, so their uploaded avatar is deleted.
+// The account is restricted until the parent consents, unless it's a child profile created by its parent or was already consented to.
+func HandleAdminSetAccountChild(c echo.Context) error {
+	adminAccountID := helpers.GetAccountID(c)
+	accountID := c.Param("accountId")
+	if len(accountID) == 0 {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters)
+	}
+
+	reqBody := new(adminAccountChildRequestBody)
+	err := c.Bind(reqBody)
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorBadRequestBody)
+	}
+
+	accInfo, err := globals.AccountDatabase.GetAccountInfo(accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	} else if accInfo == nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorAccountNotFound)
+	}
+
+	profile, err := profileservice.GetProfile(accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	requiresConsent := reqBody.IsChild && len(profile.ParentID) == 0 && profile.ParentalConsentStatus != models.ParentalConsentStatusGranted
+	parentEmail := strings.TrimSpace(reqBody.ParentEmail)
+	if requiresConsent {
+		if len(parentEmail) == 0 {
+			return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("parentEmail"))
+		} else if !emailutils.IsValidEmailAddressFormat(parentEmail) || strings.EqualFold(parentEmail, accInfo.Email) {
+			return apirequests.EchoSetClientError(c, apierrors.ErrorInputInvalidFormat.WithField("parentEmail"))
+		}
+	}
+
+	err = profileservice.SetChild(accountID, reqBody.IsChild)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	if reqBody.IsChild {
+		err = avatarservice.DeleteAvatar(accountID)
+		if err != nil {
+			return helpers.HandleInternalError(c, err)
+		}
+	}
+
+	if requiresConsent {
+		_, err = parentalconsentservice.RequestConsent(parentalconsentservice.NewStore(), accountID, parentEmail, accInfo.Language, getParentalConsentOrigin(c))
+		if err != nil {
+			return helpers.HandleInternalError(c, err)
+		}
+		logger.LogFormat("[ADMIN] Account [%s] requested the parental consent to account [%s]\n", adminAccountID, accountID)
+	}
+
+	logger.LogFormat("[ADMIN] Account [%s] set the child flag of account [%s] to %t\n", adminAccountID, accountID, reqBody.IsChild)
+	return c.NoContent(http.StatusOK)
+}
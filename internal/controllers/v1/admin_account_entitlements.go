@@ -0,0 +1,176 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/entitlements"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/entitlementservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/transactionservice"
+	"bitbucket.org/calmisland/go-server-logs/logger"
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+	"bitbucket.org/calmisland/go-server-requests/apirequests"
+	"bitbucket.org/calmisland/go-server-utils/textutils"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	maxEntitlementChangeReasonLength = 256
+)
+
+type adminEntitlementChangeRequestBody struct {
+	// Kind is either "pass" or "product"
+	Kind         string `json:"kind"`
+	ID           string `json:"id"`
+	Reason       string `json:"reason"`
+	DurationDays int    `json:"durationDays"`
+	// ExpirationDate is the new expiration date of an extension, in epoch milliseconds
+	ExpirationDate int64 `json:"expirationTm"`
+	// Shareable shares a granted or extended item with the family of the account
+	Shareable bool `json:"shareable"`
+}
+
+type adminAccountEntitlementsResponseBody struct {
+	*entitlements.Entitlements
+	Transactions []*models.AccountTransaction `json:"transactions"`
+}
+
+// HandleAdminGetAccountEntitlements handles requests to get the entitlements and all the transactions of an account.
+func HandleAdminGetAccountEntitlements(c echo.Context) error {
+	accountID := c.Param("accountId")
+	if len(accountID) == 0 {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters)
+	}
+
+	transactions, err := transactionservice.ListAllTransactions(accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	result, err := entitlementservice.GetEntitlements(accountID, time.Now().UnixNano()/int64(time.Millisecond))
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, adminAccountEntitlementsResponseBody{
+		Entitlements: result,
+		Transactions: transactions,
+	})
+}
+
+// HandleAdminGrantAccountEntitlement handles requests to grant a pass or a product to an account.
+func HandleAdminGrantAccountEntitlement(c echo.Context) error {
+	return handleAdminEntitlementChange(c, "granted", setEntitlementGrantDuration, entitlementservice.Grant)
+}
+
+// HandleAdminExtendAccountEntitlement handles requests to extend a pass or a product that an account owns.
+func HandleAdminExtendAccountEntitlement(c echo.Context) error {
+	return handleAdminEntitlementChange(c, "extended", setEntitlementExtensionDuration, entitlementservice.Extend)
+}
+
+// HandleAdminRevokeAccountEntitlement handles requests to revoke a pass or a product that an account owns.
+func HandleAdminRevokeAccountEntitlement(c echo.Context) error {
+	return handleAdminEntitlementChange(c, "revoked", nil, entitlementservice.Revoke)
+}
+
+// entitlementDurationSetter validates the duration of a change request and sets it to the change.
+type entitlementDurationSetter func(reqBody *adminEntitlementChangeRequestBody, change *entitlementservice.Change) *apierrors.APIError
+
+type entitlementChangeApplier func(change *entitlementservice.Change) (*models.AccountTransaction, error)
+
+func handleAdminEntitlementChange(c echo.Context, action string, setDuration entitlementDurationSetter, apply entitlementChangeApplier) error {
+	adminAccountID := helpers.GetAccountID(c)
+	accountID := c.Param("accountId")
+	if len(accountID) == 0 {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters)
+	}
+
+	reqBody := new(adminEntitlementChangeRequestBody)
+	err := c.Bind(reqBody)
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorBadRequestBody)
+	}
+
+	change, clientErr := getEntitlementChange(reqBody)
+	if clientErr == nil && setDuration != nil {
+		clientErr = setDuration(reqBody, change)
+	}
+	if clientErr != nil {
+		return apirequests.EchoSetClientError(c, clientErr)
+	}
+	change.AccountID = accountID
+	change.AdminAccountID = adminAccountID
+
+	accInfo, err := globals.AccountDatabase.GetAccountInfo(accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	} else if accInfo == nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorAccountNotFound)
+	}
+
+	transaction, err := apply(change)
+	switch err {
+	case nil:
+	case entitlementservice.ErrEntitlementNotActive:
+		return apirequests.EchoSetClientError(c, apierrors.ErrorItemNotFound.WithField("id"))
+	case entitlementservice.ErrEntitlementPermanent, entitlementservice.ErrInvalidExpirationDate:
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("expirationTm").WithMessage(err.Error()))
+	default:
+		return helpers.HandleInternalError(c, err)
+	}
+
+	logger.LogFormat("[ADMIN] Account [%s] %s the %s [%s] of account [%s] in transaction [%s]: %s\n",
+		adminAccountID, action, change.Kind, change.ItemID, accountID, transaction.TransactionID, change.Reason)
+	return c.JSON(http.StatusOK, transaction)
+}
+
+// getEntitlementChange validates the item and the reason of a change request.
+func getEntitlementChange(reqBody *adminEntitlementChangeRequestBody) (*entitlementservice.Change, *apierrors.APIError) {
+	kind := entitlements.Kind(reqBody.Kind)
+	if kind != entitlements.KindPass && kind != entitlements.KindProduct {
+		return nil, apierrors.ErrorInvalidParameters.WithField("kind")
+	} else if len(reqBody.ID) == 0 {
+		return nil, apierrors.ErrorInvalidParameters.WithField("id")
+	} else if len(reqBody.ID) > maxVoucherItemIDLength {
+		return nil, apierrors.ErrorInputTooLong.WithField("id").WithValue(maxVoucherItemIDLength)
+	}
+
+	reason := textutils.SanitizeString(reqBody.Reason)
+	if len(reason) == 0 {
+		return nil, apierrors.ErrorInvalidParameters.WithField("reason")
+	} else if defs.NameLength(reason) > maxEntitlementChangeReasonLength {
+		return nil, apierrors.ErrorInputTooLong.WithField("reason").WithValue(maxEntitlementChangeReasonLength)
+	}
+
+	return &entitlementservice.Change{
+		Kind:   kind,
+		ItemID: reqBody.ID,
+		Reason: reason,
+	}, nil
+}
+
+func setEntitlementGrantDuration(reqBody *adminEntitlementChangeRequestBody, change *entitlementservice.Change) *apierrors.APIError {
+	// Passes always expire, while products can be permanent
+	if reqBody.DurationDays < 0 || (change.Kind == entitlements.KindPass && reqBody.DurationDays == 0) {
+		return apierrors.ErrorInvalidParameters.WithField("durationDays")
+	}
+	change.DurationDays = reqBody.DurationDays
+	change.Shareable = reqBody.Shareable
+	return nil
+}
+
+func setEntitlementExtensionDuration(reqBody *adminEntitlementChangeRequestBody, change *entitlementservice.Change) *apierrors.APIError {
+	if reqBody.ExpirationDate < 0 {
+		return apierrors.ErrorInvalidParameters.WithField("expirationTm")
+	} else if reqBody.DurationDays < 0 || (reqBody.ExpirationDate == 0 && reqBody.DurationDays == 0) {
+		return apierrors.ErrorInvalidParameters.WithField("durationDays")
+	}
+	change.DurationDays = reqBody.DurationDays
+	change.ExpirationDate = reqBody.ExpirationDate
+	change.Shareable = reqBody.Shareable
+	return nil
+}
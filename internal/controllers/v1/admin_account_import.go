@@ -0,0 +1,58 @@
+package v1
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/accountimportservice"
+	"bitbucket.org/calmisland/go-server-logs/logger"
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+	"bitbucket.org/calmisland/go-server-requests/apirequests"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	accountImportMaxBodySize = 2 * 1024 * 1024 // 2 MB
+	accountImportMaxRows     = 2000
+)
+
+// HandleAdminAccountImport handles bulk account import requests from CSV data.
+func HandleAdminAccountImport(c echo.Context) error {
+	adminAccountID := helpers.GetAccountID(c)
+
+	dryRun := false
+	if dryRunParam := c.QueryParam("dryRun"); len(dryRunParam) > 0 {
+		var err error
+		dryRun, err = strconv.ParseBool(dryRunParam)
+		if err != nil {
+			return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("dryRun"))
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, accountImportMaxBodySize+1))
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorBadRequestBody)
+	} else if len(body) > accountImportMaxBodySize {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInputTooLong.WithValue(accountImportMaxBodySize))
+	}
+
+	rows, err := accountimportservice.ParseCSV(bytes.NewReader(body))
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorBadRequestBody.WithMessage(err.Error()))
+	} else if len(rows) == 0 {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters)
+	} else if len(rows) > accountImportMaxRows {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInputTooLong.WithValue(accountImportMaxRows))
+	}
+
+	logger.LogFormat("[ACCOUNTIMPORT] An import request of %d rows (dry run: %t) by admin account [%s]\n", len(rows), dryRun, adminAccountID)
+
+	report := accountimportservice.Import(rows, accountimportservice.Options{
+		DryRun: dryRun,
+	})
+
+	return c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,30 @@
+package v1
+
+import (
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/go-server-logs/logger"
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+	"bitbucket.org/calmisland/go-server-requests/apirequests"
+	"github.com/labstack/echo/v4"
+)
+
+// HandleAdminEditAccountInfo handles requests to edit the information of an account, with the same fields as the account's own information.
+// Unlike the account itself, an admin can correct the birth date and country of a child account.
+func HandleAdminEditAccountInfo(c echo.Context) error {
+	adminAccountID := helpers.GetAccountID(c)
+	accountID := c.Param("accountId")
+	if len(accountID) == 0 {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters)
+	}
+
+	accInfo, err := globals.AccountDatabase.GetAccountInfo(accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	} else if accInfo == nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorAccountNotFound)
+	}
+
+	logger.LogFormat("[ADMIN] Account [%s] is editing the information of account [%s]\n", adminAccountID, accountID)
+	return editAccountInfo(c, accountID, true)
+}
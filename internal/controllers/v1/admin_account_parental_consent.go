@@ -0,0 +1,43 @@
+package v1
+
+import (
+	"net/http"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/parentalconsentservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+	"bitbucket.org/calmisland/go-server-requests/apirequests"
+	"github.com/labstack/echo/v4"
+)
+
+type adminAccountParentalConsentResponseBody struct {
+	// Status is empty for the accounts that don't need the consent of a parent
+	Status string                        `json:"status,omitempty"`
+	Events []models.ParentalConsentEvent `json:"events"`
+}
+
+// HandleAdminGetAccountParentalConsent handles requests to audit the parental consent of an account.
+// The events are kept after the account is deleted.
+func HandleAdminGetAccountParentalConsent(c echo.Context) error {
+	accountID := c.Param("accountId")
+	if len(accountID) == 0 {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters)
+	}
+
+	profile, err := profileservice.GetProfile(accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	events, err := parentalconsentservice.GetEvents(parentalconsentservice.NewStore(), accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, adminAccountParentalConsentResponseBody{
+		Status: profile.ParentalConsentStatus,
+		Events: events,
+	})
+}
@@ -0,0 +1,37 @@
+package v1
+
+import (
+	"net/http"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/kl15migrationservice"
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+	"bitbucket.org/calmisland/go-server-requests/apirequests"
+	"github.com/labstack/echo/v4"
+)
+
+// HandleAdminKl15MigrationReport handles requests for the KL1.5 migration progress.
+func HandleAdminKl15MigrationReport(c echo.Context) error {
+	defaultFrom, defaultTo := kl15migrationservice.DefaultReportRange()
+
+	from, err := kl15migrationservice.ParseReportDay(c.QueryParam("from"), defaultFrom)
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInputInvalidFormat.WithField("from"))
+	}
+
+	to, err := kl15migrationservice.ParseReportDay(c.QueryParam("to"), defaultTo)
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInputInvalidFormat.WithField("to"))
+	}
+
+	if !kl15migrationservice.IsValidReportRange(from, to) {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("from").WithValue(kl15migrationservice.MaxReportDays))
+	}
+
+	report, err := kl15migrationservice.BuildReport(from, to)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
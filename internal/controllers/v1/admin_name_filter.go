@@ -0,0 +1,139 @@
+package v1
+
+import (
+	"net/http"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/localizednames"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/namefilter"
+	"bitbucket.org/calmisland/go-server-logs/logger"
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+	"bitbucket.org/calmisland/go-server-requests/apirequests"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// maxNameFilterWordsPerRequest is the maximum number of words added or removed in a single request.
+	maxNameFilterWordsPerRequest = 100
+	// maxNameFilterWordLength is the maximum length of a blocked word, in characters.
+	maxNameFilterWordLength = 64
+)
+
+type adminNameFilterWordsRequestBody struct {
+	Language string   `json:"lang"`
+	Words    []string `json:"words"`
+	// Match is the match mode of the added words, whole words by default
+	Match string `json:"match"`
+}
+
+type adminNameFilterWordsResponseBody struct {
+	Words []*models.NameFilterWord `json:"words"`
+}
+
+// HandleAdminGetNameFilterWords handles requests to list the words blocked in the account names, optionally of a single language.
+func HandleAdminGetNameFilterWords(c echo.Context) error {
+	language := c.QueryParam("lang")
+	if len(language) > 0 {
+		tag, err := localizednames.ParseTag(language)
+		if err != nil {
+			return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("lang"))
+		}
+		language = tag
+	}
+
+	words, err := namefilter.ListWords(language)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	if words == nil {
+		words = []*models.NameFilterWord{}
+	}
+	return c.JSON(http.StatusOK, adminNameFilterWordsResponseBody{
+		Words: words,
+	})
+}
+
+// HandleAdminAddNameFilterWords handles requests to block words in the account names.
+func HandleAdminAddNameFilterWords(c echo.Context) error {
+	adminAccountID := helpers.GetAccountID(c)
+	reqBody, language, words, apiErr := bindNameFilterWords(c)
+	if apiErr != nil {
+		return apirequests.EchoSetClientError(c, apiErr)
+	}
+
+	match := reqBody.Match
+	if len(match) == 0 {
+		match = namefilter.MatchWord
+	} else if !namefilter.IsValidMatch(match) {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("match"))
+	}
+
+	err := namefilter.AddWords(language, words, match, adminAccountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+	globals.NameFilter.Invalidate()
+
+	logger.LogFormat("[ADMIN] Account [%s] blocked [%d] words in the [%s] name filter\n", adminAccountID, len(words), language)
+	return c.NoContent(http.StatusOK)
+}
+
+// HandleAdminRemoveNameFilterWords handles requests to unblock words in the account names.
+func HandleAdminRemoveNameFilterWords(c echo.Context) error {
+	adminAccountID := helpers.GetAccountID(c)
+	_, language, words, apiErr := bindNameFilterWords(c)
+	if apiErr != nil {
+		return apirequests.EchoSetClientError(c, apiErr)
+	}
+
+	err := namefilter.RemoveWords(language, words)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+	globals.NameFilter.Invalidate()
+
+	logger.LogFormat("[ADMIN] Account [%s] unblocked [%d] words in the [%s] name filter\n", adminAccountID, len(words), language)
+	return c.NoContent(http.StatusOK)
+}
+
+// bindNameFilterWords parses and validates the language and the words of a name filter request.
+// The duplicate words are removed.
+func bindNameFilterWords(c echo.Context) (*adminNameFilterWordsRequestBody, string, []string, *apierrors.APIError) {
+	reqBody := new(adminNameFilterWordsRequestBody)
+	err := c.Bind(reqBody)
+	if err != nil {
+		return nil, "", nil, apierrors.ErrorBadRequestBody
+	}
+
+	language, err := localizednames.ParseTag(reqBody.Language)
+	if err != nil {
+		return nil, "", nil, apierrors.ErrorInvalidParameters.WithField("lang")
+	}
+
+	if len(reqBody.Words) == 0 {
+		return nil, "", nil, apierrors.ErrorInvalidParameters.WithField("words")
+	} else if len(reqBody.Words) > maxNameFilterWordsPerRequest {
+		return nil, "", nil, apierrors.ErrorInputTooLong.WithField("words").WithValue(maxNameFilterWordsPerRequest)
+	}
+
+	seen := make(map[string]bool, len(reqBody.Words))
+	words := make([]string, 0, len(reqBody.Words))
+	for _, word := range reqBody.Words {
+		word = defs.SanitizeName(word)
+		if len(word) == 0 {
+			return nil, "", nil, apierrors.ErrorInvalidParameters.WithField("words")
+		} else if defs.NameLength(word) > maxNameFilterWordLength {
+			return nil, "", nil, apierrors.ErrorInputTooLong.WithField("words").WithValue(maxNameFilterWordLength)
+		} else if seen[word] {
+			continue
+		}
+		seen[word] = true
+		words = append(words, word)
+	}
+
+	return reqBody, language, words, nil
+}
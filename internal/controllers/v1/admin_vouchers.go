@@ -0,0 +1,140 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/voucherservice"
+	"bitbucket.org/calmisland/go-server-logs/logger"
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+	"bitbucket.org/calmisland/go-server-requests/apirequests"
+	"bitbucket.org/calmisland/go-server-utils/textutils"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	maxVoucherBatchNameLength = 64
+	maxVoucherItemIDLength    = 64
+	maxVoucherRedemptions     = 100000
+)
+
+type adminVoucherBatchRequestBody struct {
+	Name     string                         `json:"name"`
+	Count    int                            `json:"count"`
+	Passes   map[string]*models.VoucherItem `json:"passes"`
+	Products map[string]*models.VoucherItem `json:"products"`
+	// MaxRedemptions is the number of accounts that can redeem each code, one by default
+	MaxRedemptions int `json:"maxRedemptions"`
+	// ExpirationDate is when the codes expire in epoch milliseconds, they never expire if not set
+	ExpirationDate int64 `json:"expirationTm"`
+}
+
+type adminVoucherBatchResponseBody struct {
+	Batch *models.VoucherBatch `json:"batch"`
+	Codes []string             `json:"codes,omitempty"`
+	// Vouchers are the codes with their redemption count, only returned when getting an existing batch
+	Vouchers []*models.Voucher `json:"vouchers,omitempty"`
+}
+
+// HandleAdminCreateVoucherBatch handles requests to generate a batch of voucher codes.
+func HandleAdminCreateVoucherBatch(c echo.Context) error {
+	adminAccountID := helpers.GetAccountID(c)
+
+	reqBody := new(adminVoucherBatchRequestBody)
+	err := c.Bind(reqBody)
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorBadRequestBody)
+	}
+
+	name := textutils.SanitizeString(reqBody.Name)
+	if len(name) == 0 {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("name"))
+	} else if defs.NameLength(name) > maxVoucherBatchNameLength {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInputTooLong.WithField("name").WithValue(maxVoucherBatchNameLength))
+	}
+
+	if reqBody.Count <= 0 {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("count"))
+	} else if reqBody.Count > voucherservice.MaxBatchSize {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInputTooLong.WithField("count").WithValue(voucherservice.MaxBatchSize))
+	}
+
+	if len(reqBody.Passes) == 0 && len(reqBody.Products) == 0 {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("passes"))
+	}
+	// Passes always expire, while products can be permanent
+	if field := findInvalidVoucherItem("passes", reqBody.Passes, true); len(field) > 0 {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField(field))
+	} else if field := findInvalidVoucherItem("products", reqBody.Products, false); len(field) > 0 {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField(field))
+	}
+
+	maxRedemptions := reqBody.MaxRedemptions
+	if maxRedemptions == 0 {
+		maxRedemptions = 1
+	} else if maxRedemptions < 0 || maxRedemptions > maxVoucherRedemptions {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("maxRedemptions"))
+	}
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	if reqBody.ExpirationDate < 0 || (reqBody.ExpirationDate > 0 && reqBody.ExpirationDate <= now) {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("expirationTm"))
+	}
+
+	batch, codes, err := voucherservice.CreateBatch(voucherservice.NewStore(), &voucherservice.BatchInput{
+		Name:           name,
+		Passes:         reqBody.Passes,
+		Products:       reqBody.Products,
+		MaxRedemptions: maxRedemptions,
+		ExpirationDate: reqBody.ExpirationDate,
+		Count:          reqBody.Count,
+		CreatedBy:      adminAccountID,
+	})
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	formattedCodes := make([]string, len(codes))
+	for i, code := range codes {
+		formattedCodes[i] = voucherservice.FormatCode(code)
+	}
+
+	logger.LogFormat("[ADMIN] Account [%s] generated [%d] voucher codes in batch [%s]\n", adminAccountID, len(codes), batch.BatchID)
+	return c.JSON(http.StatusOK, adminVoucherBatchResponseBody{
+		Batch: batch,
+		Codes: formattedCodes,
+	})
+}
+
+// HandleAdminGetVoucherBatch handles requests to get a batch of voucher codes with their redemption counts.
+func HandleAdminGetVoucherBatch(c echo.Context) error {
+	batchID := c.Param("batchId")
+	if len(batchID) == 0 {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters)
+	}
+
+	batch, vouchers, err := voucherservice.GetBatch(voucherservice.NewStore(), batchID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	} else if batch == nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorItemNotFound)
+	}
+
+	return c.JSON(http.StatusOK, adminVoucherBatchResponseBody{
+		Batch:    batch,
+		Vouchers: vouchers,
+	})
+}
+
+// findInvalidVoucherItem returns the field of the first invalid item, or an empty field if they are all valid.
+func findInvalidVoucherItem(field string, items map[string]*models.VoucherItem, mustExpire bool) string {
+	for itemID, item := range items {
+		if len(itemID) == 0 || len(itemID) > maxVoucherItemIDLength || item == nil || item.DurationDays < 0 || (mustExpire && item.DurationDays == 0) {
+			return field + "." + itemID
+		}
+	}
+	return ""
+}
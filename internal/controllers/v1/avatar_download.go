@@ -0,0 +1,132 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/defaultavatars"
+	"bitbucket.org/calmisland/go-server-account/accountdatabase"
+	"bitbucket.org/calmisland/go-server-cloud/cloudstorage"
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+	"bitbucket.org/calmisland/go-server-requests/apirequests"
+	"github.com/calmisland/go-errors"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultAvatarSize         = 128
+	defaultAvatarCacheControl = "private, max-age=86400"
+
+	// defaultAvatarStyleNone disables the default avatar, the download then fails if there is no avatar.
+	defaultAvatarStyleNone = "none"
+)
+
+var errInvalidAvatarSize = errors.New("Invalid avatar size")
+
+// getAvatarSizeParam returns the requested avatar size, or zero for the uploaded avatar.
+func getAvatarSizeParam(c echo.Context) (int, error) {
+	sizeParam := c.QueryParam("size")
+	if len(sizeParam) == 0 {
+		return 0, nil
+	}
+
+	size, err := strconv.Atoi(sizeParam)
+	if err != nil || !defs.IsValidAvatarSize(size) {
+		return 0, errInvalidAvatarSize
+	}
+	return size, nil
+}
+
+// getProfileAvatarDownloadURL returns the download URL of the avatar of an account, either its preset avatar
+// or its uploaded avatar of the given size. Avatars uploaded before the sizes were generated fall back
+// to the uploaded avatar.
+func getProfileAvatarDownloadURL(profile *models.AccountProfile, size int, input *cloudstorage.GetFileDownloadURLUsingCacheInput) (*cloudstorage.GetFileDownloadURLUsingCacheOutput, error) {
+	if len(profile.AvatarPresetID) > 0 {
+		return globals.AvatarPresetStorage.GetAvatarFileDownloadURL(profile.AvatarPresetID, input)
+	}
+
+	accountID := profile.AccountID
+	if size > 0 {
+		downloadURLResult, err := globals.AvatarSizeStorages[size].GetAvatarFileDownloadURL(accountID, input)
+		if err != nil || downloadURLResult != nil {
+			return downloadURLResult, err
+		}
+	}
+
+	return globals.AvatarStorage.GetAvatarFileDownloadURL(accountID, input)
+}
+
+// handleDefaultAvatar responds with the generated default avatar of an account that has no avatar.
+// The style is picked with the default query parameter and the format with the format query parameter.
+func handleDefaultAvatar(c echo.Context, profile *models.AccountProfile, accInfo *accountdatabase.AccountInfo, size int) error {
+	style := c.QueryParam("default")
+	if style == defaultAvatarStyleNone {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorItemNotFound)
+	}
+
+	format := c.QueryParam("format")
+	if !defaultavatars.IsSupported(style, "") {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("default"))
+	} else if !defaultavatars.IsSupported(style, format) {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("format"))
+	}
+
+	ifModifiedSince, hasIfModifiedSince, err := apirequests.EchoGetHeaderIfModifiedSince(c)
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("If-Modified-Since"))
+	}
+
+	if size == 0 {
+		size = defaultAvatarSize
+	}
+
+	options := defaultavatars.Options{
+		AccountID: profile.AccountID,
+		Initials:  defaultavatars.Initials(accInfo.FullName, accInfo.FirstName, accInfo.LastName),
+		Style:     style,
+		Format:    format,
+		Size:      size,
+	}
+
+	etag := defaultavatars.ETag(options)
+	lastModified := defaultavatars.LastModified(options, time.Unix(0, profile.UpdatedDate*int64(time.Millisecond)))
+	header := c.Response().Header()
+	header.Set("ETag", etag)
+	header.Set("Last-Modified", lastModified.Format(http.TimeFormat))
+	header.Set("Cache-Control", defaultAvatarCacheControl)
+
+	// Skip the rendering if the client can use the cached version.
+	// If-Modified-Since is ignored when If-None-Match is sent, since the ETag is more precise.
+	ifNoneMatch := c.Request().Header.Get("If-None-Match")
+	if len(ifNoneMatch) > 0 {
+		if ifNoneMatchContains(ifNoneMatch, etag) {
+			return c.NoContent(http.StatusNotModified)
+		}
+	} else if hasIfModifiedSince && !lastModified.After(ifModifiedSince) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	avatar, err := defaultavatars.Render(options)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	return c.Blob(http.StatusOK, avatar.ContentType, avatar.Data)
+}
+
+// ifNoneMatchContains checks if an If-None-Match header value matches an ETag.
+func ifNoneMatchContains(ifNoneMatch string, etag string) bool {
+	for _, value := range strings.Split(ifNoneMatch, ",") {
+		value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+		if value == "*" || value == etag {
+			return true
+		}
+	}
+	return false
+}
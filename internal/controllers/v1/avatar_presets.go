@@ -0,0 +1,43 @@
+package v1
+
+import (
+	"net/http"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/avatarpresets"
+	"bitbucket.org/calmisland/go-server-utils/langutils"
+	"github.com/labstack/echo/v4"
+)
+
+type avatarPresetResponseItem struct {
+	ID       string `json:"id"`
+	Category string `json:"category"`
+	Name     string `json:"name"`
+}
+
+type avatarPresetsResponseBody struct {
+	Presets []*avatarPresetResponseItem `json:"presets"`
+}
+
+// HandleGetAvatarPresets handles requests for the preset avatar catalog.
+func HandleGetAvatarPresets(c echo.Context) error {
+	language := c.QueryParam("lang")
+	if !langutils.IsValidLanguageCode(language) {
+		language = defs.DefaultLanguageCode
+	}
+
+	presets := avatarpresets.List()
+	response := &avatarPresetsResponseBody{
+		Presets: make([]*avatarPresetResponseItem, 0, len(presets)),
+	}
+
+	for _, preset := range presets {
+		response.Presets = append(response.Presets, &avatarPresetResponseItem{
+			ID:       preset.ID,
+			Category: preset.Category,
+			Name:     preset.LocalizedName(language),
+		})
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
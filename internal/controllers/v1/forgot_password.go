@@ -6,6 +6,8 @@ import (
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notifications"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notificationservice"
 	"bitbucket.org/calmisland/go-server-account/accountdatabase"
 	"bitbucket.org/calmisland/go-server-account/accounts"
 	"bitbucket.org/calmisland/go-server-logs/logger"
@@ -139,13 +141,13 @@ func HandleForgotPassword(c echo.Context) error {
 		}
 
 		if isUsingEmail {
-			err = sendForgotPasswordEmailFound(userEmail, userLanguage, template)
+			err = sendForgotPasswordEmailFound(accInfo.ID, userEmail, userLanguage, template)
 			if err != nil {
 				return helpers.HandleInternalError(c, err)
 			}
 		} else {
 			userPhoneNumber = accInfo.PhoneNumber
-			err = sendForgotPasswordSMSFound(userPhoneNumber, userLanguage, template)
+			err = sendForgotPasswordSMSFound(accInfo.ID, userPhoneNumber, userLanguage, template)
 			if err != nil {
 				return helpers.HandleInternalError(c, err)
 			}
@@ -166,7 +168,7 @@ func HandleForgotPassword(c echo.Context) error {
 	return nil
 }
 
-func sendForgotPasswordEmailFound(email, language string, template *messagetemplates.PasswordResetTemplate) error {
+func sendForgotPasswordEmailFound(accountID, email, language string, template *messagetemplates.PasswordResetTemplate) error {
 	emailMessage := &messages.Message{
 		MessageType: messages.MessageTypeEmail,
 		Priority:    messages.MessagePriorityEmailHigh,
@@ -174,10 +176,10 @@ func sendForgotPasswordEmailFound(email, language string, template *messagetempl
 		Language:    language,
 		Template:    template,
 	}
-	return globals.MessageSendQueue.EnqueueMessage(emailMessage)
+	return notificationservice.Enqueue(accountID, notifications.CategorySecurity, emailMessage)
 }
 
-func sendForgotPasswordSMSFound(phoneNumber, language string, template *messagetemplates.PasswordResetTemplate) error {
+func sendForgotPasswordSMSFound(accountID, phoneNumber, language string, template *messagetemplates.PasswordResetTemplate) error {
 	emailMessage := &messages.Message{
 		MessageType: messages.MessageTypeSMS,
 		Priority:    messages.MessagePrioritySMSTransactional,
@@ -185,7 +187,7 @@ func sendForgotPasswordSMSFound(phoneNumber, language string, template *messaget
 		Language:    language,
 		Template:    template,
 	}
-	return globals.MessageSendQueue.EnqueueMessage(emailMessage)
+	return notificationservice.Enqueue(accountID, notifications.CategorySecurity, emailMessage)
 }
 
 func sendForgotPasswordEmailNotFound(email, language string) error {
@@ -196,5 +198,5 @@ func sendForgotPasswordEmailNotFound(email, language string) error {
 		Language:    language,
 		Template:    &messagetemplates.PasswordResetFailTemplate{},
 	}
-	return globals.MessageSendQueue.EnqueueMessage(emailMessage)
+	return notificationservice.Enqueue("", notifications.CategorySecurity, emailMessage)
 }
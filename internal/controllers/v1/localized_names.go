@@ -0,0 +1,46 @@
+package v1
+
+import (
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/localizednames"
+	"bitbucket.org/calmisland/go-server-account/accountdatabase"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/text/language"
+)
+
+// getCallerLanguages returns the languages preferred by the caller, from the lang query parameter or the Accept-Language header.
+func getCallerLanguages(c echo.Context) []language.Tag {
+	return localizednames.ParsePreferences(c.QueryParam("lang"), c.Request().Header.Get("Accept-Language"))
+}
+
+// selectAccountNames returns the variant of the account names that best matches the languages of the caller,
+// along with its language tag, which is empty for the default names.
+func selectAccountNames(accInfo *accountdatabase.AccountInfo, profile *models.AccountProfile, callerLanguages []language.Tag) (*models.LocalizedName, string) {
+	variantTags := make([]string, 0, len(profile.LocalizedNames))
+	for tag := range profile.LocalizedNames {
+		variantTags = append(variantTags, tag)
+	}
+
+	tag := localizednames.Select(accInfo.Language, variantTags, callerLanguages)
+	if len(tag) > 0 {
+		return profile.LocalizedNames[tag], tag
+	}
+
+	return &models.LocalizedName{
+		FullName:      accInfo.FullName,
+		FirstName:     accInfo.FirstName,
+		LastName:      accInfo.LastName,
+		PhoneticNames: profile.PhoneticNames,
+	}, ""
+}
+
+func newPhoneticNameInfo(names *models.PhoneticNames) *phoneticNameInfo {
+	if names == nil {
+		return nil
+	}
+	return &phoneticNameInfo{
+		FullName:  names.FullName,
+		FirstName: names.FirstName,
+		LastName:  names.LastName,
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/visibility"
 	"bitbucket.org/calmisland/go-server-cloud/cloudstorage"
 	"bitbucket.org/calmisland/go-server-requests/apierrors"
 	"bitbucket.org/calmisland/go-server-requests/apirequests"
@@ -18,12 +19,19 @@ const (
 )
 
 // HandleOtherAccountAvatarDownload handles other account avatar download requests.
+// The default avatar is returned if the visibility settings of the account don't allow the caller to see its avatar.
 func HandleOtherAccountAvatarDownload(c echo.Context) error {
+	callerAccountID := helpers.GetAccountID(c)
 	accountID := c.Param("accountId")
 	if len(accountID) == 0 {
 		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters)
 	}
 
+	avatarSize, err := getAvatarSizeParam(c)
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("size"))
+	}
+
 	// Gets the If-Modified-Since header value, if there is one
 	ifNoETagMatch, _ := apirequests.EchoGetHeaderIfNoneMatch(c)
 
@@ -44,10 +52,21 @@ func HandleOtherAccountAvatarDownload(c echo.Context) error {
 		return apirequests.EchoSetClientError(c, apierrors.ErrorItemNotFound)
 	}
 
+	profile, relationship, err := getProfileVisibility(callerAccountID, accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	settings := getVisibilitySettings(profile)
+	accInfo = filterAccountNames(accInfo, settings, relationship)
+	if !settings.CanSee(visibility.FieldAvatar, relationship) {
+		return handleDefaultAvatar(c, profile, accInfo, avatarSize)
+	}
+
 	// Get the download URL expiration time
 	urlExpireTime := timeutils.EpochMSNow().Add(avatarDownloadURLExpireDuration)
 
-	downloadURLResult, err := globals.AvatarStorage.GetAvatarFileDownloadURL(accountID, &cloudstorage.GetFileDownloadURLUsingCacheInput{
+	downloadURLResult, err := getProfileAvatarDownloadURL(profile, avatarSize, &cloudstorage.GetFileDownloadURLUsingCacheInput{
 		IfNoETagMatch:   ifNoETagMatch,
 		IfModifiedSince: ifModifiedSinceTime,
 		DownloadInput: &cloudstorage.GetFileDownloadURLInput{
@@ -57,7 +76,7 @@ func HandleOtherAccountAvatarDownload(c echo.Context) error {
 	if err != nil {
 		return helpers.HandleInternalError(c, err)
 	} else if downloadURLResult == nil {
-		return apirequests.EchoSetClientError(c, apierrors.ErrorItemNotFound)
+		return handleDefaultAvatar(c, profile, accInfo, avatarSize)
 	}
 
 	// Skip the redirection if the client can use the cached version
@@ -5,6 +5,7 @@ import (
 
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/visibility"
 	"bitbucket.org/calmisland/go-server-requests/apierrors"
 	"bitbucket.org/calmisland/go-server-requests/apirequests"
 	"github.com/labstack/echo/v4"
@@ -14,11 +15,17 @@ type otherAccountInfoResponseBody struct {
 	FullName  string `json:"fullName,omitempty"`
 	FirstName string `json:"firstName,omitempty"`
 	LastName  string `json:"lastName,omitempty"`
-	Email     string `json:"email"`
+	Email     string `json:"email,omitempty"`
+	// NamesLanguage is the language tag of the name variant, omitted for the default names
+	NamesLanguage string            `json:"namesLang,omitempty"`
+	PhoneticNames *phoneticNameInfo `json:"phoneticNames,omitempty"`
 }
 
 // HandleGetOtherAccountInfo handles retrieving the other account information requests.
+// Only the fields that the visibility settings of the account allow the caller to see are returned,
+// and the names are in the variant that best matches the languages of the caller.
 func HandleGetOtherAccountInfo(c echo.Context) error {
+	callerAccountID := helpers.GetAccountID(c)
 	accountID := c.Param("accountId")
 	if len(accountID) == 0 {
 		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters)
@@ -32,11 +39,28 @@ func HandleGetOtherAccountInfo(c echo.Context) error {
 		return apirequests.EchoSetClientError(c, apierrors.ErrorItemNotFound)
 	}
 
-	response := otherAccountInfoResponseBody{
-		FullName:  accInfo.FullName,
-		FirstName: accInfo.FirstName,
-		LastName:  accInfo.LastName,
-		Email:     accInfo.Email,
+	profile, relationship, err := getProfileVisibility(callerAccountID, accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	// An account the caller can't see anything of is reported like a missing one, not to reveal that it exists
+	settings := getVisibilitySettings(profile)
+	if !settings.CanSee(visibility.FieldNames, relationship) && !settings.CanSee(visibility.FieldEmail, relationship) && !settings.CanSee(visibility.FieldAvatar, relationship) {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorItemNotFound)
+	}
+
+	response := otherAccountInfoResponseBody{}
+	if settings.CanSee(visibility.FieldNames, relationship) {
+		names, namesLanguage := selectAccountNames(accInfo, profile, getCallerLanguages(c))
+		response.FullName = names.FullName
+		response.FirstName = names.FirstName
+		response.LastName = names.LastName
+		response.NamesLanguage = namesLanguage
+		response.PhoneticNames = newPhoneticNameInfo(names.PhoneticNames)
+	}
+	if settings.CanSee(visibility.FieldEmail, relationship) {
+		response.Email = accInfo.Email
 	}
 
 	return c.JSON(http.StatusOK, response)
@@ -0,0 +1,116 @@
+package v1
+
+import (
+	"net/http"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/accountlookup"
+	"bitbucket.org/calmisland/go-server-cloud/cloudstorage"
+	"bitbucket.org/calmisland/go-server-logs/logger"
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+	"bitbucket.org/calmisland/go-server-requests/apirequests"
+	"bitbucket.org/calmisland/go-server-utils/timeutils"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/text/language"
+)
+
+type otherAccountsInfoRequestBody struct {
+	AccountIDs []string `json:"accountIds"`
+	AvatarSize int      `json:"avatarSize"`
+}
+
+type otherAccountsInfoResponseBody struct {
+	Accounts []*batchAccountInfo `json:"accounts"`
+}
+
+type batchAccountInfo struct {
+	AccountID string `json:"accountId"`
+	Status    string `json:"status"`
+	FullName  string `json:"fullName,omitempty"`
+	FirstName string `json:"firstName,omitempty"`
+	LastName  string `json:"lastName,omitempty"`
+	Email     string `json:"email,omitempty"`
+	AvatarURL string `json:"avatarUrl,omitempty"`
+
+	NamesLanguage string            `json:"namesLang,omitempty"`
+	PhoneticNames *phoneticNameInfo `json:"phoneticNames,omitempty"`
+}
+
+// HandleGetOtherAccountsInfo handles retrieving the information of several other accounts in a single request.
+// Every requested account gets a result, in the request order; the accounts that don't exist or that the caller
+// can't see anything of are marked as not found, and the fields that the caller can't see are omitted.
+func HandleGetOtherAccountsInfo(c echo.Context) error {
+	callerAccountID := helpers.GetAccountID(c)
+
+	reqBody := new(otherAccountsInfoRequestBody)
+	err := c.Bind(reqBody)
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorBadRequestBody)
+	}
+
+	avatarSize := reqBody.AvatarSize
+	if avatarSize != 0 && !defs.IsValidAvatarSize(avatarSize) {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("avatarSize"))
+	}
+
+	accounts, err := accountlookup.Lookup(accountlookup.NewStore(), callerAccountID, reqBody.AccountIDs)
+	switch err {
+	case nil:
+	case accountlookup.ErrNoAccountIDs:
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("accountIds"))
+	case accountlookup.ErrTooManyAccountIDs:
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInputTooLong.WithField("accountIds").WithValue(accountlookup.MaxAccountIDs))
+	default:
+		return helpers.HandleInternalError(c, err)
+	}
+
+	callerLanguages := getCallerLanguages(c)
+	urlExpireTime := timeutils.EpochMSNow().Add(avatarDownloadURLExpireDuration)
+	results := make([]*batchAccountInfo, len(accounts))
+	for i, account := range accounts {
+		results[i], err = buildBatchAccountInfo(account, avatarSize, callerLanguages, &cloudstorage.GetFileDownloadURLUsingCacheInput{
+			DownloadInput: &cloudstorage.GetFileDownloadURLInput{
+				Expires: urlExpireTime.Time(),
+			},
+		})
+		if err != nil {
+			return helpers.HandleInternalError(c, err)
+		}
+	}
+
+	logger.LogFormat("[OTHERACCOUNTSINFO] Account [%s] looked up [%d] accounts\n", callerAccountID, len(accounts))
+	return c.JSON(http.StatusOK, otherAccountsInfoResponseBody{
+		Accounts: results,
+	})
+}
+
+func buildBatchAccountInfo(account *accountlookup.Account, avatarSize int, callerLanguages []language.Tag, input *cloudstorage.GetFileDownloadURLUsingCacheInput) (*batchAccountInfo, error) {
+	result := &batchAccountInfo{
+		AccountID: account.AccountID,
+		Status:    account.Status,
+	}
+	if account.Status != accountlookup.StatusFound {
+		return result, nil
+	}
+
+	if account.CanSeeNames {
+		names, namesLanguage := selectAccountNames(account.Info, account.Profile, callerLanguages)
+		result.FullName = names.FullName
+		result.FirstName = names.FirstName
+		result.LastName = names.LastName
+		result.NamesLanguage = namesLanguage
+		result.PhoneticNames = newPhoneticNameInfo(names.PhoneticNames)
+	}
+	result.Email = account.Info.Email
+	if account.CanSeeAvatar {
+		downloadURLResult, err := getProfileAvatarDownloadURL(account.Profile, avatarSize, input)
+		if err != nil {
+			return nil, err
+		} else if downloadURLResult != nil {
+			result.AvatarURL = downloadURLResult.DownloadOutput.URL
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,129 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/accountdeletionservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/attemptlimiter"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/parentalconsentservice"
+	"bitbucket.org/calmisland/go-server-logs/logger"
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+	"bitbucket.org/calmisland/go-server-requests/apirequests"
+	"github.com/labstack/echo/v4"
+)
+
+var parentalConsentResendLimit = attemptlimiter.New("parentalconsent-resend", defs.ParentalConsentMaxResendsPerAccount, defs.ParentalConsentResendWindowMinutes*time.Minute)
+
+type parentalConsentRequestBody struct {
+	ConsentID string `json:"consentId"`
+	Code      string `json:"code"`
+}
+
+// HandleGrantParentalConsent handles requests from parents to consent to the account of their child, with the code sent to their email.
+func HandleGrantParentalConsent(c echo.Context) error {
+	reqBody := new(parentalConsentRequestBody)
+	err := c.Bind(reqBody)
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorBadRequestBody)
+	} else if len(reqBody.ConsentID) == 0 || len(reqBody.Code) == 0 {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters)
+	}
+
+	consent, err := parentalconsentservice.GrantConsent(parentalconsentservice.NewStore(), reqBody.ConsentID, reqBody.Code, getParentalConsentOrigin(c))
+	if err != nil {
+		return handleParentalConsentError(c, err)
+	}
+
+	logger.LogFormat("[PARENTALCONSENT] The parent consented to account [%s] from IP [%s]\n", consent.AccountID, c.RealIP())
+	return c.NoContent(http.StatusOK)
+}
+
+// HandleWithdrawParentalConsent handles requests from parents to withdraw or refuse their consent, with the code sent to their email.
+// The account of the child is deleted.
+func HandleWithdrawParentalConsent(c echo.Context) error {
+	reqBody := new(parentalConsentRequestBody)
+	err := c.Bind(reqBody)
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorBadRequestBody)
+	} else if len(reqBody.ConsentID) == 0 || len(reqBody.Code) == 0 {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters)
+	}
+
+	consent, err := parentalconsentservice.WithdrawConsent(parentalconsentservice.NewStore(), reqBody.ConsentID, reqBody.Code, getParentalConsentOrigin(c))
+	if err != nil {
+		return handleParentalConsentError(c, err)
+	}
+
+	err = accountdeletionservice.DeleteAccount(accountdeletionservice.NewStore(), consent.AccountID)
+	if err != nil && err != accountdeletionservice.ErrAccountNotFound {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	logger.LogFormat("[PARENTALCONSENT] The parent withdrew the consent to account [%s] from IP [%s], the account was deleted\n", consent.AccountID, c.RealIP())
+	return c.NoContent(http.StatusOK)
+}
+
+// HandleResendSelfParentalConsent handles requests from child accounts waiting for the consent of their parent
+// to send the consent request again, to the same parent, once the previous one expired or was lost.
+func HandleResendSelfParentalConsent(c echo.Context) error {
+	accountID := helpers.GetAccountID(c)
+	profile, err := helpers.GetProfile(c)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	} else if profile.ParentalConsentStatus != models.ParentalConsentStatusPending {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorVerificationNotFound)
+	}
+
+	isBlocked, err := parentalConsentResendLimit.IsBlocked(accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	} else if isBlocked {
+		logger.LogFormat("[PARENTALCONSENT] A blocked consent request resend for account [%s] from IP [%s]\n", accountID, c.RealIP())
+		return apirequests.EchoSetClientError(c, helpers.ErrorTooManyAttempts)
+	}
+
+	accInfo, err := globals.AccountDatabase.GetAccountInfo(accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	} else if accInfo == nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorAccountNotFound)
+	}
+
+	// Every resend counts toward the limit, since each one sends an email to the parent
+	err = parentalConsentResendLimit.RecordFailure(accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	_, err = parentalconsentservice.ResendConsent(parentalconsentservice.NewStore(), accountID, accInfo.Language, getParentalConsentOrigin(c))
+	if err != nil {
+		return handleParentalConsentError(c, err)
+	}
+
+	logger.LogFormat("[PARENTALCONSENT] The consent request of account [%s] was resent from IP [%s]\n", accountID, c.RealIP())
+	return c.NoContent(http.StatusOK)
+}
+
+func handleParentalConsentError(c echo.Context, err error) error {
+	switch err {
+	case parentalconsentservice.ErrConsentNotFound:
+		return apirequests.EchoSetClientError(c, apierrors.ErrorVerificationNotFound)
+	case parentalconsentservice.ErrInvalidConsentCode:
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidVerificationCode)
+	default:
+		return helpers.HandleInternalError(c, err)
+	}
+}
+
+// getParentalConsentOrigin returns where the request is made from, recorded with the parental consent events.
+func getParentalConsentOrigin(c echo.Context) parentalconsentservice.Origin {
+	return parentalconsentservice.Origin{
+		IPAddress: c.RealIP(),
+		UserAgent: c.Request().UserAgent(),
+	}
+}
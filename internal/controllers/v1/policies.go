@@ -0,0 +1,22 @@
+package v1
+
+import (
+	"net/http"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/policies"
+	"github.com/labstack/echo/v4"
+)
+
+type policiesResponseBody struct {
+	Versions policies.Versions `json:"versions"`
+}
+
+// HandleGetPolicies handles requests for the policy versions that the accounts must accept.
+func HandleGetPolicies(c echo.Context) error {
+	response := policiesResponseBody{
+		Versions: globals.RequiredPolicyVersions,
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,39 @@
+package v1
+
+import (
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/visibility"
+	"bitbucket.org/calmisland/go-server-account/accountdatabase"
+)
+
+// getProfileVisibility returns the profile of an account, with its visibility settings, and the relationship of the caller to it.
+func getProfileVisibility(callerAccountID string, accountID string) (*models.AccountProfile, visibility.Relationship, error) {
+	relationship, err := globals.RelationshipResolver.Resolve(callerAccountID, accountID)
+	if err != nil {
+		return nil, visibility.RelationshipNone, err
+	}
+
+	profile, err := profileservice.GetProfile(accountID)
+	if err != nil {
+		return nil, visibility.RelationshipNone, err
+	}
+
+	return profile, relationship, nil
+}
+
+// getVisibilitySettings returns the visibility settings of a profile, the profiles of children being at most visible to their organisations.
+func getVisibilitySettings(profile *models.AccountProfile) visibility.Settings {
+	return profileservice.GetVisibilitySettings(profile, time.Now())
+}
+
+// filterAccountNames returns the account information without the names if the caller can't see them.
+func filterAccountNames(accInfo *accountdatabase.AccountInfo, settings visibility.Settings, relationship visibility.Relationship) *accountdatabase.AccountInfo {
+	if settings.CanSee(visibility.FieldNames, relationship) {
+		return accInfo
+	}
+	return &accountdatabase.AccountInfo{}
+}
@@ -7,6 +7,8 @@ import (
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notifications"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notificationservice"
 	"bitbucket.org/calmisland/go-server-account/accountdatabase"
 	"bitbucket.org/calmisland/go-server-account/accounts"
 	"bitbucket.org/calmisland/go-server-logs/logger"
@@ -80,7 +82,7 @@ func HandleResendPhoneNumberVerification(c echo.Context) error {
 			Code: verificationCode,
 		},
 	}
-	err = globals.MessageSendQueue.EnqueueMessage(smsMessage)
+	err = notificationservice.Enqueue(accountID, notifications.CategorySecurity, smsMessage)
 	if err != nil {
 		return helpers.HandleInternalError(c, err)
 	}
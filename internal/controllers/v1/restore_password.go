@@ -7,6 +7,8 @@ import (
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notifications"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notificationservice"
 	"bitbucket.org/calmisland/go-server-account/accountdatabase"
 	"bitbucket.org/calmisland/go-server-account/accounts"
 	"bitbucket.org/calmisland/go-server-logs/logger"
@@ -133,6 +135,18 @@ func HandleRestorePassword(c echo.Context) error {
 		if err != nil {
 			return helpers.HandleInternalError(c, err)
 		}
+
+		// The imported accounts receive their invite code on their only contact, which is verified by using it
+		if !accounts.IsAccountVerified(verificationInfo.Flags) {
+			verifiedFlags := int32(accounts.IsAccountVerifiedFlag | accounts.IsAccountPhoneNumberVerifiedFlag)
+			if len(verificationInfo.Email) > 0 {
+				verifiedFlags = int32(accounts.IsAccountVerifiedFlag | accounts.IsAccountEmailVerifiedFlag)
+			}
+			err = globals.AccountDatabase.SetAccountFlags(accountID, verifiedFlags)
+			if err != nil {
+				return helpers.HandleInternalError(c, err)
+			}
+		}
 	}
 
 	userEmail := verificationInfo.Email
@@ -153,7 +167,7 @@ func HandleRestorePassword(c echo.Context) error {
 			Language:    userLanguage,
 			Template:    &messagetemplates.ChangedPasswordTemplate{},
 		}
-		err = globals.MessageSendQueue.EnqueueMessage(emailMessage)
+		err = notificationservice.Enqueue(accountID, notifications.CategorySecurity, emailMessage)
 		if err != nil {
 			return helpers.HandleInternalError(c, err)
 		}
@@ -3,15 +3,30 @@ package v1
 import (
 	"net/http"
 
-	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/avatarservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/utils"
 	"github.com/labstack/echo/v4"
 )
 
 // HandleSelfAccountAvatarDelete handles avatar image delete requests.
 func HandleSelfAccountAvatarDelete(c echo.Context) error {
 	accountID := helpers.GetAccountID(c)
-	err := globals.AvatarStorage.DeleteAvatarFile(accountID)
+
+	profile, err := profileservice.GetProfile(accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	} else if len(profile.ParentID) > 0 {
+		return utils.EchoHandleHTTPError(http.StatusForbidden, errManagedByParent)
+	}
+
+	err = avatarservice.DeleteAvatar(accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	err = profileservice.RemoveAvatarPreset(accountID)
 	if err != nil {
 		return helpers.HandleInternalError(c, err)
 	}
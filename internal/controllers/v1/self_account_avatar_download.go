@@ -6,6 +6,7 @@ import (
 
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
 	"bitbucket.org/calmisland/go-server-cloud/cloudstorage"
 	"bitbucket.org/calmisland/go-server-requests/apierrors"
 	"bitbucket.org/calmisland/go-server-requests/apirequests"
@@ -16,6 +17,12 @@ import (
 // HandleSelfAccountAvatarDownload handles self account avatar download requests.
 func HandleSelfAccountAvatarDownload(c echo.Context) error {
 	accountID := helpers.GetAccountID(c)
+
+	avatarSize, err := getAvatarSizeParam(c)
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("size"))
+	}
+
 	// Gets the If-Modified-Since header value, if there is one
 	ifNoETagMatch, _ := apirequests.EchoGetHeaderIfNoneMatch(c)
 
@@ -31,7 +38,12 @@ func HandleSelfAccountAvatarDownload(c echo.Context) error {
 	// Get the download URL expiration time
 	urlExpireTime := timeutils.EpochMSNow().Add(avatarDownloadURLExpireDuration)
 
-	downloadURLResult, err := globals.AvatarStorage.GetAvatarFileDownloadURL(accountID, &cloudstorage.GetFileDownloadURLUsingCacheInput{
+	profile, err := profileservice.GetProfile(accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	downloadURLResult, err := getProfileAvatarDownloadURL(profile, avatarSize, &cloudstorage.GetFileDownloadURLUsingCacheInput{
 		IfNoETagMatch:   ifNoETagMatch,
 		IfModifiedSince: ifModifiedSinceTime,
 		DownloadInput: &cloudstorage.GetFileDownloadURLInput{
@@ -41,7 +53,13 @@ func HandleSelfAccountAvatarDownload(c echo.Context) error {
 	if err != nil {
 		return helpers.HandleInternalError(c, err)
 	} else if downloadURLResult == nil {
-		return apirequests.EchoSetClientError(c, apierrors.ErrorItemNotFound)
+		accInfo, err := globals.AccountDatabase.GetAccountInfo(accountID)
+		if err != nil {
+			return helpers.HandleInternalError(c, err)
+		} else if accInfo == nil {
+			return apirequests.EchoSetClientError(c, apierrors.ErrorItemNotFound)
+		}
+		return handleDefaultAvatar(c, profile, accInfo, avatarSize)
 	}
 
 	// Skip the redirection if the client can use the cached version
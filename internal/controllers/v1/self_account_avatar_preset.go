@@ -0,0 +1,55 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/avatarpresets"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/utils"
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+	"bitbucket.org/calmisland/go-server-requests/apirequests"
+	"github.com/labstack/echo/v4"
+)
+
+// errChildAvatarUpload is returned when a child account tries to upload an avatar.
+var errChildAvatarUpload = errors.New("Child accounts can only use preset avatars")
+
+type avatarPresetRequestBody struct {
+	PresetID string `json:"presetId"`
+}
+
+// HandleSelfAvatarPresetSelect handles requests to use a preset avatar.
+func HandleSelfAvatarPresetSelect(c echo.Context) error {
+	accountID := helpers.GetAccountID(c)
+
+	profile, err := profileservice.GetProfile(accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	} else if len(profile.ParentID) > 0 {
+		return utils.EchoHandleHTTPError(http.StatusForbidden, errManagedByParent)
+	}
+
+	return setAvatarPreset(c, accountID)
+}
+
+// setAvatarPreset sets the preset avatar of an account from the request body.
+func setAvatarPreset(c echo.Context, accountID string) error {
+	reqBody := new(avatarPresetRequestBody)
+	err := c.Bind(reqBody)
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorBadRequestBody)
+	}
+
+	if _, exists := avatarpresets.Get(reqBody.PresetID); !exists {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("presetId"))
+	}
+
+	err = profileservice.SetAvatarPreset(accountID, reqBody.PresetID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
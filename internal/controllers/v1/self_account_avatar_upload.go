@@ -8,6 +8,9 @@ import (
 
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/avatarservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/utils"
 	"bitbucket.org/calmisland/go-server-cloud/cloudstorage"
 	"bitbucket.org/calmisland/go-server-requests/apierrors"
 	"bitbucket.org/calmisland/go-server-requests/apirequests"
@@ -29,9 +32,7 @@ type avatarUploadResponseBody struct {
 
 const (
 	contentLengthHeaderName = "Content-Length"
-	imageContentType        = "image/jpeg"
 
-	avatarMaxSize                 = 1 * 1024 * 1024 // 1 MB
 	avatarExpireDuration          = 24 * time.Hour
 	avatarUploadURLExpireDuration = 30 * time.Minute
 
@@ -43,8 +44,15 @@ const (
 func HandleSelfAvatarUpload(c echo.Context) error {
 	accountID := helpers.GetAccountID(c)
 
+	profile, err := profileservice.GetProfile(accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	} else if profileservice.IsChildAccount(profile, time.Now()) {
+		return utils.EchoHandleHTTPError(http.StatusForbidden, errChildAvatarUpload)
+	}
+
 	reqBody := new(avatarUploadRequestBody)
-	err := c.Bind(reqBody)
+	err = c.Bind(reqBody)
 	if err != nil {
 		return apirequests.EchoSetClientError(c, apierrors.ErrorBadRequestBody)
 	}
@@ -55,12 +63,12 @@ func HandleSelfAvatarUpload(c echo.Context) error {
 		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("contentSha256"))
 	} else if reqBody.ContentLength <= 0 {
 		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("contentLength"))
-	} else if reqBody.ContentLength > avatarMaxSize {
-		return apirequests.EchoSetClientError(c, apierrors.ErrorInputTooLong.WithField("contentLength").WithValue(avatarMaxSize))
+	} else if reqBody.ContentLength > avatarservice.MaxUploadSize {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInputTooLong.WithField("contentLength").WithValue(avatarservice.MaxUploadSize))
 	}
 
 	// Validate the content type
-	if reqBody.ContentType != imageContentType {
+	if !avatarservice.IsSupportedContentType(reqBody.ContentType) {
 		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("contentType"))
 	}
 
@@ -78,7 +86,8 @@ func HandleSelfAvatarUpload(c echo.Context) error {
 	// Get the upload URL expiration time
 	urlExpireTime := timeutils.EpochMSNow().Add(avatarUploadURLExpireDuration)
 
-	uploadURLResult, err := globals.AvatarStorage.GetAvatarFileUploadURL(accountID, &cloudstorage.GetFileUploadURLInput{
+	// The avatar is only published once moderated by the upload completion request
+	uploadURLResult, err := globals.AvatarQuarantineStorage.GetAvatarFileUploadURL(accountID, &cloudstorage.GetFileUploadURLInput{
 		ContentLength:  &reqBody.ContentLength,
 		ContentType:    &reqBody.ContentType,
 		ContentExpires: &avatarExpireTime,
@@ -0,0 +1,74 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/avatarservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/utils"
+	"bitbucket.org/calmisland/go-server-logs/logger"
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+	"bitbucket.org/calmisland/go-server-requests/apirequests"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	avatarStatusPublished = "published"
+	avatarStatusRejected  = "rejected"
+)
+
+type avatarUploadCompleteResponseBody struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+	Sizes  []int  `json:"sizes,omitempty"`
+}
+
+// HandleSelfAvatarUploadComplete handles the requests sent once the avatar has been uploaded to the upload URL.
+// The avatar is moderated before being published, the response tells the user if it was rejected.
+func HandleSelfAvatarUploadComplete(c echo.Context) error {
+	accountID := helpers.GetAccountID(c)
+
+	profile, err := profileservice.GetProfile(accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	} else if profileservice.IsChildAccount(profile, time.Now()) {
+		return utils.EchoHandleHTTPError(http.StatusForbidden, errChildAvatarUpload)
+	}
+
+	decision, err := avatarservice.CompleteUpload(accountID)
+	switch err {
+	case nil:
+	case avatarservice.ErrAvatarNotFound:
+		return apirequests.EchoSetClientError(c, apierrors.ErrorItemNotFound)
+	case avatarservice.ErrInvalidImage:
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInputInvalidFormat.WithField("contentType"))
+	case avatarservice.ErrImageTooLarge:
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInputTooLong.WithField("contentLength"))
+	default:
+		return helpers.HandleInternalError(c, err)
+	}
+
+	if !decision.Approved {
+		logger.LogFormat("[AVATAR] The avatar of account [%s] was rejected: %s\n", accountID, decision.Reason)
+		return c.JSON(http.StatusOK, &avatarUploadCompleteResponseBody{
+			Status: avatarStatusRejected,
+			Reason: decision.Reason,
+		})
+	}
+
+	// The uploaded avatar replaces the preset one
+	if len(profile.AvatarPresetID) > 0 {
+		err = profileservice.RemoveAvatarPreset(accountID)
+		if err != nil {
+			return helpers.HandleInternalError(c, err)
+		}
+	}
+
+	return c.JSON(http.StatusOK, &avatarUploadCompleteResponseBody{
+		Status: avatarStatusPublished,
+		Sizes:  defs.AvatarSizes,
+	})
+}
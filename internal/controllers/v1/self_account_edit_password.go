@@ -7,6 +7,8 @@ import (
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notifications"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notificationservice"
 	"bitbucket.org/calmisland/go-server-account/accountdatabase"
 	"bitbucket.org/calmisland/go-server-account/accounts"
 	"bitbucket.org/calmisland/go-server-logs/logger"
@@ -106,7 +108,7 @@ func HandleEditSelfAccountPassword(c echo.Context) error {
 			Language:    userLanguage,
 			Template:    &messagetemplates.ChangedPasswordTemplate{},
 		}
-		err = globals.MessageSendQueue.EnqueueMessage(emailMessage)
+		err = notificationservice.Enqueue(accountID, notifications.CategorySecurity, emailMessage)
 		if err != nil {
 			return helpers.HandleInternalError(c, err)
 		}
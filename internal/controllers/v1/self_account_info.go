@@ -2,9 +2,14 @@ package v1
 
 import (
 	"net/http"
+	"time"
 
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/childservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/visibility"
 	"bitbucket.org/calmisland/go-server-requests/apierrors"
 	"bitbucket.org/calmisland/go-server-requests/apirequests"
 	"github.com/labstack/echo/v4"
@@ -17,6 +22,38 @@ type selfAccountInfoResponseBody struct {
 	Email     string `json:"email"`
 	Country   string `json:"country"`
 	Language  string `json:"lang"`
+
+	DisplayName   string            `json:"displayName,omitempty"`
+	BirthYear     int               `json:"birthYear,omitempty"`
+	BirthMonth    int               `json:"birthMonth,omitempty"`
+	Timezone      string            `json:"timezone"`
+	PhoneticNames *phoneticNameInfo `json:"phoneticNames,omitempty"`
+
+	LocalizedNames map[string]*localizedNameInfo `json:"localizedNames,omitempty"`
+
+	Visibility visibility.Settings `json:"visibility"`
+
+	// ParentID is the account managing the signed in child profile
+	ParentID string `json:"parentId,omitempty"`
+	// ChildIDs are the child profiles managed by the signed in account
+	ChildIDs []string `json:"childIds,omitempty"`
+	// AgeBand is the age band of the account, empty if its birth date is unknown
+	AgeBand string `json:"ageBand,omitempty"`
+	// ParentalConsent is the parental consent status of a child account, the account being restricted while it's pending
+	ParentalConsent string `json:"parentalConsent,omitempty"`
+}
+
+type localizedNameInfo struct {
+	FullName      string            `json:"fullName,omitempty"`
+	FirstName     string            `json:"firstName,omitempty"`
+	LastName      string            `json:"lastName,omitempty"`
+	PhoneticNames *phoneticNameInfo `json:"phoneticNames,omitempty"`
+}
+
+type phoneticNameInfo struct {
+	FullName  string `json:"fullName,omitempty"`
+	FirstName string `json:"firstName,omitempty"`
+	LastName  string `json:"lastName,omitempty"`
 }
 
 // HandleGetSelfAccountInfo handles retrieving the signed in account information requests.
@@ -31,6 +68,11 @@ func HandleGetSelfAccountInfo(c echo.Context) error {
 		return apirequests.EchoSetClientError(c, apierrors.ErrorItemNotFound)
 	}
 
+	profile, err := profileservice.GetProfile(accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
 	response := selfAccountInfoResponseBody{
 		FullName:  accInfo.FullName,
 		FirstName: accInfo.FirstName,
@@ -38,6 +80,45 @@ func HandleGetSelfAccountInfo(c echo.Context) error {
 		Email:     accInfo.Email,
 		Country:   accInfo.Country,
 		Language:  accInfo.Language,
+
+		DisplayName: profile.DisplayName,
+		BirthYear:   profile.BirthYear,
+		BirthMonth:  profile.BirthMonth,
+		Timezone:    profile.Timezone,
+
+		Visibility: getVisibilitySettings(profile).Effective(),
+
+		ParentID:        profile.ParentID,
+		ParentalConsent: profile.ParentalConsentStatus,
+		AgeBand:         profileservice.GetAgeBand(profile, time.Now()),
+	}
+
+	if len(profile.ParentID) == 0 {
+		response.ChildIDs, err = childservice.GetChildIDs(childservice.NewStore(), accountID)
+		if err != nil {
+			return helpers.HandleInternalError(c, err)
+		}
+	}
+
+	// The country can be corrected by the user, the timezone then defaults to the one of the country
+	if len(profile.Country) > 0 {
+		response.Country = profile.Country
+	}
+	if len(response.Timezone) == 0 {
+		response.Timezone = defs.GetCountryDefaultTimezone(response.Country)
+	}
+
+	response.PhoneticNames = newPhoneticNameInfo(profile.PhoneticNames)
+	if len(profile.LocalizedNames) > 0 {
+		response.LocalizedNames = make(map[string]*localizedNameInfo, len(profile.LocalizedNames))
+		for tag, names := range profile.LocalizedNames {
+			response.LocalizedNames[tag] = &localizedNameInfo{
+				FullName:      names.FullName,
+				FirstName:     names.FirstName,
+				LastName:      names.LastName,
+				PhoneticNames: newPhoneticNameInfo(names.PhoneticNames),
+			}
+		}
 	}
 
 	return c.JSON(http.StatusOK, response)
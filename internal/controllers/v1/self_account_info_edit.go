@@ -1,10 +1,20 @@
 package v1
 
 import (
+	"errors"
 	"net/http"
+	"sort"
+	"strings"
+	"time"
 
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/localizednames"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/visibility"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/utils"
 	"bitbucket.org/calmisland/go-server-account/accountdatabase"
 	"bitbucket.org/calmisland/go-server-logs/logger"
 	"bitbucket.org/calmisland/go-server-requests/apierrors"
@@ -15,9 +25,29 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
+// errAgeManagedByParent is returned when a child account tries to change the birth date or country of its age band.
+var errAgeManagedByParent = errors.New("The birth date and country of a child account can only be changed by a parent or an admin")
+
 type editSelfAccountInfoRequestBody struct {
 	Language *string       `json:"lang"`
 	Names    *editNameInfo `json:"names"`
+	// Visibility sets the visibility level of the profile fields, the fields that are not set are left unchanged
+	Visibility map[string]string `json:"visibility"`
+
+	// The profile fields, which are removed when set to empty values
+	DisplayName   *string       `json:"displayName"`
+	BirthYear     *int          `json:"birthYear"`
+	BirthMonth    *int          `json:"birthMonth"`
+	Timezone      *string       `json:"timezone"`
+	Country       *string       `json:"country"`
+	PhoneticNames *editNameInfo `json:"phoneticNames"`
+	// LocalizedNames sets the name variants by language tag, a null variant is removed
+	LocalizedNames map[string]*editLocalizedName `json:"localizedNames"`
+}
+
+type editLocalizedName struct {
+	editNameInfo
+	PhoneticNames *editNameInfo `json:"phoneticNames"`
 }
 
 type editNameInfo struct {
@@ -26,14 +56,25 @@ type editNameInfo struct {
 	LastName  string `json:"lastName"`
 }
 
-const (
-	maxFullNameLength = 64
-	maxPartNameLength = 32
-)
-
 // HandleEditSelfAccountInfo handles requests for editing account information for the signed in account.
 func HandleEditSelfAccountInfo(c echo.Context) error {
 	accountID := helpers.GetAccountID(c)
+
+	profile, err := helpers.GetProfile(c)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	} else if len(profile.ParentID) > 0 {
+		return utils.EchoHandleHTTPError(http.StatusForbidden, errManagedByParent)
+	}
+
+	// A child can't leave the child age band by changing its own birth date or country
+	canEditAge := !profileservice.IsChildAccount(profile, time.Now())
+	return editAccountInfo(c, accountID, canEditAge)
+}
+
+// editAccountInfo edits the account information of an account from the request body.
+// The birth date and the country, which set the age band, are only edited if canEditAge is set.
+func editAccountInfo(c echo.Context, accountID string, canEditAge bool) error {
 	// Parse the request body
 	reqBody := new(editSelfAccountInfoRequestBody)
 	err := c.Bind(reqBody)
@@ -53,37 +94,270 @@ func HandleEditSelfAccountInfo(c echo.Context) error {
 	}
 
 	var editNameInfo *accountdatabase.AccountNameInfo
-	names := reqBody.Names
-	if names != nil {
-		fullName := textutils.SanitizeString(names.FullName)
-		firstName := textutils.SanitizeString(names.FirstName)
-		lastName := textutils.SanitizeString(names.LastName)
-		if len(fullName) == 0 && len(firstName) == 0 && len(lastName) == 0 { // At least one name must be specified
-			return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters)
-		} else if len(fullName) > maxFullNameLength {
-			return apirequests.EchoSetClientError(c, apierrors.ErrorInputTooLong.WithField("names.fullName").WithValue(maxFullNameLength))
-		} else if len(firstName) > maxPartNameLength {
-			return apirequests.EchoSetClientError(c, apierrors.ErrorInputTooLong.WithField("names.firstName").WithValue(maxPartNameLength))
-		} else if len(lastName) > maxPartNameLength {
-			return apirequests.EchoSetClientError(c, apierrors.ErrorInputTooLong.WithField("names.lastName").WithValue(maxPartNameLength))
-		}
-
-		editNameInfo = &accountdatabase.AccountNameInfo{
-			FullName:  &fullName,
-			FirstName: &firstName,
-			LastName:  &lastName,
+	if reqBody.Names != nil {
+		var apiErr *apierrors.APIError
+		editNameInfo, apiErr = getEditNameInfo(reqBody.Names)
+		if apiErr != nil {
+			return apirequests.EchoSetClientError(c, apiErr)
 		}
 	}
 
-	err = globals.AccountDatabase.EditAccount(accountID, &accountdatabase.AccountEditInfo{
-		Language: language,
-		Names:    editNameInfo,
-	})
+	profileEdit, apiErr := getProfileEdit(reqBody)
+	if apiErr != nil {
+		return apirequests.EchoSetClientError(c, apiErr)
+	} else if !canEditAge && profileEdit != nil && (profileEdit.BirthYear != nil || profileEdit.Country != nil) {
+		logger.LogFormat("[EDITACCOUNTINFO] A birth date or country edit was rejected for child account [%s]\n", accountID)
+		return utils.EchoHandleHTTPError(http.StatusForbidden, errAgeManagedByParent)
+	}
 
+	blockedField, err := findBlockedName(editNameInfo, profileEdit)
 	if err != nil {
 		return helpers.HandleInternalError(c, err)
+	} else if len(blockedField) > 0 {
+		logger.LogFormat("[EDITACCOUNTINFO] A blocked name was rejected for account [%s] in [%s]\n", accountID, blockedField)
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField(blockedField).WithMessage("The name is not allowed"))
+	}
+
+	if apiErr := checkVisibility(reqBody.Visibility); apiErr != nil {
+		return apirequests.EchoSetClientError(c, apiErr)
+	}
+
+	if language != nil || editNameInfo != nil {
+		err = globals.AccountDatabase.EditAccount(accountID, &accountdatabase.AccountEditInfo{
+			Language: language,
+			Names:    editNameInfo,
+		})
+
+		if err != nil {
+			return helpers.HandleInternalError(c, err)
+		}
+	}
+
+	if editNameInfo != nil {
+		err = profileservice.MarkNamesEdited(accountID)
+		if err != nil {
+			return helpers.HandleInternalError(c, err)
+		}
+	}
+
+	if profileEdit != nil {
+		err = profileservice.EditProfile(accountID, profileEdit)
+		if err == profileservice.ErrTooManyLocalizedNames {
+			return apirequests.EchoSetClientError(c, apierrors.ErrorInputTooLong.WithField("localizedNames").WithValue(defs.MaxLocalizedNames))
+		} else if err != nil {
+			return helpers.HandleInternalError(c, err)
+		}
+
+		if profileEdit.BirthYear != nil || profileEdit.Country != nil {
+			err = profileservice.RefreshAgeBand(accountID)
+			if err != nil {
+				return helpers.HandleInternalError(c, err)
+			}
+		}
+	}
+
+	if len(reqBody.Visibility) > 0 {
+		err = profileservice.SetVisibility(accountID, reqBody.Visibility)
+		if err != nil {
+			return helpers.HandleInternalError(c, err)
+		}
 	}
 
 	logger.LogFormat("[EDITACCOUNTINFO] A successful edit account request for account [%s]\n", accountID)
 	return c.NoContent(http.StatusOK)
 }
+
+// getEditNameInfo validates the account names of the request.
+func getEditNameInfo(names *editNameInfo) (*accountdatabase.AccountNameInfo, *apierrors.APIError) {
+	fullName := defs.SanitizeName(names.FullName)
+	firstName := defs.SanitizeName(names.FirstName)
+	lastName := defs.SanitizeName(names.LastName)
+	if len(fullName) == 0 && len(firstName) == 0 && len(lastName) == 0 { // At least one name must be specified
+		return nil, apierrors.ErrorInvalidParameters
+	} else if field, maxLength := defs.CheckNameLengths(fullName, firstName, lastName); len(field) > 0 {
+		return nil, apierrors.ErrorInputTooLong.WithField("names." + field).WithValue(int64(maxLength))
+	}
+
+	return &accountdatabase.AccountNameInfo{
+		FullName:  &fullName,
+		FirstName: &firstName,
+		LastName:  &lastName,
+	}, nil
+}
+
+// checkVisibility validates the visibility levels of the request.
+func checkVisibility(levels map[string]string) *apierrors.APIError {
+	for field, level := range levels {
+		if !visibility.IsValidField(field) || !visibility.IsValidLevel(level) {
+			return apierrors.ErrorInvalidParameters.WithField("visibility." + field)
+		}
+	}
+	return nil
+}
+
+// getProfileEdit validates the profile fields of the request, returning nil if none is set.
+func getProfileEdit(reqBody *editSelfAccountInfoRequestBody) (*profileservice.ProfileEdit, *apierrors.APIError) {
+	edit := &profileservice.ProfileEdit{}
+	hasChanges := false
+
+	if reqBody.DisplayName != nil {
+		displayName := defs.SanitizeName(*reqBody.DisplayName)
+		if defs.NameLength(displayName) > defs.MaxDisplayNameLength {
+			return nil, apierrors.ErrorInputTooLong.WithField("displayName").WithValue(defs.MaxDisplayNameLength)
+		}
+		edit.DisplayName = &displayName
+		hasChanges = true
+	}
+
+	if reqBody.BirthYear != nil {
+		birthYear := *reqBody.BirthYear
+		if reqBody.BirthMonth != nil {
+			edit.BirthMonth = *reqBody.BirthMonth
+		}
+		if birthYear != 0 && !defs.IsValidBirthDate(birthYear, edit.BirthMonth, time.Now()) {
+			return nil, apierrors.ErrorInvalidParameters.WithField("birthYear")
+		}
+		edit.BirthYear = &birthYear
+		hasChanges = true
+	} else if reqBody.BirthMonth != nil { // The month can't be set without the year
+		return nil, apierrors.ErrorInvalidParameters.WithField("birthMonth")
+	}
+
+	if reqBody.Timezone != nil {
+		timezone := textutils.SanitizeString(*reqBody.Timezone)
+		if len(timezone) > 0 && !defs.IsValidTimezone(timezone) {
+			return nil, apierrors.ErrorInvalidParameters.WithField("timezone")
+		}
+		edit.Timezone = &timezone
+		hasChanges = true
+	}
+
+	if reqBody.Country != nil {
+		country := strings.ToUpper(textutils.SanitizeString(*reqBody.Country))
+		if len(country) > 0 && !defs.IsValidCountryCode(country) {
+			return nil, apierrors.ErrorInputInvalidFormat.WithField("country")
+		}
+		edit.Country = &country
+		hasChanges = true
+	}
+
+	if reqBody.PhoneticNames != nil {
+		phoneticNames, apiErr := getPhoneticNames(reqBody.PhoneticNames, "phoneticNames.")
+		if apiErr != nil {
+			return nil, apiErr
+		}
+		edit.PhoneticNames = phoneticNames
+		hasChanges = true
+	}
+
+	if len(reqBody.LocalizedNames) > defs.MaxLocalizedNames {
+		return nil, apierrors.ErrorInputTooLong.WithField("localizedNames").WithValue(defs.MaxLocalizedNames)
+	}
+	for tag, names := range reqBody.LocalizedNames {
+		fieldPrefix := "localizedNames." + tag + "."
+		canonicalTag, err := localizednames.ParseTag(tag)
+		if err != nil {
+			return nil, apierrors.ErrorInvalidParameters.WithField("localizedNames." + tag)
+		}
+
+		if edit.LocalizedNames == nil {
+			edit.LocalizedNames = map[string]*models.LocalizedName{}
+		}
+		hasChanges = true
+		if names == nil {
+			edit.LocalizedNames[canonicalTag] = nil
+			continue
+		}
+
+		localizedName := &models.LocalizedName{
+			FullName:  defs.SanitizeName(names.FullName),
+			FirstName: defs.SanitizeName(names.FirstName),
+			LastName:  defs.SanitizeName(names.LastName),
+		}
+		if len(localizedName.FullName) == 0 && len(localizedName.FirstName) == 0 && len(localizedName.LastName) == 0 {
+			return nil, apierrors.ErrorInvalidParameters.WithField("localizedNames." + tag)
+		} else if field, maxLength := defs.CheckNameLengths(localizedName.FullName, localizedName.FirstName, localizedName.LastName); len(field) > 0 {
+			return nil, apierrors.ErrorInputTooLong.WithField(fieldPrefix + field).WithValue(int64(maxLength))
+		}
+
+		if names.PhoneticNames != nil {
+			phoneticNames, apiErr := getPhoneticNames(names.PhoneticNames, fieldPrefix+"phoneticNames.")
+			if apiErr != nil {
+				return nil, apiErr
+			}
+			localizedName.PhoneticNames = phoneticNames
+		}
+		edit.LocalizedNames[canonicalTag] = localizedName
+	}
+
+	if !hasChanges {
+		return nil, nil
+	}
+	return edit, nil
+}
+
+// getPhoneticNames validates phonetic names, the field prefix being used to report the invalid field.
+func getPhoneticNames(names *editNameInfo, fieldPrefix string) (*models.PhoneticNames, *apierrors.APIError) {
+	phoneticNames := &models.PhoneticNames{
+		FullName:  defs.SanitizeName(names.FullName),
+		FirstName: defs.SanitizeName(names.FirstName),
+		LastName:  defs.SanitizeName(names.LastName),
+	}
+	if field, maxLength := defs.CheckNameLengths(phoneticNames.FullName, phoneticNames.FirstName, phoneticNames.LastName); len(field) > 0 {
+		return nil, apierrors.ErrorInputTooLong.WithField(fieldPrefix + field).WithValue(int64(maxLength))
+	}
+	return phoneticNames, nil
+}
+
+// findBlockedName returns the field of the first edited name that the name filter blocks, or an empty field if none is blocked.
+func findBlockedName(editNameInfo *accountdatabase.AccountNameInfo, profileEdit *profileservice.ProfileEdit) (string, error) {
+	var fields, names []string
+	addNames := func(fieldPrefix string, fullName string, firstName string, lastName string) {
+		fields = append(fields, fieldPrefix+"fullName", fieldPrefix+"firstName", fieldPrefix+"lastName")
+		names = append(names, fullName, firstName, lastName)
+	}
+
+	if editNameInfo != nil {
+		addNames("names.", *editNameInfo.FullName, *editNameInfo.FirstName, *editNameInfo.LastName)
+	}
+
+	if profileEdit != nil {
+		if profileEdit.DisplayName != nil {
+			fields = append(fields, "displayName")
+			names = append(names, *profileEdit.DisplayName)
+		}
+		if phoneticNames := profileEdit.PhoneticNames; phoneticNames != nil {
+			addNames("phoneticNames.", phoneticNames.FullName, phoneticNames.FirstName, phoneticNames.LastName)
+		}
+
+		tags := make([]string, 0, len(profileEdit.LocalizedNames))
+		for tag := range profileEdit.LocalizedNames {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+		for _, tag := range tags {
+			localizedName := profileEdit.LocalizedNames[tag]
+			if localizedName == nil {
+				continue
+			}
+			addNames("localizedNames."+tag+".", localizedName.FullName, localizedName.FirstName, localizedName.LastName)
+			if phoneticNames := localizedName.PhoneticNames; phoneticNames != nil {
+				addNames("localizedNames."+tag+".phoneticNames.", phoneticNames.FullName, phoneticNames.FirstName, phoneticNames.LastName)
+			}
+		}
+	}
+
+	for i, name := range names {
+		if len(name) == 0 {
+			continue
+		}
+
+		isBlocked, err := globals.NameFilter.IsBlocked(name)
+		if err != nil {
+			return "", err
+		} else if isBlocked {
+			return fields[i], nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,379 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/accountdeletionservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/attemptlimiter"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/childservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/parentalconsentservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/utils"
+	"bitbucket.org/calmisland/go-server-logs/logger"
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+	"bitbucket.org/calmisland/go-server-requests/apirequests"
+	"bitbucket.org/calmisland/go-server-utils/langutils"
+	"bitbucket.org/calmisland/go-server-utils/textutils"
+	"github.com/labstack/echo/v4"
+)
+
+// errManagedByParent is returned when a child profile tries to change what its parent account manages.
+var errManagedByParent = errors.New("The child profile is managed by its parent account")
+
+var (
+	childDeviceCredentialLimit = attemptlimiter.New("childdevice-credential", defs.ChildDeviceMaxAttemptsPerCredential, defs.ChildDeviceAttemptWindowMinutes*time.Minute)
+	childDeviceIPLimit         = attemptlimiter.New("childdevice-ip", defs.ChildDeviceMaxAttemptsPerIP, defs.ChildDeviceAttemptWindowMinutes*time.Minute)
+)
+
+type selfChildInfo struct {
+	AccountID      string `json:"accountId"`
+	FullName       string `json:"fullName,omitempty"`
+	FirstName      string `json:"firstName,omitempty"`
+	LastName       string `json:"lastName,omitempty"`
+	Language       string `json:"lang"`
+	DisplayName    string `json:"displayName,omitempty"`
+	BirthYear      int    `json:"birthYear,omitempty"`
+	BirthMonth     int    `json:"birthMonth,omitempty"`
+	AvatarPresetID string `json:"avatarPresetId,omitempty"`
+}
+
+type selfChildrenResponseBody struct {
+	Children []*selfChildInfo `json:"children"`
+}
+
+type createSelfChildResponseBody struct {
+	AccountID string `json:"accountId"`
+}
+
+type createSelfChildDeviceRequestBody struct {
+	DeviceName string `json:"deviceName"`
+}
+
+type selfChildDevicesResponseBody struct {
+	Devices []models.ChildDeviceCredential `json:"devices"`
+}
+
+type createSelfChildDeviceResponseBody struct {
+	models.ChildDeviceCredential
+	// Credential is only returned when the device credential is created
+	Credential string `json:"credential"`
+}
+
+type verifyChildDeviceRequestBody struct {
+	Credential string `json:"credential"`
+}
+
+type verifyChildDeviceResponseBody struct {
+	AccountID string `json:"accountId"`
+}
+
+// HandleGetSelfChildren handles requests to list the child profiles managed by the signed in account.
+func HandleGetSelfChildren(c echo.Context) error {
+	accountID := helpers.GetAccountID(c)
+
+	childIDs, err := childservice.GetChildIDs(childservice.NewStore(), accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	profiles, err := profileservice.GetProfiles(childIDs)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	response := selfChildrenResponseBody{
+		Children: make([]*selfChildInfo, 0, len(childIDs)),
+	}
+	for _, childID := range childIDs {
+		accInfo, err := globals.AccountDatabase.GetAccountInfo(childID)
+		if err != nil {
+			return helpers.HandleInternalError(c, err)
+		} else if accInfo == nil {
+			continue
+		}
+
+		child := &selfChildInfo{
+			AccountID: childID,
+			FullName:  accInfo.FullName,
+			FirstName: accInfo.FirstName,
+			LastName:  accInfo.LastName,
+			Language:  accInfo.Language,
+		}
+		if profile := profiles[childID]; profile != nil {
+			child.DisplayName = profile.DisplayName
+			child.BirthYear = profile.BirthYear
+			child.BirthMonth = profile.BirthMonth
+			child.AvatarPresetID = profile.AvatarPresetID
+		}
+		response.Children = append(response.Children, child)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// HandleCreateSelfChild handles requests to create a child profile managed by the signed in account.
+// The child profile takes the same fields as an account edit, the names being required.
+func HandleCreateSelfChild(c echo.Context) error {
+	accountID := helpers.GetAccountID(c)
+
+	profile, err := profileservice.GetProfile(accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	} else if profile.IsChild {
+		return utils.EchoHandleHTTPError(http.StatusForbidden, errManagedByParent)
+	}
+
+	reqBody := new(editSelfAccountInfoRequestBody)
+	err = c.Bind(reqBody)
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorBadRequestBody)
+	} else if reqBody.Names == nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("names"))
+	}
+
+	accInfo, err := globals.AccountDatabase.GetAccountInfo(accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	} else if accInfo == nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorAccountNotFound)
+	}
+
+	// The child profile defaults to the language of its parent
+	language := accInfo.Language
+	if reqBody.Language != nil {
+		language = textutils.SanitizeString(*reqBody.Language)
+		if !langutils.IsValidLanguageCode(language) {
+			return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("lang"))
+		}
+	}
+
+	nameInfo, apiErr := getEditNameInfo(reqBody.Names)
+	if apiErr != nil {
+		return apirequests.EchoSetClientError(c, apiErr)
+	}
+
+	profileEdit, apiErr := getProfileEdit(reqBody)
+	if apiErr != nil {
+		return apirequests.EchoSetClientError(c, apiErr)
+	}
+
+	blockedField, err := findBlockedName(nameInfo, profileEdit)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	} else if len(blockedField) > 0 {
+		logger.LogFormat("[CHILDREN] A blocked name was rejected for a child profile of account [%s] in [%s]\n", accountID, blockedField)
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField(blockedField).WithMessage("The name is not allowed"))
+	}
+
+	if apiErr := checkVisibility(reqBody.Visibility); apiErr != nil {
+		return apirequests.EchoSetClientError(c, apiErr)
+	}
+
+	childID, err := childservice.CreateChild(childservice.NewStore(), accountID, &childservice.NewChild{
+		FullName:  *nameInfo.FullName,
+		FirstName: *nameInfo.FirstName,
+		LastName:  *nameInfo.LastName,
+		Country:   accInfo.Country,
+		Language:  language,
+		Profile:   profileEdit,
+	})
+	if err == childservice.ErrTooManyChildren {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInputTooLong.WithField("children").WithValue(defs.MaxChildProfiles))
+	} else if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	if len(reqBody.Visibility) > 0 {
+		err = profileservice.SetVisibility(childID, reqBody.Visibility)
+		if err != nil {
+			return helpers.HandleInternalError(c, err)
+		}
+	}
+
+	// The parent creating the child profile consents to it
+	err = parentalconsentservice.RecordParentAccountConsent(parentalconsentservice.NewStore(), childID, accountID, getParentalConsentOrigin(c))
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	logger.LogFormat("[CHILDREN] Account [%s] created the child profile [%s]\n", accountID, childID)
+	return c.JSON(http.StatusOK, createSelfChildResponseBody{
+		AccountID: childID,
+	})
+}
+
+// HandleEditSelfChildInfo handles requests to edit the account information of a child profile managed by the signed in account.
+func HandleEditSelfChildInfo(c echo.Context) error {
+	childID, err := getManagedChildID(c)
+	if len(childID) == 0 {
+		return err
+	}
+	return editAccountInfo(c, childID, true)
+}
+
+// HandleSelfChildAvatarPresetSelect handles requests to choose the preset avatar of a child profile managed by the signed in account.
+func HandleSelfChildAvatarPresetSelect(c echo.Context) error {
+	childID, err := getManagedChildID(c)
+	if len(childID) == 0 {
+		return err
+	}
+	return setAvatarPreset(c, childID)
+}
+
+// HandleDeleteSelfChild handles requests to delete a child profile managed by the signed in account.
+func HandleDeleteSelfChild(c echo.Context) error {
+	accountID := helpers.GetAccountID(c)
+	childID := c.Param("childId")
+
+	err := accountdeletionservice.DeleteChild(accountdeletionservice.NewStore(), accountID, childID)
+	if err == accountdeletionservice.ErrChildNotFound {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorItemNotFound)
+	} else if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	err = parentalconsentservice.RecordParentAccountWithdrawal(parentalconsentservice.NewStore(), childID, accountID, getParentalConsentOrigin(c))
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	logger.LogFormat("[CHILDREN] Account [%s] deleted the child profile [%s]\n", accountID, childID)
+	return c.NoContent(http.StatusOK)
+}
+
+// HandleGetSelfChildDevices handles requests to list the device credentials of a child profile managed by the signed in account.
+func HandleGetSelfChildDevices(c echo.Context) error {
+	childID, err := getManagedChildID(c)
+	if len(childID) == 0 {
+		return err
+	}
+
+	credentials, err := childservice.ListDeviceCredentials(childservice.NewStore(), childID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, selfChildDevicesResponseBody{
+		Devices: credentials,
+	})
+}
+
+// HandleCreateSelfChildDevice handles requests to create a device credential for a child profile managed by the signed in account.
+// The credential is only returned in this response.
+func HandleCreateSelfChildDevice(c echo.Context) error {
+	childID, err := getManagedChildID(c)
+	if len(childID) == 0 {
+		return err
+	}
+
+	reqBody := new(createSelfChildDeviceRequestBody)
+	err = c.Bind(reqBody)
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorBadRequestBody)
+	}
+
+	deviceName := textutils.SanitizeString(reqBody.DeviceName)
+	if len(deviceName) > defs.MaxDeviceNameLength {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInputTooLong.WithField("deviceName").WithValue(defs.MaxDeviceNameLength))
+	}
+
+	accountID := helpers.GetAccountID(c)
+	credential, deviceCredential, err := childservice.CreateDeviceCredential(childservice.NewStore(), accountID, childID, deviceName)
+	if err == childservice.ErrTooManyDeviceCredentials {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInputTooLong.WithField("devices").WithValue(defs.MaxChildDeviceCredentials))
+	} else if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	logger.LogFormat("[CHILDREN] Account [%s] created the device credential [%s] for the child profile [%s]\n", accountID, credential.CredentialID, childID)
+	return c.JSON(http.StatusOK, createSelfChildDeviceResponseBody{
+		ChildDeviceCredential: *credential,
+		Credential:            deviceCredential,
+	})
+}
+
+// HandleDeleteSelfChildDevice handles requests to revoke a device credential of a child profile managed by the signed in account.
+func HandleDeleteSelfChildDevice(c echo.Context) error {
+	childID, err := getManagedChildID(c)
+	if len(childID) == 0 {
+		return err
+	}
+
+	credentialID := c.Param("credentialId")
+	err = childservice.DeleteDeviceCredential(childservice.NewStore(), childID, credentialID)
+	if err == childservice.ErrDeviceCredentialNotFound {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorItemNotFound)
+	} else if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	logger.LogFormat("[CHILDREN] Account [%s] revoked the device credential [%s] of the child profile [%s]\n", helpers.GetAccountID(c), credentialID, childID)
+	return c.NoContent(http.StatusOK)
+}
+
+// HandleVerifyChildDevice handles requests from the authentication service to verify the device credential of a child profile.
+// The authentication service signs the child profile in with the returned account ID.
+func HandleVerifyChildDevice(c echo.Context) error {
+	reqBody := new(verifyChildDeviceRequestBody)
+	err := c.Bind(reqBody)
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorBadRequestBody)
+	}
+
+	// The attempts are counted by credential ID so the unknown credentials are limited the same as the existing ones
+	credentialID, _, err := childservice.ParseDeviceCredential(reqBody.Credential)
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidLogin)
+	}
+
+	isBlocked, err := childDeviceCredentialLimit.IsBlocked(credentialID)
+	if err == nil && !isBlocked {
+		isBlocked, err = childDeviceIPLimit.IsBlocked(c.RealIP())
+	}
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	} else if isBlocked {
+		logger.LogFormat("[CHILDREN] A blocked device verification for credential [%s] from IP [%s]\n", credentialID, c.RealIP())
+		return apirequests.EchoSetClientError(c, helpers.ErrorTooManyAttempts)
+	}
+
+	childID, err := childservice.VerifyDeviceCredential(childservice.NewStore(), reqBody.Credential)
+	if err == childservice.ErrInvalidDeviceCredential {
+		logger.LogFormat("[CHILDREN] An invalid device credential was rejected from IP [%s]\n", c.RealIP())
+		err = childDeviceCredentialLimit.RecordFailure(credentialID)
+		if err == nil {
+			err = childDeviceIPLimit.RecordFailure(c.RealIP())
+		}
+		if err != nil {
+			return helpers.HandleInternalError(c, err)
+		}
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidLogin)
+	} else if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, verifyChildDeviceResponseBody{
+		AccountID: childID,
+	})
+}
+
+// getManagedChildID returns the child profile of the request if it's managed by the signed in account.
+// The error response is set and returned with an empty ID if the child profile isn't managed by the account.
+func getManagedChildID(c echo.Context) (string, error) {
+	accountID := helpers.GetAccountID(c)
+	childID := c.Param("childId")
+
+	isParent, err := childservice.IsParentOf(childservice.NewStore(), accountID, childID)
+	if err != nil {
+		return "", helpers.HandleInternalError(c, err)
+	} else if !isParent {
+		return "", apirequests.EchoSetClientError(c, apierrors.ErrorItemNotFound)
+	}
+	return childID, nil
+}
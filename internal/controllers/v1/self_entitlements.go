@@ -0,0 +1,23 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/entitlementservice"
+	"github.com/labstack/echo/v4"
+)
+
+// HandleGetSelfEntitlements handles requests to get the passes and products currently owned by the signed in account,
+// including the ones shared by its family.
+func HandleGetSelfEntitlements(c echo.Context) error {
+	accountID := helpers.GetAccountID(c)
+
+	result, err := entitlementservice.GetEntitlements(accountID, time.Now().UnixNano()/int64(time.Millisecond))
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,255 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/familyservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/utils"
+	"bitbucket.org/calmisland/go-server-logs/logger"
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+	"bitbucket.org/calmisland/go-server-requests/apirequests"
+	"bitbucket.org/calmisland/go-server-utils/emailutils"
+	"bitbucket.org/calmisland/go-server-utils/phoneutils"
+	"github.com/labstack/echo/v4"
+)
+
+// errFamilyOwnerRequired is returned when a family member calls an endpoint reserved to the family owner.
+var errFamilyOwnerRequired = errors.New("Only the family owner can make this request")
+
+type selfFamilyResponseBody struct {
+	*models.Family
+	Members []*models.FamilyMember `json:"members"`
+}
+
+type selfFamilyInviteRequestBody struct {
+	Email       string `json:"email"`
+	PhoneNumber string `json:"phoneNr"`
+}
+
+type selfFamilyInvitationsResponseBody struct {
+	Invitations []*models.FamilyInvitation `json:"invitations"`
+}
+
+// HandleGetSelfFamily handles requests to get the family of the signed in account.
+func HandleGetSelfFamily(c echo.Context) error {
+	accountID := helpers.GetAccountID(c)
+
+	member, err := familyservice.GetMembership(accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	} else if member == nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorItemNotFound)
+	}
+
+	family, members, err := familyservice.GetFamily(member.FamilyID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	} else if family == nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorItemNotFound)
+	}
+
+	// The pending invitations are not returned, since they would tell which of the invited users have an account
+	return c.JSON(http.StatusOK, selfFamilyResponseBody{
+		Family:  family,
+		Members: members,
+	})
+}
+
+// HandleCreateSelfFamily handles requests to create a family owned by the signed in account.
+func HandleCreateSelfFamily(c echo.Context) error {
+	accountID := helpers.GetAccountID(c)
+
+	family, err := familyservice.CreateFamily(accountID)
+	if err == familyservice.ErrAlreadyInFamily {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithMessage(err.Error()))
+	} else if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	logger.LogFormat("[FAMILY] Account [%s] created family [%s]\n", accountID, family.FamilyID)
+	return c.JSON(http.StatusOK, family)
+}
+
+// HandleLeaveSelfFamily handles requests from the signed in account to leave its family.
+// The family is deleted when its owner leaves, which ends the sharing with all its members.
+func HandleLeaveSelfFamily(c echo.Context) error {
+	accountID := helpers.GetAccountID(c)
+
+	member, err := familyservice.GetMembership(accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	} else if member == nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorItemNotFound)
+	}
+
+	if member.Role == models.FamilyRoleOwner {
+		err = familyservice.DeleteFamily(member.FamilyID)
+	} else {
+		err = familyservice.RemoveMember(member.FamilyID, accountID)
+	}
+	if err == familyservice.ErrMemberNotFound {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorItemNotFound)
+	} else if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	logger.LogFormat("[FAMILY] Account [%s] left family [%s] as %s\n", accountID, member.FamilyID, member.Role)
+	return c.NoContent(http.StatusOK)
+}
+
+// HandleRemoveSelfFamilyMember handles requests from a family owner to remove a member from its family.
+func HandleRemoveSelfFamilyMember(c echo.Context) error {
+	accountID := helpers.GetAccountID(c)
+	memberAccountID := c.Param("accountId")
+	if len(memberAccountID) == 0 {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters)
+	}
+
+	family, err := getOwnedFamily(c, accountID)
+	if family == nil {
+		return err
+	}
+
+	err = familyservice.RemoveMember(family.FamilyID, memberAccountID)
+	if err == familyservice.ErrMemberNotFound {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorItemNotFound)
+	} else if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	logger.LogFormat("[FAMILY] Account [%s] removed account [%s] from family [%s]\n", accountID, memberAccountID, family.FamilyID)
+	return c.NoContent(http.StatusOK)
+}
+
+// HandleInviteSelfFamilyMember handles requests from a family owner to invite an account, by email or phone number.
+// The response is the same whether the account exists and can join the family or not, so it doesn't tell who has an account.
+func HandleInviteSelfFamilyMember(c echo.Context) error {
+	accountID := helpers.GetAccountID(c)
+
+	reqBody := new(selfFamilyInviteRequestBody)
+	err := c.Bind(reqBody)
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorBadRequestBody)
+	}
+
+	family, err := getOwnedFamily(c, accountID)
+	if family == nil {
+		return err
+	} else if family.MemberCount >= defs.MaxFamilyMembers {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithMessage(familyservice.ErrFamilyFull.Error()))
+	}
+
+	var invitedAccountID string
+	var foundAccount bool
+	if len(reqBody.Email) > 0 {
+		if !emailutils.IsValidEmailAddressFormat(reqBody.Email) {
+			return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidEmailFormat)
+		}
+		invitedAccountID, foundAccount, err = globals.AccountDatabase.GetAccountIDFromEmail(reqBody.Email)
+	} else if len(reqBody.PhoneNumber) > 0 {
+		phoneNumber, cleanErr := phoneutils.CleanPhoneNumber(reqBody.PhoneNumber)
+		if cleanErr != nil || !phoneutils.IsValidPhoneNumber(phoneNumber) {
+			return apirequests.EchoSetClientError(c, apierrors.ErrorInputInvalidFormat.WithField("phoneNr"))
+		}
+		invitedAccountID, foundAccount, err = globals.AccountDatabase.GetAccountIDFromPhoneNumber(phoneNumber)
+	} else {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("email"))
+	}
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	} else if !foundAccount {
+		logger.LogFormat("[FAMILY] Account [%s] invited an unknown account to family [%s]\n", accountID, family.FamilyID)
+		return c.NoContent(http.StatusAccepted)
+	}
+
+	_, err = familyservice.Invite(family, accountID, invitedAccountID)
+	switch err {
+	case nil:
+		logger.LogFormat("[FAMILY] Account [%s] invited account [%s] to family [%s]\n", accountID, invitedAccountID, family.FamilyID)
+	case familyservice.ErrAlreadyInFamily:
+		logger.LogFormat("[FAMILY] Account [%s] invited account [%s] which is already in a family\n", accountID, invitedAccountID)
+	case familyservice.ErrFamilyFull:
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithMessage(err.Error()))
+	default:
+		return helpers.HandleInternalError(c, err)
+	}
+	return c.NoContent(http.StatusAccepted)
+}
+
+// HandleGetSelfFamilyInvitations handles requests to list the family invitations received by the signed in account.
+func HandleGetSelfFamilyInvitations(c echo.Context) error {
+	accountID := helpers.GetAccountID(c)
+
+	invitations, err := familyservice.ListInvitations(accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, selfFamilyInvitationsResponseBody{
+		Invitations: invitations,
+	})
+}
+
+// HandleAcceptSelfFamilyInvitation handles requests from the signed in account to join the family that invited it.
+func HandleAcceptSelfFamilyInvitation(c echo.Context) error {
+	accountID := helpers.GetAccountID(c)
+	familyID := c.Param("familyId")
+	if len(familyID) == 0 {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters)
+	}
+
+	member, err := familyservice.AcceptInvitation(accountID, familyID)
+	switch err {
+	case nil:
+	case familyservice.ErrInvitationNotFound:
+		return apirequests.EchoSetClientError(c, apierrors.ErrorItemNotFound)
+	case familyservice.ErrAlreadyInFamily, familyservice.ErrFamilyFull:
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithMessage(err.Error()))
+	default:
+		return helpers.HandleInternalError(c, err)
+	}
+
+	logger.LogFormat("[FAMILY] Account [%s] joined family [%s]\n", accountID, familyID)
+	return c.JSON(http.StatusOK, member)
+}
+
+// HandleDeclineSelfFamilyInvitation handles requests from the signed in account to decline a family invitation.
+func HandleDeclineSelfFamilyInvitation(c echo.Context) error {
+	accountID := helpers.GetAccountID(c)
+	familyID := c.Param("familyId")
+	if len(familyID) == 0 {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters)
+	}
+
+	err := familyservice.DeleteInvitation(accountID, familyID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// getOwnedFamily returns the family owned by an account.
+// The error response is set and returned with a nil family if the account doesn't own a family.
+func getOwnedFamily(c echo.Context, accountID string) (*models.Family, error) {
+	member, err := familyservice.GetMembership(accountID)
+	if err != nil {
+		return nil, helpers.HandleInternalError(c, err)
+	} else if member == nil {
+		return nil, apirequests.EchoSetClientError(c, apierrors.ErrorItemNotFound)
+	} else if member.Role != models.FamilyRoleOwner {
+		return nil, utils.EchoHandleHTTPError(http.StatusForbidden, errFamilyOwnerRequired)
+	}
+
+	family, _, err := familyservice.GetFamily(member.FamilyID)
+	if err != nil {
+		return nil, helpers.HandleInternalError(c, err)
+	} else if family == nil {
+		return nil, apirequests.EchoSetClientError(c, apierrors.ErrorItemNotFound)
+	}
+	return family, nil
+}
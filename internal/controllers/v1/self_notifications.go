@@ -0,0 +1,111 @@
+package v1
+
+import (
+	"net/http"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notifications"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notificationservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/utils"
+	"bitbucket.org/calmisland/go-server-logs/logger"
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+	"bitbucket.org/calmisland/go-server-requests/apirequests"
+	"github.com/labstack/echo/v4"
+)
+
+type selfNotificationsResponseBody struct {
+	Preferences notifications.Preferences `json:"preferences"`
+}
+
+type selfNotificationsRequestBody struct {
+	// Preferences enable or disable the notification categories by channel, the ones that are not set are left unchanged
+	Preferences map[string]map[string]bool `json:"preferences"`
+}
+
+type selfNotificationsConfirmRequestBody struct {
+	Channel string `json:"channel"`
+	Code    string `json:"code"`
+}
+
+// HandleGetSelfNotifications handles requests for the notification preferences of the signed in account.
+func HandleGetSelfNotifications(c echo.Context) error {
+	return respondSelfNotifications(c, helpers.GetAccountID(c))
+}
+
+// HandleEditSelfNotifications handles requests to enable or disable notification categories by channel.
+// The marketing notifications by email or SMS stay pending until the account confirms the code sent through the channel.
+func HandleEditSelfNotifications(c echo.Context) error {
+	reqBody := new(selfNotificationsRequestBody)
+	err := c.Bind(reqBody)
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorBadRequestBody)
+	} else if len(reqBody.Preferences) == 0 {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("preferences"))
+	}
+
+	for category, channels := range reqBody.Preferences {
+		if !notifications.IsValidCategory(category) {
+			return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("preferences."+category))
+		}
+		for channel, enabled := range channels {
+			if !notifications.IsValidChannel(channel) || (!enabled && notifications.IsMandatory(category)) {
+				return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("preferences."+category+"."+channel))
+			}
+		}
+	}
+
+	accountID := helpers.GetAccountID(c)
+	err = notificationservice.SetPreferences(notificationservice.NewStore(), accountID, reqBody.Preferences, notificationservice.Origin{
+		IPAddress: c.RealIP(),
+	})
+	if err == notificationservice.ErrChildAccount {
+		return utils.EchoHandleHTTPError(http.StatusForbidden, err)
+	} else if err == notificationservice.ErrNoRecipient {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("preferences"))
+	} else if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	return respondSelfNotifications(c, accountID)
+}
+
+// HandleConfirmSelfNotifications handles requests to confirm the opt-in to the marketing notifications on a channel.
+func HandleConfirmSelfNotifications(c echo.Context) error {
+	reqBody := new(selfNotificationsConfirmRequestBody)
+	err := c.Bind(reqBody)
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorBadRequestBody)
+	} else if !notifications.IsValidChannel(reqBody.Channel) || !notifications.RequiresDoubleOptIn(notifications.CategoryMarketing, reqBody.Channel) {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("channel"))
+	} else if len(reqBody.Code) == 0 {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("code"))
+	}
+
+	accountID := helpers.GetAccountID(c)
+	err = notificationservice.ConfirmOptIn(notificationservice.NewStore(), accountID, reqBody.Channel, reqBody.Code, notificationservice.Origin{
+		IPAddress: c.RealIP(),
+	})
+	if err == notificationservice.ErrOptInNotFound {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorVerificationNotFound)
+	} else if err == notificationservice.ErrInvalidOptInCode {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidVerificationCode)
+	} else if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	logger.LogFormat("[NOTIFICATIONS] Account [%s] confirmed the marketing notifications on channel [%s] from IP [%s]\n", accountID, reqBody.Channel, c.RealIP())
+	return respondSelfNotifications(c, accountID)
+}
+
+// respondSelfNotifications responds with the statuses of all the notification categories of an account.
+func respondSelfNotifications(c echo.Context, accountID string) error {
+	preferences, err := notificationservice.GetPreferences(accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	response := selfNotificationsResponseBody{
+		Preferences: preferences.All(),
+	}
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,96 @@
+package v1
+
+import (
+	"net/http"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/policies"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/policyconsentservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
+	"bitbucket.org/calmisland/go-server-logs/logger"
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+	"bitbucket.org/calmisland/go-server-requests/apirequests"
+	"github.com/labstack/echo/v4"
+)
+
+type selfPolicyConsentsResponseBody struct {
+	Required policies.Versions                `json:"required"`
+	Accepted map[string]string                `json:"accepted"`
+	Consents []selfPolicyConsentsResponseItem `json:"consents"`
+}
+
+type selfPolicyConsentsResponseItem struct {
+	Policy       string `json:"policy"`
+	Version      string `json:"version"`
+	AcceptedDate int64  `json:"acceptTm"`
+	Language     string `json:"lang,omitempty"`
+}
+
+type selfPolicyConsentsRequestBody struct {
+	Policies map[string]string `json:"policies"`
+}
+
+// HandleGetSelfPolicyConsents handles requests for the policy versions accepted by the account.
+func HandleGetSelfPolicyConsents(c echo.Context) error {
+	accountID := helpers.GetAccountID(c)
+
+	profile, err := profileservice.GetProfile(accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	consents, err := policyconsentservice.GetConsents(policyconsentservice.NewStore(), accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	response := selfPolicyConsentsResponseBody{
+		Required: globals.RequiredPolicyVersions,
+		Accepted: profile.AcceptedPolicies,
+		Consents: make([]selfPolicyConsentsResponseItem, len(consents)),
+	}
+	if response.Accepted == nil {
+		response.Accepted = map[string]string{}
+	}
+	for i, consent := range consents {
+		response.Consents[i] = selfPolicyConsentsResponseItem{
+			Policy:       consent.Policy,
+			Version:      consent.Version,
+			AcceptedDate: consent.AcceptedDate,
+			Language:     consent.Language,
+		}
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// HandleAcceptSelfPolicies handles requests to accept the current policy versions.
+func HandleAcceptSelfPolicies(c echo.Context) error {
+	reqBody := new(selfPolicyConsentsRequestBody)
+	err := c.Bind(reqBody)
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorBadRequestBody)
+	} else if len(reqBody.Policies) == 0 || !globals.RequiredPolicyVersions.AreCurrent(reqBody.Policies) {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("policies"))
+	}
+
+	accountID := helpers.GetAccountID(c)
+	accInfo, err := globals.AccountDatabase.GetAccountInfo(accountID)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	} else if accInfo == nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorItemNotFound)
+	}
+
+	err = policyconsentservice.Accept(policyconsentservice.NewStore(), accountID, reqBody.Policies, policyconsentservice.Origin{
+		IPAddress: c.RealIP(),
+		Language:  accInfo.Language,
+	})
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	logger.LogFormat("[POLICIES] Account [%s] accepted the policies %v from IP [%s]\n", accountID, reqBody.Policies, c.RealIP())
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,77 @@
+package v1
+
+import (
+	"net/http"
+	"strings"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/purchaseservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/purchaseverification"
+	"bitbucket.org/calmisland/go-server-logs/logger"
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+	"bitbucket.org/calmisland/go-server-requests/apirequests"
+	"github.com/labstack/echo/v4"
+)
+
+type selfPurchasesRequestBody struct {
+	Store string `json:"store"`
+	// Receipt is the base64 encoded receipt for the App Store, and the purchase token for Google Play.
+	Receipt      string `json:"receipt"`
+	ProductID    string `json:"productId"`
+	Subscription bool   `json:"subscription"`
+}
+
+type selfPurchasesResponseBody struct {
+	Transactions []*models.AccountTransaction `json:"transactions"`
+}
+
+// HandleSelfPurchases handles requests to apply an in-app purchase receipt to the signed in account.
+// The response has the transactions of all the purchases in the receipt.
+func HandleSelfPurchases(c echo.Context) error {
+	accountID := helpers.GetAccountID(c)
+
+	reqBody := new(selfPurchasesRequestBody)
+	err := c.Bind(reqBody)
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorBadRequestBody)
+	} else if len(reqBody.Store) == 0 {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("store"))
+	} else if len(reqBody.Receipt) == 0 {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("receipt"))
+	}
+
+	store := strings.ToLower(reqBody.Store)
+	purchases, err := globals.PurchaseVerifier.Verify(&purchaseverification.Receipt{
+		Store:        store,
+		Data:         reqBody.Receipt,
+		ProductID:    reqBody.ProductID,
+		Subscription: reqBody.Subscription,
+	})
+	switch err {
+	case nil:
+	case purchaseverification.ErrUnsupportedStore:
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("store"))
+	case purchaseverification.ErrInvalidReceipt:
+		logger.LogFormat("[PURCHASES] The store [%s] rejected a receipt of account [%s]\n", store, accountID)
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("receipt"))
+	case purchaseverification.ErrSandboxReceipt:
+		logger.LogFormat("[PURCHASES] Account [%s] sent a sandbox receipt of the store [%s]\n", accountID, store)
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("receipt").WithMessage(err.Error()))
+	default:
+		return helpers.HandleInternalError(c, err)
+	}
+
+	transactions, err := purchaseservice.ApplyPurchases(accountID, store, purchases)
+	if err == purchaseservice.ErrPurchaseOwnedByOtherAccount {
+		logger.LogFormat("[PURCHASES] Account [%s] sent a receipt of another account\n", accountID)
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("receipt").WithMessage(err.Error()))
+	} else if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, selfPurchasesResponseBody{
+		Transactions: transactions,
+	})
+}
@@ -0,0 +1,45 @@
+package v1
+
+import (
+	"net/http"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/voucherservice"
+	"bitbucket.org/calmisland/go-server-logs/logger"
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+	"bitbucket.org/calmisland/go-server-requests/apirequests"
+	"github.com/labstack/echo/v4"
+)
+
+type selfRedeemRequestBody struct {
+	Code string `json:"code"`
+}
+
+// HandleSelfRedeem handles requests to redeem a voucher code for the signed in account.
+// The response is the transaction granting the passes and products of the code.
+func HandleSelfRedeem(c echo.Context) error {
+	accountID := helpers.GetAccountID(c)
+
+	reqBody := new(selfRedeemRequestBody)
+	err := c.Bind(reqBody)
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorBadRequestBody)
+	} else if len(reqBody.Code) == 0 {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("code"))
+	}
+
+	transaction, err := voucherservice.Redeem(voucherservice.NewStore(), accountID, reqBody.Code)
+	switch err {
+	case nil:
+	case voucherservice.ErrVoucherNotFound:
+		return apirequests.EchoSetClientError(c, apierrors.ErrorItemNotFound.WithField("code"))
+	case voucherservice.ErrVoucherExpired, voucherservice.ErrVoucherExhausted, voucherservice.ErrVoucherAlreadyRedeemed:
+		logger.LogFormat("[REDEEM] Account [%s] failed to redeem a code: %s\n", accountID, err.Error())
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("code").WithMessage(err.Error()))
+	default:
+		return helpers.HandleInternalError(c, err)
+	}
+
+	logger.LogFormat("[REDEEM] Account [%s] redeemed a code in transaction [%s]\n", accountID, transaction.TransactionID)
+	return c.JSON(http.StatusOK, transaction)
+}
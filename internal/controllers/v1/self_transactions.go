@@ -0,0 +1,57 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/transactionservice"
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+	"bitbucket.org/calmisland/go-server-requests/apirequests"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultTransactionsPageSize = 20
+	maxTransactionsPageSize     = 100
+)
+
+type selfTransactionsResponseBody struct {
+	Transactions []*models.AccountTransaction `json:"transactions"`
+	// NextCursor is the cursor of the next page, omitted on the last page
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// HandleGetSelfTransactions handles requests to list the transactions of the signed in account, a page at a time.
+func HandleGetSelfTransactions(c echo.Context) error {
+	accountID := helpers.GetAccountID(c)
+
+	limit := defaultTransactionsPageSize
+	if limitParam := c.QueryParam("limit"); len(limitParam) > 0 {
+		var err error
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit <= 0 || limit > maxTransactionsPageSize {
+			return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("limit"))
+		}
+	}
+
+	transactions, nextCursor, err := transactionservice.ListTransactions(accountID, limit, c.QueryParam("cursor"))
+	if err == transactionservice.ErrInvalidCursor {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("cursor"))
+	} else if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	if transactions == nil {
+		transactions = []*models.AccountTransaction{}
+	}
+	// The admins who changed the entitlements are only shown to the other admins
+	for _, transaction := range transactions {
+		transaction.CreatedBy = ""
+	}
+	return c.JSON(http.StatusOK, selfTransactionsResponseBody{
+		Transactions: transactions,
+		NextCursor:   nextCursor,
+	})
+}
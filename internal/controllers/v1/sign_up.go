@@ -3,10 +3,16 @@ package v1
 import (
 	"net"
 	"net/http"
+	"time"
 
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notifications"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notificationservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/parentalconsentservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/policyconsentservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
 	"bitbucket.org/calmisland/go-server-account/accountdatabase"
 	"bitbucket.org/calmisland/go-server-logs/logger"
 	"bitbucket.org/calmisland/go-server-messages/messages"
@@ -27,6 +33,12 @@ type signUpRequestBody struct {
 	PhoneNumber string `json:"phoneNr"`
 	Password    string `json:"pw"`
 	Language    string `json:"lang"`
+	// BirthYear and BirthMonth are optional, the parent email is required when the user is under the parental consent age
+	BirthYear   int    `json:"birthYear,omitempty"`
+	BirthMonth  int    `json:"birthMonth,omitempty"`
+	ParentEmail string `json:"parentEmail,omitempty"`
+	// AcceptedPolicies are the policy versions accepted by the user, by policy
+	AcceptedPolicies map[string]string `json:"acceptedPolicies,omitempty"`
 }
 
 type signUpResponseBody struct {
@@ -87,6 +99,26 @@ func HandleSignUp(c echo.Context) error {
 		return defs.HandlePasswordValidatorError(c, err)
 	}
 
+	geoIPResult, err := globals.GeoIPService.GetCountryFromIP(clientIP)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	countryCode := defs.DefaultCountryCode
+	if geoIPResult != nil && len(geoIPResult.CountryCode) > 0 {
+		countryCode = geoIPResult.CountryCode
+	}
+
+	// Children under the parental consent age of their country need the consent of a parent
+	parentEmail, apiErr := defs.ValidateSignUpBirthDate(reqBody.BirthYear, reqBody.BirthMonth, reqBody.ParentEmail, userEmail, countryCode, time.Now())
+	if apiErr != nil {
+		return apirequests.EchoSetClientError(c, apiErr)
+	}
+
+	if !globals.RequiredPolicyVersions.AreCurrent(reqBody.AcceptedPolicies) {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("acceptedPolicies"))
+	}
+
 	if isUsingEmail {
 		// Check if the email is already used by another account
 		accountExists, err := globals.AccountDatabase.AccountExistsWithEmail(userEmail)
@@ -122,16 +154,6 @@ func HandleSignUp(c echo.Context) error {
 		return helpers.HandleInternalError(c, err)
 	}
 
-	geoIPResult, err := globals.GeoIPService.GetCountryFromIP(clientIP)
-	if err != nil {
-		return helpers.HandleInternalError(c, err)
-	}
-
-	countryCode := defs.DefaultCountryCode
-	if geoIPResult != nil && len(geoIPResult.CountryCode) > 0 {
-		countryCode = geoIPResult.CountryCode
-	}
-
 	// Sets the default language if none is set
 	if !langutils.IsValidLanguageCode(userLanguage) {
 		userLanguage = defs.DefaultLanguageCode
@@ -163,7 +185,7 @@ func HandleSignUp(c echo.Context) error {
 		}
 	}
 
-	err = globals.MessageSendQueue.EnqueueMessage(message)
+	err = notificationservice.Enqueue(accountID, notifications.CategorySecurity, message)
 	if err != nil {
 		return helpers.HandleInternalError(c, err)
 	}
@@ -191,6 +213,36 @@ func HandleSignUp(c echo.Context) error {
 		return helpers.HandleInternalError(c, err)
 	}
 
+	if reqBody.BirthYear != 0 {
+		err = profileservice.SetBirthDate(accountID, reqBody.BirthYear, reqBody.BirthMonth, countryCode)
+		if err != nil {
+			return helpers.HandleInternalError(c, err)
+		}
+	}
+
+	if len(reqBody.AcceptedPolicies) > 0 {
+		err = policyconsentservice.Accept(policyconsentservice.NewStore(), accountID, reqBody.AcceptedPolicies, policyconsentservice.Origin{
+			IPAddress: c.RealIP(),
+			Language:  userLanguage,
+		})
+		if err != nil {
+			return helpers.HandleInternalError(c, err)
+		}
+	}
+
+	if len(parentEmail) > 0 {
+		err = profileservice.SetChild(accountID, true)
+		if err != nil {
+			return helpers.HandleInternalError(c, err)
+		}
+
+		_, err = parentalconsentservice.RequestConsent(parentalconsentservice.NewStore(), accountID, parentEmail, userLanguage, getParentalConsentOrigin(c))
+		if err != nil {
+			return helpers.HandleInternalError(c, err)
+		}
+		logger.LogFormat("[SIGNUP] The parental consent was requested for account [%s]\n", accountID)
+	}
+
 	logger.LogFormat("[SIGNUP] A successful sign-up request for account [%s] from IP [%s] UserAgent [%s]\n", userEmail, clientIP, clientUserAgent)
 
 	response := signUpResponseBody{
@@ -0,0 +1,106 @@
+package v2
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/attemptlimiter"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/legacypasswords"
+	"bitbucket.org/calmisland/go-server-logs/logger"
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+	"bitbucket.org/calmisland/go-server-requests/apirequests"
+	"bitbucket.org/calmisland/go-server-utils/emailutils"
+	"bitbucket.org/calmisland/go-server-utils/phoneutils"
+	"github.com/labstack/echo/v4"
+)
+
+type legacyPasswordMigrationRequestBody struct {
+	Email       string `json:"email"`
+	PhoneNumber string `json:"phoneNr"`
+	Password    string `json:"pw"`
+}
+
+type legacyPasswordMigrationResponseBody struct {
+	Status string `json:"status"`
+}
+
+var (
+	legacyPasswordAccountLimit = attemptlimiter.New("legacypassword-account", defs.LegacyPasswordMaxAttemptsPerAccount, defs.LegacyPasswordAttemptWindowMinutes*time.Minute)
+	legacyPasswordIPLimit      = attemptlimiter.New("legacypassword-ip", defs.LegacyPasswordMaxAttemptsPerIP, defs.LegacyPasswordAttemptWindowMinutes*time.Minute)
+)
+
+// HandleLegacyPasswordMigration handles the first sign-in of accounts imported with a legacy password hash.
+func HandleLegacyPasswordMigration(c echo.Context) error {
+	reqBody := new(legacyPasswordMigrationRequestBody)
+	err := c.Bind(reqBody)
+	if err != nil {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorBadRequestBody)
+	}
+
+	userEmail := reqBody.Email
+	userPhoneNumber := reqBody.PhoneNumber
+	clientIP := net.ParseIP(c.RealIP())
+
+	var accountID string
+	var found bool
+	if len(userEmail) > 0 {
+		if !emailutils.IsValidEmailAddressFormat(userEmail) {
+			return apirequests.EchoSetClientError(c, apierrors.ErrorInputInvalidFormat.WithField("email"))
+		}
+		accountID, found, err = globals.AccountDatabase.GetAccountIDFromEmail(userEmail)
+	} else if len(userPhoneNumber) > 0 {
+		userPhoneNumber, err = phoneutils.CleanPhoneNumber(userPhoneNumber)
+		if err != nil || !phoneutils.IsValidPhoneNumber(userPhoneNumber) {
+			return apirequests.EchoSetClientError(c, apierrors.ErrorInputInvalidFormat.WithField("phoneNr"))
+		}
+		accountID, found, err = globals.AccountDatabase.GetAccountIDFromPhoneNumber(userPhoneNumber)
+	} else {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("email"))
+	}
+
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	// The attempts are counted by login ID so the unknown accounts are limited the same as the existing ones
+	loginID := userEmail + userPhoneNumber
+	isBlocked, err := legacyPasswordAccountLimit.IsBlocked(loginID)
+	if err == nil && !isBlocked {
+		isBlocked, err = legacyPasswordIPLimit.IsBlocked(c.RealIP())
+	}
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	} else if isBlocked {
+		logger.LogFormat("[LEGACYPASSWORD] A blocked migration request for [%s] from IP [%s]\n", loginID, clientIP)
+		return apirequests.EchoSetClientError(c, helpers.ErrorTooManyAttempts)
+	}
+
+	isMigrated := false
+	if found {
+		isMigrated, err = legacypasswords.MigratePassword(legacypasswords.NewStore(), accountID, reqBody.Password, len(userEmail) > 0)
+		if err != nil {
+			return helpers.HandleInternalError(c, err)
+		}
+	}
+
+	if !isMigrated {
+		// Unknown accounts and accounts without a legacy hash are treated the same so they can't be told apart
+		logger.LogFormat("[LEGACYPASSWORD] A failed migration request for [%s] from IP [%s]\n", loginID, clientIP)
+		err = legacyPasswordAccountLimit.RecordFailure(loginID)
+		if err == nil {
+			err = legacyPasswordIPLimit.RecordFailure(c.RealIP())
+		}
+		if err != nil {
+			return helpers.HandleInternalError(c, err)
+		}
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidLogin)
+	}
+
+	return c.JSON(http.StatusOK, &legacyPasswordMigrationResponseBody{
+		Status: "ok",
+	})
+}
@@ -8,6 +8,11 @@ import (
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/account_jwt_service"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notifications"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notificationservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/parentalconsentservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/policyconsentservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
 	"bitbucket.org/calmisland/go-server-account/accountdatabase"
 	"bitbucket.org/calmisland/go-server-account/accounts"
 	"bitbucket.org/calmisland/go-server-logs/logger"
@@ -31,6 +36,8 @@ type signUpByTokenRequestBody struct {
 
 type signUpByTokenResponseBody struct {
 	AccountID string `json:"accountId"`
+	// ParentalConsentRequired is set when the account is restricted until the parent consents
+	ParentalConsentRequired bool `json:"parentalConsentRequired,omitempty"`
 }
 
 // HandleSignUp handles sign-up requests.
@@ -144,14 +151,18 @@ func HandleSignUpConfirm(c echo.Context) error {
 		return helpers.HandleInternalError(c, err)
 	}
 
-	geoIPResult, err := globals.GeoIPService.GetCountryFromIP(clientIP)
-	if err != nil {
-		return helpers.HandleInternalError(c, err)
-	}
+	// The country of the sign-up request, which the birth date was checked against
+	countryCode := claims.Country
+	if len(countryCode) == 0 {
+		geoIPResult, err := globals.GeoIPService.GetCountryFromIP(clientIP)
+		if err != nil {
+			return helpers.HandleInternalError(c, err)
+		}
 
-	countryCode := defs.DefaultCountryCode
-	if geoIPResult != nil && len(geoIPResult.CountryCode) > 0 {
-		countryCode = geoIPResult.CountryCode
+		countryCode = defs.DefaultCountryCode
+		if geoIPResult != nil && len(geoIPResult.CountryCode) > 0 {
+			countryCode = geoIPResult.CountryCode
+		}
 	}
 
 	// Sets the default language if none is set
@@ -176,6 +187,40 @@ func HandleSignUpConfirm(c echo.Context) error {
 
 	logger.LogFormat("[SIGNUP] A successful sign-up request for account [%s] from IP [%s] UserAgent [%s]\n", userEmail, clientIP, clientUserAgent)
 
+	if claims.BirthYear != 0 {
+		err = profileservice.SetBirthDate(accountID, claims.BirthYear, claims.BirthMonth, countryCode)
+		if err != nil {
+			return helpers.HandleInternalError(c, err)
+		}
+	}
+
+	if len(claims.AcceptedPolicies) > 0 {
+		err = policyconsentservice.Accept(policyconsentservice.NewStore(), accountID, claims.AcceptedPolicies, policyconsentservice.Origin{
+			IPAddress: c.RealIP(),
+			Language:  userLanguage,
+		})
+		if err != nil {
+			return helpers.HandleInternalError(c, err)
+		}
+	}
+
+	parentalConsentRequired := len(claims.ParentEmail) > 0
+	if parentalConsentRequired {
+		err = profileservice.SetChild(accountID, true)
+		if err != nil {
+			return helpers.HandleInternalError(c, err)
+		}
+
+		_, err = parentalconsentservice.RequestConsent(parentalconsentservice.NewStore(), accountID, claims.ParentEmail, userLanguage, parentalconsentservice.Origin{
+			IPAddress: c.RealIP(),
+			UserAgent: clientUserAgent,
+		})
+		if err != nil {
+			return helpers.HandleInternalError(c, err)
+		}
+		logger.LogFormat("[SIGNUP] The parental consent was requested for account [%s]\n", accountID)
+	}
+
 	// send welcome email
 	if len(userEmail) > 0 {
 		emailMessage := &messages.Message{
@@ -185,14 +230,15 @@ func HandleSignUpConfirm(c echo.Context) error {
 			Language:    userLanguage,
 			Template:    &messagetemplates.WelcomeTemplate{},
 		}
-		err = globals.MessageSendQueue.EnqueueMessage(emailMessage)
+		err = notificationservice.Enqueue(accountID, notifications.CategoryAccount, emailMessage)
 		if err != nil {
 			return helpers.HandleInternalError(c, err)
 		}
 	}
 
 	response := signUpByTokenResponseBody{
-		AccountID: accountID,
+		AccountID:               accountID,
+		ParentalConsentRequired: parentalConsentRequired,
 	}
 
 	return c.JSON(http.StatusOK, response)
@@ -3,11 +3,14 @@ package v2
 import (
 	"net"
 	"net/http"
+	"time"
 
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/account_jwt_service"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notifications"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notificationservice"
 	"bitbucket.org/calmisland/go-server-logs/logger"
 	"bitbucket.org/calmisland/go-server-messages/messages"
 	"bitbucket.org/calmisland/go-server-messages/messagetemplates"
@@ -27,6 +30,12 @@ type verifyCodeRequestBody struct {
 	Password     string `json:"pw"`
 	Language     string `json:"lang"`
 	TemplateName string `json:"template,omitempty"`
+	// BirthYear and BirthMonth are optional, the parent email is required when the user is under the parental consent age
+	BirthYear   int    `json:"birthYear,omitempty"`
+	BirthMonth  int    `json:"birthMonth,omitempty"`
+	ParentEmail string `json:"parentEmail,omitempty"`
+	// AcceptedPolicies are the policy versions accepted by the user, by policy
+	AcceptedPolicies map[string]string `json:"acceptedPolicies,omitempty"`
 }
 
 type verifyCodeResponseBody struct {
@@ -86,6 +95,27 @@ func HandleSignupRequest(c echo.Context) error {
 		return defs.HandlePasswordValidatorError(c, err)
 	}
 
+	// The country is carried in the token, so the account is created in the country its birth date was checked against
+	geoIPResult, err := globals.GeoIPService.GetCountryFromIP(clientIP)
+	if err != nil {
+		return helpers.HandleInternalError(c, err)
+	}
+
+	countryCode := defs.DefaultCountryCode
+	if geoIPResult != nil && len(geoIPResult.CountryCode) > 0 {
+		countryCode = geoIPResult.CountryCode
+	}
+
+	// Children under the parental consent age of their country need the consent of a parent
+	parentEmail, apiErr := defs.ValidateSignUpBirthDate(reqBody.BirthYear, reqBody.BirthMonth, reqBody.ParentEmail, userEmail, countryCode, time.Now())
+	if apiErr != nil {
+		return apirequests.EchoSetClientError(c, apiErr)
+	}
+
+	if !globals.RequiredPolicyVersions.AreCurrent(reqBody.AcceptedPolicies) {
+		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("acceptedPolicies"))
+	}
+
 	if isUsingEmail {
 		// Check if the email is already used by another account
 		accountExists, err := globals.AccountDatabase.AccountExistsWithEmail(userEmail)
@@ -126,7 +156,12 @@ func HandleSignupRequest(c echo.Context) error {
 		PhoneNumber:      userPhoneNumber,
 		Password:         hashedPassword,
 		Language:         userLanguage,
+		Country:          countryCode,
 		VerificationCode: verificationCode,
+		BirthYear:        reqBody.BirthYear,
+		BirthMonth:       reqBody.BirthMonth,
+		ParentEmail:      parentEmail,
+		AcceptedPolicies: reqBody.AcceptedPolicies,
 	})
 
 	verificationLink := globals.AccountVerificationService.GetVerificationLinkByToken(token, verificationCode, userLanguage)
@@ -164,7 +199,7 @@ func HandleSignupRequest(c echo.Context) error {
 		}
 	}
 
-	err = globals.MessageSendQueue.EnqueueMessage(message)
+	err = notificationservice.Enqueue("", notifications.CategorySecurity, message)
 	if err != nil {
 		return helpers.HandleInternalError(c, err)
 	}
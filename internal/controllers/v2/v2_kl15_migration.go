@@ -7,12 +7,14 @@ import (
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/kl15migrationservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/legacypasswords"
 	"bitbucket.org/calmisland/go-server-account/accountdatabase"
 	"bitbucket.org/calmisland/go-server-account/accounts"
 	"bitbucket.org/calmisland/go-server-logs/logger"
 	"bitbucket.org/calmisland/go-server-requests/apierrors"
 	"bitbucket.org/calmisland/go-server-requests/apirequests"
-	"bitbucket.org/calmisland/go-server-security/passwords"
 	"bitbucket.org/calmisland/go-server-utils/emailutils"
 	"bitbucket.org/calmisland/go-server-utils/langutils"
 	"bitbucket.org/calmisland/go-server-utils/phoneutils"
@@ -79,43 +81,81 @@ func HandleKl15Migration(c echo.Context) error {
 		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidParameters.WithField("email"))
 	}
 
-	kl15AccInfo, found, err := globals.AccountDatabase.GetAccountsMigrationKl1dot5InfoFromEmail(userEmail)
-	if !found || err != nil {
-		return apirequests.EchoSetClientError(c, apierrors.ErrorAccountNotFound)
-	}
-
-	// Check Migration status.
-	if kl15AccInfo.MigrationStatus == accountdatabase.AccountsKl1dot5MigrationStatusDone {
-		return c.JSON(http.StatusOK, &kl15MigrationResponseBody{
-			Status: "ok",
+	kl15Record, found, err := kl15migrationservice.GetRecord(userEmail, userPhoneNumber)
+	if err != nil {
+		kl15migrationservice.RecordEvent(&kl15migrationservice.Event{
+			Kind:        kl15migrationservice.EventKindFailed,
+			Reason:      kl15migrationservice.FailureReasonInternalError,
+			Source:      kl15migrationservice.EventSourceSignIn,
+			Email:       userEmail,
+			PhoneNumber: userPhoneNumber,
+		})
+		return helpers.HandleInternalError(c, err)
+	} else if !found {
+		kl15migrationservice.RecordEvent(&kl15migrationservice.Event{
+			Kind:        kl15migrationservice.EventKindFailed,
+			Reason:      kl15migrationservice.FailureReasonAccountNotFound,
+			Source:      kl15migrationservice.EventSourceSignIn,
+			Email:       userEmail,
+			PhoneNumber: userPhoneNumber,
 		})
+		return apirequests.EchoSetClientError(c, apierrors.ErrorAccountNotFound)
 	}
 
 	// Check password
-	isPassed := passwords.ValidateSha3_512_Password(userPassword, &passwords.Sha3_512_Hash{
-		Hash:   kl15AccInfo.PwHash,
-		Secret: kl15AccInfo.PwHashSecret,
+	isPassed, err := legacypasswords.Verify(userPassword, &legacypasswords.Hash{
+		Algorithm: legacypasswords.AlgorithmSha3_512,
+		Hash:      kl15Record.PwHash,
+		Salt:      kl15Record.PwHashSecret,
 	})
+	if err != nil {
+		return handleKl15MigrationInternalError(c, kl15Record, err)
+	}
 
-	// Check if this account is in AMS without migration status
-	accountExists, err := globals.AccountDatabase.AccountExistsWithEmail(userEmail)
+	// Check if this account is in AMS
+	var accountIDResult string
+	var accountExists bool
+	if isUsingEmail {
+		accountIDResult, accountExists, err = globals.AccountDatabase.GetAccountIDFromEmail(userEmail)
+	} else {
+		accountIDResult, accountExists, err = globals.AccountDatabase.GetAccountIDFromPhoneNumber(userPhoneNumber)
+	}
 	if err != nil {
-		return helpers.HandleInternalError(c, err)
+		return handleKl15MigrationInternalError(c, kl15Record, err)
+	}
+
+	// Check Migration status.
+	if kl15Record.MigrationStatus == accountdatabase.AccountsKl1dot5MigrationStatusDone {
+		// Accounts pre-created by the batch migration have no usable password yet,
+		// so the first sign-in with the legacy password sets it.
+		if isPassed && accountExists {
+			isWaiting, err := kl15migrationservice.IsAccountWaitingForLegacyPassword(accountIDResult)
+			if err != nil {
+				return handleKl15MigrationInternalError(c, kl15Record, err)
+			} else if isWaiting {
+				return overridePasswordToExistingAccount(c, accountIDResult, kl15Record, userPassword)
+			}
+		}
+
+		return c.JSON(http.StatusOK, &kl15MigrationResponseBody{
+			Status: "ok",
+		})
 	}
 
 	// Override 1.5 password if account is in AMS AND Password is correct AND MigrationStatus is not done
-	if accountExists && isPassed && kl15AccInfo.MigrationStatus != accountdatabase.AccountsKl1dot5MigrationStatusDone {
-		return overridePasswordToExistingAccount(c, userEmail, userPassword)
+	if accountExists && isPassed {
+		return overridePasswordToExistingAccount(c, accountIDResult, kl15Record, userPassword)
 	}
 
 	if !isPassed {
+		recordKl15MigrationFailure(kl15Record, kl15migrationservice.FailureReasonInvalidPassword)
 		return apirequests.EchoSetClientError(c, apierrors.ErrorInvalidPassword)
 	}
 
 	// generate AMS hashed password
 	hashedPassword, err := globals.PasswordHasher.GeneratePasswordHash(userPassword, false)
 	if err != nil {
-		return helpers.HandleInternalError(c, err)
+		return handleKl15MigrationInternalError(c, kl15Record, err)
 	}
 
 	// NOTE: Password validator skipped because KL 1.5 password policy is unknown
@@ -125,8 +165,9 @@ func HandleKl15Migration(c echo.Context) error {
 		// Check if the email is already used by another account
 		accountExists, err := globals.AccountDatabase.AccountExistsWithEmail(userEmail)
 		if err != nil {
-			return helpers.HandleInternalError(c, err)
+			return handleKl15MigrationInternalError(c, kl15Record, err)
 		} else if accountExists {
+			recordKl15MigrationFailure(kl15Record, kl15migrationservice.FailureReasonEmailAlreadyUsed)
 			logger.LogFormat("[KL1.5-MIGRATION] A sign-up request for already existing account [%s] email from IP [%s] UserAgent [%s]\n", userEmail, clientIP, clientUserAgent)
 			return apirequests.EchoSetClientError(c, apierrors.ErrorEmailAlreadyUsed)
 		}
@@ -135,8 +176,9 @@ func HandleKl15Migration(c echo.Context) error {
 		// Check if the phone number is already used by another account
 		accountExists, err := globals.AccountDatabase.AccountExistsWithPhoneNumber(userPhoneNumber)
 		if err != nil {
-			return helpers.HandleInternalError(c, err)
+			return handleKl15MigrationInternalError(c, kl15Record, err)
 		} else if accountExists {
+			recordKl15MigrationFailure(kl15Record, kl15migrationservice.FailureReasonPhoneNumberAlreadyUsed)
 			logger.LogFormat("[KL1.5-MIGRATION] A sign-up request for already existing account [%s] phone number from IP [%s] UserAgent [%s]\n", userPhoneNumber, clientIP, clientUserAgent)
 			return apirequests.EchoSetClientError(c, apierrors.ErrorPhoneNumberAlreadyUsed)
 		}
@@ -145,12 +187,12 @@ func HandleKl15Migration(c echo.Context) error {
 
 	accountUUID, err := uuid.NewRandom()
 	if err != nil {
-		return helpers.HandleInternalError(c, err)
+		return handleKl15MigrationInternalError(c, kl15Record, err)
 	}
 
 	geoIPResult, err := globals.GeoIPService.GetCountryFromIP(clientIP)
 	if err != nil {
-		return helpers.HandleInternalError(c, err)
+		return handleKl15MigrationInternalError(c, kl15Record, err)
 	}
 
 	countryCode := defs.DefaultCountryCode
@@ -175,16 +217,24 @@ func HandleKl15Migration(c echo.Context) error {
 		Language:     userLanguage,
 	})
 	if err != nil {
-		return helpers.HandleInternalError(c, err)
+		return handleKl15MigrationInternalError(c, kl15Record, err)
 	}
 
-	logger.LogFormat("[KL1.5-MIGRATION] A successful sign-up request for account [%s] from IP [%s] UserAgent [%s]\n", userEmail, clientIP, clientUserAgent)
+	logger.LogFormat("[KL1.5-MIGRATION] A successful sign-up request for account [%s%s] from IP [%s] UserAgent [%s]\n", userEmail, userPhoneNumber, clientIP, clientUserAgent)
 
-	err = globals.AccountDatabase.SetAccountsMigrationKl1dot5MigrationStatus(userEmail, accountdatabase.AccountsKl1dot5MigrationStatusDone)
+	err = kl15migrationservice.SetRecordMigrationStatus(kl15Record, accountdatabase.AccountsKl1dot5MigrationStatusDone)
 	if err != nil {
-		return helpers.HandleInternalError(c, err)
+		return handleKl15MigrationInternalError(c, kl15Record, err)
 	}
 
+	kl15migrationservice.RecordEvent(&kl15migrationservice.Event{
+		Kind:        kl15migrationservice.EventKindNewAccount,
+		Source:      kl15migrationservice.EventSourceSignIn,
+		Email:       kl15Record.Email,
+		PhoneNumber: kl15Record.PhoneNumber,
+		AccountID:   accountID,
+	})
+
 	response := kl15MigrationResponseBody{
 		Status: "ok",
 	}
@@ -192,16 +242,12 @@ func HandleKl15Migration(c echo.Context) error {
 	return c.JSON(http.StatusOK, response)
 }
 
-func overridePasswordToExistingAccount(c echo.Context, userEmail string, userPassword string) error {
+func overridePasswordToExistingAccount(c echo.Context, accountIDResult string, kl15Record *models.AccountMigrationKl1dot5, userPassword string) error {
 	//override and return
-	accountIDResult, _, err := globals.AccountDatabase.GetAccountIDFromEmail(userEmail)
-	if err != nil {
-		return helpers.HandleInternalError(c, err)
-	}
 	// generate AMS hashed password
 	hashedPassword, err := globals.PasswordHasher.GeneratePasswordHash(userPassword, false)
 	if err != nil {
-		return helpers.HandleInternalError(c, err)
+		return handleKl15MigrationInternalError(c, kl15Record, err)
 	}
 
 	// Change the password
@@ -209,19 +255,42 @@ func overridePasswordToExistingAccount(c echo.Context, userEmail string, userPas
 		PasswordHash: &hashedPassword,
 	})
 	if err != nil {
-		return helpers.HandleInternalError(c, err)
+		return handleKl15MigrationInternalError(c, kl15Record, err)
+	}
+
+	// Resets the flag of the accounts pre-created by the batch migration
+	err = globals.AccountDatabase.RemoveAccountFlags(accountIDResult, accounts.MustSetPasswordFlag)
+	if err != nil {
+		return handleKl15MigrationInternalError(c, kl15Record, err)
 	}
 
 	// update migration Status to done
-	err = globals.AccountDatabase.SetAccountsMigrationKl1dot5MigrationStatus(userEmail, accountdatabase.AccountsKl1dot5MigrationStatusDone)
+	err = kl15migrationservice.SetRecordMigrationStatus(kl15Record, accountdatabase.AccountsKl1dot5MigrationStatusDone)
 	if err != nil {
-		return helpers.HandleInternalError(c, err)
+		return handleKl15MigrationInternalError(c, kl15Record, err)
 	}
 
+	kl15migrationservice.RecordEvent(&kl15migrationservice.Event{
+		Kind:        kl15migrationservice.EventKindPasswordOverride,
+		Source:      kl15migrationservice.EventSourceSignIn,
+		Email:       kl15Record.Email,
+		PhoneNumber: kl15Record.PhoneNumber,
+		AccountID:   accountIDResult,
+	})
+
 	response := kl15MigrationResponseBody{
 		Status: "ok",
 	}
 
-	logger.LogFormat("[KL1.5-MIGRATION] Override password using 1.5 [%s] \n", userEmail)
+	logger.LogFormat("[KL1.5-MIGRATION] Override password using 1.5 [%s%s] \n", kl15Record.Email, kl15Record.PhoneNumber)
 	return c.JSON(http.StatusOK, response)
 }
+
+func recordKl15MigrationFailure(kl15Record *models.AccountMigrationKl1dot5, reason string) {
+	kl15migrationservice.RecordFailure(kl15Record, reason, kl15migrationservice.EventSourceSignIn)
+}
+
+func handleKl15MigrationInternalError(c echo.Context, kl15Record *models.AccountMigrationKl1dot5, err error) error {
+	recordKl15MigrationFailure(kl15Record, kl15migrationservice.FailureReasonInternalError)
+	return helpers.HandleInternalError(c, err)
+}
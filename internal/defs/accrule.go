@@ -12,8 +12,68 @@ const (
 	DefaultLanguageCode = "en_US"
 
 	SignUpVerificationCodeByteLength = 4
+
+	MaxFullNameLength = 64
+	MaxPartNameLength = 32
+
+	MaxOrganizationIDLength = 64
+
+	// MaxFamilyMembers is the maximum number of members of a family, including its owner.
+	MaxFamilyMembers = 6
+	// FamilyInvitationValidDays is how long a family invitation can be accepted.
+	FamilyInvitationValidDays = 7
+
+	// MaxChildProfiles is the maximum number of child profiles a parent account can manage.
+	MaxChildProfiles = 10
+	// MaxChildDeviceCredentials is the maximum number of device credentials of a child profile.
+	MaxChildDeviceCredentials = 5
+	// MaxDeviceNameLength is the maximum length of the name of a child device.
+	MaxDeviceNameLength = 64
+	// ChildDeviceMaxAttemptsPerCredential is how many times a device credential can be wrong in a window.
+	ChildDeviceMaxAttemptsPerCredential = 5
+	// ChildDeviceMaxAttemptsPerIP is how many device verifications can fail from the same IP address in a window.
+	// It's higher than the credential limit since schools share the IP address of their devices.
+	ChildDeviceMaxAttemptsPerIP = 50
+	// ChildDeviceAttemptWindowMinutes is the window of the device verification attempt limits.
+	ChildDeviceAttemptWindowMinutes = 15
+
+	// ParentalConsentCodeByteLength is the length of the code sent to the parent to consent to an account.
+	ParentalConsentCodeByteLength = 16
+	// ParentalConsentRequestValidDays is how long a parent can consent to an account after the request was sent.
+	ParentalConsentRequestValidDays = 14
+	// ParentalConsentMaxResendsPerAccount is how many times a child account can resend the consent request in a window.
+	ParentalConsentMaxResendsPerAccount = 3
+	// ParentalConsentResendWindowMinutes is the window of the consent request resend limit.
+	ParentalConsentResendWindowMinutes = 24 * 60
+
+	// MarketingOptInCodeByteLength is the length of the code sent to confirm the opt-in to the marketing notifications.
+	MarketingOptInCodeByteLength = 4
+	// MarketingOptInValidDays is how long an opt-in to the marketing notifications can be confirmed.
+	MarketingOptInValidDays = 7
+
+	// LegacyPasswordMaxAttemptsPerAccount is how many times the legacy password of an account can be wrong in a window.
+	LegacyPasswordMaxAttemptsPerAccount = 5
+	// LegacyPasswordMaxAttemptsPerIP is how many legacy password migrations can fail from the same IP address in a window.
+	// It's higher than the account limit since schools and families share their IP addresses.
+	LegacyPasswordMaxAttemptsPerIP = 50
+	// LegacyPasswordAttemptWindowMinutes is the window of the legacy password attempt limits.
+	LegacyPasswordAttemptWindowMinutes = 15
 )
 
+// IsValidCountryCodeFormat checks if a country code looks like an ISO 3166-1 alpha-2 code.
+func IsValidCountryCodeFormat(countryCode string) bool {
+	if len(countryCode) != 2 {
+		return false
+	}
+
+	for i := 0; i < len(countryCode); i++ {
+		if countryCode[i] < 'A' || countryCode[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
 func HandlePasswordValidatorError(c echo.Context, err error) error {
 	switch err.(type) {
 	case *passwords.PasswordTooShortError:
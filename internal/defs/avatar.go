@@ -0,0 +1,31 @@
+package defs
+
+import "strconv"
+
+// AvatarSizes are the widths in pixels of the normalised square avatars.
+var AvatarSizes = []int{64, 128, 512}
+
+// IsValidAvatarSize checks if an avatar size is one of the normalised sizes.
+func IsValidAvatarSize(size int) bool {
+	for _, avatarSize := range AvatarSizes {
+		if avatarSize == size {
+			return true
+		}
+	}
+	return false
+}
+
+// AvatarSizePath returns the storage path of the avatars of a given size.
+func AvatarSizePath(avatarPath string, size int) string {
+	return avatarPath + strconv.Itoa(size) + "px/"
+}
+
+// AvatarPresetPath returns the storage path of the preset avatars.
+func AvatarPresetPath(avatarPath string) string {
+	return avatarPath + "presets/"
+}
+
+// AvatarQuarantinePath returns the storage path of the uploaded avatars waiting for moderation.
+func AvatarQuarantinePath(avatarPath string) string {
+	return avatarPath + "quarantine/"
+}
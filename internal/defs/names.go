@@ -0,0 +1,35 @@
+package defs
+
+import (
+	"unicode/utf8"
+
+	"bitbucket.org/calmisland/go-server-utils/textutils"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLocalizedNames is the maximum number of name variants of an account.
+const MaxLocalizedNames = 8
+
+// SanitizeName sanitizes a name and normalizes it to the NFC form,
+// so the same name typed with different input methods is stored the same way.
+func SanitizeName(name string) string {
+	return norm.NFC.String(textutils.SanitizeString(name))
+}
+
+// NameLength returns the number of characters of a name.
+// The name length limits count characters rather than bytes so they are the same for every script.
+func NameLength(name string) int {
+	return utf8.RuneCountInString(name)
+}
+
+// CheckNameLengths returns the name that is too long along with its maximum length, or an empty name if they all fit.
+func CheckNameLengths(fullName string, firstName string, lastName string) (string, int) {
+	if NameLength(fullName) > MaxFullNameLength {
+		return "fullName", MaxFullNameLength
+	} else if NameLength(firstName) > MaxPartNameLength {
+		return "firstName", MaxPartNameLength
+	} else if NameLength(lastName) > MaxPartNameLength {
+		return "lastName", MaxPartNameLength
+	}
+	return "", 0
+}
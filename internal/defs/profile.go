@@ -0,0 +1,172 @@
+package defs
+
+import (
+	"strings"
+	"time"
+
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+	"bitbucket.org/calmisland/go-server-utils/emailutils"
+
+	// Embeds the timezone database since the Lambda runtime doesn't have one
+	_ "time/tzdata"
+)
+
+const (
+	MaxDisplayNameLength = 32
+	MaxTimezoneLength    = 64
+
+	MinBirthYear = 1900
+
+	// DefaultParentalConsentAge is the age under which a parent must consent to the account, as required by COPPA.
+	DefaultParentalConsentAge = 13
+	// DefaultAdulthoodAge is the age from which an account is an adult.
+	DefaultAdulthoodAge = 18
+
+	// DefaultTimezone is the timezone of the accounts whose country has no known timezone.
+	DefaultTimezone = "UTC"
+)
+
+// countryCodes are the officially assigned ISO 3166-1 alpha-2 codes.
+var countryCodes = makeCountryCodeSet("" +
+	"AD AE AF AG AI AL AM AO AQ AR AS AT AU AW AX AZ BA BB BD BE BF BG BH BI BJ BL BM BN BO BQ BR BS BT BV BW BY BZ " +
+	"CA CC CD CF CG CH CI CK CL CM CN CO CR CU CV CW CX CY CZ DE DJ DK DM DO DZ EC EE EG EH ER ES ET FI FJ FK FM FO FR " +
+	"GA GB GD GE GF GG GH GI GL GM GN GP GQ GR GS GT GU GW GY HK HM HN HR HT HU ID IE IL IM IN IO IQ IR IS IT JE JM JO JP " +
+	"KE KG KH KI KM KN KP KR KW KY KZ LA LB LC LI LK LR LS LT LU LV LY MA MC MD ME MF MG MH MK ML MM MN MO MP MQ MR MS MT " +
+	"MU MV MW MX MY MZ NA NC NE NF NG NI NL NO NP NR NU NZ OM PA PE PF PG PH PK PL PM PN PR PS PT PW PY QA RE RO RS RU RW " +
+	"SA SB SC SD SE SG SH SI SJ SK SL SM SN SO SR SS ST SV SX SY SZ TC TD TF TG TH TJ TK TL TM TN TO TR TT TV TW TZ UA UG " +
+	"UM US UY UZ VA VC VE VG VI VN VU WF WS YE YT ZA ZM ZW")
+
+// parentalConsentAges are the countries whose parental consent age differs from the default one, such as 14 under the Korean PIPA.
+var parentalConsentAges = map[string]int{
+	"KR": 14,
+}
+
+// adulthoodAges are the countries whose adulthood age differs from the default one.
+var adulthoodAges = map[string]int{
+	"KR": 19,
+}
+
+// The age bands of the accounts, the accounts without a birth date having no band.
+const (
+	// AgeBandChild accounts are under the parental consent age of their country.
+	AgeBandChild = "child"
+	// AgeBandTeen accounts are over the parental consent age but under the adulthood age of their country.
+	AgeBandTeen = "teen"
+	// AgeBandAdult accounts are over the adulthood age of their country.
+	AgeBandAdult = "adult"
+)
+
+func makeCountryCodeSet(codes string) map[string]bool {
+	set := map[string]bool{}
+	for _, code := range strings.Fields(codes) {
+		set[code] = true
+	}
+	return set
+}
+
+// IsValidCountryCode checks if a country code is an assigned ISO 3166-1 alpha-2 code.
+func IsValidCountryCode(countryCode string) bool {
+	return countryCodes[countryCode]
+}
+
+// IsValidTimezone checks if a timezone is an IANA timezone name.
+func IsValidTimezone(timezone string) bool {
+	if len(timezone) == 0 || len(timezone) > MaxTimezoneLength || timezone == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(timezone)
+	return err == nil
+}
+
+// GetCountryDefaultTimezone returns the default timezone of a country.
+func GetCountryDefaultTimezone(countryCode string) string {
+	if timezone, exists := countryTimezones[countryCode]; exists {
+		return timezone
+	}
+	return DefaultTimezone
+}
+
+// IsValidBirthDate checks if a birth year, and optionally a month, is not in the future.
+// The month is zero when only the birth year is known.
+func IsValidBirthDate(year int, month int, now time.Time) bool {
+	if year < MinBirthYear || year > now.Year() || month < 0 || month > 12 {
+		return false
+	}
+	return year < now.Year() || month <= int(now.Month())
+}
+
+// GetAge returns the age of an account from its birth date.
+// The youngest possible age is returned when the birth month, or the day in the birth month, is unknown.
+func GetAge(year int, month int, now time.Time) int {
+	age := now.Year() - year
+	if month == 0 || month >= int(now.Month()) {
+		age--
+	}
+	if age < 0 {
+		return 0
+	}
+	return age
+}
+
+// GetParentalConsentAge returns the age under which a parent must consent to the accounts of a country.
+func GetParentalConsentAge(countryCode string) int {
+	if age, exists := parentalConsentAges[countryCode]; exists {
+		return age
+	}
+	return DefaultParentalConsentAge
+}
+
+// RequiresParentalConsent checks if an account born at a birth date needs the consent of a parent in a country.
+func RequiresParentalConsent(year int, month int, countryCode string, now time.Time) bool {
+	return GetAge(year, month, now) < GetParentalConsentAge(countryCode)
+}
+
+// ValidateSignUpBirthDate validates the optional birth date given at sign-up in a country, and the parent email required
+// when the user is under the parental consent age of the country. It returns the parent email to ask for consent,
+// empty if none is needed, or the error of the first invalid field.
+func ValidateSignUpBirthDate(year int, month int, parentEmail string, userEmail string, countryCode string, now time.Time) (string, *apierrors.APIError) {
+	if year == 0 {
+		// The month can't be set without the year
+		if month != 0 {
+			return "", apierrors.ErrorInvalidParameters.WithField("birthMonth")
+		}
+		return "", nil
+	}
+
+	if !IsValidBirthDate(year, month, now) {
+		return "", apierrors.ErrorInvalidParameters.WithField("birthYear")
+	} else if !RequiresParentalConsent(year, month, countryCode, now) {
+		return "", nil
+	}
+
+	parentEmail = strings.TrimSpace(parentEmail)
+	if len(parentEmail) == 0 {
+		return "", apierrors.ErrorInvalidParameters.WithField("parentEmail")
+	} else if !emailutils.IsValidEmailAddressFormat(parentEmail) || strings.EqualFold(parentEmail, userEmail) {
+		return "", apierrors.ErrorInputInvalidFormat.WithField("parentEmail")
+	}
+	return parentEmail, nil
+}
+
+// GetAdulthoodAge returns the age from which the accounts of a country are adults.
+func GetAdulthoodAge(countryCode string) int {
+	if age, exists := adulthoodAges[countryCode]; exists {
+		return age
+	}
+	return DefaultAdulthoodAge
+}
+
+// GetAgeBand returns the age band of an account born at a birth date in a country, or an empty band if the birth year is unknown.
+func GetAgeBand(year int, month int, countryCode string, now time.Time) string {
+	if year == 0 {
+		return ""
+	}
+
+	age := GetAge(year, month, now)
+	if age < GetParentalConsentAge(countryCode) {
+		return AgeBandChild
+	} else if age < GetAdulthoodAge(countryCode) {
+		return AgeBandTeen
+	}
+	return AgeBandAdult
+}
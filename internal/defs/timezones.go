@@ -0,0 +1,66 @@
+package defs
+
+import (
+	"bufio"
+	"strings"
+	"time"
+
+	_ "embed"
+)
+
+// zoneTab is the zone.tab file of the tz database 2025b, which lists the timezones of each country.
+// It's used rather than zone1970.tab since each row belongs to a single country, so the timezones are named
+// after the country, such as Europe/Copenhagen rather than Europe/Berlin.
+//
+//go:embed zone.tab
+var zoneTab string
+
+// populousTimezones are the most populated timezones of the countries whose zone.tab rows are only in geographic order.
+var populousTimezones = map[string]string{
+	"AU": "Australia/Sydney",
+	"BR": "America/Sao_Paulo",
+	"CA": "America/Toronto",
+	"RU": "Europe/Moscow",
+	// Europe/Kyiv is missing from the tz database of the older Go versions
+	"UA": "Europe/Kiev",
+}
+
+// countryTimezones are the default timezones of the countries, by country code.
+var countryTimezones = parseCountryTimezones(zoneTab)
+
+// parseCountryTimezones picks a default timezone for each country of a zone.tab file: the most populated one if known,
+// else the first one that covers most of the country, else the first one of the country. The timezones that the
+// embedded tz database doesn't know are skipped.
+func parseCountryTimezones(table string) map[string]string {
+	timezones := map[string]string{}
+	coversMost := map[string]bool{}
+	scanner := bufio.NewScanner(strings.NewReader(table))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// The columns are the country code, the coordinates, the timezone and an optional comment
+		columns := strings.Split(line, "\t")
+		if len(columns) < 3 {
+			continue
+		}
+
+		countryCode, timezone := columns[0], columns[2]
+		isMost := len(columns) > 3 && strings.HasPrefix(columns[3], "most ")
+		if _, exists := timezones[countryCode]; exists && (coversMost[countryCode] || !isMost) {
+			continue
+		} else if _, err := time.LoadLocation(timezone); err != nil {
+			continue
+		}
+
+		timezones[countryCode] = timezone
+		coversMost[countryCode] = isMost
+	}
+
+	for countryCode, timezone := range populousTimezones {
+		timezones[countryCode] = timezone
+	}
+	return timezones
+}
@@ -1,7 +1,14 @@
 package globals
 
 import (
+	"time"
+
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/accountverificationservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/avatarmoderation"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/namefilter"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/policies"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/purchaseverification"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/visibility"
 	"bitbucket.org/calmisland/go-server-account/accountdatabase"
 	"bitbucket.org/calmisland/go-server-account/avatars"
 	"bitbucket.org/calmisland/go-server-geoip/geoip"
@@ -28,12 +35,34 @@ var (
 
 	// AvatarStorage is store handle avatar image.
 	AvatarStorage avatars.Storage
+	// AvatarSizeStorages are the stores of the normalised avatar images, by size.
+	AvatarSizeStorages map[int]avatars.Storage
+	// AvatarPresetStorage is the store of the preset avatar images, named by preset ID.
+	AvatarPresetStorage avatars.Storage
+	// AvatarQuarantineStorage is the store of the uploaded avatars waiting for moderation.
+	AvatarQuarantineStorage avatars.Storage
+	// AvatarModerator is the moderator of the uploaded avatars.
+	AvatarModerator avatarmoderation.Moderator
 
 	// AccountVerificationService is the account verification service.
 	AccountVerificationService accountverificationservice.Service
 
 	// AccountDatabase is the account database.
 	AccountDatabase accountdatabase.Database
+
+	// RelationshipResolver resolves the relationships used by the profile visibility settings.
+	RelationshipResolver visibility.Resolver
+
+	// NameFilter checks the account names against the blocked words.
+	NameFilter namefilter.Filter
+
+	// PurchaseVerifier verifies the in-app purchase receipts with their store.
+	PurchaseVerifier purchaseverification.Verifier
+
+	// RequiredPolicyVersions are the policy versions that the accounts must accept.
+	RequiredPolicyVersions policies.Versions
+	// PolicyConsentGraceEndDate is when the accounts that never accepted any policy stop being let through.
+	PolicyConsentGraceEndDate time.Time
 )
 
 // Verify verifies if all variables have been properly set.
@@ -58,6 +87,14 @@ func Verify() {
 
 	if AvatarStorage == nil {
 		panic(errors.New("The avatar storage has not been set"))
+	} else if AvatarSizeStorages == nil {
+		panic(errors.New("The avatar size storages have not been set"))
+	} else if AvatarPresetStorage == nil {
+		panic(errors.New("The avatar preset storage has not been set"))
+	} else if AvatarQuarantineStorage == nil {
+		panic(errors.New("The avatar quarantine storage has not been set"))
+	} else if AvatarModerator == nil {
+		panic(errors.New("The avatar moderator has not been set"))
 	}
 
 	if AccountVerificationService == nil {
@@ -67,4 +104,18 @@ func Verify() {
 	if AccountDatabase == nil {
 		panic(errors.New("The account database has not been set"))
 	}
+
+	if RelationshipResolver == nil {
+		panic(errors.New("The relationship resolver has not been set"))
+	} else if NameFilter == nil {
+		panic(errors.New("The name filter has not been set"))
+	}
+
+	if PurchaseVerifier == nil {
+		panic(errors.New("The purchase verifier has not been set"))
+	}
+
+	if len(RequiredPolicyVersions) == 0 {
+		panic(errors.New("The required policy versions have not been set"))
+	}
 }
@@ -0,0 +1,30 @@
+package helpers
+
+import (
+	"errors"
+	"net/http"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/utils"
+	"github.com/labstack/echo/v4"
+)
+
+// ErrAdminRoleRequired is returned when a non-admin account calls an admin endpoint.
+var ErrAdminRoleRequired = errors.New("An admin role is required for this request")
+
+// AdminRoleMiddleware only lets through requests made by accounts with an admin role.
+// It must be used after the authentication middleware.
+func AdminRoleMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		accountID := GetAccountID(c)
+
+		accInfo, err := globals.AccountDatabase.GetAccountSignInInfoByID(accountID)
+		if err != nil {
+			return HandleInternalError(c, err)
+		} else if accInfo == nil || accInfo.AdminRole <= 0 {
+			return utils.EchoHandleHTTPError(http.StatusForbidden, ErrAdminRoleRequired)
+		}
+
+		return next(c)
+	}
+}
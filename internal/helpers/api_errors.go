@@ -0,0 +1,30 @@
+package helpers
+
+import (
+	"net/http"
+
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+)
+
+// The codes of the API errors of the account functions, in their own range so they don't collide with the ones of go-server-requests.
+const (
+	errorCodeTooManyAttempts = 100001 + iota
+	errorCodePolicyConsentRequired
+)
+
+var (
+	// ErrorTooManyAttempts is returned when an action failed too many times and must be tried again later.
+	ErrorTooManyAttempts = &apierrors.APIError{
+		StatusCode: http.StatusTooManyRequests,
+		ErrorCode:  errorCodeTooManyAttempts,
+		ErrorName:  "ERR_TOO_MANY_ATTEMPTS",
+		Message:    "Too many failed attempts, try again later",
+	}
+	// ErrorPolicyConsentRequired is returned until the account accepts the current policy versions.
+	ErrorPolicyConsentRequired = &apierrors.APIError{
+		StatusCode: http.StatusForbidden,
+		ErrorCode:  errorCodePolicyConsentRequired,
+		ErrorName:  "ERR_POLICY_CONSENT_REQUIRED",
+		Message:    "The current policies must be accepted",
+	}
+)
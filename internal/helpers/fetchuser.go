@@ -1,12 +1,17 @@
 package helpers
 
 import (
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
 	"bitbucket.org/calmisland/go-server-auth/authmiddlewares"
 	"github.com/getsentry/sentry-go"
 	sentryecho "github.com/getsentry/sentry-go/echo"
 	"github.com/labstack/echo/v4"
 )
 
+// ProfileContextKey is the key of the profile of the signed in account in the request context.
+const ProfileContextKey = "accountProfile"
+
 // GetAccountID will extract an AccountID from a JWT
 func GetAccountID(c echo.Context) string {
 	cc := c.(*authmiddlewares.AuthContext)
@@ -20,3 +25,18 @@ func GetAccountID(c echo.Context) string {
 	})
 	return accountID
 }
+
+// GetProfile returns the profile of the signed in account.
+// It's loaded once per request, so the middlewares checking the profile share it.
+func GetProfile(c echo.Context) (*models.AccountProfile, error) {
+	if profile, ok := c.Get(ProfileContextKey).(*models.AccountProfile); ok {
+		return profile, nil
+	}
+
+	profile, err := profileservice.GetProfile(GetAccountID(c))
+	if err != nil {
+		return nil, err
+	}
+	c.Set(ProfileContextKey, profile)
+	return profile, nil
+}
@@ -0,0 +1,28 @@
+package helpers
+
+import (
+	"errors"
+	"net/http"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/utils"
+	"github.com/labstack/echo/v4"
+)
+
+// ErrParentalConsentRequired is returned when a child account calls an endpoint before its parent consented.
+var ErrParentalConsentRequired = errors.New("The consent of a parent is required for this request")
+
+// ParentalConsentMiddleware only lets through requests made by accounts that don't wait for the consent of a parent.
+// It must be used after the authentication middleware.
+func ParentalConsentMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		profile, err := GetProfile(c)
+		if err != nil {
+			return HandleInternalError(c, err)
+		} else if profile.ParentalConsentStatus == models.ParentalConsentStatusPending {
+			return utils.EchoHandleHTTPError(http.StatusForbidden, ErrParentalConsentRequired)
+		}
+
+		return next(c)
+	}
+}
@@ -0,0 +1,29 @@
+package helpers
+
+import (
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/policies"
+	"bitbucket.org/calmisland/go-server-requests/apirequests"
+	"github.com/labstack/echo/v4"
+)
+
+// PolicyConsentMiddleware only lets through requests made by accounts that accepted the current policy versions.
+// The accounts that never accepted any policy are let through until the end of the grace period.
+// It must be used after the authentication middleware.
+func PolicyConsentMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		profile, err := GetProfile(c)
+		if err != nil {
+			return HandleInternalError(c, err)
+		}
+
+		missing := globals.RequiredPolicyVersions.Missing(profile.AcceptedPolicies)
+		if len(missing) > 0 && !policies.IsInGracePeriod(profile.AcceptedPolicies, globals.PolicyConsentGraceEndDate, time.Now()) {
+			return apirequests.EchoSetClientError(c, ErrorPolicyConsentRequired)
+		}
+
+		return next(c)
+	}
+}
@@ -0,0 +1,42 @@
+// Package messagetemplates has the message templates of the account functions that are not in the message templates
+// of go-server-messages yet. They are sent through the message queue like the templates of go-server-messages.
+package messagetemplates
+
+// AvatarRejectedTemplate is the message telling an account that its uploaded avatar was rejected by the moderation.
+// The message is localized in the language of the account when sent.
+type AvatarRejectedTemplate struct {
+	Reason string `json:"reason"`
+}
+
+// FamilyInvitationTemplate is the message telling an account that it was invited to join a family.
+// The message is localized in the language of the account when sent.
+type FamilyInvitationTemplate struct {
+	FamilyID string `json:"familyId"`
+	// ExpirationDate is when the invitation expires, in epoch milliseconds.
+	ExpirationDate int64 `json:"expirationTm"`
+}
+
+// MarketingOptInTemplate is the message with the code confirming the opt-in of an account to the marketing notifications.
+// The message is localized in the language of the account when sent.
+type MarketingOptInTemplate struct {
+	Code string `json:"code"`
+}
+
+// ParentalConsentTemplate is the message asking a parent to consent to the account of their child.
+// The message is localized in the language of the child account when sent.
+type ParentalConsentTemplate struct {
+	// Link opens the page where the parent grants or refuses the consent.
+	Link string `json:"link"`
+	// ExpirationDate is when the consent request expires, in epoch milliseconds.
+	ExpirationDate int64 `json:"expirationTm"`
+}
+
+// PassExpiryTemplate is the message reminding an account that a pass is about to expire.
+// The message is localized in the language of the account when sent.
+type PassExpiryTemplate struct {
+	PassID string `json:"passId"`
+	// ExpirationDate is when the pass expires, in epoch milliseconds.
+	ExpirationDate int64 `json:"expirationTm"`
+	// DaysLeft is the reminder window, in days.
+	DaysLeft int `json:"daysLeft"`
+}
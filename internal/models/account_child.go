@@ -0,0 +1,26 @@
+package models
+
+const (
+	TABLE_NAME_ACCOUNT_CHILDREN         = "account_children"
+	TABLE_NAME_CHILD_DEVICE_CREDENTIALS = "child_device_credentials"
+
+	CHILD_DEVICE_CREDENTIAL_GSI_CHILDID = "childId"
+)
+
+// AccountChild links a parent account to a child profile it manages.
+type AccountChild struct {
+	ParentID    string `dynamo:"accId,hash"`
+	ChildID     string `dynamo:"childId,range"`
+	CreatedDate int64  `dynamo:"createTm"`
+}
+
+// ChildDeviceCredential is a credential that lets a child profile sign in on a device set up by its parent.
+type ChildDeviceCredential struct {
+	CredentialID string `dynamo:"credentialId,hash" json:"credentialId"`
+	ChildID      string `dynamo:"childId" index:"childId,hash" json:"-"`
+	ParentID     string `dynamo:"parentId" json:"-"`
+	SecretHash   string `dynamo:"secretHash" json:"-"`
+	DeviceName   string `dynamo:"deviceName,omitempty" json:"deviceName,omitempty"`
+	CreatedDate  int64  `dynamo:"createTm" json:"createTm"`
+	LastUsedDate int64  `dynamo:"lastUseTm,omitempty" json:"lastUseTm,omitempty"`
+}
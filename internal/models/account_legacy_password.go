@@ -0,0 +1,17 @@
+package models
+
+const (
+	TABLE_NAME_ACCOUNT_LEGACY_PASSWORDS = "account_legacy_passwords"
+)
+
+// AccountLegacyPassword is the password hash an account was imported with from another product.
+// It's deleted once the password has been rehashed into the native format.
+type AccountLegacyPassword struct {
+	AccountID   string `dynamo:"accId,hash"`
+	Algorithm   string `dynamo:"algorithm"`
+	Hash        string `dynamo:"hash"`
+	Salt        string `dynamo:"salt,omitempty"`
+	Iterations  int    `dynamo:"iterations,omitempty"`
+	Source      string `dynamo:"source,omitempty"`
+	CreatedDate int64  `dynamo:"createTm"`
+}
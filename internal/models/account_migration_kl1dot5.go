@@ -0,0 +1,24 @@
+package models
+
+import "bitbucket.org/calmisland/go-server-account/accountdatabase"
+
+const (
+	TABLE_NAME_ACCOUNTS_MIGRATION_KL1DOT5 = "accounts_migration_kl1dot5"
+	// ACCOUNTS_MIGRATION_KL1DOT5_GSI_PHONENR is the global secondary index of the KL1.5 records by phone number.
+	// The KL1.5 export doesn't create it: it must be added to the table, with the "phoneNr" string attribute as its
+	// hash key and all the attributes projected, before the phone-only accounts can be migrated.
+	ACCOUNTS_MIGRATION_KL1DOT5_GSI_PHONENR = "phoneNr"
+)
+
+// AccountMigrationKl1dot5 is a KL1.5 account waiting to be migrated to AMS.
+// It mirrors the record read by the account database KL1.5 migration functions.
+// The "email" hash key is the email address of the account, except for the phone-only KL1.5 accounts:
+// those have no email address, so the KL1.5 export keys them by their legacy login ID instead, and they
+// are looked up through the phone number index. The status updates always use the hash key.
+type AccountMigrationKl1dot5 struct {
+	Email           string                                         `dynamo:"email,hash"`
+	PhoneNumber     string                                         `dynamo:"phoneNr,omitempty" index:"phoneNr,hash"`
+	PwHash          string                                         `dynamo:"pwHash"`
+	PwHashSecret    string                                         `dynamo:"pwHashSecret"`
+	MigrationStatus accountdatabase.AccountsKl1dot5MigrationStatus `dynamo:"migrationStatus"`
+}
@@ -0,0 +1,36 @@
+package models
+
+const (
+	TABLE_NAME_ACCOUNTS_MIGRATION_KL1DOT5_EVENTS = "accounts_migration_kl1dot5_events"
+)
+
+// AccountMigrationKl1dot5Event is a structured event recorded for each KL1.5 migration attempt.
+// Events are partitioned by their UTC day so the report can query a date range.
+type AccountMigrationKl1dot5Event struct {
+	Day         string `dynamo:"day,hash" json:"day"`
+	EventID     string `dynamo:"eventId,range" json:"eventId"`
+	Kind        string `dynamo:"kind" json:"kind"`
+	Reason      string `dynamo:"reason,omitempty" json:"reason,omitempty"`
+	Source      string `dynamo:"source" json:"source"`
+	Email       string `dynamo:"email,omitempty" json:"email,omitempty"`
+	PhoneNumber string `dynamo:"phoneNr,omitempty" json:"phoneNr,omitempty"`
+	AccountID   string `dynamo:"accId,omitempty" json:"accId,omitempty"`
+	CreatedDate int64  `dynamo:"createTm" json:"createTm"`
+	// ExpirationTime is when DynamoDB deletes the event, in seconds since the epoch. Zero if it's kept.
+	ExpirationTime int64 `dynamo:"ttl,omitempty" json:"ttl,omitempty"`
+}
+
+const (
+	// ACCOUNTS_MIGRATION_KL1DOT5_STATUS_COUNTS_DAY is the partition of the events table keeping the status counts, which is never a day.
+	ACCOUNTS_MIGRATION_KL1DOT5_STATUS_COUNTS_DAY = "status-counts"
+	ACCOUNTS_MIGRATION_KL1DOT5_STATUS_COUNTS_ID  = "all"
+)
+
+// AccountMigrationKl1dot5StatusCounts are the running counts of the KL1.5 records done and failed, updated with their migration status.
+// They are kept in the events table so the report doesn't scan the KL1.5 records.
+type AccountMigrationKl1dot5StatusCounts struct {
+	Day     string `dynamo:"day,hash" json:"-"`
+	EventID string `dynamo:"eventId,range" json:"-"`
+	Done    int    `dynamo:"done" json:"done"`
+	Failed  int    `dynamo:"failed" json:"failed"`
+}
@@ -0,0 +1,12 @@
+package models
+
+const (
+	TABLE_NAME_ACCOUNT_ORGANIZATIONS = "account_organizations"
+)
+
+// AccountOrganization is the membership of an account in an organisation, such as a school.
+type AccountOrganization struct {
+	AccountID      string `dynamo:"accId,hash"`
+	OrganizationID string `dynamo:"orgId,range"`
+	CreatedDate    int64  `dynamo:"createTm"`
+}
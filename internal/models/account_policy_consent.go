@@ -0,0 +1,23 @@
+package models
+
+const (
+	TABLE_NAME_ACCOUNT_POLICY_CONSENTS = "account_policy_consents"
+)
+
+// The policies that the accounts must accept.
+const (
+	PolicyTermsOfService = "tos"
+	PolicyPrivacy        = "privacy"
+)
+
+// AccountPolicyConsent is the record of an account accepting a version of a policy.
+type AccountPolicyConsent struct {
+	AccountID string `dynamo:"accId,hash" json:"-"`
+	// ConsentID is the policy and its version, separated by "#"
+	ConsentID    string `dynamo:"consentId,range" json:"-"`
+	Policy       string `dynamo:"policy" json:"policy"`
+	Version      string `dynamo:"version" json:"version"`
+	AcceptedDate int64  `dynamo:"acceptTm" json:"acceptTm"`
+	IPAddress    string `dynamo:"ip,omitempty" json:"ip,omitempty"`
+	Language     string `dynamo:"lang,omitempty" json:"lang,omitempty"`
+}
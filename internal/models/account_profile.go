@@ -0,0 +1,58 @@
+package models
+
+const (
+	TABLE_NAME_ACCOUNT_PROFILES = "account_profiles"
+)
+
+// AccountProfile holds the account settings that are not managed by the account database.
+type AccountProfile struct {
+	AccountID      string `dynamo:"accId,hash"`
+	AvatarPresetID string `dynamo:"avatarPresetId,omitempty"`
+	IsChild        bool   `dynamo:"isChild,omitempty"`
+	// ParentID is the account managing the child profile, empty for accounts that were not created by a parent.
+	ParentID string `dynamo:"parentId,omitempty"`
+	// ChildCount is the number of child profiles managed by the account
+	ChildCount int `dynamo:"childCount,omitempty"`
+	// ParentalConsentStatus is set for the child accounts that need the consent of a parent
+	ParentalConsentStatus string `dynamo:"consentStatus,omitempty"`
+	// Visibility are the visibility levels of the profile fields, by field name.
+	Visibility map[string]string `dynamo:"visibility,omitempty"`
+
+	DisplayName string `dynamo:"displayName,omitempty"`
+	// BirthMonth is zero when only the birth year is known.
+	BirthYear  int    `dynamo:"birthYear,omitempty"`
+	BirthMonth int    `dynamo:"birthMonth,omitempty"`
+	Timezone   string `dynamo:"timezone,omitempty"`
+	// Country overrides the country set from GeoIP when the account was created.
+	Country       string         `dynamo:"country,omitempty"`
+	PhoneticNames *PhoneticNames `dynamo:"phoneticNames,omitempty"`
+	// LocalizedNames are the variants of the names in other languages or scripts, by language tag such as "ja-Latn".
+	LocalizedNames map[string]*LocalizedName `dynamo:"localizedNames,omitempty"`
+
+	// AcceptedPolicies are the last accepted versions of the policies, by policy
+	AcceptedPolicies map[string]string `dynamo:"acceptedPolicies,omitempty"`
+
+	// Notifications are the statuses of the notification categories, by category and channel
+	Notifications map[string]map[string]string `dynamo:"notifications,omitempty"`
+
+	// AgeBand is the age band when the birth date was set, in the country used for its thresholds
+	AgeBand        string `dynamo:"ageBand,omitempty"`
+	AgeBandCountry string `dynamo:"ageBandCountry,omitempty"`
+
+	UpdatedDate int64 `dynamo:"updateTm,omitempty"`
+}
+
+// LocalizedName is a variant of the account names in another language or script.
+type LocalizedName struct {
+	FullName      string         `dynamo:"fullName,omitempty"`
+	FirstName     string         `dynamo:"firstName,omitempty"`
+	LastName      string         `dynamo:"lastName,omitempty"`
+	PhoneticNames *PhoneticNames `dynamo:"phoneticNames,omitempty"`
+}
+
+// PhoneticNames are the pronunciation of the account names, such as the furigana of Japanese names.
+type PhoneticNames struct {
+	FullName  string `dynamo:"fullName,omitempty"`
+	FirstName string `dynamo:"firstName,omitempty"`
+	LastName  string `dynamo:"lastName,omitempty"`
+}
@@ -3,6 +3,8 @@ package models
 const (
 	TABLE_NAME_ACCOUNT_TRANSACTIONS = "account_transactions"
 	// ACCOUNT_GSI_ACCID        = "accId"
+	// ACCOUNT_TRANSACTION_GSI_CREATETM sorts the transactions of an account by creation date
+	ACCOUNT_TRANSACTION_GSI_CREATETM = "accId-createTm"
 )
 
 type AccountTransactionItem struct {
@@ -10,13 +12,23 @@ type AccountTransactionItem struct {
 	Currency       string `dynamo:"currency" json:"currency"`
 	StartDate      int    `dynamo:"startTm" json:"startTm"`
 	ExpirationDate int    `dynamo:"expirationTm" json:"expirationTm"`
+	// Revoked tells that the item is revoked from its start date, ending the periods granted by the earlier transactions.
+	Revoked bool `dynamo:"revoked,omitempty" json:"revoked,omitempty"`
+	// Shareable tells that the item is shared with the members of the family of the account.
+	Shareable bool `dynamo:"shareable,omitempty" json:"shareable,omitempty"`
 }
 
 type AccountTransaction struct {
-	AccountID     string                             `dynamo:"accId,hash" json:"accId"`
+	AccountID     string                             `dynamo:"accId,hash" index:"accId-createTm,hash" json:"accId"`
 	TransactionID string                             `dynamo:"transactionId,range" json:"transactionId"`
 	Passes        map[string]*AccountTransactionItem `dynamo:"passes" json:"passes"`
 	Products      map[string]*AccountTransactionItem `dynamo:"products" json:"products"`
-	CreatedDate   int                                `dynamo:"createTm" json:"createTm"`
+	CreatedDate   int                                `dynamo:"createTm" index:"accId-createTm,range" json:"createTm"`
 	UpdatedDate   int                                `dynamo:"updateTm" json:"updateTm"`
+	// CreatedBy is the admin account that created the transaction, if it wasn't created by the account itself.
+	CreatedBy string `dynamo:"createdBy,omitempty" json:"createdBy,omitempty"`
+	// Reason explains why an admin created the transaction.
+	Reason string `dynamo:"reason,omitempty" json:"reason,omitempty"`
+	// Sandbox tells that the transaction is a purchase in the sandbox environment of a store, which wasn't paid.
+	Sandbox bool `dynamo:"sandbox,omitempty" json:"sandbox,omitempty"`
 }
@@ -0,0 +1,14 @@
+package models
+
+const (
+	TABLE_NAME_ATTEMPT_COUNTERS = "attempt_counters"
+)
+
+// AttemptCounter counts the failed attempts of a limited action in a time window.
+// The key contains the window, so a new window starts a new counter and DynamoDB deletes the old ones.
+type AttemptCounter struct {
+	Key      string `dynamo:"key,hash" json:"key"`
+	Attempts int    `dynamo:"attempts" json:"attempts"`
+	// ExpirationTime is when DynamoDB deletes the counter, in seconds since the epoch.
+	ExpirationTime int64 `dynamo:"ttl" json:"ttl"`
+}
@@ -0,0 +1,18 @@
+package models
+
+const (
+	TABLE_NAME_EXPIRY_REMINDERS = "expiry_reminders"
+)
+
+// ExpiryReminder is a reminder sent to an account about a pass that is about to expire.
+// It's recorded before the reminder is sent so the same reminder is never sent twice.
+type ExpiryReminder struct {
+	AccountID string `dynamo:"accId,hash" json:"accId"`
+	// ReminderID identifies the pass, its expiration date and the reminder window.
+	ReminderID     string `dynamo:"reminderId,range" json:"reminderId"`
+	PassID         string `dynamo:"passId" json:"passId"`
+	ExpirationDate int64  `dynamo:"expirationTm" json:"expirationTm"`
+	WindowDays     int    `dynamo:"windowDays" json:"windowDays"`
+	Recipient      string `dynamo:"recipient" json:"recipient"`
+	SentDate       int64  `dynamo:"sentTm" json:"sentTm"`
+}
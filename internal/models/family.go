@@ -0,0 +1,42 @@
+package models
+
+const (
+	TABLE_NAME_FAMILIES           = "families"
+	TABLE_NAME_FAMILY_MEMBERS     = "family_members"
+	TABLE_NAME_FAMILY_INVITATIONS = "family_invitations"
+
+	FAMILY_MEMBER_GSI_FAMILYID     = "familyId"
+	FAMILY_INVITATION_GSI_FAMILYID = "familyId"
+)
+
+// The roles of the family members.
+const (
+	FamilyRoleOwner  = "owner"
+	FamilyRoleMember = "member"
+)
+
+// Family is a group of accounts sharing the shareable passes and products of its owner.
+type Family struct {
+	FamilyID string `dynamo:"familyId,hash" json:"familyId"`
+	OwnerID  string `dynamo:"ownerId" json:"ownerId"`
+	// MemberCount is the number of members, including the owner.
+	MemberCount int   `dynamo:"memberCount" json:"memberCount"`
+	CreatedDate int64 `dynamo:"createTm" json:"createTm"`
+}
+
+// FamilyMember is the membership of an account in a family. An account can only be a member of one family.
+type FamilyMember struct {
+	AccountID   string `dynamo:"accId,hash" json:"accountId"`
+	FamilyID    string `dynamo:"familyId" index:"familyId,hash" json:"familyId"`
+	Role        string `dynamo:"role" json:"role"`
+	CreatedDate int64  `dynamo:"createTm" json:"joinTm"`
+}
+
+// FamilyInvitation is an invitation of an account to join a family.
+type FamilyInvitation struct {
+	AccountID      string `dynamo:"accId,hash" json:"accountId"`
+	FamilyID       string `dynamo:"familyId,range" index:"familyId,hash" json:"familyId"`
+	InvitedBy      string `dynamo:"invitedBy" json:"invitedBy"`
+	ExpirationDate int64  `dynamo:"expirationTm" json:"expirationTm"`
+	CreatedDate    int64  `dynamo:"createTm" json:"createTm"`
+}
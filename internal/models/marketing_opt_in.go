@@ -0,0 +1,31 @@
+package models
+
+const (
+	TABLE_NAME_MARKETING_OPT_INS = "marketing_opt_ins"
+)
+
+// The statuses of the opt-ins to the marketing notifications.
+const (
+	// MarketingOptInStatusPending opt-ins wait for the code sent through the channel.
+	MarketingOptInStatusPending = "pending"
+	// MarketingOptInStatusConfirmed opt-ins were confirmed with the code.
+	MarketingOptInStatusConfirmed = "confirmed"
+	// MarketingOptInStatusWithdrawn opt-ins were confirmed, then the account disabled the marketing notifications.
+	MarketingOptInStatusWithdrawn = "withdrawn"
+)
+
+// MarketingOptIn is the double opt-in of an account to the marketing notifications on a channel.
+// The confirmed ones are kept as the proof of the consent.
+type MarketingOptIn struct {
+	AccountID      string `dynamo:"accId,hash"`
+	Channel        string `dynamo:"channel,range"`
+	Recipient      string `dynamo:"recipient"`
+	CodeHash       string `dynamo:"codeHash"`
+	Status         string `dynamo:"status"`
+	ExpirationDate int64  `dynamo:"expirationTm"`
+	RequestedDate  int64  `dynamo:"requestTm"`
+	RequestIP      string `dynamo:"requestIp"`
+	ConfirmedDate  int64  `dynamo:"confirmTm,omitempty"`
+	ConfirmIP      string `dynamo:"confirmIp,omitempty"`
+	WithdrawnDate  int64  `dynamo:"withdrawTm,omitempty"`
+}
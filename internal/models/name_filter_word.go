@@ -0,0 +1,14 @@
+package models
+
+const (
+	TABLE_NAME_NAME_FILTER_WORDS = "name_filter_words"
+)
+
+// NameFilterWord is a word blocked in the account names, managed by the admins.
+type NameFilterWord struct {
+	Language    string `dynamo:"lang,hash" json:"lang"`
+	Word        string `dynamo:"word,range" json:"word"`
+	Match       string `dynamo:"match" json:"match"`
+	CreatedBy   string `dynamo:"createdBy,omitempty" json:"createdBy,omitempty"`
+	CreatedDate int64  `dynamo:"createTm" json:"createTm"`
+}
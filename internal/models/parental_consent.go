@@ -0,0 +1,59 @@
+package models
+
+const (
+	TABLE_NAME_PARENTAL_CONSENTS       = "parental_consents"
+	TABLE_NAME_PARENTAL_CONSENT_EVENTS = "parental_consent_events"
+
+	PARENTAL_CONSENT_GSI_ACCID = "accId"
+)
+
+// The parental consent statuses of the child accounts.
+const (
+	// ParentalConsentStatusPending restricts the account until the parent consents.
+	ParentalConsentStatusPending = "pending"
+	// ParentalConsentStatusGranted lifts the restriction of the account.
+	ParentalConsentStatusGranted = "granted"
+)
+
+// The parental consent events recorded for audit.
+const (
+	ParentalConsentEventRequested = "requested"
+	ParentalConsentEventGranted   = "granted"
+	ParentalConsentEventWithdrawn = "withdrawn"
+)
+
+// The methods used by the parents to consent.
+const (
+	// ParentalConsentMethodEmail is the consent given through the link sent to the email of the parent.
+	ParentalConsentMethodEmail = "email"
+	// ParentalConsentMethodParentAccount is the consent given by a signed in parent creating a child profile.
+	ParentalConsentMethodParentAccount = "parent_account"
+)
+
+// ParentalConsent is a consent request sent to the parent of a child account.
+// The code sent to the parent is used to grant the consent and later to withdraw it.
+type ParentalConsent struct {
+	ConsentID      string `dynamo:"consentId,hash"`
+	AccountID      string `dynamo:"accId" index:"accId,hash"`
+	ParentEmail    string `dynamo:"parentEmail"`
+	CodeHash       string `dynamo:"codeHash"`
+	Status         string `dynamo:"status"`
+	ExpirationDate int64  `dynamo:"expirationTm"`
+	CreatedDate    int64  `dynamo:"createTm"`
+	GrantedDate    int64  `dynamo:"grantTm,omitempty"`
+}
+
+// ParentalConsentEvent is the audit record of a parental consent being requested, granted or withdrawn.
+// The events are kept when the account is deleted.
+type ParentalConsentEvent struct {
+	AccountID   string `dynamo:"accId,hash" json:"-"`
+	CreatedDate int64  `dynamo:"createTm,range" json:"createTm"`
+	Event       string `dynamo:"event" json:"event"`
+	Method      string `dynamo:"method,omitempty" json:"method,omitempty"`
+	ConsentID   string `dynamo:"consentId,omitempty" json:"consentId,omitempty"`
+	// ParentID is the parent account for the consents given by a signed in parent
+	ParentID    string `dynamo:"parentId,omitempty" json:"parentId,omitempty"`
+	ParentEmail string `dynamo:"parentEmail,omitempty" json:"parentEmail,omitempty"`
+	IPAddress   string `dynamo:"ip,omitempty" json:"ip,omitempty"`
+	UserAgent   string `dynamo:"userAgent,omitempty" json:"userAgent,omitempty"`
+}
@@ -0,0 +1,15 @@
+package models
+
+const (
+	TABLE_NAME_STORE_PURCHASES = "store_purchases"
+
+	STORE_PURCHASE_GSI_ACCID = "accId"
+)
+
+// StorePurchase is the account owning a store purchase and its renewals, so a receipt can only grant passes to one account.
+type StorePurchase struct {
+	// PurchaseID is the store followed by the original transaction ID of the purchase.
+	PurchaseID  string `dynamo:"purchaseId,hash" json:"purchaseId"`
+	AccountID   string `dynamo:"accId" json:"accId"`
+	CreatedDate int64  `dynamo:"createTm" json:"createTm"`
+}
@@ -1,6 +1,18 @@
 package models
 
-import "os"
+import (
+	"os"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/guregu/dynamo"
+)
+
+var (
+	dbOnce sync.Once
+	db     *dynamo.DB
+)
 
 func GetTableName(tableName string) string {
 	var ret string = tableName
@@ -11,3 +23,17 @@ func GetTableName(tableName string) string {
 
 	return ret
 }
+
+// GetDB returns the DynamoDB handle for the tables that are not managed by the account database.
+// DYNAMODB_ENDPOINT overrides the endpoint of the region, to use DynamoDB Local.
+func GetDB() *dynamo.DB {
+	dbOnce.Do(func() {
+		sess := session.Must(session.NewSession())
+		config := &aws.Config{Region: aws.String(os.Getenv("DYNAMODB_REGION"))}
+		if endpoint := os.Getenv("DYNAMODB_ENDPOINT"); len(endpoint) > 0 {
+			config.Endpoint = aws.String(endpoint)
+		}
+		db = dynamo.New(sess, config)
+	})
+	return db
+}
@@ -0,0 +1,41 @@
+package models
+
+const (
+	TABLE_NAME_VOUCHER_BATCHES = "voucher_batches"
+	TABLE_NAME_VOUCHERS        = "vouchers"
+	VOUCHER_GSI_BATCHID        = "batchId"
+)
+
+// VoucherItem is a pass or a product granted by a voucher.
+type VoucherItem struct {
+	// DurationDays is how long the item lasts once redeemed, zero if it never expires.
+	DurationDays int `dynamo:"durationDays" json:"durationDays"`
+	// Shareable tells if the item is shared with the family of the account redeeming it.
+	Shareable bool `dynamo:"shareable,omitempty" json:"shareable,omitempty"`
+}
+
+// VoucherBatch is a batch of voucher codes granting the same passes and products.
+type VoucherBatch struct {
+	BatchID  string                  `dynamo:"batchId,hash" json:"batchId"`
+	Name     string                  `dynamo:"name" json:"name"`
+	Passes   map[string]*VoucherItem `dynamo:"passes,omitempty" json:"passes,omitempty"`
+	Products map[string]*VoucherItem `dynamo:"products,omitempty" json:"products,omitempty"`
+	// MaxRedemptions is the number of accounts that can redeem each code.
+	MaxRedemptions int `dynamo:"maxRedemptions" json:"maxRedemptions"`
+	// ExpirationDate is when the codes can't be redeemed anymore, zero if they never expire.
+	ExpirationDate int64  `dynamo:"expirationTm" json:"expirationTm,omitempty"`
+	CodeCount      int    `dynamo:"codeCount" json:"codeCount"`
+	CreatedBy      string `dynamo:"createdBy" json:"createdBy"`
+	CreatedDate    int64  `dynamo:"createTm" json:"createTm"`
+}
+
+// Voucher is a voucher code, counting its redemptions.
+// The redemption limit and the expiration date are copied from the batch so they can be checked atomically.
+type Voucher struct {
+	Code           string `dynamo:"code,hash" json:"code"`
+	BatchID        string `dynamo:"batchId" index:"batchId,hash" json:"batchId"`
+	MaxRedemptions int    `dynamo:"maxRedemptions" json:"maxRedemptions"`
+	Redemptions    int    `dynamo:"redemptions" json:"redemptions"`
+	ExpirationDate int64  `dynamo:"expirationTm" json:"expirationTm,omitempty"`
+	CreatedDate    int64  `dynamo:"createTm" json:"createTm"`
+}
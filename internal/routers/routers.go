@@ -4,6 +4,7 @@ import (
 	apiControllerV1 "bitbucket.org/calmisland/account-lambda-funcs/internal/controllers/v1"
 	apiControllerV2 "bitbucket.org/calmisland/account-lambda-funcs/internal/controllers/v2"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
 	"bitbucket.org/calmisland/go-server-auth/authmiddlewares"
 	"github.com/getsentry/sentry-go"
 	sentryecho "github.com/getsentry/sentry-go/echo"
@@ -41,6 +42,9 @@ func SetupRouter() *echo.Echo {
 	v1.POST("/restorepassword", apiControllerV1.HandleRestorePassword)
 	v1.POST("/signup", apiControllerV1.HandleSignUp)
 
+	v1.GET("/avatar/presets", apiControllerV1.HandleGetAvatarPresets)
+	v1.GET("/policies", apiControllerV1.HandleGetPolicies)
+
 	v1resend := v1.Group("/resend/verification")
 	v1resend.POST("/email", apiControllerV1.HandleResendEmailVerification)
 	v1resend.POST("/phonenumber", apiControllerV1.HandleResendPhoneNumberVerification)
@@ -51,22 +55,80 @@ func SetupRouter() *echo.Echo {
 	v1verify.GET("/phonenumber", apiControllerV1.HandleAccountPhoneVerified)
 	v1verify.POST("/phonenumber", apiControllerV1.HandleVerifyPhoneNumber)
 
+	v1.POST("/children/devices/verify", apiControllerV1.HandleVerifyChildDevice)
+
+	v1parentalconsent := v1.Group("/parentalconsent")
+	v1parentalconsent.POST("/grant", apiControllerV1.HandleGrantParentalConsent)
+	v1parentalconsent.POST("/withdraw", apiControllerV1.HandleWithdrawParentalConsent)
+
 	authMiddleware := authmiddlewares.EchoAuthMiddleware(globals.AccessTokenValidator, true)
 
 	v1self := v1.Group("/self")
 	v1self.Use(authMiddleware)
-	v1self.GET("/info", apiControllerV1.HandleGetSelfAccountInfo)
-	v1self.POST("/info", apiControllerV1.HandleEditSelfAccountInfo)
-	v1self.POST("/password", apiControllerV1.HandleEditSelfAccountPassword)
-	v1self.GET("/avatar", apiControllerV1.HandleSelfAccountAvatarDownload)
-	v1self.PUT("/avatar", apiControllerV1.HandleSelfAvatarUpload)
-	v1self.DELETE("/avatar", apiControllerV1.HandleSelfAccountAvatarDelete)
+	v1self.GET("/consents", apiControllerV1.HandleGetSelfPolicyConsents)
+	v1self.POST("/consents", apiControllerV1.HandleAcceptSelfPolicies)
+
+	// The accounts that did not accept the current policies can only get and accept them
+	v1selfAccepted := v1self.Group("", helpers.PolicyConsentMiddleware)
+	v1selfAccepted.GET("/info", apiControllerV1.HandleGetSelfAccountInfo)
+	v1selfAccepted.POST("/parentalconsent/resend", apiControllerV1.HandleResendSelfParentalConsent)
+
+	// The child accounts waiting for the consent of a parent can only get their information and resend the consent request
+	v1selfConsented := v1selfAccepted.Group("", helpers.ParentalConsentMiddleware)
+	v1selfConsented.POST("/info", apiControllerV1.HandleEditSelfAccountInfo)
+	v1selfConsented.POST("/password", apiControllerV1.HandleEditSelfAccountPassword)
+	v1selfConsented.GET("/avatar", apiControllerV1.HandleSelfAccountAvatarDownload)
+	v1selfConsented.PUT("/avatar", apiControllerV1.HandleSelfAvatarUpload)
+	v1selfConsented.POST("/avatar/complete", apiControllerV1.HandleSelfAvatarUploadComplete)
+	v1selfConsented.PUT("/avatar/preset", apiControllerV1.HandleSelfAvatarPresetSelect)
+	v1selfConsented.DELETE("/avatar", apiControllerV1.HandleSelfAccountAvatarDelete)
+	v1selfConsented.GET("/transactions", apiControllerV1.HandleGetSelfTransactions)
+	v1selfConsented.GET("/entitlements", apiControllerV1.HandleGetSelfEntitlements)
+	v1selfConsented.POST("/redeem", apiControllerV1.HandleSelfRedeem)
+	v1selfConsented.POST("/purchases", apiControllerV1.HandleSelfPurchases)
+	v1selfConsented.GET("/family", apiControllerV1.HandleGetSelfFamily)
+	v1selfConsented.POST("/family", apiControllerV1.HandleCreateSelfFamily)
+	v1selfConsented.POST("/family/leave", apiControllerV1.HandleLeaveSelfFamily)
+	v1selfConsented.POST("/family/invitations", apiControllerV1.HandleInviteSelfFamilyMember)
+	v1selfConsented.DELETE("/family/members/:accountId", apiControllerV1.HandleRemoveSelfFamilyMember)
+	v1selfConsented.GET("/familyinvitations", apiControllerV1.HandleGetSelfFamilyInvitations)
+	v1selfConsented.POST("/familyinvitations/:familyId/accept", apiControllerV1.HandleAcceptSelfFamilyInvitation)
+	v1selfConsented.POST("/familyinvitations/:familyId/decline", apiControllerV1.HandleDeclineSelfFamilyInvitation)
+	v1selfConsented.GET("/notifications", apiControllerV1.HandleGetSelfNotifications)
+	v1selfConsented.POST("/notifications", apiControllerV1.HandleEditSelfNotifications)
+	v1selfConsented.POST("/notifications/confirm", apiControllerV1.HandleConfirmSelfNotifications)
+	v1selfConsented.GET("/children", apiControllerV1.HandleGetSelfChildren)
+	v1selfConsented.POST("/children", apiControllerV1.HandleCreateSelfChild)
+	v1selfConsented.DELETE("/children/:childId", apiControllerV1.HandleDeleteSelfChild)
+	v1selfConsented.POST("/children/:childId/info", apiControllerV1.HandleEditSelfChildInfo)
+	v1selfConsented.PUT("/children/:childId/avatar/preset", apiControllerV1.HandleSelfChildAvatarPresetSelect)
+	v1selfConsented.GET("/children/:childId/devices", apiControllerV1.HandleGetSelfChildDevices)
+	v1selfConsented.POST("/children/:childId/devices", apiControllerV1.HandleCreateSelfChildDevice)
+	v1selfConsented.DELETE("/children/:childId/devices/:credentialId", apiControllerV1.HandleDeleteSelfChildDevice)
 
 	v1other := v1.Group("/other")
-	v1other.Use(authMiddleware)
+	v1other.Use(authMiddleware, helpers.PolicyConsentMiddleware, helpers.ParentalConsentMiddleware)
+	v1other.POST("/info", apiControllerV1.HandleGetOtherAccountsInfo)
 	v1other.GET("/:accountId/info", apiControllerV1.HandleGetOtherAccountInfo)
 	v1other.GET("/:accountId/avatar", apiControllerV1.HandleOtherAccountAvatarDownload)
 
+	v1admin := v1.Group("/admin")
+	v1admin.Use(authMiddleware, helpers.AdminRoleMiddleware)
+	v1admin.POST("/accounts/import", apiControllerV1.HandleAdminAccountImport)
+	v1admin.POST("/accounts/:accountId/info", apiControllerV1.HandleAdminEditAccountInfo)
+	v1admin.PUT("/accounts/:accountId/child", apiControllerV1.HandleAdminSetAccountChild)
+	v1admin.GET("/accounts/:accountId/parentalconsent", apiControllerV1.HandleAdminGetAccountParentalConsent)
+	v1admin.GET("/accounts/:accountId/entitlements", apiControllerV1.HandleAdminGetAccountEntitlements)
+	v1admin.POST("/accounts/:accountId/entitlements/grant", apiControllerV1.HandleAdminGrantAccountEntitlement)
+	v1admin.POST("/accounts/:accountId/entitlements/extend", apiControllerV1.HandleAdminExtendAccountEntitlement)
+	v1admin.POST("/accounts/:accountId/entitlements/revoke", apiControllerV1.HandleAdminRevokeAccountEntitlement)
+	v1admin.GET("/kl15migration/report", apiControllerV1.HandleAdminKl15MigrationReport)
+	v1admin.GET("/namefilter/words", apiControllerV1.HandleAdminGetNameFilterWords)
+	v1admin.POST("/namefilter/words", apiControllerV1.HandleAdminAddNameFilterWords)
+	v1admin.POST("/namefilter/words/remove", apiControllerV1.HandleAdminRemoveNameFilterWords)
+	v1admin.POST("/vouchers/batches", apiControllerV1.HandleAdminCreateVoucherBatch)
+	v1admin.GET("/vouchers/batches/:batchId", apiControllerV1.HandleAdminGetVoucherBatch)
+
 	v2 := e.Group("/v2")
 
 	v2.POST("/signup/request", apiControllerV2.HandleSignupRequest)
@@ -76,6 +138,7 @@ func SetupRouter() *echo.Echo {
 
 	v2.POST("/verify/email", apiControllerV2.HandleVerifyEmail)
 	v2.POST("/kl15/migrate", apiControllerV2.HandleKl15Migration)
+	v2.POST("/legacypassword/migrate", apiControllerV2.HandleLegacyPasswordMigration)
 
 	return e
 }
@@ -17,6 +17,14 @@ type TokenMapClaims struct {
 	VerificationCode string `json:"verificationCode"`
 	Language         string `json:"lang"`
 	ExpireAt         int64  `json:"expireAt"`
+	// Country is the country the sign-up request came from, which the birth date was checked against
+	Country string `json:"country,omitempty"`
+	// BirthYear, BirthMonth and ParentEmail are only set for the sign-ups that gave a birth date
+	BirthYear   int    `json:"birthYear,omitempty"`
+	BirthMonth  int    `json:"birthMonth,omitempty"`
+	ParentEmail string `json:"parentEmail,omitempty"`
+	// AcceptedPolicies are the policy versions accepted at sign-up
+	AcceptedPolicies map[string]string `json:"acceptedPolicies,omitempty"`
 }
 
 func (token *TokenMapClaims) Valid() error {
@@ -50,6 +58,11 @@ func CreateToken(claims *TokenMapClaims) (string, error) {
 		"pw":               claims.Password,
 		"verificationCode": EncryptHashedCode(claims.VerificationCode),
 		"expireAt":         time.Now().Add(time.Minute * 10).Unix(),
+		"country":          claims.Country,
+		"birthYear":        claims.BirthYear,
+		"birthMonth":       claims.BirthMonth,
+		"parentEmail":      claims.ParentEmail,
+		"acceptedPolicies": claims.AcceptedPolicies,
 	})
 	secret := GetSecret()
 	tokenString, err := token.SignedString(secret)
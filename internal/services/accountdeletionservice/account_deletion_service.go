@@ -0,0 +1,64 @@
+package accountdeletionservice
+
+import (
+	"github.com/calmisland/go-errors"
+)
+
+var (
+	// ErrAccountNotFound is returned when deleting an account that doesn't exist.
+	ErrAccountNotFound = errors.New("The account doesn't exist")
+	// ErrChildNotFound is returned when deleting a child profile that is not managed by the parent account.
+	ErrChildNotFound = errors.New("The child profile doesn't exist")
+)
+
+// DeleteAccount deletes an account with all its data, the same way whether the account deletes itself, its parent
+// deletes it or its parental consent is withdrawn. The child profiles managed by the account are deleted with it.
+// The parental consent events are kept for audit.
+// The account record is deleted last, so an interrupted deletion can be run again.
+func DeleteAccount(store Store, accountID string) error {
+	parentID, err := store.GetParentID(accountID)
+	if err != nil {
+		return err
+	}
+
+	childIDs, err := store.ListChildIDs(accountID)
+	if err != nil {
+		return err
+	}
+	for _, childID := range childIDs {
+		err = DeleteAccount(store, childID)
+		if err != nil && err != ErrAccountNotFound {
+			return err
+		}
+	}
+
+	if len(parentID) > 0 {
+		err = store.UnlinkChild(parentID, accountID)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = store.DeleteData(accountID)
+	if err != nil {
+		return err
+	}
+	return store.DeleteRecords(accountID)
+}
+
+// DeleteChild deletes a child profile managed by a parent account.
+// The child profile is unlinked explicitly, since one whose creation was interrupted may have no parent on its profile.
+func DeleteChild(store Store, parentID string, childID string) error {
+	isParent, err := store.IsParentOf(parentID, childID)
+	if err != nil {
+		return err
+	} else if !isParent {
+		return ErrChildNotFound
+	}
+
+	err = DeleteAccount(store, childID)
+	if err != nil && err != ErrAccountNotFound {
+		return err
+	}
+	return store.UnlinkChild(parentID, childID)
+}
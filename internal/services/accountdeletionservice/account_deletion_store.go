@@ -0,0 +1,154 @@
+package accountdeletionservice
+
+import (
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/avatarservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/childservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/expiryreminders"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/familyservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/legacypasswords"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notificationservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/organizationservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/parentalconsentservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/policyconsentservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/purchaseservice"
+	"github.com/guregu/dynamo"
+)
+
+// Store reads the links between the accounts and deletes their data.
+type Store interface {
+	// GetParentID returns the parent account managing a child profile, empty for the other accounts.
+	GetParentID(accountID string) (string, error)
+	// ListChildIDs returns the child profiles managed by an account.
+	ListChildIDs(accountID string) ([]string, error)
+	// IsParentOf checks if an account manages a child profile.
+	IsParentOf(parentID string, childID string) (bool, error)
+	// UnlinkChild unlinks a child profile from its parent account, doing nothing if it isn't linked.
+	UnlinkChild(parentID string, childID string) error
+	// DeleteData deletes the data of an account kept outside the account database, including its profile.
+	DeleteData(accountID string) error
+	// DeleteRecords deletes an account from the account database with its email, phone number and transactions.
+	// It fails with ErrAccountNotFound if the account doesn't exist.
+	DeleteRecords(accountID string) error
+}
+
+type standardStore struct {
+	childStore childservice.Store
+}
+
+// NewStore creates the store deleting the accounts from all the tables and storages.
+func NewStore() Store {
+	return &standardStore{
+		childStore: childservice.NewStore(),
+	}
+}
+
+func (store *standardStore) GetParentID(accountID string) (string, error) {
+	profile, err := profileservice.GetProfile(accountID)
+	if err != nil {
+		return "", err
+	}
+	return profile.ParentID, nil
+}
+
+func (store *standardStore) ListChildIDs(accountID string) ([]string, error) {
+	return childservice.GetChildIDs(store.childStore, accountID)
+}
+
+func (store *standardStore) IsParentOf(parentID string, childID string) (bool, error) {
+	return childservice.IsParentOf(store.childStore, parentID, childID)
+}
+
+func (store *standardStore) UnlinkChild(parentID string, childID string) error {
+	return childservice.UnlinkChild(store.childStore, parentID, childID)
+}
+
+func (store *standardStore) DeleteData(accountID string) error {
+	err := policyconsentservice.DeleteConsents(policyconsentservice.NewStore(), accountID)
+	if err != nil {
+		return err
+	}
+	err = parentalconsentservice.DeleteConsents(parentalconsentservice.NewStore(), accountID)
+	if err != nil {
+		return err
+	}
+	err = notificationservice.DeleteOptIns(notificationservice.NewStore(), accountID)
+	if err != nil {
+		return err
+	}
+	err = legacypasswords.Delete(legacypasswords.NewStore(), accountID)
+	if err != nil {
+		return err
+	}
+	err = childservice.DeleteDeviceCredentials(store.childStore, accountID)
+	if err != nil {
+		return err
+	}
+
+	cleanups := []func(accountID string) error{
+		familyservice.RemoveAccount,
+		organizationservice.RemoveMemberships,
+		expiryreminders.DeleteReminders,
+		purchaseservice.ReleasePurchases,
+		avatarservice.DeleteAvatar,
+		// The profile has the notification preferences and the accepted policies
+		profileservice.DeleteProfile,
+	}
+	for _, cleanup := range cleanups {
+		err = cleanup(accountID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (store *standardStore) DeleteRecords(accountID string) error {
+	db := models.GetDB()
+	tableAccount := db.Table(models.GetTableName(models.TABLE_NAME_ACCOUNT))
+
+	var resultAccount models.Account
+	err := tableAccount.Get("id", accountID).One(&resultAccount)
+	if err == dynamo.ErrNotFound {
+		return ErrAccountNotFound
+	} else if err != nil {
+		return err
+	}
+
+	var resultAccountEmail models.AccountEmail
+	tableAccountEmail := db.Table(models.GetTableName(models.TABLE_NAME_ACCOUNT_EMAIL))
+	err = tableAccountEmail.Get("accId", accountID).Index(models.ACCOUNT_EMAIL_GSI_ACCID).One(&resultAccountEmail)
+	if err == nil {
+		err = tableAccountEmail.Delete("email", resultAccountEmail.Email).Run()
+	}
+	if err != nil && err != dynamo.ErrNotFound {
+		return err
+	}
+
+	var resultAccountPhoneNumber models.AccountPhoneNumber
+	tableAccountPhoneNumber := db.Table(models.GetTableName(models.TABLE_NAME_ACCOUNT_PHONE_NUMBER))
+	err = tableAccountPhoneNumber.Get("accId", accountID).Index(models.ACCOUNT_PHONENUMBER_GSI_ACCID).One(&resultAccountPhoneNumber)
+	if err == nil {
+		err = tableAccountPhoneNumber.Delete("phoneNr", resultAccountPhoneNumber.PhoneNumber).Run()
+	}
+	if err != nil && err != dynamo.ErrNotFound {
+		return err
+	}
+
+	tableTransaction := db.Table(models.GetTableName(models.TABLE_NAME_ACCOUNT_TRANSACTIONS))
+	var resultTransaction []models.AccountTransaction
+	err = tableTransaction.Get("accId", accountID).All(&resultTransaction)
+	if err != nil {
+		return err
+	}
+
+	for _, transactionItem := range resultTransaction {
+		err = tableTransaction.Delete("accId", accountID).Range("transactionId", transactionItem.TransactionID).Run()
+		if err != nil {
+			return err
+		}
+	}
+
+	return tableAccount.Delete("id", accountID).Run()
+}
@@ -0,0 +1,435 @@
+package accountimportservice
+
+import (
+	"strconv"
+	"strings"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/legacypasswords"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notifications"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notificationservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/organizationservice"
+	"bitbucket.org/calmisland/go-server-account/accountdatabase"
+	"bitbucket.org/calmisland/go-server-account/accounts"
+	"bitbucket.org/calmisland/go-server-logs/logger"
+	"bitbucket.org/calmisland/go-server-messages/messages"
+	"bitbucket.org/calmisland/go-server-messages/messagetemplates"
+	"bitbucket.org/calmisland/go-server-security/securitycodes"
+	"bitbucket.org/calmisland/go-server-utils/emailutils"
+	"bitbucket.org/calmisland/go-server-utils/langutils"
+	"bitbucket.org/calmisland/go-server-utils/phoneutils"
+	"bitbucket.org/calmisland/go-server-utils/textutils"
+	"github.com/google/uuid"
+)
+
+const (
+	inviteCodeByteLength      = 4
+	placeholderPasswordLength = 32
+)
+
+// RowStatus is the outcome of importing a single row.
+type RowStatus string
+
+const (
+	// RowStatusValid means the row passed validation during a dry run.
+	RowStatusValid RowStatus = "valid"
+	// RowStatusCreated means the account was created and invited.
+	RowStatusCreated RowStatus = "created"
+	// RowStatusFailed means the row was rejected or could not be created.
+	RowStatusFailed RowStatus = "failed"
+)
+
+// RowErrorReason describes why a row failed.
+type RowErrorReason string
+
+const (
+	RowErrorInvalidParameters  RowErrorReason = "invalidParameters"
+	RowErrorInvalidFormat      RowErrorReason = "invalidFormat"
+	RowErrorInputTooLong       RowErrorReason = "inputTooLong"
+	RowErrorEmailAlreadyUsed   RowErrorReason = "emailAlreadyUsed"
+	RowErrorPhoneAlreadyUsed   RowErrorReason = "phoneNumberAlreadyUsed"
+	RowErrorInternalError      RowErrorReason = "internalError"
+	RowErrorInviteNotDelivered RowErrorReason = "inviteNotDelivered"
+	RowErrorPasswordNotSaved   RowErrorReason = "passwordNotSaved"
+	RowErrorOrganizationNotSet RowErrorReason = "organizationNotSet"
+)
+
+// RowError is a per-row error.
+type RowError struct {
+	Reason  RowErrorReason `json:"reason"`
+	Field   string         `json:"field,omitempty"`
+	Message string         `json:"message,omitempty"`
+}
+
+// RowResult is the import result of a single row.
+type RowResult struct {
+	Line        int       `json:"line"`
+	Email       string    `json:"email,omitempty"`
+	PhoneNumber string    `json:"phoneNr,omitempty"`
+	Status      RowStatus `json:"status"`
+	AccountID   string    `json:"accountId,omitempty"`
+	Error       *RowError `json:"error,omitempty"`
+	// OtherErrors are the errors of the steps after the account creation that failed after the first one.
+	OtherErrors []*RowError `json:"otherErrors,omitempty"`
+}
+
+// addError records the error of a step after the account creation, keeping the first one as the error of the row.
+func (result *RowResult) addError(rowErr *RowError) {
+	if result.Error == nil {
+		result.Error = rowErr
+	} else {
+		result.OtherErrors = append(result.OtherErrors, rowErr)
+	}
+}
+
+// Report is the result of an import.
+type Report struct {
+	DryRun  bool         `json:"dryRun"`
+	Total   int          `json:"total"`
+	Valid   int          `json:"valid"`
+	Created int          `json:"created"`
+	Failed  int          `json:"failed"`
+	Rows    []*RowResult `json:"rows"`
+}
+
+// Options are the import options.
+type Options struct {
+	// DryRun only validates the rows without creating any account.
+	DryRun bool
+	// Source is the product the password hashes are imported from.
+	Source string
+}
+
+// Import validates the rows with the sign-up rules and creates the accounts.
+// Created accounts must set their password and receive an invite with a password verification code,
+// unless they are imported with a legacy password hash which is then used on their first sign-in.
+func Import(rows []*Row, options Options) *Report {
+	report := &Report{
+		DryRun: options.DryRun,
+		Total:  len(rows),
+		Rows:   make([]*RowResult, 0, len(rows)),
+	}
+
+	// Used to detect duplicates within the same import
+	seenContacts := map[string]int{}
+
+	for _, row := range rows {
+		result := importRow(row, options, seenContacts)
+		switch result.Status {
+		case RowStatusValid:
+			report.Valid++
+		case RowStatusCreated:
+			report.Created++
+		case RowStatusFailed:
+			report.Failed++
+		}
+
+		report.Rows = append(report.Rows, result)
+	}
+
+	logger.LogFormat("[ACCOUNTIMPORT] Imported %d rows (dry run: %t): %d created, %d valid, %d failed\n", report.Total, report.DryRun, report.Created, report.Valid, report.Failed)
+	return report
+}
+
+type validatedRow struct {
+	email        string
+	phoneNumber  string
+	isUsingEmail bool
+	fullName     string
+	firstName    string
+	lastName     string
+	language     string
+	country      string
+	organization string
+	legacyHash   *legacypasswords.Hash
+}
+
+func importRow(row *Row, options Options, seenContacts map[string]int) *RowResult {
+	result := &RowResult{
+		Line:        row.Line,
+		Email:       row.Email,
+		PhoneNumber: row.PhoneNumber,
+	}
+
+	validated, rowErr := validateRow(row)
+	if rowErr == nil {
+		contact := validated.email
+		if !validated.isUsingEmail {
+			contact = validated.phoneNumber
+		}
+		contact = strings.ToLower(contact)
+
+		if firstLine, exists := seenContacts[contact]; exists {
+			rowErr = alreadyUsedError(validated.isUsingEmail)
+			rowErr.Message = "Duplicate of the row at line " + strconv.Itoa(firstLine)
+		} else {
+			seenContacts[contact] = row.Line
+			rowErr = checkAccountDoesNotExist(validated)
+		}
+	}
+
+	if rowErr != nil {
+		result.Status = RowStatusFailed
+		result.Error = rowErr
+		return result
+	}
+
+	result.Email = validated.email
+	result.PhoneNumber = validated.phoneNumber
+
+	if options.DryRun {
+		result.Status = RowStatusValid
+		return result
+	}
+
+	accountID, err := createAccount(validated)
+	if err != nil {
+		result.Status = RowStatusFailed
+		result.Error = &RowError{
+			Reason:  RowErrorInternalError,
+			Message: err.Error(),
+		}
+		return result
+	}
+
+	result.Status = RowStatusCreated
+	result.AccountID = accountID
+
+	if len(validated.organization) > 0 {
+		err = organizationservice.AddMember(accountID, validated.organization)
+		if err != nil {
+			result.addError(&RowError{
+				Reason:  RowErrorOrganizationNotSet,
+				Field:   "orgId",
+				Message: err.Error(),
+			})
+		}
+	}
+
+	if validated.legacyHash != nil {
+		err = legacypasswords.Save(legacypasswords.NewStore(), accountID, validated.legacyHash, options.Source)
+		if err != nil {
+			result.addError(&RowError{
+				Reason:  RowErrorPasswordNotSaved,
+				Message: err.Error(),
+			})
+		}
+		return result
+	}
+
+	err = sendInvite(accountID, validated)
+	if err != nil {
+		result.addError(&RowError{
+			Reason:  RowErrorInviteNotDelivered,
+			Message: err.Error(),
+		})
+	}
+
+	return result
+}
+
+// validateRow applies the same validation rules as the sign-up.
+func validateRow(row *Row) (*validatedRow, *RowError) {
+	var err error
+	validated := &validatedRow{
+		email:       row.Email,
+		phoneNumber: row.PhoneNumber,
+		fullName:    defs.SanitizeName(row.FullName),
+		firstName:   defs.SanitizeName(row.FirstName),
+		lastName:    defs.SanitizeName(row.LastName),
+		language:    textutils.SanitizeString(row.Language),
+		country:     strings.ToUpper(textutils.SanitizeString(row.Country)),
+	}
+
+	if len(validated.email) > 0 {
+		if !emailutils.IsValidEmailAddressFormat(validated.email) || !emailutils.IsValidEmailAddressHost(validated.email) {
+			return nil, &RowError{Reason: RowErrorInvalidFormat, Field: "email"}
+		}
+
+		// There should not be an email and a phone number at the same time
+		validated.phoneNumber = ""
+		validated.isUsingEmail = true
+	} else if len(validated.phoneNumber) > 0 {
+		validated.phoneNumber, err = phoneutils.CleanPhoneNumber(validated.phoneNumber)
+		if err != nil || !phoneutils.IsValidPhoneNumber(validated.phoneNumber) {
+			return nil, &RowError{Reason: RowErrorInvalidFormat, Field: "phoneNr"}
+		}
+
+		// There should not be an email and a phone number at the same time
+		validated.email = ""
+		validated.isUsingEmail = false
+	} else {
+		return nil, &RowError{Reason: RowErrorInvalidParameters, Field: "email"}
+	}
+
+	if len(validated.fullName) == 0 && (len(validated.firstName) > 0 || len(validated.lastName) > 0) {
+		validated.fullName = strings.TrimSpace(validated.firstName + " " + validated.lastName)
+	}
+
+	if field, _ := defs.CheckNameLengths(validated.fullName, validated.firstName, validated.lastName); len(field) > 0 {
+		return nil, &RowError{Reason: RowErrorInputTooLong, Field: field}
+	}
+
+	// Sets the default language if none is set
+	if !langutils.IsValidLanguageCode(validated.language) {
+		validated.language = defs.DefaultLanguageCode
+	}
+
+	if len(validated.country) == 0 {
+		validated.country = defs.DefaultCountryCode
+	} else if !defs.IsValidCountryCodeFormat(validated.country) {
+		return nil, &RowError{Reason: RowErrorInvalidFormat, Field: "country"}
+	}
+
+	validated.organization = row.OrganizationID
+	if len(validated.organization) > defs.MaxOrganizationIDLength {
+		return nil, &RowError{Reason: RowErrorInputTooLong, Field: "orgId"}
+	}
+
+	if len(row.PasswordHash) > 0 {
+		legacyHash, rowErr := validateLegacyHash(row)
+		if rowErr != nil {
+			return nil, rowErr
+		}
+		validated.legacyHash = legacyHash
+	}
+
+	return validated, nil
+}
+
+func validateLegacyHash(row *Row) (*legacypasswords.Hash, *RowError) {
+	if !legacypasswords.IsRegistered(row.PasswordAlgorithm) {
+		return nil, &RowError{Reason: RowErrorInvalidParameters, Field: "pwAlgorithm"}
+	}
+
+	hash := &legacypasswords.Hash{
+		Algorithm: row.PasswordAlgorithm,
+		Hash:      row.PasswordHash,
+		Salt:      row.PasswordSalt,
+	}
+
+	if len(row.PasswordIterations) > 0 {
+		iterations, err := strconv.Atoi(row.PasswordIterations)
+		if err != nil || iterations <= 0 {
+			return nil, &RowError{Reason: RowErrorInvalidFormat, Field: "pwIterations"}
+		}
+		hash.Iterations = iterations
+	}
+
+	return hash, nil
+}
+
+func checkAccountDoesNotExist(validated *validatedRow) *RowError {
+	var accountExists bool
+	var err error
+	if validated.isUsingEmail {
+		accountExists, err = globals.AccountDatabase.AccountExistsWithEmail(validated.email)
+	} else {
+		accountExists, err = globals.AccountDatabase.AccountExistsWithPhoneNumber(validated.phoneNumber)
+	}
+
+	if err != nil {
+		return &RowError{Reason: RowErrorInternalError, Message: err.Error()}
+	} else if accountExists {
+		return alreadyUsedError(validated.isUsingEmail)
+	}
+	return nil
+}
+
+func alreadyUsedError(isUsingEmail bool) *RowError {
+	if isUsingEmail {
+		return &RowError{Reason: RowErrorEmailAlreadyUsed, Field: "email"}
+	}
+	return &RowError{Reason: RowErrorPhoneAlreadyUsed, Field: "phoneNr"}
+}
+
+func createAccount(validated *validatedRow) (string, error) {
+	accountUUID, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+
+	// The account cannot sign in until the password is set through the invite,
+	// so the initial password is a random one that nobody knows.
+	placeholderPassword, err := securitycodes.GenerateSecurityCode(placeholderPasswordLength)
+	if err != nil {
+		return "", err
+	}
+
+	hashedPassword, err := globals.PasswordHasher.GeneratePasswordHash(placeholderPassword, false)
+	if err != nil {
+		return "", err
+	}
+
+	// The contact information is only verified once the invite code sent to it is used to set the password
+	flags := int32(accounts.MustSetPasswordFlag)
+
+	accountID := accountUUID.String()
+	err = globals.AccountDatabase.CreateAccount(&accountdatabase.CreateAccountInfo{
+		ID:           accountID,
+		Email:        validated.email,
+		PhoneNumber:  validated.phoneNumber,
+		PasswordHash: hashedPassword,
+		Flags:        flags,
+		Country:      validated.country,
+		Language:     validated.language,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(validated.fullName) > 0 {
+		err = globals.AccountDatabase.EditAccount(accountID, &accountdatabase.AccountEditInfo{
+			Names: &accountdatabase.AccountNameInfo{
+				FullName:  &validated.fullName,
+				FirstName: &validated.firstName,
+				LastName:  &validated.lastName,
+			},
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	logger.LogFormat("[ACCOUNTIMPORT] Created account [%s] for [%s%s]\n", accountID, validated.email, validated.phoneNumber)
+	return accountID, nil
+}
+
+// sendInvite sends a password verification code that can be used with the restore password request.
+func sendInvite(accountID string, validated *validatedRow) error {
+	verificationCode, err := securitycodes.GenerateSecurityCode(inviteCodeByteLength)
+	if err != nil {
+		return err
+	}
+
+	err = globals.AccountDatabase.CreateAccountVerification(accountID, accountdatabase.VerificationTypePassword, verificationCode)
+	if err != nil {
+		return err
+	}
+
+	template := &messagetemplates.PasswordResetTemplate{
+		Code: verificationCode,
+	}
+
+	var message *messages.Message
+	if validated.isUsingEmail {
+		message = &messages.Message{
+			MessageType: messages.MessageTypeEmail,
+			Priority:    messages.MessagePriorityEmailNormal,
+			Recipient:   validated.email,
+			Language:    validated.language,
+			Template:    template,
+		}
+	} else {
+		message = &messages.Message{
+			MessageType: messages.MessageTypeSMS,
+			Priority:    messages.MessagePrioritySMSTransactional,
+			Recipient:   validated.phoneNumber,
+			Language:    validated.language,
+			Template:    template,
+		}
+	}
+
+	return notificationservice.Enqueue(accountID, notifications.CategorySecurity, message)
+}
@@ -0,0 +1,181 @@
+package accountimportservice
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/calmisland/go-errors"
+)
+
+// Row is a single account to import.
+type Row struct {
+	Line        int    `json:"line"`
+	Email       string `json:"email,omitempty"`
+	PhoneNumber string `json:"phoneNr,omitempty"`
+	FullName    string `json:"fullName,omitempty"`
+	FirstName   string `json:"firstName,omitempty"`
+	LastName    string `json:"lastName,omitempty"`
+	Language    string `json:"lang,omitempty"`
+	Country     string `json:"country,omitempty"`
+	// The optional organisation the account is added to
+	OrganizationID string `json:"orgId,omitempty"`
+
+	// The optional password hash of accounts imported from another product
+	PasswordHash       string `json:"-"`
+	PasswordAlgorithm  string `json:"-"`
+	PasswordSalt       string `json:"-"`
+	PasswordIterations string `json:"-"`
+}
+
+// columnAliases maps the accepted CSV header names to the row columns.
+var columnAliases = map[string]string{
+	"email":        "email",
+	"phonenr":      "phoneNr",
+	"phone":        "phoneNr",
+	"phonenumber":  "phoneNr",
+	"fullname":     "fullName",
+	"firstname":    "firstName",
+	"lastname":     "lastName",
+	"lang":         "lang",
+	"language":     "lang",
+	"country":      "country",
+	"orgid":        "orgId",
+	"organization": "orgId",
+	"pwhash":       "pwHash",
+	"pwalgorithm":  "pwAlgorithm",
+	"pwsalt":       "pwSalt",
+	"pwiterations": "pwIterations",
+}
+
+// headerNameReplacer removes the separators so "First Name" and "first_name" match "firstName".
+var headerNameReplacer = strings.NewReplacer(" ", "", "_", "", "-", "")
+
+// ParseCSV parses accounts from CSV data.
+// The first record must be a header naming the columns; unknown columns are ignored.
+func ParseCSV(r io.Reader) ([]*Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("The CSV data is empty")
+	} else if err != nil {
+		return nil, err
+	}
+
+	columns := make([]string, len(header))
+	hasContactColumn := false
+	for i, name := range header {
+		name = headerNameReplacer.Replace(strings.TrimPrefix(name, "\ufeff"))
+		name = strings.ToLower(name)
+		columns[i] = columnAliases[name]
+		if columns[i] == "email" || columns[i] == "phoneNr" {
+			hasContactColumn = true
+		}
+	}
+
+	if !hasContactColumn {
+		return nil, errors.New("The CSV header must contain an email or phoneNr column")
+	}
+
+	var rows []*Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := &Row{
+			Line: line,
+		}
+
+		isEmpty := true
+		for i, value := range record {
+			if i >= len(columns) {
+				break
+			}
+
+			value = strings.TrimSpace(value)
+			if len(value) > 0 {
+				isEmpty = false
+			}
+
+			switch columns[i] {
+			case "email":
+				row.Email = value
+			case "phoneNr":
+				row.PhoneNumber = value
+			case "fullName":
+				row.FullName = value
+			case "firstName":
+				row.FirstName = value
+			case "lastName":
+				row.LastName = value
+			case "lang":
+				row.Language = value
+			case "country":
+				row.Country = value
+			case "orgId":
+				row.OrganizationID = value
+			case "pwHash":
+				row.PasswordHash = value
+			case "pwAlgorithm":
+				row.PasswordAlgorithm = value
+			case "pwSalt":
+				row.PasswordSalt = value
+			case "pwIterations":
+				row.PasswordIterations = value
+			}
+		}
+
+		// Skip blank lines that spreadsheets tend to leave at the end
+		if isEmpty {
+			continue
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// WriteCSV writes an import report as CSV.
+func WriteCSV(w io.Writer, report *Report) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{"line", "email", "phoneNr", "status", "accountId", "errReason", "errField", "errMessage"})
+	if err != nil {
+		return err
+	}
+
+	for _, result := range report.Rows {
+		var errReason, errField, errMessage string
+		if result.Error != nil {
+			errReason = string(result.Error.Reason)
+			errField = result.Error.Field
+			errMessage = result.Error.Message
+		}
+
+		err = writer.Write([]string{
+			fmt.Sprint(result.Line),
+			result.Email,
+			result.PhoneNumber,
+			string(result.Status),
+			result.AccountID,
+			errReason,
+			errField,
+			errMessage,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
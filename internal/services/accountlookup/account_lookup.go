@@ -0,0 +1,185 @@
+package accountlookup
+
+import (
+	"sync"
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/visibility"
+	"bitbucket.org/calmisland/go-server-account/accountdatabase"
+	"github.com/calmisland/go-errors"
+)
+
+const (
+	// MaxAccountIDs is the maximum number of accounts in a single lookup.
+	MaxAccountIDs = 200
+	// readConcurrency is the number of accounts read at the same time from the account store.
+	readConcurrency = 16
+)
+
+// The statuses of the looked up accounts.
+// The accounts that the caller can't see anything of are not found, so a lookup doesn't tell which accounts exist.
+const (
+	StatusFound    = "found"
+	StatusNotFound = "notFound"
+)
+
+var (
+	// ErrNoAccountIDs is returned when a lookup has no account ID.
+	ErrNoAccountIDs = errors.New("No account ID to look up")
+	// ErrTooManyAccountIDs is returned when a lookup has more than MaxAccountIDs account IDs.
+	ErrTooManyAccountIDs = errors.New("Too many account IDs to look up")
+)
+
+// Store reads the looked up accounts.
+type Store interface {
+	// GetAccountInfo returns the information of an account, nil if it doesn't exist.
+	GetAccountInfo(accountID string) (*accountdatabase.AccountInfo, error)
+	// GetProfiles returns the profiles of several accounts, mapped by account ID.
+	GetProfiles(accountIDs []string) (map[string]*models.AccountProfile, error)
+	// ResolveMany returns the relationship of the caller to several accounts, mapped by account ID.
+	ResolveMany(callerAccountID string, accountIDs []string) (map[string]visibility.Relationship, error)
+}
+
+type standardStore struct{}
+
+// NewStore creates the store reading the accounts from the account database and the profiles table.
+func NewStore() Store {
+	return &standardStore{}
+}
+
+func (store *standardStore) GetAccountInfo(accountID string) (*accountdatabase.AccountInfo, error) {
+	return globals.AccountDatabase.GetAccountInfo(accountID)
+}
+
+func (store *standardStore) GetProfiles(accountIDs []string) (map[string]*models.AccountProfile, error) {
+	return profileservice.GetProfiles(accountIDs)
+}
+
+func (store *standardStore) ResolveMany(callerAccountID string, accountIDs []string) (map[string]visibility.Relationship, error) {
+	return globals.RelationshipResolver.ResolveMany(callerAccountID, accountIDs)
+}
+
+// Account is a looked up account.
+type Account struct {
+	AccountID string
+	Status    string
+	// Info and Profile are only set for the found accounts. The fields that the caller can't see are removed from Info.
+	Info    *accountdatabase.AccountInfo
+	Profile *models.AccountProfile
+
+	CanSeeNames  bool
+	CanSeeEmail  bool
+	CanSeeAvatar bool
+}
+
+// UniqueAccountIDs removes the empty and duplicate account IDs, keeping the order.
+func UniqueAccountIDs(accountIDs []string) []string {
+	seen := make(map[string]bool, len(accountIDs))
+	unique := make([]string, 0, len(accountIDs))
+	for _, accountID := range accountIDs {
+		if len(accountID) == 0 || seen[accountID] {
+			continue
+		}
+		seen[accountID] = true
+		unique = append(unique, accountID)
+	}
+	return unique
+}
+
+// Lookup reads several accounts for a caller, with only the fields that their visibility settings allow the caller to see.
+// Every account ID gets a result, in the order of the account IDs once the empty and duplicate ones are removed.
+func Lookup(store Store, callerAccountID string, accountIDs []string) ([]*Account, error) {
+	accountIDs = UniqueAccountIDs(accountIDs)
+	if len(accountIDs) == 0 {
+		return nil, ErrNoAccountIDs
+	} else if len(accountIDs) > MaxAccountIDs {
+		return nil, ErrTooManyAccountIDs
+	}
+
+	relationships, err := store.ResolveMany(callerAccountID, accountIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles, err := store.GetProfiles(accountIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make([]*Account, len(accountIDs))
+	errs := make([]error, len(accountIDs))
+	now := time.Now()
+
+	// The account store has no batch read, so the accounts are read a few at a time
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, readConcurrency)
+	for i, accountID := range accountIDs {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, accountID string) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+
+			accInfo, err := store.GetAccountInfo(accountID)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+
+			profile := profiles[accountID]
+			if profile == nil {
+				profile = &models.AccountProfile{AccountID: accountID}
+			}
+			accounts[i] = newAccount(accountID, accInfo, profile, relationships[accountID], now)
+		}(i, accountID)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return accounts, nil
+}
+
+func newAccount(accountID string, accInfo *accountdatabase.AccountInfo, profile *models.AccountProfile, relationship visibility.Relationship, now time.Time) *Account {
+	account := &Account{
+		AccountID: accountID,
+		Status:    StatusNotFound,
+	}
+	if accInfo == nil {
+		return account
+	}
+
+	settings := profileservice.GetVisibilitySettings(profile, now)
+	account.CanSeeNames = settings.CanSee(visibility.FieldNames, relationship)
+	account.CanSeeEmail = settings.CanSee(visibility.FieldEmail, relationship)
+	account.CanSeeAvatar = settings.CanSee(visibility.FieldAvatar, relationship)
+	if !account.CanSeeNames && !account.CanSeeEmail && !account.CanSeeAvatar {
+		return &Account{
+			AccountID: accountID,
+			Status:    StatusNotFound,
+		}
+	}
+
+	visibleInfo := &accountdatabase.AccountInfo{}
+	if account.CanSeeNames {
+		visibleInfo.FullName = accInfo.FullName
+		visibleInfo.FirstName = accInfo.FirstName
+		visibleInfo.LastName = accInfo.LastName
+	}
+	if account.CanSeeEmail {
+		visibleInfo.Email = accInfo.Email
+	}
+
+	account.Status = StatusFound
+	account.Info = visibleInfo
+	account.Profile = profile
+	return account
+}
@@ -12,6 +12,8 @@ type Service interface {
 	// GetVerificationLink returns a verification link.
 	GetVerificationLink(accountID, verificationCode, language string) string
 	GetVerificationLinkByToken(verificationToken, verificationCode, language string) string
+	// GetParentalConsentLink returns the link sent to a parent to consent to a child account.
+	GetParentalConsentLink(consentID, consentCode, language string) string
 }
 
 // Config is the configuration for the account verification service.
@@ -51,3 +53,12 @@ func (service *standardService) GetVerificationLinkByToken(verificationToken str
 
 	return fmt.Sprintf(`%s/#/verify_email_with_token?verificationToken=%s&code=%s&lang=%s`, service.passFrontendHost, verificationToken, verificationCode, language)
 }
+
+// GetParentalConsentLink returns the link sent to a parent to consent to a child account.
+func (service *standardService) GetParentalConsentLink(consentID string, consentCode string, language string) string {
+	consentID = url.QueryEscape(consentID)
+	consentCode = url.QueryEscape(consentCode)
+	language = url.QueryEscape(language)
+
+	return fmt.Sprintf("%s/#/parental_consent?consentId=%s&code=%s&lang=%s", service.passFrontendHost, consentID, consentCode, language)
+}
@@ -16,3 +16,9 @@ func (service *MockService) GetVerificationLink(accountID, verificationCode, lan
 	args := service.Called(accountID, verificationCode, language)
 	return args.String(0)
 }
+
+// GetParentalConsentLink returns the link sent to a parent to consent to a child account.
+func (service *MockService) GetParentalConsentLink(consentID, consentCode, language string) string {
+	args := service.Called(consentID, consentCode, language)
+	return args.String(0)
+}
@@ -0,0 +1,83 @@
+package attemptlimiter
+
+import (
+	"strconv"
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"github.com/guregu/dynamo"
+)
+
+// Limit is the maximum number of failed attempts of an action in a time window.
+// Guessed secrets, such as passwords or codes, are limited per target and per client IP address.
+type Limit struct {
+	scope       string
+	maxAttempts int
+	window      time.Duration
+}
+
+// New creates a limit of maxAttempts failed attempts per window.
+// The scope separates the counters of the different limited actions.
+func New(scope string, maxAttempts int, window time.Duration) *Limit {
+	return &Limit{
+		scope:       scope,
+		maxAttempts: maxAttempts,
+		window:      window,
+	}
+}
+
+func getTable() dynamo.Table {
+	return models.GetDB().Table(models.GetTableName(models.TABLE_NAME_ATTEMPT_COUNTERS))
+}
+
+// CounterKey returns the key of the counter of an ID in the window of a time.
+func (l *Limit) CounterKey(id string, t time.Time) string {
+	windowStart := t.UTC().Truncate(l.window).Unix()
+	return l.scope + "#" + id + "#" + strconv.FormatInt(windowStart, 10)
+}
+
+// IsBlocked checks if any of the IDs has reached the maximum number of failed attempts in the current window.
+// Empty IDs are ignored.
+func (l *Limit) IsBlocked(ids ...string) (bool, error) {
+	now := time.Now()
+	for _, id := range ids {
+		if len(id) == 0 {
+			continue
+		}
+
+		var counter models.AttemptCounter
+		err := getTable().Get("key", l.CounterKey(id, now)).One(&counter)
+		if err == dynamo.ErrNotFound {
+			continue
+		} else if err != nil {
+			return false, err
+		}
+
+		if counter.Attempts >= l.maxAttempts {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RecordFailure counts a failed attempt for each of the IDs in the current window.
+// Empty IDs are ignored.
+func (l *Limit) RecordFailure(ids ...string) error {
+	now := time.Now()
+	// The counter is kept for one more window so it's not deleted while still in use
+	expirationTime := now.UTC().Truncate(l.window).Add(2 * l.window).Unix()
+	for _, id := range ids {
+		if len(id) == 0 {
+			continue
+		}
+
+		err := getTable().Update("key", l.CounterKey(id, now)).
+			Add("attempts", 1).
+			SetIfNotExists("ttl", expirationTime).
+			Run()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package avatarmoderation
+
+import (
+	"image"
+
+	"github.com/calmisland/go-errors"
+)
+
+// The moderator types that can be configured.
+const (
+	ModeratorTypeRules = "rules"
+	ModeratorTypeHTTP  = "http"
+)
+
+// Avatar is an uploaded avatar waiting for moderation.
+type Avatar struct {
+	AccountID string
+	// Width and Height are the dimensions of the uploaded image.
+	Width  int
+	Height int
+	// Data is the uploaded file, before processing.
+	Data []byte
+	// Image is the normalised image that would be published.
+	Image image.Image
+	// ImageData is the normalised image encoded with ImageContentType.
+	ImageData        []byte
+	ImageContentType string
+}
+
+// Decision is the moderation decision of an avatar.
+type Decision struct {
+	Approved bool   `json:"approved"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Moderator decides if an avatar can be published.
+type Moderator interface {
+	// Moderate checks an avatar. An error is only returned if the avatar couldn't be checked.
+	Moderate(avatar *Avatar) (*Decision, error)
+}
+
+// Config is the configuration of the avatar moderator.
+type Config struct {
+	// Type is the moderator type, rules by default.
+	Type string `env:"AMS_AVATAR_MODERATOR"`
+}
+
+// New creates the avatar moderator of the configured type.
+func New(config Config, rulesConfig RulesConfig, httpConfig HTTPConfig) (Moderator, error) {
+	switch config.Type {
+	case "", ModeratorTypeRules:
+		return NewRulesModerator(rulesConfig), nil
+	case ModeratorTypeHTTP:
+		return NewHTTPModerator(httpConfig)
+	default:
+		return nil, errors.New("Unknown avatar moderator type: " + config.Type)
+	}
+}
@@ -0,0 +1,90 @@
+package avatarmoderation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/calmisland/go-errors"
+)
+
+const (
+	defaultHTTPTimeoutSeconds = 10
+	maxHTTPResponseSize       = 64 * 1024
+)
+
+// HTTPConfig is the configuration of the HTTP moderator.
+type HTTPConfig struct {
+	// URL receives the normalised image as the request body and returns a JSON decision.
+	URL string `env:"AMS_AVATAR_MODERATOR_URL"`
+	// APIKey is sent as a bearer token, if set.
+	APIKey string `env:"AMS_AVATAR_MODERATOR_API_KEY"`
+	// TimeoutSeconds is the request timeout.
+	TimeoutSeconds int `env:"AMS_AVATAR_MODERATOR_TIMEOUT"`
+}
+
+type httpModerator struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+// NewHTTPModerator creates a moderator that delegates the decision to an external moderation service.
+// The service receives a POST request with the image and must respond with {"approved": bool, "reason": string}.
+func NewHTTPModerator(config HTTPConfig) (Moderator, error) {
+	if len(config.URL) == 0 {
+		return nil, errors.New("The avatar moderator URL cannot be empty")
+	}
+
+	timeoutSeconds := config.TimeoutSeconds
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = defaultHTTPTimeoutSeconds
+	}
+
+	return &httpModerator{
+		url:    config.URL,
+		apiKey: config.APIKey,
+		client: &http.Client{
+			Timeout: time.Duration(timeoutSeconds) * time.Second,
+		},
+	}, nil
+}
+
+// Moderate checks an avatar.
+func (moderator *httpModerator) Moderate(avatar *Avatar) (*Decision, error) {
+	req, err := http.NewRequest(http.MethodPost, moderator.url, bytes.NewReader(avatar.ImageData))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", avatar.ImageContentType)
+	req.Header.Set("X-Account-Id", avatar.AccountID)
+	if len(moderator.apiKey) > 0 {
+		req.Header.Set("Authorization", "Bearer "+moderator.apiKey)
+	}
+
+	resp, err := moderator.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unexpected status code from the avatar moderator: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPResponseSize))
+	if err != nil {
+		return nil, err
+	}
+
+	decision := &Decision{}
+	err = json.Unmarshal(body, decision)
+	if err != nil {
+		return nil, err
+	}
+	return decision, nil
+}
@@ -0,0 +1,81 @@
+package avatarmoderation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Rejection reasons of the rules-based moderator.
+const (
+	ReasonTooSmall       = "tooSmall"
+	ReasonBadAspectRatio = "badAspectRatio"
+	ReasonBlocked        = "blocked"
+)
+
+const (
+	defaultMinDimension   = 64
+	defaultMaxAspectRatio = 4
+)
+
+// RulesConfig is the configuration of the rules-based moderator.
+type RulesConfig struct {
+	// MinDimension is the minimum width and height of the uploaded image, in pixels.
+	MinDimension int `env:"AMS_AVATAR_MODERATOR_MIN_DIMENSION"`
+	// MaxAspectRatio is the maximum ratio between the longest and the shortest side.
+	MaxAspectRatio int `env:"AMS_AVATAR_MODERATOR_MAX_ASPECT_RATIO"`
+	// BlockedHashes is a comma separated list of the SHA-256 hex hashes of known bad uploads.
+	BlockedHashes string `env:"AMS_AVATAR_MODERATOR_BLOCKED_HASHES"`
+}
+
+type rulesModerator struct {
+	minDimension   int
+	maxAspectRatio int
+	blockedHashes  map[string]bool
+}
+
+// NewRulesModerator creates a moderator that checks avatars with local rules.
+func NewRulesModerator(config RulesConfig) Moderator {
+	moderator := &rulesModerator{
+		minDimension:   config.MinDimension,
+		maxAspectRatio: config.MaxAspectRatio,
+		blockedHashes:  map[string]bool{},
+	}
+
+	if moderator.minDimension <= 0 {
+		moderator.minDimension = defaultMinDimension
+	}
+	if moderator.maxAspectRatio <= 0 {
+		moderator.maxAspectRatio = defaultMaxAspectRatio
+	}
+
+	for _, hash := range strings.Split(config.BlockedHashes, ",") {
+		hash = strings.ToLower(strings.TrimSpace(hash))
+		if len(hash) > 0 {
+			moderator.blockedHashes[hash] = true
+		}
+	}
+
+	return moderator
+}
+
+// Moderate checks an avatar.
+func (moderator *rulesModerator) Moderate(avatar *Avatar) (*Decision, error) {
+	shortSide, longSide := avatar.Width, avatar.Height
+	if shortSide > longSide {
+		shortSide, longSide = longSide, shortSide
+	}
+
+	if shortSide < moderator.minDimension {
+		return &Decision{Reason: ReasonTooSmall}, nil
+	} else if longSide > shortSide*moderator.maxAspectRatio {
+		return &Decision{Reason: ReasonBadAspectRatio}, nil
+	}
+
+	hash := sha256.Sum256(avatar.Data)
+	if moderator.blockedHashes[hex.EncodeToString(hash[:])] {
+		return &Decision{Reason: ReasonBlocked}, nil
+	}
+
+	return &Decision{Approved: true}, nil
+}
@@ -0,0 +1,65 @@
+package avatarpresets
+
+import (
+	_ "embed"
+	"encoding/json"
+	"strings"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+)
+
+//go:embed presets.json
+var presetsJSON []byte
+
+// Preset is a curated avatar that can be used instead of an uploaded one.
+// The preset images are stored in the avatar preset storage with the preset ID as name.
+type Preset struct {
+	ID       string            `json:"id"`
+	Category string            `json:"category"`
+	Names    map[string]string `json:"names"`
+}
+
+var (
+	presets     []*Preset
+	presetsByID map[string]*Preset
+)
+
+func init() {
+	err := json.Unmarshal(presetsJSON, &presets)
+	if err != nil {
+		panic(err)
+	}
+
+	presetsByID = make(map[string]*Preset, len(presets))
+	for _, preset := range presets {
+		presetsByID[preset.ID] = preset
+	}
+}
+
+// List returns all the presets of the catalog.
+func List() []*Preset {
+	return presets
+}
+
+// Get returns a preset from its ID.
+func Get(presetID string) (*Preset, bool) {
+	preset, exists := presetsByID[presetID]
+	return preset, exists
+}
+
+// LocalizedName returns the name of the preset in a language.
+// It falls back to another region of the same language, then to the default language.
+func (preset *Preset) LocalizedName(language string) string {
+	if name, exists := preset.Names[language]; exists {
+		return name
+	}
+
+	languagePrefix := strings.SplitN(language, "_", 2)[0] + "_"
+	for nameLanguage, name := range preset.Names {
+		if strings.HasPrefix(nameLanguage, languagePrefix) {
+			return name
+		}
+	}
+
+	return preset.Names[defs.DefaultLanguageCode]
+}
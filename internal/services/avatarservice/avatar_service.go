@@ -0,0 +1,218 @@
+package avatarservice
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/messagetemplates"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/avatarmoderation"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notifications"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notificationservice"
+	"bitbucket.org/calmisland/go-server-account/avatars"
+	"bitbucket.org/calmisland/go-server-cloud/cloudstorage"
+	"bitbucket.org/calmisland/go-server-logs/logger"
+	"github.com/calmisland/go-errors"
+)
+
+const (
+	// MaxUploadSize is the maximum size of an uploaded avatar.
+	MaxUploadSize = 5 * 1024 * 1024 // 5 MB
+
+	avatarExpireDuration = 24 * time.Hour
+	urlExpireDuration    = 10 * time.Minute
+	transferTimeout      = 30 * time.Second
+)
+
+// ErrAvatarNotFound is returned when the account has not uploaded an avatar.
+var ErrAvatarNotFound = errors.New("The avatar has not been uploaded")
+
+var httpClient = &http.Client{
+	Timeout: transferTimeout,
+}
+
+// CompleteUpload processes and moderates the avatar uploaded by an account to the quarantine storage.
+// Approved avatars are published in the normalised sizes, the uploaded file being replaced by the
+// largest size so its metadata is never served. Rejected avatars are deleted.
+// The quarantined upload is deleted in both cases, as well as when it isn't a valid image.
+func CompleteUpload(accountID string) (*avatarmoderation.Decision, error) {
+	data, err := downloadAvatar(globals.AvatarQuarantineStorage, accountID)
+	if err == ErrImageTooLarge {
+		return nil, deleteQuarantinedAvatar(accountID, err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	processed, err := ProcessImage(data, defs.AvatarSizes)
+	if err != nil {
+		return nil, deleteQuarantinedAvatar(accountID, err)
+	}
+
+	largestSize := 0
+	for size := range processed.Data {
+		if size > largestSize {
+			largestSize = size
+		}
+	}
+
+	decision, err := globals.AvatarModerator.Moderate(&avatarmoderation.Avatar{
+		AccountID:        accountID,
+		Width:            processed.Width,
+		Height:           processed.Height,
+		Data:             data,
+		Image:            processed.Images[largestSize],
+		ImageData:        processed.Data[largestSize],
+		ImageContentType: ProcessedContentType,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if decision.Approved {
+		err = publishAvatar(accountID, processed, largestSize)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// The user is told about the rejection in the response too, so failing to notify is only logged
+		err = sendRejectionNotice(accountID, decision)
+		if err != nil {
+			logger.LogFormat("[AVATAR] Failed to send the rejection notice to account [%s]: %v\n", accountID, err)
+		}
+	}
+
+	err = globals.AvatarQuarantineStorage.DeleteAvatarFile(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.LogFormat("[AVATAR] Moderated the avatar of account [%s] (%d bytes): approved %t, reason [%s]\n", accountID, len(data), decision.Approved, decision.Reason)
+	return decision, nil
+}
+
+// deleteQuarantinedAvatar deletes an upload that can't be processed and returns the processing error.
+func deleteQuarantinedAvatar(accountID string, processingErr error) error {
+	err := globals.AvatarQuarantineStorage.DeleteAvatarFile(accountID)
+	if err != nil {
+		return err
+	}
+
+	logger.LogFormat("[AVATAR] Deleted the invalid avatar uploaded by account [%s]: %v\n", accountID, processingErr)
+	return processingErr
+}
+
+// sendRejectionNotice tells an account that its avatar was rejected.
+func sendRejectionNotice(accountID string, decision *avatarmoderation.Decision) error {
+	return notificationservice.EnqueueToAccount(accountID, notifications.CategoryAccount, &messagetemplates.AvatarRejectedTemplate{
+		Reason: decision.Reason,
+	})
+}
+
+func publishAvatar(accountID string, processed *ProcessedImage, largestSize int) error {
+	for size, data := range processed.Data {
+		err := uploadAvatar(globals.AvatarSizeStorages[size], accountID, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return uploadAvatar(globals.AvatarStorage, accountID, processed.Data[largestSize])
+}
+
+// DeleteAvatar deletes the avatar of an account in all sizes, including any upload waiting for moderation.
+func DeleteAvatar(accountID string) error {
+	err := globals.AvatarStorage.DeleteAvatarFile(accountID)
+	if err != nil {
+		return err
+	}
+
+	err = globals.AvatarQuarantineStorage.DeleteAvatarFile(accountID)
+	if err != nil {
+		return err
+	}
+
+	for _, storage := range globals.AvatarSizeStorages {
+		err = storage.DeleteAvatarFile(accountID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downloadAvatar(storage avatars.Storage, accountID string) ([]byte, error) {
+	downloadURLResult, err := storage.GetAvatarFileDownloadURL(accountID, &cloudstorage.GetFileDownloadURLUsingCacheInput{
+		DownloadInput: &cloudstorage.GetFileDownloadURLInput{
+			Expires: time.Now().Add(urlExpireDuration),
+		},
+	})
+	if err != nil {
+		return nil, err
+	} else if downloadURLResult == nil || downloadURLResult.DownloadOutput == nil {
+		return nil, ErrAvatarNotFound
+	}
+
+	resp, err := httpClient.Get(downloadURLResult.DownloadOutput.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrAvatarNotFound
+	} else if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unexpected status code when downloading the avatar: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxUploadSize+1))
+	if err != nil {
+		return nil, err
+	} else if len(data) > MaxUploadSize {
+		return nil, ErrImageTooLarge
+	}
+	return data, nil
+}
+
+func uploadAvatar(storage avatars.Storage, accountID string, data []byte) error {
+	contentType := ProcessedContentType
+	contentLength := int64(len(data))
+	contentSHA256 := sha256.Sum256(data)
+	contentExpires := time.Now().Add(avatarExpireDuration)
+
+	uploadURLResult, err := storage.GetAvatarFileUploadURL(accountID, &cloudstorage.GetFileUploadURLInput{
+		ContentLength:  &contentLength,
+		ContentType:    &contentType,
+		ContentExpires: &contentExpires,
+		ContentSHA256:  contentSHA256[:],
+		Expires:        time.Now().Add(urlExpireDuration),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(uploadURLResult.Method, uploadURLResult.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = contentLength
+	for name, value := range uploadURLResult.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Unexpected status code when uploading the avatar: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package avatarservice
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"github.com/calmisland/go-errors"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+const (
+	// The maximum number of pixels decoded, to protect against decompression bombs
+	maxSourcePixels = 4096 * 4096
+	jpegQuality     = 85
+
+	// ProcessedContentType is the content type of the normalised avatars.
+	ProcessedContentType = "image/jpeg"
+)
+
+var (
+	// ErrInvalidImage is returned when the uploaded avatar cannot be decoded.
+	ErrInvalidImage = errors.New("The avatar is not a supported image")
+	// ErrImageTooLarge is returned when the uploaded avatar has too many pixels.
+	ErrImageTooLarge = errors.New("The avatar dimensions are too large")
+)
+
+type imageDecoder struct {
+	decode       func(r *bytes.Reader) (image.Image, error)
+	decodeConfig func(r *bytes.Reader) (image.Config, error)
+}
+
+// decoders are the supported content types. They are selected explicitly instead of
+// through image.Decode so other registered formats are never accepted.
+var decoders = map[string]imageDecoder{
+	"image/jpeg": {
+		decode:       func(r *bytes.Reader) (image.Image, error) { return jpeg.Decode(r) },
+		decodeConfig: func(r *bytes.Reader) (image.Config, error) { return jpeg.DecodeConfig(r) },
+	},
+	"image/png": {
+		decode:       func(r *bytes.Reader) (image.Image, error) { return png.Decode(r) },
+		decodeConfig: func(r *bytes.Reader) (image.Config, error) { return png.DecodeConfig(r) },
+	},
+	"image/webp": {
+		decode:       func(r *bytes.Reader) (image.Image, error) { return webp.Decode(r) },
+		decodeConfig: func(r *bytes.Reader) (image.Config, error) { return webp.DecodeConfig(r) },
+	},
+}
+
+// IsSupportedContentType checks if an avatar can be uploaded with a content type.
+func IsSupportedContentType(contentType string) bool {
+	_, exists := decoders[contentType]
+	return exists
+}
+
+// ProcessedImage is an uploaded avatar converted to the normalised sizes.
+type ProcessedImage struct {
+	// Width and Height are the dimensions of the uploaded image.
+	Width  int
+	Height int
+	// Images are the square images by size.
+	Images map[int]image.Image
+	// Data are the encoded images by size.
+	Data map[int][]byte
+}
+
+// ProcessImage decodes an uploaded avatar and returns it as square JPEG images of the given sizes.
+// The format is detected from the content since the declared content type can't be trusted.
+// Re-encoding drops all the metadata of the original file, including the EXIF and GPS data.
+func ProcessImage(data []byte, sizes []int) (*ProcessedImage, error) {
+	decoder, exists := decoders[http.DetectContentType(data)]
+	if !exists {
+		return nil, ErrInvalidImage
+	}
+
+	config, err := decoder.decodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	} else if config.Width <= 0 || config.Height <= 0 {
+		return nil, ErrInvalidImage
+	} else if config.Width*config.Height > maxSourcePixels {
+		return nil, ErrImageTooLarge
+	}
+
+	source, err := decoder.decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	crop := centerSquare(source.Bounds())
+	processed := &ProcessedImage{
+		Width:  config.Width,
+		Height: config.Height,
+		Images: make(map[int]image.Image, len(sizes)),
+		Data:   make(map[int][]byte, len(sizes)),
+	}
+	for _, size := range sizes {
+		resized := image.NewRGBA(image.Rect(0, 0, size, size))
+		// JPEG has no transparency, so transparent pixels become white
+		draw.Draw(resized, resized.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.CatmullRom.Scale(resized, resized.Bounds(), source, crop, draw.Over, nil)
+
+		var buffer bytes.Buffer
+		err = jpeg.Encode(&buffer, resized, &jpeg.Options{Quality: jpegQuality})
+		if err != nil {
+			return nil, err
+		}
+		processed.Images[size] = resized
+		processed.Data[size] = buffer.Bytes()
+	}
+
+	return processed, nil
+}
+
+// centerSquare returns the largest square in the center of the bounds.
+func centerSquare(bounds image.Rectangle) image.Rectangle {
+	width := bounds.Dx()
+	height := bounds.Dy()
+	if width > height {
+		offset := (width - height) / 2
+		return image.Rect(bounds.Min.X+offset, bounds.Min.Y, bounds.Min.X+offset+height, bounds.Max.Y)
+	}
+
+	offset := (height - width) / 2
+	return image.Rect(bounds.Min.X, bounds.Min.Y+offset, bounds.Max.X, bounds.Min.Y+offset+width)
+}
@@ -0,0 +1,119 @@
+package childservice
+
+import (
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
+	"bitbucket.org/calmisland/go-server-account/accountdatabase"
+	"bitbucket.org/calmisland/go-server-logs/logger"
+	"github.com/calmisland/go-errors"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrTooManyChildren is returned when a parent account already manages as many child profiles as allowed.
+	ErrTooManyChildren = errors.New("The account has too many child profiles")
+	// ErrChildNotFound is returned when a child profile doesn't exist or is not managed by the parent account.
+	ErrChildNotFound = errors.New("The child profile doesn't exist")
+)
+
+// NewChild is the information of a child profile to create.
+type NewChild struct {
+	FullName  string
+	FirstName string
+	LastName  string
+	Country   string
+	Language  string
+	// Profile are the profile fields to set, or nil if none is set
+	Profile *profileservice.ProfileEdit
+}
+
+// CreateChild creates a child profile managed by a parent account, returning the account ID of the child.
+// Child profiles have no email, phone number nor password and can only sign in through their parent or a device credential.
+// The child profile is linked to its parent first, so the parent can delete it if the creation is interrupted,
+// and the creation is rolled back if a later step fails.
+func CreateChild(store Store, parentID string, child *NewChild) (string, error) {
+	accountUUID, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+
+	childID := accountUUID.String()
+	err = store.LinkChild(&models.AccountChild{
+		ParentID:    parentID,
+		ChildID:     childID,
+		CreatedDate: time.Now().UnixNano() / int64(time.Millisecond),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	err = createChildAccount(store, parentID, childID, child)
+	if err != nil {
+		rollbackChild(store, parentID, childID)
+		return "", err
+	}
+	return childID, nil
+}
+
+// createChildAccount creates the account and the profile of a linked child profile.
+func createChildAccount(store Store, parentID string, childID string, child *NewChild) error {
+	err := store.CreateAccount(&accountdatabase.CreateAccountInfo{
+		ID:       childID,
+		Country:  child.Country,
+		Language: child.Language,
+	}, &accountdatabase.AccountNameInfo{
+		FullName:  &child.FullName,
+		FirstName: &child.FirstName,
+		LastName:  &child.LastName,
+	})
+	if err != nil {
+		return err
+	}
+
+	return store.SetProfile(childID, parentID, child.Profile)
+}
+
+// rollbackChild deletes a child profile whose creation failed. The link to the parent is deleted last,
+// so the parent can still delete the child profile if the rollback fails.
+func rollbackChild(store Store, parentID string, childID string) {
+	err := store.DeleteProfile(childID)
+	if err == nil {
+		err = store.DeleteAccount(childID)
+	}
+	if err == nil {
+		err = store.UnlinkChild(parentID, childID)
+	}
+	if err != nil {
+		logger.LogFormat("[CHILDREN] Failed to roll back the child profile [%s] of account [%s]: %s\n", childID, parentID, err.Error())
+	}
+}
+
+// GetChildIDs returns the account IDs of the child profiles managed by a parent account.
+func GetChildIDs(store Store, parentID string) ([]string, error) {
+	children, err := store.ListChildren(parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	childIDs := make([]string, len(children))
+	for i, child := range children {
+		childIDs[i] = child.ChildID
+	}
+	return childIDs, nil
+}
+
+// IsParentOf checks if an account manages a child profile.
+func IsParentOf(store Store, parentID string, childID string) (bool, error) {
+	child, err := store.GetChild(parentID, childID)
+	if err != nil {
+		return false, err
+	}
+	return child != nil, nil
+}
+
+// UnlinkChild removes a child profile from the ones managed by a parent account.
+func UnlinkChild(store Store, parentID string, childID string) error {
+	return store.UnlinkChild(parentID, childID)
+}
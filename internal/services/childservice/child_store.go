@@ -0,0 +1,187 @@
+package childservice
+
+import (
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
+	"bitbucket.org/calmisland/go-server-account/accountdatabase"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/guregu/dynamo"
+)
+
+// Store reads and writes the child profiles, their links to their parent accounts and their device credentials.
+type Store interface {
+	// LinkChild atomically links a child profile to its parent account and counts it. It fails with ErrTooManyChildren
+	// when the parent already manages defs.MaxChildProfiles.
+	LinkChild(link *models.AccountChild) error
+	// UnlinkChild atomically unlinks a child profile from its parent account and uncounts it.
+	// Nothing is done if the child profile isn't linked to the parent.
+	UnlinkChild(parentID string, childID string) error
+	// ListChildren returns the links of a parent account to its child profiles.
+	ListChildren(parentID string) ([]models.AccountChild, error)
+	// GetChild returns the link of a parent account to a child profile, nil if it doesn't exist.
+	GetChild(parentID string, childID string) (*models.AccountChild, error)
+	// CreateAccount creates the account of a child profile with its names.
+	CreateAccount(info *accountdatabase.CreateAccountInfo, names *accountdatabase.AccountNameInfo) error
+	// DeleteAccount deletes the account of a child profile that was never used.
+	DeleteAccount(childID string) error
+	// SetProfile sets the parent account and the profile fields of a child profile, edit being nil if none is set.
+	SetProfile(childID string, parentID string, edit *profileservice.ProfileEdit) error
+	// DeleteProfile deletes the profile of a child profile.
+	DeleteProfile(childID string) error
+	// PutDeviceCredential writes a new device credential.
+	PutDeviceCredential(credential *models.ChildDeviceCredential) error
+	// GetDeviceCredential returns a device credential, nil if it doesn't exist.
+	GetDeviceCredential(credentialID string) (*models.ChildDeviceCredential, error)
+	// ListDeviceCredentials returns the device credentials of a child profile.
+	ListDeviceCredentials(childID string) ([]models.ChildDeviceCredential, error)
+	// SetDeviceCredentialUsed records the last use of an existing device credential, in epoch milliseconds.
+	SetDeviceCredentialUsed(credentialID string, usedDate int64) error
+	// DeleteDeviceCredential deletes a device credential.
+	DeleteDeviceCredential(credentialID string) error
+}
+
+type standardStore struct{}
+
+// NewStore creates the store of the child profiles, which uses the children and device credentials tables,
+// the account database and the profiles table.
+func NewStore() Store {
+	return &standardStore{}
+}
+
+func getChildTable() dynamo.Table {
+	return models.GetDB().Table(models.GetTableName(models.TABLE_NAME_ACCOUNT_CHILDREN))
+}
+
+func getDeviceCredentialTable() dynamo.Table {
+	return models.GetDB().Table(models.GetTableName(models.TABLE_NAME_CHILD_DEVICE_CREDENTIALS))
+}
+
+func (store *standardStore) LinkChild(link *models.AccountChild) error {
+	err := models.GetDB().WriteTx().
+		Put(getChildTable().Put(link).If("attribute_not_exists($)", "childId")).
+		Update(profileservice.NewChildCountUpdate(link.ParentID, 1)).
+		Run()
+	if !isTransactionCanceled(err) {
+		return err
+	}
+
+	parent, getErr := profileservice.GetProfile(link.ParentID)
+	if getErr != nil {
+		return getErr
+	} else if parent.ChildCount >= defs.MaxChildProfiles {
+		return ErrTooManyChildren
+	}
+	return err
+}
+
+func (store *standardStore) UnlinkChild(parentID string, childID string) error {
+	err := models.GetDB().WriteTx().
+		Delete(getChildTable().Delete("accId", parentID).Range("childId", childID).If("attribute_exists($)", "childId")).
+		Update(profileservice.NewChildCountUpdate(parentID, -1)).
+		Run()
+	if isTransactionCanceled(err) {
+		link, getErr := store.GetChild(parentID, childID)
+		if getErr != nil {
+			return getErr
+		} else if link == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (store *standardStore) ListChildren(parentID string) ([]models.AccountChild, error) {
+	var children []models.AccountChild
+	err := getChildTable().Get("accId", parentID).All(&children)
+	if err != nil && err != dynamo.ErrNotFound {
+		return nil, err
+	}
+	return children, nil
+}
+
+func (store *standardStore) GetChild(parentID string, childID string) (*models.AccountChild, error) {
+	child := &models.AccountChild{}
+	err := getChildTable().Get("accId", parentID).Range("childId", dynamo.Equal, childID).One(child)
+	if err == dynamo.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return child, nil
+}
+
+func (store *standardStore) CreateAccount(info *accountdatabase.CreateAccountInfo, names *accountdatabase.AccountNameInfo) error {
+	err := globals.AccountDatabase.CreateAccount(info)
+	if err != nil {
+		return err
+	}
+
+	return globals.AccountDatabase.EditAccount(info.ID, &accountdatabase.AccountEditInfo{
+		Names: names,
+	})
+}
+
+func (store *standardStore) DeleteAccount(childID string) error {
+	// The child profiles have no email, phone number nor transaction when they are created
+	return models.GetDB().Table(models.GetTableName(models.TABLE_NAME_ACCOUNT)).Delete("id", childID).Run()
+}
+
+func (store *standardStore) SetProfile(childID string, parentID string, edit *profileservice.ProfileEdit) error {
+	err := profileservice.SetParent(childID, parentID)
+	if err != nil || edit == nil {
+		return err
+	}
+
+	err = profileservice.EditProfile(childID, edit)
+	if err != nil || edit.BirthYear == nil {
+		return err
+	}
+	return profileservice.RefreshAgeBand(childID)
+}
+
+func (store *standardStore) DeleteProfile(childID string) error {
+	return profileservice.DeleteProfile(childID)
+}
+
+func (store *standardStore) PutDeviceCredential(credential *models.ChildDeviceCredential) error {
+	return getDeviceCredentialTable().Put(credential).If("attribute_not_exists($)", "credentialId").Run()
+}
+
+func (store *standardStore) GetDeviceCredential(credentialID string) (*models.ChildDeviceCredential, error) {
+	credential := &models.ChildDeviceCredential{}
+	err := getDeviceCredentialTable().Get("credentialId", credentialID).One(credential)
+	if err == dynamo.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+func (store *standardStore) ListDeviceCredentials(childID string) ([]models.ChildDeviceCredential, error) {
+	var credentials []models.ChildDeviceCredential
+	err := getDeviceCredentialTable().Get("childId", childID).Index(models.CHILD_DEVICE_CREDENTIAL_GSI_CHILDID).All(&credentials)
+	if err != nil && err != dynamo.ErrNotFound {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+func (store *standardStore) SetDeviceCredentialUsed(credentialID string, usedDate int64) error {
+	return getDeviceCredentialTable().Update("credentialId", credentialID).
+		Set("lastUseTm", usedDate).
+		If("attribute_exists($)", "credentialId").
+		Run()
+}
+
+func (store *standardStore) DeleteDeviceCredential(credentialID string) error {
+	return getDeviceCredentialTable().Delete("credentialId", credentialID).Run()
+}
+
+func isTransactionCanceled(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	return ok && awsErr.Code() == dynamodb.ErrCodeTransactionCanceledException
+}
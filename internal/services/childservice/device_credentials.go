@@ -0,0 +1,142 @@
+package childservice
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"github.com/calmisland/go-errors"
+	"github.com/google/uuid"
+)
+
+const (
+	deviceSecretByteLength = 32
+	// deviceCredentialSeparator separates the credential ID from the secret in a device credential
+	deviceCredentialSeparator = "."
+)
+
+var (
+	// ErrTooManyDeviceCredentials is returned when a child profile already has as many device credentials as allowed.
+	ErrTooManyDeviceCredentials = errors.New("The child profile has too many device credentials")
+	// ErrDeviceCredentialNotFound is returned when a device credential doesn't exist for the child profile.
+	ErrDeviceCredentialNotFound = errors.New("The device credential doesn't exist")
+	// ErrInvalidDeviceCredential is returned when a device credential is malformed, revoked or its secret doesn't match.
+	ErrInvalidDeviceCredential = errors.New("The device credential is invalid")
+)
+
+// FormatDeviceCredential formats the credential given to a device from its ID and secret.
+func FormatDeviceCredential(credentialID string, secret string) string {
+	return credentialID + deviceCredentialSeparator + secret
+}
+
+// ParseDeviceCredential splits a device credential into its ID and secret.
+func ParseDeviceCredential(credential string) (credentialID string, secret string, err error) {
+	parts := strings.SplitN(credential, deviceCredentialSeparator, 2)
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return "", "", ErrInvalidDeviceCredential
+	}
+	return parts[0], parts[1], nil
+}
+
+// CreateDeviceCredential creates a credential for a child profile to sign in on a device.
+// The returned credential holds the secret, which is only stored hashed and can't be retrieved later.
+func CreateDeviceCredential(store Store, parentID string, childID string, deviceName string) (*models.ChildDeviceCredential, string, error) {
+	credentials, err := store.ListDeviceCredentials(childID)
+	if err != nil {
+		return nil, "", err
+	} else if len(credentials) >= defs.MaxChildDeviceCredentials {
+		return nil, "", ErrTooManyDeviceCredentials
+	}
+
+	credentialUUID, err := uuid.NewRandom()
+	if err != nil {
+		return nil, "", err
+	}
+
+	secretBytes := make([]byte, deviceSecretByteLength)
+	_, err = rand.Read(secretBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret := base64.RawURLEncoding.EncodeToString(secretBytes)
+	secretHash, err := globals.PasswordHasher.GeneratePasswordHash(secret, false)
+	if err != nil {
+		return nil, "", err
+	}
+
+	credential := &models.ChildDeviceCredential{
+		CredentialID: credentialUUID.String(),
+		ChildID:      childID,
+		ParentID:     parentID,
+		SecretHash:   secretHash,
+		DeviceName:   deviceName,
+		CreatedDate:  time.Now().UnixNano() / int64(time.Millisecond),
+	}
+	err = store.PutDeviceCredential(credential)
+	if err != nil {
+		return nil, "", err
+	}
+	return credential, FormatDeviceCredential(credential.CredentialID, secret), nil
+}
+
+// ListDeviceCredentials returns the device credentials of a child profile.
+func ListDeviceCredentials(store Store, childID string) ([]models.ChildDeviceCredential, error) {
+	return store.ListDeviceCredentials(childID)
+}
+
+// DeleteDeviceCredential revokes a device credential of a child profile.
+func DeleteDeviceCredential(store Store, childID string, credentialID string) error {
+	credential, err := store.GetDeviceCredential(credentialID)
+	if err != nil {
+		return err
+	} else if credential == nil || credential.ChildID != childID {
+		return ErrDeviceCredentialNotFound
+	}
+	return store.DeleteDeviceCredential(credentialID)
+}
+
+// DeleteDeviceCredentials deletes all the device credentials of a child profile.
+func DeleteDeviceCredentials(store Store, childID string) error {
+	credentials, err := store.ListDeviceCredentials(childID)
+	if err != nil {
+		return err
+	}
+
+	for _, credential := range credentials {
+		err = store.DeleteDeviceCredential(credential.CredentialID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VerifyDeviceCredential verifies a device credential, returning the account ID of its child profile.
+func VerifyDeviceCredential(store Store, deviceCredential string) (string, error) {
+	credentialID, secret, err := ParseDeviceCredential(deviceCredential)
+	if err != nil {
+		return "", err
+	}
+
+	credential, err := store.GetDeviceCredential(credentialID)
+	if err != nil {
+		return "", err
+	} else if credential == nil {
+		return "", ErrInvalidDeviceCredential
+	}
+
+	if !globals.PasswordHasher.VerifyPasswordHash(secret, credential.SecretHash) {
+		return "", ErrInvalidDeviceCredential
+	}
+
+	err = store.SetDeviceCredentialUsed(credentialID, time.Now().UnixNano()/int64(time.Millisecond))
+	if err != nil {
+		return "", err
+	}
+	return credential.ChildID, nil
+}
@@ -0,0 +1,265 @@
+package defaultavatars
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// The styles of default avatars.
+const (
+	StyleInitials  = "initials"
+	StyleIdenticon = "identicon"
+)
+
+// The formats of default avatars.
+const (
+	FormatSVG = "svg"
+	FormatPNG = "png"
+)
+
+// The content types of the formats.
+const (
+	ContentTypeSVG = "image/svg+xml"
+	ContentTypePNG = "image/png"
+)
+
+// version is part of the ETag so the cached avatars are invalidated when the rendering changes.
+const version = "1"
+
+// versionDate is when the rendering last changed, the last modification of the avatars that only depend on it.
+var versionDate = time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+const identiconGridSize = 5
+
+// palette are the background colors, picked from the account ID.
+var palette = []color.RGBA{
+	{R: 0xE5, G: 0x73, B: 0x73, A: 0xFF},
+	{R: 0xF0, G: 0x62, B: 0x92, A: 0xFF},
+	{R: 0xBA, G: 0x68, B: 0xC8, A: 0xFF},
+	{R: 0x79, G: 0x86, B: 0xCB, A: 0xFF},
+	{R: 0x4F, G: 0xC3, B: 0xF7, A: 0xFF},
+	{R: 0x4D, G: 0xB6, B: 0xAC, A: 0xFF},
+	{R: 0x81, G: 0xC7, B: 0x84, A: 0xFF},
+	{R: 0xFF, G: 0xB7, B: 0x4D, A: 0xFF},
+	{R: 0xFF, G: 0x8A, B: 0x65, A: 0xFF},
+	{R: 0xA1, G: 0x88, B: 0x7F, A: 0xFF},
+}
+
+// Options are the options of a default avatar.
+type Options struct {
+	AccountID string
+	Initials  string
+	Style     string
+	Format    string
+	// Size is the width and height in pixels.
+	Size int
+}
+
+// Avatar is a rendered default avatar.
+type Avatar struct {
+	ContentType string
+	Data        []byte
+}
+
+// Initials returns up to two initials from the names of an account.
+func Initials(fullName, firstName, lastName string) string {
+	var names []string
+	if len(strings.TrimSpace(firstName)) > 0 || len(strings.TrimSpace(lastName)) > 0 {
+		names = []string{firstName, lastName}
+	} else {
+		names = strings.Fields(fullName)
+		if len(names) > 2 {
+			names = []string{names[0], names[len(names)-1]}
+		}
+	}
+
+	var initials []rune
+	for _, name := range names {
+		for _, r := range strings.TrimSpace(name) {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				initials = append(initials, unicode.ToUpper(r))
+			}
+			break
+		}
+	}
+	return string(initials)
+}
+
+// normalise applies the fallbacks: the initials style falls back to an identicon when there are no
+// initials, and the PNG format is always rendered as an identicon.
+func (options Options) normalise() Options {
+	if options.Format != FormatPNG {
+		options.Format = FormatSVG
+	}
+	if options.Format == FormatPNG || len(options.Initials) == 0 {
+		options.Style = StyleIdenticon
+	}
+	if options.Style != StyleIdenticon {
+		options.Style = StyleInitials
+	} else {
+		options.Initials = ""
+	}
+	return options
+}
+
+// IsSupported checks if a style can be rendered in a format. Empty values are the defaults.
+// The initials are only rendered as SVG since the PNG rendering has no font.
+func IsSupported(style, format string) bool {
+	switch style {
+	case "", StyleInitials, StyleIdenticon:
+	default:
+		return false
+	}
+
+	switch format {
+	case "", FormatSVG:
+		return true
+	case FormatPNG:
+		return style != StyleInitials
+	default:
+		return false
+	}
+}
+
+// LastModified returns when a default avatar last changed, without rendering it.
+// The names the initials come from may have changed at namesDate, which is ignored by the identicons.
+func LastModified(options Options, namesDate time.Time) time.Time {
+	options = options.normalise()
+	if options.Style == StyleInitials && namesDate.After(versionDate) {
+		// HTTP dates have no fractional seconds
+		return namesDate.UTC().Truncate(time.Second)
+	}
+	return versionDate
+}
+
+// ETag returns the ETag of a default avatar, without rendering it.
+func ETag(options Options) string {
+	options = options.normalise()
+	hash := sha256.Sum256([]byte(strings.Join([]string{
+		version,
+		options.AccountID,
+		options.Initials,
+		options.Style,
+		options.Format,
+		strconv.Itoa(options.Size),
+	}, "\x00")))
+	return `"` + hex.EncodeToString(hash[:16]) + `"`
+}
+
+// Render renders a default avatar. The same options always render the same avatar.
+func Render(options Options) (*Avatar, error) {
+	options = options.normalise()
+	switch {
+	case options.Format == FormatPNG:
+		data, err := renderIdenticonPNG(options.AccountID, options.Size)
+		if err != nil {
+			return nil, err
+		}
+		return &Avatar{ContentType: ContentTypePNG, Data: data}, nil
+	case options.Style == StyleIdenticon:
+		return &Avatar{ContentType: ContentTypeSVG, Data: renderIdenticonSVG(options.AccountID, options.Size)}, nil
+	default:
+		return &Avatar{ContentType: ContentTypeSVG, Data: renderInitialsSVG(options.AccountID, options.Initials, options.Size)}, nil
+	}
+}
+
+func backgroundColor(accountID string) color.RGBA {
+	hash := fnv.New32a()
+	hash.Write([]byte(accountID))
+	return palette[hash.Sum32()%uint32(len(palette))]
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B)
+}
+
+func renderInitialsSVG(accountID, initials string, size int) []byte {
+	fontSize := size * 2 / 5
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+
+		`<rect width="100%%" height="100%%" fill="%s"/>`+
+		`<text x="50%%" y="50%%" dy=".35em" fill="#FFFFFF" font-family="Helvetica, Arial, sans-serif" font-size="%d" text-anchor="middle">%s</text>`+
+		`</svg>`, size, size, size, size, hexColor(backgroundColor(accountID)), fontSize, html.EscapeString(initials)))
+}
+
+// identiconCells returns the filled cells of a horizontally symmetric grid derived from the account ID.
+func identiconCells(accountID string) [identiconGridSize][identiconGridSize]bool {
+	hash := sha256.Sum256([]byte(accountID))
+	var cells [identiconGridSize][identiconGridSize]bool
+	bit := 0
+	for y := 0; y < identiconGridSize; y++ {
+		for x := 0; x < (identiconGridSize+1)/2; x++ {
+			filled := hash[bit/8]&(1<<uint(bit%8)) != 0
+			cells[y][x] = filled
+			cells[y][identiconGridSize-1-x] = filled
+			bit++
+		}
+	}
+	return cells
+}
+
+func renderIdenticonSVG(accountID string, size int) []byte {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, identiconGridSize+1, identiconGridSize+1)
+	buffer.WriteString(`<rect width="100%" height="100%" fill="#F0F0F0"/>`)
+	fmt.Fprintf(&buffer, `<g fill="%s" transform="translate(0.5 0.5)">`, hexColor(backgroundColor(accountID)))
+
+	cells := identiconCells(accountID)
+	for y := range cells {
+		for x := range cells[y] {
+			if cells[y][x] {
+				fmt.Fprintf(&buffer, `<rect x="%d" y="%d" width="1" height="1"/>`, x, y)
+			}
+		}
+	}
+
+	buffer.WriteString(`</g></svg>`)
+	return buffer.Bytes()
+}
+
+func renderIdenticonPNG(accountID string, size int) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	background := color.RGBA{R: 0xF0, G: 0xF0, B: 0xF0, A: 0xFF}
+	foreground := backgroundColor(accountID)
+	cells := identiconCells(accountID)
+
+	// The grid has half a cell of margin on each side, so positions are computed in half cells
+	halfCellCount := (identiconGridSize + 1) * 2
+	cellAt := func(position int) int {
+		halfCell := position * halfCellCount / size
+		if halfCell < 1 {
+			return -1
+		}
+		return (halfCell - 1) / 2
+	}
+
+	for y := 0; y < size; y++ {
+		cellY := cellAt(y)
+		for x := 0; x < size; x++ {
+			cellX := cellAt(x)
+			if cellX >= 0 && cellY >= 0 && cellX < identiconGridSize && cellY < identiconGridSize && cells[cellY][cellX] {
+				img.SetRGBA(x, y, foreground)
+			} else {
+				img.SetRGBA(x, y, background)
+			}
+		}
+	}
+
+	var buffer bytes.Buffer
+	err := png.Encode(&buffer, img)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,250 @@
+package entitlements
+
+import (
+	"sort"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+)
+
+// Kind is the kind of an entitlement.
+type Kind string
+
+const (
+	// KindPass is a pass, such as a subscription.
+	KindPass Kind = "pass"
+	// KindProduct is a product.
+	KindProduct Kind = "product"
+)
+
+// Entitlement is a pass or a product currently owned by an account.
+type Entitlement struct {
+	Kind Kind   `json:"-"`
+	ID   string `json:"id"`
+	// StartDate is when the entitlement started, in epoch milliseconds, zero if unknown.
+	StartDate int64 `json:"startTm"`
+	// ExpirationDate is when the entitlement expires, in epoch milliseconds, zero if it never expires.
+	ExpirationDate int64 `json:"expirationTm,omitempty"`
+	// TransactionIDs are the transactions that grant the current period of the entitlement.
+	TransactionIDs []string `json:"transactionIds"`
+	// SharedBy is the family owner sharing the entitlement, if the account doesn't own it itself.
+	SharedBy string `json:"sharedBy,omitempty"`
+}
+
+// Entitlements are the passes and products currently owned by an account.
+type Entitlements struct {
+	Passes   []*Entitlement `json:"passes"`
+	Products []*Entitlement `json:"products"`
+}
+
+// period is the time range granted by a transaction item.
+type period struct {
+	start         int64
+	expiration    int64
+	transactionID string
+	// created is when the transaction was created, as the revocations only end the periods granted before them.
+	created int64
+}
+
+// neverExpires checks if a period has no expiration date.
+func (p *period) neverExpires() bool {
+	return p.expiration <= 0
+}
+
+// Compute returns the entitlements active at a given time, in epoch milliseconds.
+// The periods of the same pass or product are merged when they overlap or follow each other,
+// so an extension bought before the expiration is reported as a single period.
+// A revoked item ends the periods granted by the earlier transactions at the revocation date.
+func Compute(transactions []*models.AccountTransaction, now int64) *Entitlements {
+	passPeriods := map[string][]*period{}
+	productPeriods := map[string][]*period{}
+	passRevocations := map[string][]*period{}
+	productRevocations := map[string][]*period{}
+	for _, transaction := range transactions {
+		addPeriods(passPeriods, passRevocations, transaction.Passes, transaction)
+		addPeriods(productPeriods, productRevocations, transaction.Products, transaction)
+	}
+	applyRevocations(passPeriods, passRevocations)
+	applyRevocations(productPeriods, productRevocations)
+
+	return &Entitlements{
+		Passes:   computeActive(KindPass, passPeriods, now),
+		Products: computeActive(KindProduct, productPeriods, now),
+	}
+}
+
+func addPeriods(periods map[string][]*period, revocations map[string][]*period, items map[string]*models.AccountTransactionItem, transaction *models.AccountTransaction) {
+	for itemID, item := range items {
+		if item == nil {
+			continue
+		}
+
+		p := &period{
+			start:         int64(item.StartDate),
+			expiration:    int64(item.ExpirationDate),
+			transactionID: transaction.TransactionID,
+			created:       int64(transaction.CreatedDate),
+		}
+		if item.Revoked {
+			revocations[itemID] = append(revocations[itemID], p)
+		} else {
+			periods[itemID] = append(periods[itemID], p)
+		}
+	}
+}
+
+// applyRevocations ends the periods at the revocation dates, and removes the periods that would start after them.
+// The revocation date is the start date of the revoked item.
+func applyRevocations(periodsByID map[string][]*period, revocationsByID map[string][]*period) {
+	for itemID, revocations := range revocationsByID {
+		periods := periodsByID[itemID]
+		for _, revocation := range revocations {
+			remaining := periods[:0]
+			for _, p := range periods {
+				if p.created <= revocation.created {
+					if p.start >= revocation.start {
+						continue
+					} else if p.neverExpires() || p.expiration > revocation.start {
+						p.expiration = revocation.start
+					}
+				}
+				remaining = append(remaining, p)
+			}
+			periods = remaining
+		}
+		periodsByID[itemID] = periods
+	}
+}
+
+func computeActive(kind Kind, periodsByID map[string][]*period, now int64) []*Entitlement {
+	active := []*Entitlement{}
+	for itemID, periods := range periodsByID {
+		entitlement := findActivePeriod(periods, now)
+		if entitlement == nil {
+			continue
+		}
+
+		entitlement.Kind = kind
+		entitlement.ID = itemID
+		active = append(active, entitlement)
+	}
+
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].ID < active[j].ID
+	})
+	return active
+}
+
+// findActivePeriod merges the periods of an item and returns the merged period containing the given time, if any.
+func findActivePeriod(periods []*period, now int64) *Entitlement {
+	sort.Slice(periods, func(i, j int) bool {
+		if periods[i].start != periods[j].start {
+			return periods[i].start < periods[j].start
+		}
+		return periods[i].transactionID < periods[j].transactionID
+	})
+
+	var merged []*Entitlement
+	for _, p := range periods {
+		last := len(merged) - 1
+		if last >= 0 && (merged[last].ExpirationDate == 0 || p.start <= merged[last].ExpirationDate) {
+			// The period overlaps or follows the previous one, so it extends it
+			current := merged[last]
+			if p.neverExpires() {
+				current.ExpirationDate = 0
+			} else if current.ExpirationDate != 0 && p.expiration > current.ExpirationDate {
+				current.ExpirationDate = p.expiration
+			}
+			current.TransactionIDs = append(current.TransactionIDs, p.transactionID)
+			continue
+		}
+
+		entitlement := &Entitlement{
+			StartDate:      p.start,
+			ExpirationDate: p.expiration,
+			TransactionIDs: []string{p.transactionID},
+		}
+		if p.neverExpires() {
+			entitlement.ExpirationDate = 0
+		}
+		merged = append(merged, entitlement)
+	}
+
+	for _, entitlement := range merged {
+		if entitlement.StartDate <= now && (entitlement.ExpirationDate == 0 || now < entitlement.ExpirationDate) {
+			return entitlement
+		}
+	}
+	return nil
+}
+
+// ComputeShared returns the entitlements shared by a family owner that are active at a given time.
+// Only the shareable items are shared, and the revocations of the owner still end them.
+func ComputeShared(ownerID string, transactions []*models.AccountTransaction, now int64) *Entitlements {
+	shareableTransactions := make([]*models.AccountTransaction, 0, len(transactions))
+	for _, transaction := range transactions {
+		shareableTransaction := *transaction
+		shareableTransaction.Passes = filterShareableItems(transaction.Passes)
+		shareableTransaction.Products = filterShareableItems(transaction.Products)
+		if len(shareableTransaction.Passes) > 0 || len(shareableTransaction.Products) > 0 {
+			shareableTransactions = append(shareableTransactions, &shareableTransaction)
+		}
+	}
+
+	shared := Compute(shareableTransactions, now)
+	for _, entitlement := range shared.Passes {
+		entitlement.SharedBy = ownerID
+	}
+	for _, entitlement := range shared.Products {
+		entitlement.SharedBy = ownerID
+	}
+	return shared
+}
+
+func filterShareableItems(items map[string]*models.AccountTransactionItem) map[string]*models.AccountTransactionItem {
+	shareableItems := map[string]*models.AccountTransactionItem{}
+	for itemID, item := range items {
+		if item != nil && (item.Shareable || item.Revoked) {
+			shareableItems[itemID] = item
+		}
+	}
+	return shareableItems
+}
+
+// Merge merges the entitlements shared with an account into its own entitlements.
+// An item that is both owned and shared is reported as owned, for the longest of the two periods.
+func Merge(own *Entitlements, shared *Entitlements) *Entitlements {
+	return &Entitlements{
+		Passes:   mergeEntitlements(own.Passes, shared.Passes),
+		Products: mergeEntitlements(own.Products, shared.Products),
+	}
+}
+
+func mergeEntitlements(own []*Entitlement, shared []*Entitlement) []*Entitlement {
+	merged := make([]*Entitlement, 0, len(own)+len(shared))
+	ownByID := make(map[string]*Entitlement, len(own))
+	for _, entitlement := range own {
+		ownByID[entitlement.ID] = entitlement
+		merged = append(merged, entitlement)
+	}
+
+	for _, sharedEntitlement := range shared {
+		ownEntitlement, exists := ownByID[sharedEntitlement.ID]
+		if !exists {
+			merged = append(merged, sharedEntitlement)
+			continue
+		}
+
+		if sharedEntitlement.StartDate < ownEntitlement.StartDate {
+			ownEntitlement.StartDate = sharedEntitlement.StartDate
+		}
+		if sharedEntitlement.ExpirationDate == 0 || (ownEntitlement.ExpirationDate != 0 && sharedEntitlement.ExpirationDate > ownEntitlement.ExpirationDate) {
+			ownEntitlement.ExpirationDate = sharedEntitlement.ExpirationDate
+		}
+		ownEntitlement.TransactionIDs = append(ownEntitlement.TransactionIDs, sharedEntitlement.TransactionIDs...)
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].ID < merged[j].ID
+	})
+	return merged
+}
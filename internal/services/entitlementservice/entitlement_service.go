@@ -0,0 +1,221 @@
+package entitlementservice
+
+import (
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/entitlements"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/familyservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/transactionservice"
+	"github.com/calmisland/go-errors"
+	"github.com/google/uuid"
+)
+
+const (
+	dayMilliseconds = int64(24 * time.Hour / time.Millisecond)
+)
+
+var (
+	// ErrEntitlementNotActive is returned when extending or revoking an item that the account doesn't currently own.
+	ErrEntitlementNotActive = errors.New("The account doesn't own the item")
+	// ErrEntitlementPermanent is returned when extending an item that never expires.
+	ErrEntitlementPermanent = errors.New("The item never expires")
+	// ErrInvalidExpirationDate is returned when an extension doesn't end after the current expiration date.
+	ErrInvalidExpirationDate = errors.New("The expiration date must be after the current expiration date")
+)
+
+// Change is a change of the entitlements of an account made by an admin.
+// Every change is recorded as a new transaction of the account.
+type Change struct {
+	AccountID      string
+	AdminAccountID string
+	Kind           entitlements.Kind
+	ItemID         string
+	Reason         string
+	// DurationDays is how long a granted or extended item lasts, zero for a permanent grant.
+	DurationDays int
+	// ExpirationDate is the new expiration date of an extended item, used instead of DurationDays if set.
+	ExpirationDate int64
+	// Shareable tells if a granted or extended item is shared with the family of the account.
+	Shareable bool
+}
+
+// GetEntitlements returns the entitlements of an account active at a given time, in epoch milliseconds,
+// including the items shared by the owner of its family. A child profile also gets the items shared by its parent
+// and by the owner of the family of its parent.
+func GetEntitlements(accountID string, now int64) (*entitlements.Entitlements, error) {
+	transactions, err := transactionservice.ListAllTransactions(accountID)
+	if err != nil {
+		return nil, err
+	}
+	result := entitlements.Compute(transactions, now)
+
+	sharerIDs, err := getSharerIDs(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sharerID := range sharerIDs {
+		sharerTransactions, err := transactionservice.ListAllTransactions(sharerID)
+		if err != nil {
+			return nil, err
+		}
+		result = entitlements.Merge(result, entitlements.ComputeShared(sharerID, sharerTransactions, now))
+	}
+	return result, nil
+}
+
+// getSharerIDs returns the accounts sharing their items with an account, without the account itself.
+func getSharerIDs(accountID string) ([]string, error) {
+	profile, err := profileservice.GetProfile(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	ownerID, err := familyservice.GetOwnerID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	sharerIDs := appendSharerID(nil, accountID, ownerID)
+	if len(profile.ParentID) == 0 {
+		return sharerIDs, nil
+	}
+
+	parentOwnerID, err := familyservice.GetOwnerID(profile.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	sharerIDs = appendSharerID(sharerIDs, accountID, profile.ParentID)
+	return appendSharerID(sharerIDs, accountID, parentOwnerID), nil
+}
+
+func appendSharerID(sharerIDs []string, accountID string, sharerID string) []string {
+	if len(sharerID) == 0 || sharerID == accountID {
+		return sharerIDs
+	}
+	for _, existingID := range sharerIDs {
+		if existingID == sharerID {
+			return sharerIDs
+		}
+	}
+	return append(sharerIDs, sharerID)
+}
+
+// Grant grants an item to an account.
+// An item that the account already owns is extended from its expiration date instead.
+func Grant(change *Change) (*models.AccountTransaction, error) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	active, err := getActiveEntitlement(change, now)
+	if err != nil {
+		return nil, err
+	}
+
+	startDate := now
+	if active != nil && active.ExpirationDate > now {
+		startDate = active.ExpirationDate
+	}
+
+	item := &models.AccountTransactionItem{
+		StartDate: int(startDate),
+		Shareable: change.Shareable,
+	}
+	if change.DurationDays > 0 {
+		item.ExpirationDate = int(startDate + int64(change.DurationDays)*dayMilliseconds)
+	}
+	return writeChange(change, item, now)
+}
+
+// Extend extends an item that the account currently owns.
+func Extend(change *Change) (*models.AccountTransaction, error) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	active, err := getActiveEntitlement(change, now)
+	if err != nil {
+		return nil, err
+	} else if active == nil {
+		return nil, ErrEntitlementNotActive
+	} else if active.ExpirationDate == 0 {
+		return nil, ErrEntitlementPermanent
+	}
+
+	expirationDate := change.ExpirationDate
+	if expirationDate == 0 {
+		expirationDate = active.ExpirationDate + int64(change.DurationDays)*dayMilliseconds
+	}
+	if expirationDate <= active.ExpirationDate {
+		return nil, ErrInvalidExpirationDate
+	}
+
+	return writeChange(change, &models.AccountTransactionItem{
+		StartDate:      int(active.ExpirationDate),
+		ExpirationDate: int(expirationDate),
+		Shareable:      change.Shareable,
+	}, now)
+}
+
+// Revoke revokes an item that the account currently owns, including the periods that would follow the current one.
+func Revoke(change *Change) (*models.AccountTransaction, error) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	active, err := getActiveEntitlement(change, now)
+	if err != nil {
+		return nil, err
+	} else if active == nil {
+		return nil, ErrEntitlementNotActive
+	}
+
+	return writeChange(change, &models.AccountTransactionItem{
+		StartDate: int(now),
+		Revoked:   true,
+	}, now)
+}
+
+// getActiveEntitlement returns the changed item if the account currently owns it, or nil otherwise.
+func getActiveEntitlement(change *Change, now int64) (*entitlements.Entitlement, error) {
+	transactions, err := transactionservice.ListAllTransactions(change.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	active := entitlements.Compute(transactions, now)
+	activeItems := active.Passes
+	if change.Kind == entitlements.KindProduct {
+		activeItems = active.Products
+	}
+
+	for _, entitlement := range activeItems {
+		if entitlement.ID == change.ItemID {
+			return entitlement, nil
+		}
+	}
+	return nil, nil
+}
+
+func writeChange(change *Change, item *models.AccountTransactionItem, now int64) (*models.AccountTransaction, error) {
+	transactionUUID, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+
+	transaction := &models.AccountTransaction{
+		AccountID:     change.AccountID,
+		TransactionID: "admin-" + transactionUUID.String(),
+		CreatedDate:   int(now),
+		UpdatedDate:   int(now),
+		CreatedBy:     change.AdminAccountID,
+		Reason:        change.Reason,
+	}
+	items := map[string]*models.AccountTransactionItem{
+		change.ItemID: item,
+	}
+	if change.Kind == entitlements.KindProduct {
+		transaction.Products = items
+	} else {
+		transaction.Passes = items
+	}
+
+	err = transactionservice.NewTransactionPut(transaction).Run()
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
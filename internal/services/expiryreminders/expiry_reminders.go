@@ -0,0 +1,217 @@
+package expiryreminders
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/messagetemplates"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/entitlements"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notifications"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notificationservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/transactionservice"
+	"bitbucket.org/calmisland/go-server-account/accounts"
+	"bitbucket.org/calmisland/go-server-messages/messages"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/calmisland/go-errors"
+	"github.com/guregu/dynamo"
+)
+
+const (
+	// DefaultWindowsDays are the reminder windows used if none are configured.
+	DefaultWindowsDays = "7,1"
+
+	dayMilliseconds = int64(24 * time.Hour / time.Millisecond)
+)
+
+// Outcome is the outcome of a reminder.
+type Outcome string
+
+// The reminder outcomes.
+const (
+	OutcomeSent        Outcome = "sent"
+	OutcomeAlreadySent Outcome = "alreadySent"
+	OutcomeNoRecipient Outcome = "noRecipient"
+	OutcomeWouldBeSent Outcome = "wouldBeSent"
+	OutcomeNoAccount   Outcome = "noAccount"
+	OutcomeOptedOut    Outcome = "optedOut"
+)
+
+// Config is the configuration of the expiry reminders.
+type Config struct {
+	// WindowsDays is a comma separated list of the number of days before the expiration when the reminders are sent.
+	WindowsDays string `env:"AMS_EXPIRY_REMINDER_WINDOWS_DAYS"`
+}
+
+// Reminder is a reminder due to an account about a pass that is about to expire.
+type Reminder struct {
+	AccountID      string
+	PassID         string
+	ExpirationDate int64
+	WindowDays     int
+}
+
+// ID returns the ID of the reminder, which is different for each window and expiration date of a pass.
+// A pass that is extended after a reminder gets new reminders before its new expiration date.
+func (reminder *Reminder) ID() string {
+	return fmt.Sprintf("%s#%d#%d", reminder.PassID, reminder.ExpirationDate, reminder.WindowDays)
+}
+
+func getTable() dynamo.Table {
+	return models.GetDB().Table(models.GetTableName(models.TABLE_NAME_EXPIRY_REMINDERS))
+}
+
+// ParseWindows parses the reminder windows, in days, and returns them sorted from the shortest.
+func ParseWindows(windowsDays string) ([]int, error) {
+	if len(strings.TrimSpace(windowsDays)) == 0 {
+		windowsDays = DefaultWindowsDays
+	}
+
+	windows := []int{}
+	for _, value := range strings.Split(windowsDays, ",") {
+		days, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || days <= 0 {
+			return nil, errors.New("Invalid expiry reminder window: " + value)
+		}
+		windows = append(windows, days)
+	}
+
+	sort.Ints(windows)
+	return windows, nil
+}
+
+// DueReminders returns the reminders due to an account at a given time, in epoch milliseconds.
+// Only the shortest window containing the expiration date is due, so a late run doesn't send several reminders at once.
+func DueReminders(accountID string, transactions []*models.AccountTransaction, windows []int, now int64) []*Reminder {
+	reminders := []*Reminder{}
+	for _, pass := range entitlements.Compute(transactions, now).Passes {
+		if pass.ExpirationDate == 0 {
+			continue
+		}
+
+		timeLeft := pass.ExpirationDate - now
+		for _, windowDays := range windows {
+			if timeLeft <= int64(windowDays)*dayMilliseconds {
+				reminders = append(reminders, &Reminder{
+					AccountID:      accountID,
+					PassID:         pass.ID,
+					ExpirationDate: pass.ExpirationDate,
+					WindowDays:     windowDays,
+				})
+				break
+			}
+		}
+	}
+	return reminders
+}
+
+// Send sends a reminder by email, or by SMS to the accounts without a verified email address or which disabled the reminders by email.
+// The reminder is recorded before being sent, and is only sent if it wasn't recorded yet.
+func Send(reminder *Reminder, dryRun bool) (Outcome, error) {
+	accInfo, err := globals.AccountDatabase.GetAccountSignInInfoByID(reminder.AccountID)
+	if err != nil {
+		return "", err
+	} else if accInfo == nil {
+		return OutcomeNoAccount, nil
+	}
+
+	preferences, err := notificationservice.GetPreferences(reminder.AccountID)
+	if err != nil {
+		return "", err
+	}
+
+	message := &messages.Message{
+		Language: accInfo.Language,
+		Template: &messagetemplates.PassExpiryTemplate{
+			PassID:         reminder.PassID,
+			ExpirationDate: reminder.ExpirationDate,
+			DaysLeft:       reminder.WindowDays,
+		},
+	}
+	hasEmail := len(accInfo.Email) > 0 && accounts.IsAccountEmailVerified(accInfo.Flags)
+	hasPhoneNumber := len(accInfo.PhoneNumber) > 0 && accounts.IsAccountPhoneNumberVerified(accInfo.Flags)
+	if hasEmail && preferences.Allows(notifications.CategoryReminders, notifications.ChannelEmail) {
+		message.MessageType = messages.MessageTypeEmail
+		message.Priority = messages.MessagePriorityEmailNormal
+		message.Recipient = accInfo.Email
+	} else if hasPhoneNumber && preferences.Allows(notifications.CategoryReminders, notifications.ChannelSMS) {
+		message.MessageType = messages.MessageTypeSMS
+		message.Priority = messages.MessagePrioritySMSTransactional
+		message.Recipient = accInfo.PhoneNumber
+	} else if hasEmail || hasPhoneNumber {
+		return OutcomeOptedOut, nil
+	} else {
+		return OutcomeNoRecipient, nil
+	}
+
+	if dryRun {
+		return OutcomeWouldBeSent, nil
+	}
+
+	err = getTable().Put(&models.ExpiryReminder{
+		AccountID:      reminder.AccountID,
+		ReminderID:     reminder.ID(),
+		PassID:         reminder.PassID,
+		ExpirationDate: reminder.ExpirationDate,
+		WindowDays:     reminder.WindowDays,
+		Recipient:      message.Recipient,
+		SentDate:       time.Now().UnixNano() / int64(time.Millisecond),
+	}).If("attribute_not_exists($)", "reminderId").Run()
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return OutcomeAlreadySent, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	err = notificationservice.Enqueue(reminder.AccountID, notifications.CategoryReminders, message)
+	if err != nil {
+		// The record is removed so the reminder is sent by the next run
+		deleteErr := getTable().Delete("accId", reminder.AccountID).Range("reminderId", reminder.ID()).Run()
+		if deleteErr != nil {
+			return "", deleteErr
+		}
+		return "", err
+	}
+	return OutcomeSent, nil
+}
+
+// SendAccountReminders sends the reminders due to an account and returns their outcomes.
+func SendAccountReminders(accountID string, windows []int, now int64, dryRun bool) ([]Outcome, error) {
+	transactions, err := transactionservice.ListAllTransactions(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	reminders := DueReminders(accountID, transactions, windows, now)
+	outcomes := make([]Outcome, 0, len(reminders))
+	for _, reminder := range reminders {
+		outcome, err := Send(reminder, dryRun)
+		if err != nil {
+			return outcomes, err
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	return outcomes, nil
+}
+
+// DeleteReminders deletes the records of the reminders sent to an account.
+func DeleteReminders(accountID string) error {
+	var reminders []models.ExpiryReminder
+	err := getTable().Get("accId", accountID).All(&reminders)
+	if err != nil && err != dynamo.ErrNotFound {
+		return err
+	}
+
+	for _, reminder := range reminders {
+		err = getTable().Delete("accId", accountID).Range("reminderId", reminder.ReminderID).Run()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,349 @@
+package familyservice
+
+import (
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/messagetemplates"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notifications"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notificationservice"
+	"bitbucket.org/calmisland/go-server-logs/logger"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/calmisland/go-errors"
+	"github.com/google/uuid"
+	"github.com/guregu/dynamo"
+)
+
+const (
+	dayMilliseconds = int64(24 * time.Hour / time.Millisecond)
+)
+
+var (
+	// ErrAlreadyInFamily is returned when an account that is already a member of a family creates or joins another one.
+	ErrAlreadyInFamily = errors.New("The account is already a member of a family")
+	// ErrFamilyFull is returned when a family already has as many members as allowed.
+	ErrFamilyFull = errors.New("The family has too many members")
+	// ErrInvitationNotFound is returned when accepting an invitation that doesn't exist or has expired.
+	ErrInvitationNotFound = errors.New("The invitation doesn't exist")
+	// ErrMemberNotFound is returned when removing an account that is not a member of the family.
+	ErrMemberNotFound = errors.New("The account is not a member of the family")
+)
+
+func getFamilyTable() dynamo.Table {
+	return models.GetDB().Table(models.GetTableName(models.TABLE_NAME_FAMILIES))
+}
+
+func getMemberTable() dynamo.Table {
+	return models.GetDB().Table(models.GetTableName(models.TABLE_NAME_FAMILY_MEMBERS))
+}
+
+func getInvitationTable() dynamo.Table {
+	return models.GetDB().Table(models.GetTableName(models.TABLE_NAME_FAMILY_INVITATIONS))
+}
+
+func isTransactionCanceled(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	return ok && awsErr.Code() == dynamodb.ErrCodeTransactionCanceledException
+}
+
+// CreateFamily creates a family owned by an account.
+func CreateFamily(ownerID string) (*models.Family, error) {
+	familyUUID, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	family := &models.Family{
+		FamilyID:    familyUUID.String(),
+		OwnerID:     ownerID,
+		MemberCount: 1,
+		CreatedDate: now,
+	}
+	owner := &models.FamilyMember{
+		AccountID:   ownerID,
+		FamilyID:    family.FamilyID,
+		Role:        models.FamilyRoleOwner,
+		CreatedDate: now,
+	}
+
+	err = models.GetDB().WriteTx().
+		Put(getFamilyTable().Put(family).If("attribute_not_exists($)", "familyId")).
+		Put(getMemberTable().Put(owner).If("attribute_not_exists($)", "accId")).
+		Run()
+	if isTransactionCanceled(err) {
+		return nil, ErrAlreadyInFamily
+	} else if err != nil {
+		return nil, err
+	}
+	return family, nil
+}
+
+// GetMembership returns the family membership of an account, or nil if it's not a member of a family.
+func GetMembership(accountID string) (*models.FamilyMember, error) {
+	member := &models.FamilyMember{}
+	err := getMemberTable().Get("accId", accountID).One(member)
+	if err == dynamo.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// GetFamily returns a family with its members, or nil if it doesn't exist.
+func GetFamily(familyID string) (*models.Family, []*models.FamilyMember, error) {
+	family := &models.Family{}
+	err := getFamilyTable().Get("familyId", familyID).One(family)
+	if err == dynamo.ErrNotFound {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	members, err := getMembers(familyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return family, members, nil
+}
+
+func getMembers(familyID string) ([]*models.FamilyMember, error) {
+	var members []*models.FamilyMember
+	err := getMemberTable().Get("familyId", familyID).Index(models.FAMILY_MEMBER_GSI_FAMILYID).All(&members)
+	if err != nil && err != dynamo.ErrNotFound {
+		return nil, err
+	}
+	return members, nil
+}
+
+// GetOwnerID returns the owner of the family of an account, or an empty ID if it's not a member of a family.
+// The owner of a family is its own owner.
+func GetOwnerID(accountID string) (string, error) {
+	member, err := GetMembership(accountID)
+	if err != nil || member == nil {
+		return "", err
+	}
+
+	family := &models.Family{}
+	err = getFamilyTable().Get("familyId", member.FamilyID).One(family)
+	if err == dynamo.ErrNotFound {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return family.OwnerID, nil
+}
+
+// Invite invites an account to join a family and notifies it. Inviting an account again renews its invitation.
+func Invite(family *models.Family, inviterID string, accountID string) (*models.FamilyInvitation, error) {
+	if family.MemberCount >= defs.MaxFamilyMembers {
+		return nil, ErrFamilyFull
+	}
+
+	member, err := GetMembership(accountID)
+	if err != nil {
+		return nil, err
+	} else if member != nil {
+		return nil, ErrAlreadyInFamily
+	}
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	invitation := &models.FamilyInvitation{
+		AccountID:      accountID,
+		FamilyID:       family.FamilyID,
+		InvitedBy:      inviterID,
+		ExpirationDate: now + defs.FamilyInvitationValidDays*dayMilliseconds,
+		CreatedDate:    now,
+	}
+	err = getInvitationTable().Put(invitation).Run()
+	if err != nil {
+		return nil, err
+	}
+
+	// The invitation is valid even if the invited account can't be notified
+	err = notificationservice.EnqueueToAccount(accountID, notifications.CategoryAccount, &messagetemplates.FamilyInvitationTemplate{
+		FamilyID:       invitation.FamilyID,
+		ExpirationDate: invitation.ExpirationDate,
+	})
+	if err != nil {
+		logger.LogFormat("[FAMILY] Failed to notify account [%s] of the invitation to family [%s]: %v\n", accountID, invitation.FamilyID, err)
+	}
+	return invitation, nil
+}
+
+// ListInvitations returns the invitations received by an account that haven't expired.
+func ListInvitations(accountID string) ([]*models.FamilyInvitation, error) {
+	var invitations []*models.FamilyInvitation
+	err := getInvitationTable().Get("accId", accountID).All(&invitations)
+	if err != nil && err != dynamo.ErrNotFound {
+		return nil, err
+	}
+	return filterExpiredInvitations(invitations), nil
+}
+
+func filterExpiredInvitations(invitations []*models.FamilyInvitation) []*models.FamilyInvitation {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	valid := make([]*models.FamilyInvitation, 0, len(invitations))
+	for _, invitation := range invitations {
+		if invitation.ExpirationDate > now {
+			valid = append(valid, invitation)
+		}
+	}
+	return valid
+}
+
+// DeleteInvitation deletes an invitation, which declines it.
+func DeleteInvitation(accountID string, familyID string) error {
+	return getInvitationTable().Delete("accId", accountID).Range("familyId", familyID).Run()
+}
+
+// AcceptInvitation adds an account to the family that invited it.
+// The membership, the member count and the invitation are written atomically, so the member limit is never exceeded.
+func AcceptInvitation(accountID string, familyID string) (*models.FamilyMember, error) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	member := &models.FamilyMember{
+		AccountID:   accountID,
+		FamilyID:    familyID,
+		Role:        models.FamilyRoleMember,
+		CreatedDate: now,
+	}
+
+	memberCountUpdate := getFamilyTable().Update("familyId", familyID).
+		Add("memberCount", 1).
+		If("attribute_exists($)", "familyId").
+		If("$ < ?", "memberCount", defs.MaxFamilyMembers)
+	invitationDelete := getInvitationTable().Delete("accId", accountID).Range("familyId", familyID).
+		If("$ > ?", "expirationTm", now)
+
+	err := models.GetDB().WriteTx().
+		Update(memberCountUpdate).
+		Put(getMemberTable().Put(member).If("attribute_not_exists($)", "accId")).
+		Delete(invitationDelete).
+		Run()
+	if isTransactionCanceled(err) {
+		return nil, getAcceptFailure(accountID, familyID, now, err)
+	} else if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// getAcceptFailure finds out which condition failed when accepting an invitation was canceled.
+// The cancellation error is returned if no condition failed, which means the write conflicted with another one.
+func getAcceptFailure(accountID string, familyID string, now int64, cancelErr error) error {
+	member, err := GetMembership(accountID)
+	if err != nil {
+		return err
+	} else if member != nil {
+		return ErrAlreadyInFamily
+	}
+
+	invitation := &models.FamilyInvitation{}
+	err = getInvitationTable().Get("accId", accountID).Range("familyId", dynamo.Equal, familyID).One(invitation)
+	if err == dynamo.ErrNotFound || (err == nil && invitation.ExpirationDate <= now) {
+		return ErrInvitationNotFound
+	} else if err != nil {
+		return err
+	}
+
+	family := &models.Family{}
+	err = getFamilyTable().Get("familyId", familyID).One(family)
+	if err == dynamo.ErrNotFound {
+		return ErrInvitationNotFound
+	} else if err != nil {
+		return err
+	} else if family.MemberCount >= defs.MaxFamilyMembers {
+		return ErrFamilyFull
+	}
+	return cancelErr
+}
+
+// RemoveMember removes a member from a family, which ends its access to the shared passes and products immediately.
+// The owner can't be removed, the family is deleted instead.
+func RemoveMember(familyID string, accountID string) error {
+	memberDelete := getMemberTable().Delete("accId", accountID).
+		If("$ = ?", "familyId", familyID).
+		If("$ = ?", "role", models.FamilyRoleMember)
+	memberCountUpdate := getFamilyTable().Update("familyId", familyID).
+		Add("memberCount", -1)
+
+	err := models.GetDB().WriteTx().
+		Delete(memberDelete).
+		Update(memberCountUpdate).
+		Run()
+	if isTransactionCanceled(err) {
+		return ErrMemberNotFound
+	}
+	return err
+}
+
+// DeleteFamily deletes a family with its members and invitations.
+// The members are removed first so they lose access to the shared items even if the deletion is interrupted.
+func DeleteFamily(familyID string) error {
+	members, err := getMembers(familyID)
+	if err != nil {
+		return err
+	}
+
+	if len(members) > 0 {
+		memberKeys := make([]dynamo.Keyed, len(members))
+		for i, member := range members {
+			memberKeys[i] = dynamo.Keys{member.AccountID}
+		}
+		_, err = getMemberTable().Batch("accId").Write().Delete(memberKeys...).Run()
+		if err != nil {
+			return err
+		}
+	}
+
+	var invitations []*models.FamilyInvitation
+	err = getInvitationTable().Get("familyId", familyID).Index(models.FAMILY_INVITATION_GSI_FAMILYID).All(&invitations)
+	if err != nil && err != dynamo.ErrNotFound {
+		return err
+	}
+	if len(invitations) > 0 {
+		invitationKeys := make([]dynamo.Keyed, len(invitations))
+		for i, invitation := range invitations {
+			invitationKeys[i] = dynamo.Keys{invitation.AccountID, invitation.FamilyID}
+		}
+		_, err = getInvitationTable().Batch("accId", "familyId").Write().Delete(invitationKeys...).Run()
+		if err != nil {
+			return err
+		}
+	}
+
+	return getFamilyTable().Delete("familyId", familyID).Run()
+}
+
+// RemoveAccount removes an account from its family and deletes the invitations it received.
+// The family of an owner is deleted.
+func RemoveAccount(accountID string) error {
+	member, err := GetMembership(accountID)
+	if err != nil {
+		return err
+	} else if member != nil && member.Role == models.FamilyRoleOwner {
+		err = DeleteFamily(member.FamilyID)
+	} else if member != nil {
+		err = RemoveMember(member.FamilyID, accountID)
+	}
+	if err != nil && err != ErrMemberNotFound {
+		return err
+	}
+
+	var invitations []*models.FamilyInvitation
+	err = getInvitationTable().Get("accId", accountID).All(&invitations)
+	if err != nil && err != dynamo.ErrNotFound {
+		return err
+	}
+
+	for _, invitation := range invitations {
+		err = DeleteInvitation(accountID, invitation.FamilyID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package kl15migrationservice
+
+import (
+	"encoding/json"
+	"os"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"github.com/guregu/dynamo"
+)
+
+// Checkpoint is the progress of a batch migration, saved after every page so an interrupted run can be resumed.
+type Checkpoint struct {
+	LastKey  dynamo.PagingKey `json:"lastKey,omitempty"`
+	Done     bool             `json:"done"`
+	Outcomes map[Outcome]int  `json:"outcomes"`
+	// FailedKeys are the keys of the records that failed, which the scan has moved past.
+	// They are only migrated again when the failed records are retried.
+	FailedKeys []string `json:"failedKeys,omitempty"`
+}
+
+// NewCheckpoint returns the checkpoint of a migration starting from the beginning.
+func NewCheckpoint() *Checkpoint {
+	return &Checkpoint{
+		Outcomes: map[Outcome]int{},
+	}
+}
+
+// Restart returns the checkpoint of a migration starting again from the beginning.
+// The failed records are kept, since they can still be retried even if the new scan doesn't reach them.
+func (checkpoint *Checkpoint) Restart() *Checkpoint {
+	restarted := NewCheckpoint()
+	restarted.FailedKeys = append([]string(nil), checkpoint.FailedKeys...)
+	return restarted
+}
+
+// LoadCheckpoint reads a checkpoint file, or returns a new checkpoint if the file doesn't exist.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return NewCheckpoint(), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	checkpoint := NewCheckpoint()
+	err = json.Unmarshal(data, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if checkpoint.Outcomes == nil {
+		checkpoint.Outcomes = map[Outcome]int{}
+	}
+	return checkpoint, nil
+}
+
+// Save writes the checkpoint file.
+func (checkpoint *Checkpoint) Save(path string) error {
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated checkpoint
+	tempPath := path + ".tmp"
+	err = os.WriteFile(tempPath, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tempPath, path)
+}
+
+// RecordOutcome counts the outcome of a record, removing it from the failed records if it was retried.
+func (checkpoint *Checkpoint) RecordOutcome(record *models.AccountMigrationKl1dot5, outcome Outcome) {
+	checkpoint.Outcomes[outcome]++
+
+	for i, key := range checkpoint.FailedKeys {
+		if key == record.Email {
+			checkpoint.FailedKeys = append(checkpoint.FailedKeys[:i], checkpoint.FailedKeys[i+1:]...)
+			break
+		}
+	}
+}
+
+// RecordFailure keeps the key of a failed record so it can be retried.
+func (checkpoint *Checkpoint) RecordFailure(record *models.AccountMigrationKl1dot5) {
+	for _, key := range checkpoint.FailedKeys {
+		if key == record.Email {
+			return
+		}
+	}
+	checkpoint.FailedKeys = append(checkpoint.FailedKeys, record.Email)
+}
@@ -0,0 +1,354 @@
+package kl15migrationservice
+
+import (
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/go-server-account/accountdatabase"
+	"bitbucket.org/calmisland/go-server-logs/logger"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/google/uuid"
+	"github.com/guregu/dynamo"
+)
+
+// DayLayout is the layout of the days used to partition the migration events.
+const DayLayout = "2006-01-02"
+
+// EventKind is the kind of a migration event.
+type EventKind string
+
+const (
+	// EventKindNewAccount means a new AMS account was created for the KL1.5 account.
+	EventKindNewAccount EventKind = "new_account"
+	// EventKindPasswordOverride means the legacy password was set on an existing AMS account.
+	EventKindPasswordOverride EventKind = "password_override"
+	// EventKindFailed means the migration attempt failed.
+	EventKindFailed EventKind = "failed"
+)
+
+// EventSource is where a migration event comes from.
+type EventSource string
+
+const (
+	// EventSourceSignIn is the migration request sent by the client on sign-in.
+	EventSourceSignIn EventSource = "signin"
+	// EventSourceBatch is the batch migration command.
+	EventSourceBatch EventSource = "batch"
+)
+
+// Failure reasons of the migration events.
+const (
+	FailureReasonAccountNotFound        = "account_not_found"
+	FailureReasonInvalidPassword        = "invalid_password"
+	FailureReasonEmailAlreadyUsed       = "email_already_used"
+	FailureReasonPhoneNumberAlreadyUsed = "phone_number_already_used"
+	FailureReasonInternalError          = "internal_error"
+)
+
+// MigrationStatusFailed is the migration status of the KL1.5 records whose last migration attempt failed.
+// Those records are still migrated by the next attempt, which sets them as done.
+const MigrationStatusFailed accountdatabase.AccountsKl1dot5MigrationStatus = "failed"
+
+// unknownAccountEventRetention is how long the events of unknown login IDs are kept.
+// Those login IDs were typed by anyone, so they are not recorded and the events are only kept for a limited time.
+const unknownAccountEventRetention = 30 * 24 * time.Hour
+
+// maxStatusUpdateAttempts is how many times the migration status of a record is set while it's changed concurrently.
+const maxStatusUpdateAttempts = 3
+
+// IsMigrationFailure checks if a failure reason means the migration of an existing record failed,
+// as opposed to a rejected sign-in attempt.
+func IsMigrationFailure(reason string) bool {
+	return reason != FailureReasonAccountNotFound && reason != FailureReasonInvalidPassword
+}
+
+// Event is a migration event to record.
+type Event struct {
+	Kind        EventKind
+	Reason      string
+	Source      EventSource
+	Email       string
+	PhoneNumber string
+	AccountID   string
+}
+
+// RecordEvent saves a migration event.
+// Failing to record an event never fails the migration itself, so errors are only logged.
+func RecordEvent(event *Event) {
+	eventUUID, err := uuid.NewRandom()
+	if err != nil {
+		logger.LogFormat("[KL1.5-MIGRATION] Failed to generate an event ID: %v\n", err)
+		return
+	}
+
+	now := time.Now().UTC()
+	email, phoneNumber := event.Email, event.PhoneNumber
+	var expirationTime int64
+	if event.Reason == FailureReasonAccountNotFound {
+		email, phoneNumber = "", ""
+		expirationTime = now.Add(unknownAccountEventRetention).Unix()
+	}
+
+	item := &models.AccountMigrationKl1dot5Event{
+		Day: now.Format(DayLayout),
+		// The timestamp prefix keeps the events of a day in chronological order
+		EventID:        now.Format("150405.000") + "-" + eventUUID.String(),
+		Kind:           string(event.Kind),
+		Reason:         event.Reason,
+		Source:         string(event.Source),
+		Email:          email,
+		PhoneNumber:    phoneNumber,
+		AccountID:      event.AccountID,
+		CreatedDate:    now.UnixNano() / int64(time.Millisecond),
+		ExpirationTime: expirationTime,
+	}
+
+	table := models.GetDB().Table(models.GetTableName(models.TABLE_NAME_ACCOUNTS_MIGRATION_KL1DOT5_EVENTS))
+	err = table.Put(item).Run()
+	if err != nil {
+		logger.LogFormat("[KL1.5-MIGRATION] Failed to record a [%s] event for [%s%s]: %v\n", event.Kind, email, phoneNumber, err)
+	}
+}
+
+// RecordFailure saves the failed migration attempt of a KL1.5 record.
+// The record is set as failed unless the attempt was only rejected, so the report counts it until it's migrated.
+func RecordFailure(record *models.AccountMigrationKl1dot5, reason string, source EventSource) {
+	RecordEvent(&Event{
+		Kind:        EventKindFailed,
+		Reason:      reason,
+		Source:      source,
+		Email:       record.Email,
+		PhoneNumber: record.PhoneNumber,
+	})
+
+	if !IsMigrationFailure(reason) || record.MigrationStatus == accountdatabase.AccountsKl1dot5MigrationStatusDone {
+		return
+	}
+
+	err := SetRecordMigrationStatus(record, MigrationStatusFailed)
+	if err != nil {
+		logger.LogFormat("[KL1.5-MIGRATION] Failed to set [%s%s] as failed: %v\n", record.Email, record.PhoneNumber, err)
+	}
+}
+
+func getEventsTable() dynamo.Table {
+	return models.GetDB().Table(models.GetTableName(models.TABLE_NAME_ACCOUNTS_MIGRATION_KL1DOT5_EVENTS))
+}
+
+func isTransactionCanceled(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	return ok && awsErr.Code() == dynamodb.ErrCodeTransactionCanceledException
+}
+
+// newRecordStatusUpdate returns the update of the migration status of a KL1.5 record, which fails if the record
+// doesn't have the previous status anymore.
+func newRecordStatusUpdate(key string, previousStatus, status accountdatabase.AccountsKl1dot5MigrationStatus) *dynamo.Update {
+	// Phone-only records are keyed by their legacy login ID as well, so the hash key always works
+	update := models.GetDB().Table(models.GetTableName(models.TABLE_NAME_ACCOUNTS_MIGRATION_KL1DOT5)).
+		Update("email", key).
+		Set("migrationStatus", status)
+
+	countName := getStatusCountName(previousStatus)
+	if len(countName) > 0 {
+		return update.If("$ = ?", "migrationStatus", previousStatus)
+	}
+	return update.If("attribute_exists($) AND (attribute_not_exists($) OR ($ <> ? AND $ <> ?))",
+		"email", "migrationStatus", "migrationStatus", accountdatabase.AccountsKl1dot5MigrationStatusDone, "migrationStatus", MigrationStatusFailed)
+}
+
+// newStatusCountsUpdate returns the update of the status counts when a record changes status,
+// or nil if both statuses are pending.
+func newStatusCountsUpdate(previousStatus, status accountdatabase.AccountsKl1dot5MigrationStatus) *dynamo.Update {
+	previousName, name := getStatusCountName(previousStatus), getStatusCountName(status)
+	if previousName == name {
+		return nil
+	}
+
+	update := getEventsTable().
+		Update("day", models.ACCOUNTS_MIGRATION_KL1DOT5_STATUS_COUNTS_DAY).
+		Range("eventId", models.ACCOUNTS_MIGRATION_KL1DOT5_STATUS_COUNTS_ID)
+	if len(previousName) > 0 {
+		update = update.Add(previousName, -1)
+	}
+	if len(name) > 0 {
+		update = update.Add(name, 1)
+	}
+	return update
+}
+
+// getStatusCountName returns the status count of a migration status, empty for the pending records which are not counted.
+func getStatusCountName(status accountdatabase.AccountsKl1dot5MigrationStatus) string {
+	switch status {
+	case accountdatabase.AccountsKl1dot5MigrationStatusDone:
+		return "done"
+	case MigrationStatusFailed:
+		return "failed"
+	default:
+		return ""
+	}
+}
+
+// GetStatusCounts returns the running counts of the KL1.5 records done and failed.
+func GetStatusCounts() (*models.AccountMigrationKl1dot5StatusCounts, error) {
+	counts := &models.AccountMigrationKl1dot5StatusCounts{}
+	err := getEventsTable().
+		Get("day", models.ACCOUNTS_MIGRATION_KL1DOT5_STATUS_COUNTS_DAY).
+		Range("eventId", dynamo.Equal, models.ACCOUNTS_MIGRATION_KL1DOT5_STATUS_COUNTS_ID).
+		One(counts)
+	if err != nil && err != dynamo.ErrNotFound {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// RecountStatuses scans the KL1.5 records to reset the running status counts, returning them.
+// It only needs to run once, before the records are migrated or when the counts are missing, since it's not atomic
+// with the migrations running meanwhile.
+func RecountStatuses() (*models.AccountMigrationKl1dot5StatusCounts, error) {
+	counts := &models.AccountMigrationKl1dot5StatusCounts{
+		Day:     models.ACCOUNTS_MIGRATION_KL1DOT5_STATUS_COUNTS_DAY,
+		EventID: models.ACCOUNTS_MIGRATION_KL1DOT5_STATUS_COUNTS_ID,
+	}
+
+	recordsTable := models.GetDB().Table(models.GetTableName(models.TABLE_NAME_ACCOUNTS_MIGRATION_KL1DOT5))
+	iter := recordsTable.Scan().Project("email", "migrationStatus").Iter()
+	var record models.AccountMigrationKl1dot5
+	for iter.Next(&record) {
+		switch record.MigrationStatus {
+		case accountdatabase.AccountsKl1dot5MigrationStatusDone:
+			counts.Done++
+		case MigrationStatusFailed:
+			counts.Failed++
+		}
+		record = models.AccountMigrationKl1dot5{}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	err := getEventsTable().Put(counts).Run()
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// StatusCounts are the number of KL1.5 records by migration status.
+// The total and pending counts are approximate, since DynamoDB only updates the item count of a table every six hours.
+type StatusCounts struct {
+	Total   int `json:"total"`
+	Pending int `json:"pending"`
+	Done    int `json:"done"`
+	Failed  int `json:"failed"`
+}
+
+// DayReport are the migration events of a single day.
+type DayReport struct {
+	Day               string         `json:"day"`
+	NewAccounts       int            `json:"newAccounts"`
+	PasswordOverrides int            `json:"passwordOverrides"`
+	Failures          int            `json:"failures"`
+	FailureReasons    map[string]int `json:"failureReasons"`
+	// RejectedAttempts are the sign-in attempts with an unknown login ID or an invalid password.
+	RejectedAttempts int            `json:"rejectedAttempts"`
+	RejectionReasons map[string]int `json:"rejectionReasons"`
+}
+
+// Report is the progress of the KL1.5 migration.
+type Report struct {
+	From     string       `json:"from"`
+	To       string       `json:"to"`
+	Statuses StatusCounts `json:"statuses"`
+	Days     []*DayReport `json:"days"`
+}
+
+// BuildReport summarizes the migration events between two days, both included, with the KL1.5 records by migration status.
+func BuildReport(from, to time.Time) (*Report, error) {
+	report := &Report{
+		From: from.Format(DayLayout),
+		To:   to.Format(DayLayout),
+	}
+
+	eventsTable := getEventsTable()
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		dayReport := &DayReport{
+			Day:              day.Format(DayLayout),
+			FailureReasons:   map[string]int{},
+			RejectionReasons: map[string]int{},
+		}
+
+		var events []*models.AccountMigrationKl1dot5Event
+		err := eventsTable.Get("day", dayReport.Day).All(&events)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, event := range events {
+			switch EventKind(event.Kind) {
+			case EventKindNewAccount:
+				dayReport.NewAccounts++
+			case EventKindPasswordOverride:
+				dayReport.PasswordOverrides++
+			case EventKindFailed:
+				if IsMigrationFailure(event.Reason) {
+					dayReport.Failures++
+					dayReport.FailureReasons[event.Reason]++
+				} else {
+					dayReport.RejectedAttempts++
+					dayReport.RejectionReasons[event.Reason]++
+				}
+			}
+		}
+
+		report.Days = append(report.Days, dayReport)
+	}
+
+	counts, err := GetStatusCounts()
+	if err != nil {
+		return nil, err
+	}
+
+	recordsTable := models.GetDB().Table(models.GetTableName(models.TABLE_NAME_ACCOUNTS_MIGRATION_KL1DOT5))
+	description, err := recordsTable.Describe().Run()
+	if err != nil {
+		return nil, err
+	}
+
+	report.Statuses = StatusCounts{
+		Total:  int(description.Items),
+		Done:   counts.Done,
+		Failed: counts.Failed,
+	}
+	if pending := report.Statuses.Total - counts.Done - counts.Failed; pending > 0 {
+		report.Statuses.Pending = pending
+	}
+
+	return report, nil
+}
+
+const (
+	// DefaultReportDays is the number of days reported when no range is given.
+	DefaultReportDays = 30
+	// MaxReportDays is the maximum number of days in a single report.
+	MaxReportDays = 92
+)
+
+// ParseReportDay parses a report day, returning the fallback if it's empty.
+func ParseReportDay(value string, fallback time.Time) (time.Time, error) {
+	if len(value) == 0 {
+		return fallback, nil
+	}
+	return time.Parse(DayLayout, value)
+}
+
+// DefaultReportRange returns the range of the last DefaultReportDays days, today included.
+func DefaultReportRange() (time.Time, time.Time) {
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	return to.AddDate(0, 0, -(DefaultReportDays - 1)), to
+}
+
+// IsValidReportRange checks if a report range is ordered and not longer than MaxReportDays.
+func IsValidReportRange(from, to time.Time) bool {
+	return !from.After(to) && !from.AddDate(0, 0, MaxReportDays).Before(to.AddDate(0, 0, 1))
+}
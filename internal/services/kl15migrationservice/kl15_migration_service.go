@@ -0,0 +1,253 @@
+package kl15migrationservice
+
+import (
+	"strings"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/go-server-account/accountdatabase"
+	"bitbucket.org/calmisland/go-server-account/accounts"
+	"bitbucket.org/calmisland/go-server-logs/logger"
+	"bitbucket.org/calmisland/go-server-security/securitycodes"
+	"bitbucket.org/calmisland/go-server-utils/emailutils"
+	"bitbucket.org/calmisland/go-server-utils/phoneutils"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/calmisland/go-errors"
+	"github.com/google/uuid"
+	"github.com/guregu/dynamo"
+)
+
+const placeholderPasswordLength = 32
+
+// ErrPhoneNumberIndexMissing is returned when the KL1.5 table doesn't have the index the phone-only records are looked up with.
+var ErrPhoneNumberIndexMissing = errors.New("The KL1.5 migration table doesn't have the phoneNr index")
+
+// Outcome is the outcome of pre-creating the AMS account of a KL1.5 record.
+type Outcome string
+
+const (
+	// OutcomeCreated means the AMS account was created.
+	OutcomeCreated Outcome = "created"
+	// OutcomeAlreadyMigrated means the record was migrated in the meantime.
+	OutcomeAlreadyMigrated Outcome = "alreadyMigrated"
+	// OutcomeExistingAccount means an AMS account already uses the email address or phone number.
+	// Those are left pending so the password override happens when the user signs in.
+	OutcomeExistingAccount Outcome = "existingAccount"
+	// OutcomeInvalid means the record cannot be migrated.
+	OutcomeInvalid Outcome = "invalid"
+)
+
+// ScanPendingRecords returns a page of KL1.5 records that have not been migrated yet.
+// The returned paging key is nil once the last page has been read.
+func ScanPendingRecords(startFrom dynamo.PagingKey, pageSize int64) ([]*models.AccountMigrationKl1dot5, dynamo.PagingKey, error) {
+	table := models.GetDB().Table(models.GetTableName(models.TABLE_NAME_ACCOUNTS_MIGRATION_KL1DOT5))
+
+	scan := table.Scan().
+		Filter("attribute_not_exists($) OR $ <> ?", "migrationStatus", "migrationStatus", accountdatabase.AccountsKl1dot5MigrationStatusDone).
+		SearchLimit(pageSize)
+	if startFrom != nil {
+		scan = scan.StartFrom(startFrom)
+	}
+
+	var records []*models.AccountMigrationKl1dot5
+	lastKey, err := scan.AllWithLastEvaluatedKey(&records)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return records, lastKey, nil
+}
+
+// GetRecordByKey returns a KL1.5 record from its hash key, which is the legacy login ID of the phone-only records.
+func GetRecordByKey(key string) (*models.AccountMigrationKl1dot5, bool, error) {
+	table := models.GetDB().Table(models.GetTableName(models.TABLE_NAME_ACCOUNTS_MIGRATION_KL1DOT5))
+
+	record := &models.AccountMigrationKl1dot5{}
+	err := table.Get("email", key).One(record)
+	if err == dynamo.ErrNotFound {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return record, true, nil
+}
+
+// GetRecord returns the KL1.5 record of an email address or, if the email is empty, of a phone number.
+func GetRecord(email, phoneNumber string) (*models.AccountMigrationKl1dot5, bool, error) {
+	if len(email) > 0 {
+		kl15AccInfo, found, err := globals.AccountDatabase.GetAccountsMigrationKl1dot5InfoFromEmail(email)
+		if err != nil || !found {
+			return nil, found, err
+		}
+
+		return &models.AccountMigrationKl1dot5{
+			Email:           email,
+			PwHash:          kl15AccInfo.PwHash,
+			PwHashSecret:    kl15AccInfo.PwHashSecret,
+			MigrationStatus: kl15AccInfo.MigrationStatus,
+		}, true, nil
+	}
+
+	return GetRecordFromPhoneNumber(phoneNumber)
+}
+
+// GetRecordFromPhoneNumber returns the KL1.5 record of a phone number.
+func GetRecordFromPhoneNumber(phoneNumber string) (*models.AccountMigrationKl1dot5, bool, error) {
+	if len(phoneNumber) == 0 {
+		return nil, false, nil
+	}
+
+	table := models.GetDB().Table(models.GetTableName(models.TABLE_NAME_ACCOUNTS_MIGRATION_KL1DOT5))
+
+	var records []*models.AccountMigrationKl1dot5
+	err := table.Get("phoneNr", phoneNumber).Index(models.ACCOUNTS_MIGRATION_KL1DOT5_GSI_PHONENR).All(&records)
+	if isIndexMissing(err) {
+		logger.LogFormat("[KL1.5-MIGRATION] The phone number index is missing from the KL1.5 table: %s\n", err)
+		return nil, false, ErrPhoneNumberIndexMissing
+	} else if err != nil {
+		return nil, false, err
+	} else if len(records) == 0 {
+		return nil, false, nil
+	}
+
+	// Prefer a record that still has to be migrated if a phone number was used more than once
+	for _, record := range records {
+		if record.MigrationStatus != accountdatabase.AccountsKl1dot5MigrationStatusDone {
+			return record, true, nil
+		}
+	}
+	return records[0], true, nil
+}
+
+// isIndexMissing checks if a query failed because the table doesn't have the queried index.
+func isIndexMissing(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	return ok && awsErr.Code() == "ValidationException" && strings.Contains(awsErr.Message(), "specified index")
+}
+
+// SetRecordMigrationStatus sets the migration status of a KL1.5 record and updates the status counts with it.
+// The record is only updated if its status is still the one it was read with, and it's read again otherwise.
+func SetRecordMigrationStatus(record *models.AccountMigrationKl1dot5, status accountdatabase.AccountsKl1dot5MigrationStatus) error {
+	previousStatus := record.MigrationStatus
+	for attempt := 1; ; attempt++ {
+		if previousStatus == status {
+			return nil
+		}
+
+		tx := models.GetDB().WriteTx().Update(newRecordStatusUpdate(record.Email, previousStatus, status))
+		if countsUpdate := newStatusCountsUpdate(previousStatus, status); countsUpdate != nil {
+			tx = tx.Update(countsUpdate)
+		}
+		err := tx.Run()
+		if !isTransactionCanceled(err) || attempt == maxStatusUpdateAttempts {
+			return err
+		}
+
+		// The status was changed in the meantime
+		current, found, err := GetRecordByKey(record.Email)
+		if err != nil {
+			return err
+		} else if !found {
+			return nil
+		}
+		previousStatus = current.MigrationStatus
+	}
+}
+
+// PrecreateAccount creates the AMS account of a pending KL1.5 record and marks it as migrated.
+// The SHA3 legacy hash cannot be carried over to the AMS password hash, so the account is created
+// with the MustSetPasswordFlag until the user either signs in with the legacy password or restores it.
+func PrecreateAccount(record *models.AccountMigrationKl1dot5, dryRun bool) (Outcome, error) {
+	if record.MigrationStatus == accountdatabase.AccountsKl1dot5MigrationStatusDone {
+		return OutcomeAlreadyMigrated, nil
+	}
+
+	var userEmail, userPhoneNumber string
+	var flags int32
+	var accountExists bool
+	var err error
+	if emailutils.IsValidEmailAddressFormat(record.Email) {
+		userEmail = record.Email
+		flags = int32(accounts.IsAccountVerifiedFlag | accounts.IsAccountEmailVerifiedFlag | accounts.MustSetPasswordFlag)
+		accountExists, err = globals.AccountDatabase.AccountExistsWithEmail(userEmail)
+	} else if len(record.PhoneNumber) > 0 {
+		userPhoneNumber, err = phoneutils.CleanPhoneNumber(record.PhoneNumber)
+		if err != nil || !phoneutils.IsValidPhoneNumber(userPhoneNumber) {
+			return OutcomeInvalid, nil
+		}
+
+		flags = int32(accounts.IsAccountVerifiedFlag | accounts.IsAccountPhoneNumberVerifiedFlag | accounts.MustSetPasswordFlag)
+		accountExists, err = globals.AccountDatabase.AccountExistsWithPhoneNumber(userPhoneNumber)
+	} else {
+		return OutcomeInvalid, nil
+	}
+
+	if err != nil {
+		return "", err
+	} else if accountExists {
+		return OutcomeExistingAccount, nil
+	}
+
+	if dryRun {
+		return OutcomeCreated, nil
+	}
+
+	placeholderPassword, err := securitycodes.GenerateSecurityCode(placeholderPasswordLength)
+	if err != nil {
+		return "", err
+	}
+
+	hashedPassword, err := globals.PasswordHasher.GeneratePasswordHash(placeholderPassword, false)
+	if err != nil {
+		return "", err
+	}
+
+	accountUUID, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+
+	accountID := accountUUID.String()
+	err = globals.AccountDatabase.CreateAccount(&accountdatabase.CreateAccountInfo{
+		ID:           accountID,
+		Email:        userEmail,
+		PhoneNumber:  userPhoneNumber,
+		PasswordHash: hashedPassword,
+		Flags:        flags,
+		Country:      defs.DefaultCountryCode,
+		Language:     defs.DefaultLanguageCode,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	err = SetRecordMigrationStatus(record, accountdatabase.AccountsKl1dot5MigrationStatusDone)
+	if err != nil {
+		return "", err
+	}
+
+	RecordEvent(&Event{
+		Kind:        EventKindNewAccount,
+		Source:      EventSourceBatch,
+		Email:       record.Email,
+		PhoneNumber: record.PhoneNumber,
+		AccountID:   accountID,
+	})
+
+	logger.LogFormat("[KL1.5-MIGRATION] Pre-created account [%s] for [%s%s]\n", accountID, userEmail, userPhoneNumber)
+	return OutcomeCreated, nil
+}
+
+// IsAccountWaitingForLegacyPassword checks if a migrated account was pre-created by the batch migration
+// and has not set a password yet, in which case the legacy password can still be used once.
+func IsAccountWaitingForLegacyPassword(accountID string) (bool, error) {
+	accInfo, err := globals.AccountDatabase.GetAccountSignInInfoByID(accountID)
+	if err != nil {
+		return false, err
+	} else if accInfo == nil {
+		return false, nil
+	}
+
+	return accounts.AccountMustSetPassword(accInfo.Flags), nil
+}
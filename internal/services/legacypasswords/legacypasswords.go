@@ -0,0 +1,71 @@
+package legacypasswords
+
+import (
+	"sync"
+
+	"github.com/calmisland/go-errors"
+)
+
+var (
+	// ErrUnknownAlgorithm is returned when no verifier is registered for the algorithm of a hash.
+	ErrUnknownAlgorithm = errors.New("Unknown legacy password hash algorithm")
+	// ErrInvalidHash is returned when a hash is malformed for its algorithm.
+	ErrInvalidHash = errors.New("Invalid legacy password hash")
+)
+
+// Hash is a password hash imported from another product.
+type Hash struct {
+	// Algorithm is the tag of the verifier that checks this hash.
+	Algorithm string
+	Hash      string
+	Salt      string
+	// Iterations is only used by the iterated algorithms.
+	Iterations int
+}
+
+// Verifier verifies passwords against the hashes of a legacy algorithm.
+type Verifier interface {
+	// Verify checks if the password matches the hash.
+	// An error is only returned if the hash itself is malformed.
+	Verify(password string, hash *Hash) (bool, error)
+}
+
+// VerifierFunc is a function used as a Verifier.
+type VerifierFunc func(password string, hash *Hash) (bool, error)
+
+// Verify calls the function.
+func (f VerifierFunc) Verify(password string, hash *Hash) (bool, error) {
+	return f(password, hash)
+}
+
+var (
+	verifiersMutex sync.RWMutex
+	verifiers      = map[string]Verifier{}
+)
+
+// Register registers the verifier of an algorithm, replacing any existing one.
+func Register(algorithm string, verifier Verifier) {
+	verifiersMutex.Lock()
+	defer verifiersMutex.Unlock()
+	verifiers[algorithm] = verifier
+}
+
+// IsRegistered checks if a verifier is registered for an algorithm.
+func IsRegistered(algorithm string) bool {
+	verifiersMutex.RLock()
+	defer verifiersMutex.RUnlock()
+	_, exists := verifiers[algorithm]
+	return exists
+}
+
+// Verify checks a password with the verifier registered for the algorithm of the hash.
+func Verify(password string, hash *Hash) (bool, error) {
+	verifiersMutex.RLock()
+	verifier, exists := verifiers[hash.Algorithm]
+	verifiersMutex.RUnlock()
+	if !exists {
+		return false, ErrUnknownAlgorithm
+	}
+
+	return verifier.Verify(password, hash)
+}
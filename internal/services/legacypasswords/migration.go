@@ -0,0 +1,100 @@
+package legacypasswords
+
+import (
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/go-server-account/accountdatabase"
+	"bitbucket.org/calmisland/go-server-account/accounts"
+	"bitbucket.org/calmisland/go-server-logs/logger"
+)
+
+// Save keeps the legacy hash of an imported account until its first use.
+// The source is the product the account was imported from.
+func Save(store Store, accountID string, hash *Hash, source string) error {
+	if !IsRegistered(hash.Algorithm) {
+		return ErrUnknownAlgorithm
+	}
+
+	return store.PutHash(&models.AccountLegacyPassword{
+		AccountID:   accountID,
+		Algorithm:   hash.Algorithm,
+		Hash:        hash.Hash,
+		Salt:        hash.Salt,
+		Iterations:  hash.Iterations,
+		Source:      source,
+		CreatedDate: time.Now().UnixNano() / int64(time.Millisecond),
+	})
+}
+
+// Get returns the legacy hash of an account.
+func Get(store Store, accountID string) (*Hash, bool, error) {
+	item, err := store.GetHash(accountID)
+	if err != nil || item == nil {
+		return nil, false, err
+	}
+
+	return &Hash{
+		Algorithm:  item.Algorithm,
+		Hash:       item.Hash,
+		Salt:       item.Salt,
+		Iterations: item.Iterations,
+	}, true, nil
+}
+
+// MigratePassword verifies a password against the legacy hash of an account and, if it matches,
+// rehashes it into the native format. The legacy hash is deleted afterwards so it's only used once.
+// The email address or phone number used to sign in is verified, as the only contact the account was imported with.
+// It returns false if the account has no legacy hash or the password doesn't match.
+func MigratePassword(store Store, accountID string, password string, isUsingEmail bool) (bool, error) {
+	hash, found, err := Get(store, accountID)
+	if err != nil || !found {
+		return false, err
+	}
+
+	isPassed, err := Verify(password, hash)
+	if err != nil || !isPassed {
+		return false, err
+	}
+
+	hashedPassword, err := globals.PasswordHasher.GeneratePasswordHash(password, false)
+	if err != nil {
+		return false, err
+	}
+
+	err = globals.AccountDatabase.EditAccount(accountID, &accountdatabase.AccountEditInfo{
+		PasswordHash: &hashedPassword,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	// Imported accounts are created unverified with a placeholder password that must be set
+	err = globals.AccountDatabase.RemoveAccountFlags(accountID, accounts.MustSetPasswordFlag)
+	if err != nil {
+		return false, err
+	}
+
+	verifiedFlags := int32(accounts.IsAccountVerifiedFlag | accounts.IsAccountPhoneNumberVerifiedFlag)
+	if isUsingEmail {
+		verifiedFlags = int32(accounts.IsAccountVerifiedFlag | accounts.IsAccountEmailVerifiedFlag)
+	}
+	err = globals.AccountDatabase.SetAccountFlags(accountID, verifiedFlags)
+	if err != nil {
+		return false, err
+	}
+
+	err = store.DeleteHash(accountID)
+	if err != nil {
+		return false, err
+	}
+
+	logger.LogFormat("[LEGACYPASSWORD] Rehashed the [%s] password of account [%s]\n", hash.Algorithm, accountID)
+	return true, nil
+}
+
+// Delete deletes the legacy hash of an account, if it was never used.
+func Delete(store Store, accountID string) error {
+	return store.DeleteHash(accountID)
+}
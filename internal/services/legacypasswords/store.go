@@ -0,0 +1,46 @@
+package legacypasswords
+
+import (
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"github.com/guregu/dynamo"
+)
+
+// Store reads and writes the legacy password hashes of the imported accounts.
+type Store interface {
+	// PutHash writes the legacy hash of an account.
+	PutHash(hash *models.AccountLegacyPassword) error
+	// GetHash returns the legacy hash of an account, nil if it doesn't exist.
+	GetHash(accountID string) (*models.AccountLegacyPassword, error)
+	// DeleteHash deletes the legacy hash of an account.
+	DeleteHash(accountID string) error
+}
+
+type standardStore struct{}
+
+// NewStore creates the store of the legacy passwords table.
+func NewStore() Store {
+	return &standardStore{}
+}
+
+func getTable() dynamo.Table {
+	return models.GetDB().Table(models.GetTableName(models.TABLE_NAME_ACCOUNT_LEGACY_PASSWORDS))
+}
+
+func (store *standardStore) PutHash(hash *models.AccountLegacyPassword) error {
+	return getTable().Put(hash).Run()
+}
+
+func (store *standardStore) GetHash(accountID string) (*models.AccountLegacyPassword, error) {
+	hash := &models.AccountLegacyPassword{}
+	err := getTable().Get("accId", accountID).One(hash)
+	if err == dynamo.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return hash, nil
+}
+
+func (store *standardStore) DeleteHash(accountID string) error {
+	return getTable().Delete("accId", accountID).Run()
+}
@@ -0,0 +1,72 @@
+package legacypasswords
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"strings"
+
+	"bitbucket.org/calmisland/go-server-security/passwords"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// The algorithms supported out of the box.
+const (
+	// AlgorithmSha3_512 is the KL1.5 hash, with the secret as salt.
+	AlgorithmSha3_512 = "sha3_512"
+	// AlgorithmPbkdf2Sha1 is PBKDF2 with HMAC-SHA1, the hash is base64 encoded.
+	AlgorithmPbkdf2Sha1 = "pbkdf2_sha1"
+	// AlgorithmPbkdf2Sha256 is PBKDF2 with HMAC-SHA256, the hash is base64 encoded.
+	AlgorithmPbkdf2Sha256 = "pbkdf2_sha256"
+	// AlgorithmPbkdf2Sha512 is PBKDF2 with HMAC-SHA512, the hash is base64 encoded.
+	AlgorithmPbkdf2Sha512 = "pbkdf2_sha512"
+	// AlgorithmSaltedMd5 is the MD5 of the salt followed by the password, the hash is hex encoded.
+	AlgorithmSaltedMd5 = "salted_md5"
+)
+
+func init() {
+	Register(AlgorithmSha3_512, VerifierFunc(verifySha3_512))
+	Register(AlgorithmPbkdf2Sha1, pbkdf2Verifier(sha1.New))
+	Register(AlgorithmPbkdf2Sha256, pbkdf2Verifier(sha256.New))
+	Register(AlgorithmPbkdf2Sha512, pbkdf2Verifier(sha512.New))
+	Register(AlgorithmSaltedMd5, VerifierFunc(verifySaltedMd5))
+}
+
+func verifySha3_512(password string, hash *Hash) (bool, error) {
+	return passwords.ValidateSha3_512_Password(password, &passwords.Sha3_512_Hash{
+		Hash:   hash.Hash,
+		Secret: hash.Salt,
+	}), nil
+}
+
+func pbkdf2Verifier(digest func() hash.Hash) Verifier {
+	return VerifierFunc(func(password string, hash *Hash) (bool, error) {
+		if hash.Iterations <= 0 {
+			return false, ErrInvalidHash
+		}
+
+		// Some products strip the base64 padding
+		expected, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(hash.Hash, "="))
+		if err != nil || len(expected) == 0 {
+			return false, ErrInvalidHash
+		}
+
+		actual := pbkdf2.Key([]byte(password), []byte(hash.Salt), hash.Iterations, len(expected), digest)
+		return subtle.ConstantTimeCompare(actual, expected) == 1, nil
+	})
+}
+
+func verifySaltedMd5(password string, hash *Hash) (bool, error) {
+	expected, err := hex.DecodeString(hash.Hash)
+	if err != nil || len(expected) != md5.Size {
+		return false, ErrInvalidHash
+	}
+
+	actual := md5.Sum([]byte(hash.Salt + password))
+	return subtle.ConstantTimeCompare(actual[:], expected) == 1, nil
+}
@@ -0,0 +1,80 @@
+package localizednames
+
+import (
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// ParseTag parses the language tag of a name variant, such as "ko" or "ja-Latn", and returns its canonical form.
+func ParseTag(tag string) (string, error) {
+	parsedTag, err := language.Parse(tag)
+	if err != nil {
+		return "", err
+	}
+	return parsedTag.String(), nil
+}
+
+// ParsePreferences returns the languages preferred by a caller, from a language code such as the lang query parameter
+// or else from the Accept-Language header.
+func ParsePreferences(languageCode string, acceptLanguage string) []language.Tag {
+	if len(languageCode) > 0 {
+		if tag, err := language.Parse(toBCP47(languageCode)); err == nil {
+			return []language.Tag{tag}
+		}
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil {
+		return nil
+	}
+	return tags
+}
+
+// Select returns the tag of the name variant that best matches the preferred languages,
+// or an empty tag if the default names of the account, in its own language, match best.
+// When no variant is in a preferred language, a variant written in the script of the first preferred language
+// is picked, so romanized names are shown to readers of languages written in Latin.
+func Select(defaultLanguageCode string, variantTags []string, preferred []language.Tag) string {
+	if len(variantTags) == 0 || len(preferred) == 0 {
+		return ""
+	}
+
+	// Sorts the variants so the selection doesn't depend on the map order
+	tags := append([]string{}, variantTags...)
+	sort.Strings(tags)
+
+	defaultTag, err := language.Parse(toBCP47(defaultLanguageCode))
+	if err != nil {
+		defaultTag = language.Und
+	}
+
+	supported := []language.Tag{defaultTag}
+	supportedVariantTags := []string{""}
+	for _, tag := range tags {
+		if parsedTag, err := language.Parse(tag); err == nil {
+			supported = append(supported, parsedTag)
+			supportedVariantTags = append(supportedVariantTags, tag)
+		}
+	}
+
+	matcher := language.NewMatcher(supported)
+	_, index, confidence := matcher.Match(preferred...)
+	if confidence != language.No {
+		return supportedVariantTags[index]
+	}
+
+	preferredScript, _ := preferred[0].Script()
+	for i, tag := range supported[1:] {
+		if script, confidence := tag.Script(); confidence == language.Exact && script == preferredScript {
+			return supportedVariantTags[i+1]
+		}
+	}
+	return ""
+}
+
+// toBCP47 converts the language codes of the accounts, such as "en_US", to BCP 47 tags.
+func toBCP47(languageCode string) string {
+	return strings.Replace(languageCode, "_", "-", -1)
+}
@@ -0,0 +1,106 @@
+package namefilter
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+)
+
+// The match modes of the blocked words.
+const (
+	// MatchWord blocks the names containing the word as a whole word.
+	MatchWord = "word"
+	// MatchSubstring blocks the names containing the word anywhere, which is needed for the languages written without spaces.
+	MatchSubstring = "substring"
+)
+
+// IsValidMatch checks if a match mode exists.
+func IsValidMatch(match string) bool {
+	return match == MatchWord || match == MatchSubstring
+}
+
+// Filter checks the names against the blocked words.
+type Filter interface {
+	// IsBlocked checks if a name contains a blocked word.
+	IsBlocked(name string) (bool, error)
+	// Invalidate drops the cached word lists so the next check uses the latest ones.
+	Invalidate()
+}
+
+// blockList is a compiled list of blocked words.
+type blockList struct {
+	// words maps the collapsed blocked words to the length of the shortest original word,
+	// so the collapsing doesn't block shorter innocent words
+	words      map[string]int
+	substrings []string
+}
+
+func newBlockList(words []*models.NameFilterWord) *blockList {
+	list := &blockList{
+		words: map[string]int{},
+	}
+
+	for _, word := range words {
+		normalized := strings.Join(tokenize(normalizeText(word.Word, false)), "")
+		if len(normalized) == 0 {
+			continue
+		}
+
+		collapsed := collapseRepeats(normalized)
+		if word.Match == MatchSubstring {
+			list.substrings = append(list.substrings, collapsed)
+			continue
+		}
+
+		length := utf8.RuneCountInString(normalized)
+		if minLength, exists := list.words[collapsed]; !exists || length < minLength {
+			list.words[collapsed] = length
+		}
+	}
+	return list
+}
+
+// isBlocked checks a name with the trailing leetspeak symbols both kept as is and mapped to letters.
+func (list *blockList) isBlocked(name string) bool {
+	return list.containsBlockedWord(tokenize(normalizeText(name, false))) ||
+		list.containsBlockedWord(tokenize(normalizeText(name, true)))
+}
+
+func (list *blockList) containsBlockedWord(tokens []string) bool {
+	for _, token := range tokens {
+		minLength, exists := list.words[collapseRepeats(token)]
+		if exists && utf8.RuneCountInString(token) >= minLength {
+			return true
+		}
+	}
+
+	if len(list.substrings) > 0 {
+		joined := collapseRepeats(strings.Join(tokens, ""))
+		for _, substring := range list.substrings {
+			if strings.Contains(joined, substring) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type staticFilter struct {
+	list *blockList
+}
+
+// NewStaticFilter creates a filter with a fixed list of blocked words.
+func NewStaticFilter(words []*models.NameFilterWord) Filter {
+	return &staticFilter{
+		list: newBlockList(words),
+	}
+}
+
+// IsBlocked checks if a name contains a blocked word.
+func (filter *staticFilter) IsBlocked(name string) (bool, error) {
+	return filter.list.isBlocked(name), nil
+}
+
+// Invalidate does nothing since the words never change.
+func (filter *staticFilter) Invalidate() {}
@@ -0,0 +1,97 @@
+package namefilter
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// homoglyphs maps the letters of other scripts that look like Latin letters, and the leetspeak symbols, to Latin letters.
+var homoglyphs = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p', 'с': 'c',
+	'т': 't', 'у': 'y', 'х': 'x', 'і': 'i', 'ї': 'i', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w',
+	// Greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p', 'τ': 't',
+	'υ': 'u', 'χ': 'x',
+	// Leetspeak
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '8': 'b', '9': 'g',
+	'@': 'a', '$': 's', '!': 'i', '|': 'i', '+': 't',
+}
+
+// normalizeText folds a text to a canonical form: full-width and compatibility characters are folded,
+// letters are lowercased, Latin diacritics are removed and homoglyphs and leetspeak are mapped to Latin letters.
+// Unless mapTrailing is set, the leetspeak symbols are only mapped when followed by a letter,
+// since names like "Jane!" or "Sam2" end with symbols that are not meant as letters.
+func normalizeText(text string, mapTrailing bool) string {
+	text = strings.ToLower(norm.NFKC.String(text))
+
+	runes := []rune(norm.NFD.String(text))
+	var builder strings.Builder
+	previousIsLatin := false
+	for i, r := range runes {
+		if unicode.Is(unicode.Mn, r) && previousIsLatin {
+			continue
+		}
+		if mapped, exists := homoglyphs[r]; exists && (unicode.IsLetter(r) || mapTrailing || isFollowedByLetter(runes, i)) {
+			r = mapped
+		}
+		previousIsLatin = unicode.Is(unicode.Latin, r)
+		builder.WriteRune(r)
+	}
+	return norm.NFC.String(builder.String())
+}
+
+// isFollowedByLetter checks if a rune is followed by a letter, possibly written with other leetspeak symbols.
+func isFollowedByLetter(runes []rune, index int) bool {
+	for _, r := range runes[index+1:] {
+		if unicode.IsLetter(r) {
+			return true
+		} else if _, exists := homoglyphs[r]; !exists {
+			return false
+		}
+	}
+	return false
+}
+
+// tokenize splits a normalized text into words. The runs of single letters are joined,
+// so spaced out words such as "b a d" are found as well.
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	var tokens []string
+	letters := ""
+	for _, word := range words {
+		if utf8.RuneCountInString(word) == 1 {
+			letters += word
+			continue
+		}
+		if len(letters) > 0 {
+			tokens = append(tokens, letters)
+			letters = ""
+		}
+		tokens = append(tokens, word)
+	}
+	if len(letters) > 0 {
+		tokens = append(tokens, letters)
+	}
+	return tokens
+}
+
+// collapseRepeats removes the repeated letters, so "baaad" is found as "bad".
+func collapseRepeats(text string) string {
+	var builder strings.Builder
+	var previous rune
+	for i, r := range text {
+		if i > 0 && r == previous {
+			continue
+		}
+		builder.WriteRune(r)
+		previous = r
+	}
+	return builder.String()
+}
@@ -0,0 +1,120 @@
+package namefilter
+
+import (
+	"sync"
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"github.com/guregu/dynamo"
+)
+
+const defaultCacheSeconds = 300
+
+func getTable() dynamo.Table {
+	return models.GetDB().Table(models.GetTableName(models.TABLE_NAME_NAME_FILTER_WORDS))
+}
+
+// ListWords returns the blocked words of a language, or of all the languages if the language is empty.
+func ListWords(language string) ([]*models.NameFilterWord, error) {
+	var words []*models.NameFilterWord
+	var err error
+	if len(language) > 0 {
+		err = getTable().Get("lang", language).All(&words)
+	} else {
+		err = getTable().Scan().All(&words)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return words, nil
+}
+
+// AddWords adds blocked words to the list of a language.
+func AddWords(language string, words []string, match string, createdBy string) error {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	items := make([]interface{}, len(words))
+	for i, word := range words {
+		items[i] = &models.NameFilterWord{
+			Language:    language,
+			Word:        word,
+			Match:       match,
+			CreatedBy:   createdBy,
+			CreatedDate: now,
+		}
+	}
+
+	_, err := getTable().Batch().Write().Put(items...).Run()
+	return err
+}
+
+// RemoveWords removes blocked words from the list of a language.
+func RemoveWords(language string, words []string) error {
+	keys := make([]dynamo.Keyed, len(words))
+	for i, word := range words {
+		keys[i] = dynamo.Keys{language, word}
+	}
+
+	_, err := getTable().Batch("lang", "word").Write().Delete(keys...).Run()
+	return err
+}
+
+// Config is the configuration of the name filter.
+type Config struct {
+	// CacheSeconds is how long the word lists are cached before they are read again, 300 seconds by default.
+	CacheSeconds int `env:"AMS_NAME_FILTER_CACHE_SECONDS"`
+}
+
+type storedFilter struct {
+	cacheDuration time.Duration
+
+	mutex    sync.Mutex
+	list     *blockList
+	loadedAt time.Time
+}
+
+// NewStoredFilter creates a filter using the words managed by the admins.
+// The words are cached, so the changes made by the other instances are applied after the cache duration.
+func NewStoredFilter(config Config) Filter {
+	cacheSeconds := config.CacheSeconds
+	if cacheSeconds <= 0 {
+		cacheSeconds = defaultCacheSeconds
+	}
+
+	return &storedFilter{
+		cacheDuration: time.Duration(cacheSeconds) * time.Second,
+	}
+}
+
+// IsBlocked checks if a name contains a blocked word.
+func (filter *storedFilter) IsBlocked(name string) (bool, error) {
+	list, err := filter.getList()
+	if err != nil {
+		return false, err
+	}
+	return list.isBlocked(name), nil
+}
+
+// Invalidate drops the cached word lists so the next check uses the latest ones.
+func (filter *storedFilter) Invalidate() {
+	filter.mutex.Lock()
+	defer filter.mutex.Unlock()
+	filter.list = nil
+}
+
+func (filter *storedFilter) getList() (*blockList, error) {
+	filter.mutex.Lock()
+	defer filter.mutex.Unlock()
+
+	if filter.list != nil && time.Since(filter.loadedAt) < filter.cacheDuration {
+		return filter.list, nil
+	}
+
+	words, err := ListWords("")
+	if err != nil {
+		return nil, err
+	}
+
+	filter.list = newBlockList(words)
+	filter.loadedAt = time.Now()
+	return filter.list, nil
+}
@@ -0,0 +1,111 @@
+package notifications
+
+import (
+	"bitbucket.org/calmisland/go-server-messages/messages"
+)
+
+// The notification categories, which the accounts can enable or disable by channel.
+const (
+	// CategorySecurity notifications are the verification codes and the account security notices, which are mandatory.
+	CategorySecurity = "security"
+	// CategoryAccount notifications are the transactional messages about the account, such as the welcome messages
+	// and the family invitations, which are mandatory.
+	CategoryAccount = "account"
+	// CategoryProductNews notifications are the news about the products.
+	CategoryProductNews = "product"
+	// CategoryMarketing notifications are the newsletters and promotions, which require a double opt-in.
+	CategoryMarketing = "marketing"
+	// CategoryReminders notifications are the reminders about the account, such as the pass expiry reminders.
+	CategoryReminders = "reminders"
+)
+
+// The channels the notifications are sent through.
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+	ChannelPush  = "push"
+)
+
+// The statuses of a notification category on a channel.
+const (
+	// StatusEnabled notifications are sent.
+	StatusEnabled = "enabled"
+	// StatusDisabled notifications are not sent.
+	StatusDisabled = "disabled"
+	// StatusPending notifications are not sent until the account confirms the double opt-in.
+	StatusPending = "pending"
+)
+
+var categories = []string{CategorySecurity, CategoryAccount, CategoryProductNews, CategoryMarketing, CategoryReminders}
+
+var channels = []string{ChannelEmail, ChannelSMS, ChannelPush}
+
+// defaultStatuses are the statuses of the categories that were never set, on all channels.
+// The marketing notifications are only sent to the accounts that opted in.
+var defaultStatuses = map[string]string{
+	CategorySecurity:    StatusEnabled,
+	CategoryAccount:     StatusEnabled,
+	CategoryProductNews: StatusEnabled,
+	CategoryMarketing:   StatusDisabled,
+	CategoryReminders:   StatusEnabled,
+}
+
+// Preferences are the statuses of the notification categories, by category and channel.
+type Preferences map[string]map[string]string
+
+// IsValidCategory checks if a notification category exists.
+func IsValidCategory(category string) bool {
+	_, exists := defaultStatuses[category]
+	return exists
+}
+
+// IsValidChannel checks if a notification channel exists.
+func IsValidChannel(channel string) bool {
+	return channel == ChannelEmail || channel == ChannelSMS || channel == ChannelPush
+}
+
+// IsMandatory checks if the notifications of a category can't be disabled.
+func IsMandatory(category string) bool {
+	return category == CategorySecurity || category == CategoryAccount
+}
+
+// RequiresDoubleOptIn checks if enabling a category on a channel must be confirmed with a code sent through the channel.
+// The push notifications are already opted in through the permission of the device.
+func RequiresDoubleOptIn(category string, channel string) bool {
+	return category == CategoryMarketing && channel != ChannelPush
+}
+
+// GetChannel returns the channel a message is sent through.
+func GetChannel(messageType messages.MessageType) string {
+	if messageType == messages.MessageTypeSMS {
+		return ChannelSMS
+	}
+	return ChannelEmail
+}
+
+// Status returns the status of a category on a channel, the default one if it was never set.
+func (preferences Preferences) Status(category string, channel string) string {
+	if IsMandatory(category) {
+		return StatusEnabled
+	} else if status, exists := preferences[category][channel]; exists {
+		return status
+	}
+	return defaultStatuses[category]
+}
+
+// Allows checks if the notifications of a category can be sent through a channel.
+func (preferences Preferences) Allows(category string, channel string) bool {
+	return preferences.Status(category, channel) == StatusEnabled
+}
+
+// All returns the statuses of all the categories on all the channels.
+func (preferences Preferences) All() Preferences {
+	all := make(Preferences, len(categories))
+	for _, category := range categories {
+		all[category] = make(map[string]string, len(channels))
+		for _, channel := range channels {
+			all[category][channel] = preferences.Status(category, channel)
+		}
+	}
+	return all
+}
@@ -0,0 +1,268 @@
+package notificationservice
+
+import (
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/messagetemplates"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notifications"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
+	"bitbucket.org/calmisland/go-server-account/accounts"
+	"bitbucket.org/calmisland/go-server-logs/logger"
+	"bitbucket.org/calmisland/go-server-messages/messages"
+	"bitbucket.org/calmisland/go-server-security/securitycodes"
+	"github.com/calmisland/go-errors"
+)
+
+const (
+	dayMilliseconds = int64(24 * time.Hour / time.Millisecond)
+)
+
+var (
+	// ErrChildAccount is returned when a child account opts in to the marketing notifications.
+	ErrChildAccount = errors.New("Child accounts can't receive marketing notifications")
+	// ErrNoRecipient is returned when an account opts in to a channel it has no email or phone number for.
+	ErrNoRecipient = errors.New("The account has no recipient for this channel")
+	// ErrOptInNotFound is returned when an opt-in doesn't exist, was already confirmed, was withdrawn or has expired.
+	ErrOptInNotFound = errors.New("The marketing opt-in doesn't exist")
+	// ErrInvalidOptInCode is returned when the code of an opt-in doesn't match.
+	ErrInvalidOptInCode = errors.New("The marketing opt-in code is invalid")
+)
+
+func now() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+// Origin is where the preferences are changed from, recorded with the marketing opt-ins.
+type Origin struct {
+	IPAddress string
+}
+
+// Enqueue sends a message of a notification category, unless the account disabled the category on the channel of the message.
+// The security notifications are always sent, and the messages to recipients without an account use the default preferences.
+func Enqueue(accountID string, category string, message *messages.Message) error {
+	if !notifications.IsMandatory(category) {
+		preferences, err := GetPreferences(accountID)
+		if err != nil {
+			return err
+		}
+
+		channel := notifications.GetChannel(message.MessageType)
+		if !preferences.Allows(category, channel) {
+			logger.LogFormat("[NOTIFICATIONS] Skipped a [%s] message to account [%s] which disabled them on channel [%s]\n", category, accountID, channel)
+			return nil
+		}
+	}
+
+	return globals.MessageSendQueue.EnqueueMessage(message)
+}
+
+// EnqueueToAccount sends a message of a notification category to an account, by email or else by SMS.
+// The message goes to the verified email address or phone number of the account on a channel where the category
+// is enabled, and isn't sent if there is none.
+func EnqueueToAccount(accountID string, category string, template messages.MessageTemplate) error {
+	accInfo, err := globals.AccountDatabase.GetAccountSignInInfoByID(accountID)
+	if err != nil || accInfo == nil {
+		return err
+	}
+
+	preferences, err := GetPreferences(accountID)
+	if err != nil {
+		return err
+	}
+
+	message := &messages.Message{
+		Language: accInfo.Language,
+		Template: template,
+	}
+	if len(accInfo.Email) > 0 && accounts.IsAccountEmailVerified(accInfo.Flags) && preferences.Allows(category, notifications.ChannelEmail) {
+		message.MessageType = messages.MessageTypeEmail
+		message.Priority = messages.MessagePriorityEmailNormal
+		message.Recipient = accInfo.Email
+	} else if len(accInfo.PhoneNumber) > 0 && accounts.IsAccountPhoneNumberVerified(accInfo.Flags) && preferences.Allows(category, notifications.ChannelSMS) {
+		message.MessageType = messages.MessageTypeSMS
+		message.Priority = messages.MessagePrioritySMSTransactional
+		message.Recipient = accInfo.PhoneNumber
+	} else {
+		logger.LogFormat("[NOTIFICATIONS] Skipped a [%s] message to account [%s] which has no recipient for them\n", category, accountID)
+		return nil
+	}
+
+	return globals.MessageSendQueue.EnqueueMessage(message)
+}
+
+// GetPreferences returns the notification preferences of an account.
+func GetPreferences(accountID string) (notifications.Preferences, error) {
+	if len(accountID) == 0 {
+		return notifications.Preferences{}, nil
+	}
+
+	profile, err := profileservice.GetProfile(accountID)
+	if err != nil {
+		return nil, err
+	}
+	return notifications.Preferences(profile.Notifications), nil
+}
+
+// SetPreferences enables or disables notification categories by channel.
+// Enabling the marketing notifications on a channel with a double opt-in sends a confirmation code, and they stay pending until it is confirmed.
+// Disabling them withdraws the opt-in of the channel, whether it was confirmed or still pending.
+func SetPreferences(store Store, accountID string, changes map[string]map[string]bool, origin Origin) error {
+	profile, err := store.GetProfile(accountID)
+	if err != nil {
+		return err
+	}
+	preferences := notifications.Preferences(profile.Notifications)
+
+	statuses := make(map[string]map[string]string, len(changes))
+	var optInChannels []string
+	var withdrawnChannels []string
+	for category, channels := range changes {
+		statuses[category] = make(map[string]string, len(channels))
+		for channel, enabled := range channels {
+			currentStatus := preferences.Status(category, channel)
+			doubleOptIn := notifications.RequiresDoubleOptIn(category, channel)
+			if !enabled {
+				statuses[category][channel] = notifications.StatusDisabled
+				if doubleOptIn {
+					withdrawnChannels = append(withdrawnChannels, channel)
+				}
+			} else if category == notifications.CategoryMarketing && profileservice.IsChildAccount(profile, time.Now()) {
+				return ErrChildAccount
+			} else if doubleOptIn && currentStatus != notifications.StatusEnabled {
+				statuses[category][channel] = notifications.StatusPending
+				optInChannels = append(optInChannels, channel)
+			} else {
+				statuses[category][channel] = notifications.StatusEnabled
+			}
+		}
+	}
+
+	if len(optInChannels) > 0 {
+		err = requestOptIns(store, accountID, optInChannels, origin)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, channel := range withdrawnChannels {
+		err = store.WithdrawOptIn(accountID, channel, now())
+		if err != nil {
+			return err
+		}
+	}
+
+	return store.SetNotifications(accountID, statuses)
+}
+
+// requestOptIns sends the codes confirming the opt-ins to the marketing notifications, after checking the account has a recipient for each channel.
+func requestOptIns(store Store, accountID string, channels []string, origin Origin) error {
+	accInfo, err := globals.AccountDatabase.GetAccountSignInInfoByID(accountID)
+	if err != nil {
+		return err
+	} else if accInfo == nil {
+		return ErrNoRecipient
+	}
+
+	recipients := make(map[string]string, len(channels))
+	for _, channel := range channels {
+		if channel == notifications.ChannelSMS {
+			recipients[channel] = accInfo.PhoneNumber
+		} else {
+			recipients[channel] = accInfo.Email
+		}
+		if len(recipients[channel]) == 0 {
+			return ErrNoRecipient
+		}
+	}
+
+	for _, channel := range channels {
+		code, err := securitycodes.GenerateSecurityCode(defs.MarketingOptInCodeByteLength)
+		if err != nil {
+			return err
+		}
+
+		codeHash, err := globals.PasswordHasher.GeneratePasswordHash(code, false)
+		if err != nil {
+			return err
+		}
+
+		requestedDate := now()
+		err = store.PutOptIn(&models.MarketingOptIn{
+			AccountID:      accountID,
+			Channel:        channel,
+			Recipient:      recipients[channel],
+			CodeHash:       codeHash,
+			Status:         models.MarketingOptInStatusPending,
+			ExpirationDate: requestedDate + defs.MarketingOptInValidDays*dayMilliseconds,
+			RequestedDate:  requestedDate,
+			RequestIP:      origin.IPAddress,
+		})
+		if err != nil {
+			return err
+		}
+
+		message := &messages.Message{
+			MessageType: messages.MessageTypeEmail,
+			Priority:    messages.MessagePriorityEmailHigh,
+			Recipient:   recipients[channel],
+			Language:    accInfo.Language,
+			Template: &messagetemplates.MarketingOptInTemplate{
+				Code: code,
+			},
+		}
+		if channel == notifications.ChannelSMS {
+			message.MessageType = messages.MessageTypeSMS
+			message.Priority = messages.MessagePrioritySMSTransactional
+		}
+
+		// The confirmation itself is mandatory, since it was requested by the account
+		err = Enqueue(accountID, notifications.CategorySecurity, message)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConfirmOptIn confirms the opt-in to the marketing notifications on a channel with the code sent through it, enabling them.
+// The opt-in can only be confirmed while the marketing notifications of the channel are still pending.
+func ConfirmOptIn(store Store, accountID string, channel string, code string, origin Origin) error {
+	profile, err := store.GetProfile(accountID)
+	if err != nil {
+		return err
+	} else if notifications.Preferences(profile.Notifications).Status(notifications.CategoryMarketing, channel) != notifications.StatusPending {
+		return ErrOptInNotFound
+	}
+
+	optIn, err := store.GetOptIn(accountID, channel)
+	if err != nil {
+		return err
+	} else if optIn == nil || optIn.Status != models.MarketingOptInStatusPending || optIn.ExpirationDate < now() {
+		return ErrOptInNotFound
+	}
+
+	if !globals.PasswordHasher.VerifyPasswordHash(code, optIn.CodeHash) {
+		return ErrInvalidOptInCode
+	}
+
+	return store.ConfirmOptIn(accountID, channel, now(), origin.IPAddress)
+}
+
+// DeleteOptIns deletes the marketing opt-ins of an account.
+func DeleteOptIns(store Store, accountID string) error {
+	optIns, err := store.ListOptIns(accountID)
+	if err != nil {
+		return err
+	}
+
+	for _, optIn := range optIns {
+		err = store.DeleteOptIn(accountID, optIn.Channel)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
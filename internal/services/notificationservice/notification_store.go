@@ -0,0 +1,125 @@
+package notificationservice
+
+import (
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notifications"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/guregu/dynamo"
+)
+
+// Store reads and writes the marketing opt-ins and the notification preferences of the profiles.
+type Store interface {
+	// GetProfile returns the profile of an account.
+	GetProfile(accountID string) (*models.AccountProfile, error)
+	// SetNotifications sets the statuses of notification categories by channel in the profile of an account.
+	SetNotifications(accountID string, statuses map[string]map[string]string) error
+	// PutOptIn writes an opt-in, replacing the previous one of the channel.
+	PutOptIn(optIn *models.MarketingOptIn) error
+	// GetOptIn returns the opt-in of an account on a channel, nil if it doesn't exist.
+	GetOptIn(accountID string, channel string) (*models.MarketingOptIn, error)
+	// ConfirmOptIn atomically confirms a pending opt-in at the date in epoch milliseconds and enables the marketing
+	// notifications on its channel. It fails with ErrOptInNotFound unless both the opt-in and the marketing
+	// notifications of the channel are still pending.
+	ConfirmOptIn(accountID string, channel string, confirmedDate int64, ipAddress string) error
+	// WithdrawOptIn marks a confirmed opt-in as withdrawn at the date in epoch milliseconds, and deletes a pending one
+	// so that its code can't be confirmed anymore.
+	WithdrawOptIn(accountID string, channel string, withdrawnDate int64) error
+	// ListOptIns returns the opt-ins of an account.
+	ListOptIns(accountID string) ([]*models.MarketingOptIn, error)
+	// DeleteOptIn deletes the opt-in of an account on a channel.
+	DeleteOptIn(accountID string, channel string) error
+}
+
+type standardStore struct{}
+
+// NewStore creates the store of the marketing opt-ins table and the profiles table.
+func NewStore() Store {
+	return &standardStore{}
+}
+
+func getOptInTable() dynamo.Table {
+	return models.GetDB().Table(models.GetTableName(models.TABLE_NAME_MARKETING_OPT_INS))
+}
+
+func (store *standardStore) GetProfile(accountID string) (*models.AccountProfile, error) {
+	return profileservice.GetProfile(accountID)
+}
+
+func (store *standardStore) SetNotifications(accountID string, statuses map[string]map[string]string) error {
+	return profileservice.SetNotifications(accountID, statuses)
+}
+
+func (store *standardStore) PutOptIn(optIn *models.MarketingOptIn) error {
+	return getOptInTable().Put(optIn).Run()
+}
+
+func (store *standardStore) GetOptIn(accountID string, channel string) (*models.MarketingOptIn, error) {
+	optIn := &models.MarketingOptIn{}
+	err := getOptInTable().Get("accId", accountID).Range("channel", dynamo.Equal, channel).One(optIn)
+	if err == dynamo.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return optIn, nil
+}
+
+func (store *standardStore) ConfirmOptIn(accountID string, channel string, confirmedDate int64, ipAddress string) error {
+	optInUpdate := getOptInTable().Update("accId", accountID).Range("channel", channel).
+		Set("status", models.MarketingOptInStatusConfirmed).
+		Set("confirmTm", confirmedDate).
+		Set("confirmIp", ipAddress).
+		If("$ = ?", "status", models.MarketingOptInStatusPending)
+	profileUpdate := profileservice.NewNotificationStatusUpdate(accountID, notifications.CategoryMarketing, channel,
+		notifications.StatusEnabled, notifications.StatusPending)
+
+	err := models.GetDB().WriteTx().
+		Update(optInUpdate).
+		Update(profileUpdate).
+		Run()
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dynamodb.ErrCodeTransactionCanceledException {
+		return ErrOptInNotFound
+	}
+	return err
+}
+
+func (store *standardStore) WithdrawOptIn(accountID string, channel string, withdrawnDate int64) error {
+	err := getOptInTable().Update("accId", accountID).Range("channel", channel).
+		Set("status", models.MarketingOptInStatusWithdrawn).
+		Set("withdrawTm", withdrawnDate).
+		If("$ = ?", "status", models.MarketingOptInStatusConfirmed).
+		Run()
+	if !isConditionFailed(err) {
+		return err
+	}
+
+	// The opt-in isn't confirmed, so a pending one is deleted with its code
+	err = getOptInTable().Delete("accId", accountID).Range("channel", channel).
+		If("$ = ?", "status", models.MarketingOptInStatusPending).
+		Run()
+	if isConditionFailed(err) {
+		// There is no opt-in to withdraw
+		return nil
+	}
+	return err
+}
+
+func (store *standardStore) ListOptIns(accountID string) ([]*models.MarketingOptIn, error) {
+	var optIns []*models.MarketingOptIn
+	err := getOptInTable().Get("accId", accountID).All(&optIns)
+	if err != nil && err != dynamo.ErrNotFound {
+		return nil, err
+	}
+	return optIns, nil
+}
+
+func (store *standardStore) DeleteOptIn(accountID string, channel string) error {
+	return getOptInTable().Delete("accId", accountID).Range("channel", channel).Run()
+}
+
+func isConditionFailed(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	return ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
@@ -0,0 +1,101 @@
+package organizationservice
+
+import (
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"github.com/guregu/dynamo"
+)
+
+func getTable() dynamo.Table {
+	return models.GetDB().Table(models.GetTableName(models.TABLE_NAME_ACCOUNT_ORGANIZATIONS))
+}
+
+// AddMember adds an account to an organisation.
+func AddMember(accountID string, organizationID string) error {
+	return getTable().Put(&models.AccountOrganization{
+		AccountID:      accountID,
+		OrganizationID: organizationID,
+		CreatedDate:    time.Now().UnixNano() / int64(time.Millisecond),
+	}).Run()
+}
+
+// GetOrganizationIDs returns the organisations of an account.
+func GetOrganizationIDs(accountID string) ([]string, error) {
+	var memberships []*models.AccountOrganization
+	err := getTable().Get("accId", accountID).All(&memberships)
+	if err != nil {
+		return nil, err
+	}
+
+	organizationIDs := make([]string, 0, len(memberships))
+	for _, membership := range memberships {
+		organizationIDs = append(organizationIDs, membership.OrganizationID)
+	}
+	return organizationIDs, nil
+}
+
+// ShareOrganization checks if two accounts are members of the same organisation.
+func ShareOrganization(accountID string, otherAccountID string) (bool, error) {
+	organizationIDs, err := GetOrganizationIDs(accountID)
+	if err != nil || len(organizationIDs) == 0 {
+		return false, err
+	}
+
+	otherOrganizationIDs, err := GetOrganizationIDs(otherAccountID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, organizationID := range organizationIDs {
+		for _, otherOrganizationID := range otherOrganizationIDs {
+			if organizationID == otherOrganizationID {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// FindMembers returns the accounts, among the given ones, that are members of at least one of the given organisations.
+// The memberships are read in batches instead of querying the organisations of every account.
+func FindMembers(organizationIDs []string, accountIDs []string) (map[string]bool, error) {
+	members := map[string]bool{}
+	if len(organizationIDs) == 0 || len(accountIDs) == 0 {
+		return members, nil
+	}
+
+	keys := make([]dynamo.Keyed, 0, len(organizationIDs)*len(accountIDs))
+	for _, accountID := range accountIDs {
+		for _, organizationID := range organizationIDs {
+			keys = append(keys, dynamo.Keys{accountID, organizationID})
+		}
+	}
+
+	var memberships []*models.AccountOrganization
+	err := getTable().Batch("accId", "orgId").Get(keys...).All(&memberships)
+	if err != nil && err != dynamo.ErrNotFound {
+		return nil, err
+	}
+
+	for _, membership := range memberships {
+		members[membership.AccountID] = true
+	}
+	return members, nil
+}
+
+// RemoveMemberships removes an account from all its organisations.
+func RemoveMemberships(accountID string) error {
+	organizationIDs, err := GetOrganizationIDs(accountID)
+	if err != nil && err != dynamo.ErrNotFound {
+		return err
+	}
+
+	for _, organizationID := range organizationIDs {
+		err = getTable().Delete("accId", accountID).Range("orgId", organizationID).Run()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+package parentalconsentservice
+
+import (
+	"sort"
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/messagetemplates"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notifications"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notificationservice"
+	"bitbucket.org/calmisland/go-server-messages/messages"
+	"bitbucket.org/calmisland/go-server-security/securitycodes"
+	"github.com/calmisland/go-errors"
+	"github.com/google/uuid"
+)
+
+const (
+	dayMilliseconds = int64(24 * time.Hour / time.Millisecond)
+)
+
+var (
+	// ErrConsentNotFound is returned when a consent request doesn't exist, or has expired before being granted.
+	ErrConsentNotFound = errors.New("The parental consent request doesn't exist")
+	// ErrInvalidConsentCode is returned when the code of a consent request doesn't match.
+	ErrInvalidConsentCode = errors.New("The parental consent code is invalid")
+)
+
+func now() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+// Origin is where a consent request is made from, recorded for audit.
+type Origin struct {
+	IPAddress string
+	UserAgent string
+}
+
+// RequestConsent sends a consent request to the email of the parent of a child account, restricting the account until the parent consents.
+func RequestConsent(store Store, accountID string, parentEmail string, language string, origin Origin) (*models.ParentalConsent, error) {
+	consentUUID, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := securitycodes.GenerateSecurityCode(defs.ParentalConsentCodeByteLength)
+	if err != nil {
+		return nil, err
+	}
+
+	codeHash, err := globals.PasswordHasher.GeneratePasswordHash(code, false)
+	if err != nil {
+		return nil, err
+	}
+
+	createdDate := now()
+	consent := &models.ParentalConsent{
+		ConsentID:      consentUUID.String(),
+		AccountID:      accountID,
+		ParentEmail:    parentEmail,
+		CodeHash:       codeHash,
+		Status:         models.ParentalConsentStatusPending,
+		ExpirationDate: createdDate + defs.ParentalConsentRequestValidDays*dayMilliseconds,
+		CreatedDate:    createdDate,
+	}
+	err = store.PutConsent(consent)
+	if err != nil {
+		return nil, err
+	}
+
+	err = store.SetConsentStatus(accountID, models.ParentalConsentStatusPending)
+	if err != nil {
+		return nil, err
+	}
+
+	err = store.PutEvent(&models.ParentalConsentEvent{
+		AccountID:   accountID,
+		CreatedDate: createdDate,
+		Event:       models.ParentalConsentEventRequested,
+		Method:      models.ParentalConsentMethodEmail,
+		ConsentID:   consent.ConsentID,
+		ParentEmail: parentEmail,
+		IPAddress:   origin.IPAddress,
+		UserAgent:   origin.UserAgent,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = notificationservice.Enqueue("", notifications.CategorySecurity, &messages.Message{
+		MessageType: messages.MessageTypeEmail,
+		Priority:    messages.MessagePriorityEmailHigh,
+		Recipient:   parentEmail,
+		Language:    language,
+		Template: &messagetemplates.ParentalConsentTemplate{
+			Link:           globals.AccountVerificationService.GetParentalConsentLink(consent.ConsentID, code, language),
+			ExpirationDate: consent.ExpirationDate,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return consent, nil
+}
+
+// ResendConsent sends a new consent request to the parent of the last request of a child account, once the previous one
+// expired or was lost. The previous pending requests are deleted so only the latest code can be used.
+func ResendConsent(store Store, accountID string, language string, origin Origin) (*models.ParentalConsent, error) {
+	previousConsents, err := store.ListConsents(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastConsent *models.ParentalConsent
+	for _, previousConsent := range previousConsents {
+		if lastConsent == nil || previousConsent.CreatedDate > lastConsent.CreatedDate {
+			lastConsent = previousConsent
+		}
+	}
+	if lastConsent == nil {
+		return nil, ErrConsentNotFound
+	}
+
+	consent, err := RequestConsent(store, accountID, lastConsent.ParentEmail, language, origin)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, previousConsent := range previousConsents {
+		if previousConsent.Status != models.ParentalConsentStatusPending {
+			continue
+		}
+		err = store.DeleteConsent(previousConsent.ConsentID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return consent, nil
+}
+
+// getConsent returns a consent request after checking its code.
+func getConsent(store Store, consentID string, code string) (*models.ParentalConsent, error) {
+	consent, err := store.GetConsent(consentID)
+	if err != nil {
+		return nil, err
+	} else if consent == nil {
+		return nil, ErrConsentNotFound
+	}
+
+	if !globals.PasswordHasher.VerifyPasswordHash(code, consent.CodeHash) {
+		return nil, ErrInvalidConsentCode
+	}
+	return consent, nil
+}
+
+// GrantConsent grants a consent request with the code sent to the parent, lifting the restriction of the child account.
+func GrantConsent(store Store, consentID string, code string, origin Origin) (*models.ParentalConsent, error) {
+	consent, err := getConsent(store, consentID, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if consent.Status == models.ParentalConsentStatusGranted {
+		return consent, nil
+	}
+
+	grantedDate := now()
+	if consent.ExpirationDate < grantedDate {
+		return nil, ErrConsentNotFound
+	}
+
+	err = store.GrantConsent(consentID, grantedDate)
+	if err != nil {
+		return nil, err
+	}
+	consent.Status = models.ParentalConsentStatusGranted
+	consent.GrantedDate = grantedDate
+
+	err = store.PutEvent(&models.ParentalConsentEvent{
+		AccountID:   consent.AccountID,
+		CreatedDate: grantedDate,
+		Event:       models.ParentalConsentEventGranted,
+		Method:      models.ParentalConsentMethodEmail,
+		ConsentID:   consentID,
+		ParentEmail: consent.ParentEmail,
+		IPAddress:   origin.IPAddress,
+		UserAgent:   origin.UserAgent,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = store.SetConsentStatus(consent.AccountID, models.ParentalConsentStatusGranted)
+	if err != nil {
+		return nil, err
+	}
+	return consent, nil
+}
+
+// WithdrawConsent withdraws a consent with the code sent to the parent, after which the child account must be deleted.
+// A pending consent request can also be refused this way.
+func WithdrawConsent(store Store, consentID string, code string, origin Origin) (*models.ParentalConsent, error) {
+	consent, err := getConsent(store, consentID, code)
+	if err != nil {
+		return nil, err
+	}
+
+	err = store.PutEvent(&models.ParentalConsentEvent{
+		AccountID:   consent.AccountID,
+		CreatedDate: now(),
+		Event:       models.ParentalConsentEventWithdrawn,
+		Method:      models.ParentalConsentMethodEmail,
+		ConsentID:   consentID,
+		ParentEmail: consent.ParentEmail,
+		IPAddress:   origin.IPAddress,
+		UserAgent:   origin.UserAgent,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return consent, nil
+}
+
+// RecordParentAccountConsent records the consent of a signed in parent to a child profile it manages.
+func RecordParentAccountConsent(store Store, childID string, parentID string, origin Origin) error {
+	err := store.PutEvent(&models.ParentalConsentEvent{
+		AccountID:   childID,
+		CreatedDate: now(),
+		Event:       models.ParentalConsentEventGranted,
+		Method:      models.ParentalConsentMethodParentAccount,
+		ParentID:    parentID,
+		IPAddress:   origin.IPAddress,
+		UserAgent:   origin.UserAgent,
+	})
+	if err != nil {
+		return err
+	}
+	return store.SetConsentStatus(childID, models.ParentalConsentStatusGranted)
+}
+
+// RecordParentAccountWithdrawal records the withdrawal of the consent of a signed in parent deleting a child profile it manages.
+func RecordParentAccountWithdrawal(store Store, childID string, parentID string, origin Origin) error {
+	return store.PutEvent(&models.ParentalConsentEvent{
+		AccountID:   childID,
+		CreatedDate: now(),
+		Event:       models.ParentalConsentEventWithdrawn,
+		Method:      models.ParentalConsentMethodParentAccount,
+		ParentID:    parentID,
+		IPAddress:   origin.IPAddress,
+		UserAgent:   origin.UserAgent,
+	})
+}
+
+// GetEvents returns the parental consent events of an account, from the oldest one.
+func GetEvents(store Store, accountID string) ([]models.ParentalConsentEvent, error) {
+	events, err := store.ListEvents(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].CreatedDate < events[j].CreatedDate
+	})
+	return events, nil
+}
+
+// DeleteConsents deletes the consent requests of an account, keeping the consent events for audit.
+func DeleteConsents(store Store, accountID string) error {
+	consents, err := store.ListConsents(accountID)
+	if err != nil {
+		return err
+	}
+
+	for _, consent := range consents {
+		err = store.DeleteConsent(consent.ConsentID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package parentalconsentservice
+
+import (
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
+	"github.com/guregu/dynamo"
+)
+
+// Store reads and writes the consent requests, their audit events and the consent status of the profiles.
+type Store interface {
+	// PutConsent writes a new consent request.
+	PutConsent(consent *models.ParentalConsent) error
+	// GetConsent returns a consent request, nil if it doesn't exist.
+	GetConsent(consentID string) (*models.ParentalConsent, error)
+	// ListConsents returns the consent requests of an account.
+	ListConsents(accountID string) ([]*models.ParentalConsent, error)
+	// GrantConsent marks an existing consent request as granted at the date in epoch milliseconds.
+	GrantConsent(consentID string, grantedDate int64) error
+	// DeleteConsent deletes a consent request.
+	DeleteConsent(consentID string) error
+	// PutEvent records a consent event.
+	PutEvent(event *models.ParentalConsentEvent) error
+	// ListEvents returns the consent events of an account, in any order.
+	ListEvents(accountID string) ([]models.ParentalConsentEvent, error)
+	// SetConsentStatus sets the parental consent status of the profile of an account.
+	SetConsentStatus(accountID string, status string) error
+}
+
+type standardStore struct{}
+
+// NewStore creates the store of the parental consent tables and the profiles table.
+func NewStore() Store {
+	return &standardStore{}
+}
+
+func getConsentTable() dynamo.Table {
+	return models.GetDB().Table(models.GetTableName(models.TABLE_NAME_PARENTAL_CONSENTS))
+}
+
+func getEventTable() dynamo.Table {
+	return models.GetDB().Table(models.GetTableName(models.TABLE_NAME_PARENTAL_CONSENT_EVENTS))
+}
+
+func (store *standardStore) PutConsent(consent *models.ParentalConsent) error {
+	return getConsentTable().Put(consent).If("attribute_not_exists($)", "consentId").Run()
+}
+
+func (store *standardStore) GetConsent(consentID string) (*models.ParentalConsent, error) {
+	consent := &models.ParentalConsent{}
+	err := getConsentTable().Get("consentId", consentID).One(consent)
+	if err == dynamo.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return consent, nil
+}
+
+func (store *standardStore) ListConsents(accountID string) ([]*models.ParentalConsent, error) {
+	var consents []*models.ParentalConsent
+	err := getConsentTable().Get("accId", accountID).Index(models.PARENTAL_CONSENT_GSI_ACCID).All(&consents)
+	if err != nil && err != dynamo.ErrNotFound {
+		return nil, err
+	}
+	return consents, nil
+}
+
+func (store *standardStore) GrantConsent(consentID string, grantedDate int64) error {
+	return getConsentTable().Update("consentId", consentID).
+		Set("status", models.ParentalConsentStatusGranted).
+		Set("grantTm", grantedDate).
+		If("attribute_exists($)", "consentId").
+		Run()
+}
+
+func (store *standardStore) DeleteConsent(consentID string) error {
+	return getConsentTable().Delete("consentId", consentID).Run()
+}
+
+func (store *standardStore) PutEvent(event *models.ParentalConsentEvent) error {
+	return getEventTable().Put(event).Run()
+}
+
+func (store *standardStore) ListEvents(accountID string) ([]models.ParentalConsentEvent, error) {
+	var events []models.ParentalConsentEvent
+	err := getEventTable().Get("accId", accountID).All(&events)
+	if err != nil && err != dynamo.ErrNotFound {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (store *standardStore) SetConsentStatus(accountID string, status string) error {
+	return profileservice.SetParentalConsentStatus(accountID, status)
+}
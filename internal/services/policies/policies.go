@@ -0,0 +1,86 @@
+package policies
+
+import (
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"github.com/calmisland/go-errors"
+)
+
+// Config is the configuration of the policy versions that the accounts must accept.
+type Config struct {
+	TermsOfServiceVersion string `env:"AMS_TERMS_OF_SERVICE_VERSION"`
+	PrivacyPolicyVersion  string `env:"AMS_PRIVACY_POLICY_VERSION"`
+	// ConsentGraceEndDate is the date, formatted as YYYY-MM-DD in UTC, until when the accounts that never accepted
+	// any policy are let through. It gives the accounts created before the consents were recorded time to accept them,
+	// so it must be set at release time to a cut-off after the deployment. A past date ends the grace period.
+	ConsentGraceEndDate string `env:"AMS_POLICY_CONSENT_GRACE_END_DATE"`
+}
+
+// consentGraceEndDateLayout is the format of the grace end date of the configuration.
+const consentGraceEndDateLayout = "2006-01-02"
+
+// Versions are the versions of the policies, by policy.
+type Versions map[string]string
+
+// NewRequiredVersions returns the policy versions that the accounts must accept.
+func NewRequiredVersions(config Config) (Versions, error) {
+	if len(config.TermsOfServiceVersion) == 0 {
+		return nil, errors.New("The terms of service version cannot be empty")
+	} else if len(config.PrivacyPolicyVersion) == 0 {
+		return nil, errors.New("The privacy policy version cannot be empty")
+	}
+
+	return Versions{
+		models.PolicyTermsOfService: config.TermsOfServiceVersion,
+		models.PolicyPrivacy:        config.PrivacyPolicyVersion,
+	}, nil
+}
+
+// NewConsentGraceEndDate returns the date until when the accounts that never accepted any policy are let through.
+func NewConsentGraceEndDate(config Config) (time.Time, error) {
+	if len(config.ConsentGraceEndDate) == 0 {
+		return time.Time{}, errors.New("The policy consent grace end date cannot be empty")
+	}
+
+	graceEndDate, err := time.Parse(consentGraceEndDateLayout, config.ConsentGraceEndDate)
+	if err != nil {
+		return time.Time{}, errors.New("The policy consent grace end date must be formatted as YYYY-MM-DD")
+	}
+	return graceEndDate, nil
+}
+
+// IsInGracePeriod checks if an account is let through without accepting the policies, because it never accepted
+// any policy and the grace period isn't over.
+func IsInGracePeriod(accepted map[string]string, graceEndDate time.Time, now time.Time) bool {
+	return len(accepted) == 0 && now.Before(graceEndDate)
+}
+
+// IsValidPolicy checks if a policy must be accepted by the accounts.
+func IsValidPolicy(policy string) bool {
+	return policy == models.PolicyTermsOfService || policy == models.PolicyPrivacy
+}
+
+// AreCurrent checks if the accepted versions are all current versions of policies that must be accepted.
+func (required Versions) AreCurrent(accepted map[string]string) bool {
+	for policy, version := range accepted {
+		if !IsValidPolicy(policy) || required[policy] != version {
+			return false
+		}
+	}
+	return true
+}
+
+// Missing returns the required versions that were not accepted, or nil if all of them were.
+func (required Versions) Missing(accepted map[string]string) Versions {
+	var missing Versions
+	for policy, version := range required {
+		if accepted[policy] != version {
+			if missing == nil {
+				missing = Versions{}
+			}
+			missing[policy] = version
+		}
+	}
+	return missing
+}
@@ -0,0 +1,65 @@
+package policyconsentservice
+
+import (
+	"sort"
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/policies"
+)
+
+// Origin is where the policies are accepted from, recorded with the consents.
+type Origin struct {
+	IPAddress string
+	Language  string
+}
+
+// Accept records an account accepting versions of the policies.
+func Accept(store Store, accountID string, versions policies.Versions, origin Origin) error {
+	acceptedDate := time.Now().UnixNano() / int64(time.Millisecond)
+	for policy, version := range versions {
+		err := store.PutConsent(&models.AccountPolicyConsent{
+			AccountID:    accountID,
+			ConsentID:    policy + "#" + version,
+			Policy:       policy,
+			Version:      version,
+			AcceptedDate: acceptedDate,
+			IPAddress:    origin.IPAddress,
+			Language:     origin.Language,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return store.SetAcceptedPolicies(accountID, versions)
+}
+
+// GetConsents returns the policy versions accepted by an account, from the oldest one.
+func GetConsents(store Store, accountID string) ([]models.AccountPolicyConsent, error) {
+	consents, err := store.ListConsents(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(consents, func(i, j int) bool {
+		return consents[i].AcceptedDate < consents[j].AcceptedDate
+	})
+	return consents, nil
+}
+
+// DeleteConsents deletes the policy consents of an account.
+func DeleteConsents(store Store, accountID string) error {
+	consents, err := store.ListConsents(accountID)
+	if err != nil {
+		return err
+	}
+
+	for _, consent := range consents {
+		err = store.DeleteConsent(accountID, consent.ConsentID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package policyconsentservice
+
+import (
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
+	"github.com/guregu/dynamo"
+)
+
+// Store reads and writes the policy consents and the accepted policy versions of the profiles.
+type Store interface {
+	// PutConsent records an account accepting a version of a policy.
+	PutConsent(consent *models.AccountPolicyConsent) error
+	// ListConsents returns the policy consents of an account, in any order.
+	ListConsents(accountID string) ([]models.AccountPolicyConsent, error)
+	// DeleteConsent deletes a policy consent of an account.
+	DeleteConsent(accountID string, consentID string) error
+	// SetAcceptedPolicies sets the last accepted versions of some policies in the profile of an account, keeping the other ones.
+	SetAcceptedPolicies(accountID string, versions map[string]string) error
+}
+
+type standardStore struct{}
+
+// NewStore creates the store of the policy consents table and the profiles table.
+func NewStore() Store {
+	return &standardStore{}
+}
+
+func getTable() dynamo.Table {
+	return models.GetDB().Table(models.GetTableName(models.TABLE_NAME_ACCOUNT_POLICY_CONSENTS))
+}
+
+func (store *standardStore) PutConsent(consent *models.AccountPolicyConsent) error {
+	return getTable().Put(consent).Run()
+}
+
+func (store *standardStore) ListConsents(accountID string) ([]models.AccountPolicyConsent, error) {
+	var consents []models.AccountPolicyConsent
+	err := getTable().Get("accId", accountID).All(&consents)
+	if err != nil && err != dynamo.ErrNotFound {
+		return nil, err
+	}
+	return consents, nil
+}
+
+func (store *standardStore) DeleteConsent(accountID string, consentID string) error {
+	return getTable().Delete("accId", accountID).Range("consentId", consentID).Run()
+}
+
+func (store *standardStore) SetAcceptedPolicies(accountID string, versions map[string]string) error {
+	return profileservice.SetAcceptedPolicies(accountID, versions)
+}
@@ -0,0 +1,445 @@
+package profileservice
+
+import (
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/visibility"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/calmisland/go-errors"
+	"github.com/guregu/dynamo"
+)
+
+func getTable() dynamo.Table {
+	return models.GetDB().Table(models.GetTableName(models.TABLE_NAME_ACCOUNT_PROFILES))
+}
+
+// GetProfile returns the profile of an account.
+// Accounts that never changed their profile get an empty one.
+func GetProfile(accountID string) (*models.AccountProfile, error) {
+	profile := &models.AccountProfile{}
+	err := getTable().Get("accId", accountID).One(profile)
+	if err == dynamo.ErrNotFound {
+		return &models.AccountProfile{AccountID: accountID}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// newUpdate starts an update of the profile fields, creating the profile if needed.
+func newUpdate(accountID string) *dynamo.Update {
+	return getTable().Update("accId", accountID).
+		Set("updateTm", time.Now().UnixNano()/int64(time.Millisecond))
+}
+
+// MarkNamesEdited updates the profile when the names of an account are edited in the account database,
+// so the profile update time is the last change of the default avatar initials.
+func MarkNamesEdited(accountID string) error {
+	return newUpdate(accountID).Run()
+}
+
+// SetAvatarPreset sets the preset avatar of an account.
+func SetAvatarPreset(accountID string, presetID string) error {
+	return newUpdate(accountID).Set("avatarPresetId", presetID).Run()
+}
+
+// RemoveAvatarPreset removes the preset avatar of an account.
+func RemoveAvatarPreset(accountID string) error {
+	return newUpdate(accountID).Remove("avatarPresetId").Run()
+}
+
+// SetChild sets whether an account is a child account.
+func SetChild(accountID string, isChild bool) error {
+	if isChild {
+		return newUpdate(accountID).Set("isChild", true).Run()
+	}
+	return newUpdate(accountID).Remove("isChild").Run()
+}
+
+// SetParent marks an account as a child profile managed by a parent account.
+func SetParent(accountID string, parentID string) error {
+	return newUpdate(accountID).Set("isChild", true).Set("parentId", parentID).Run()
+}
+
+// NewChildCountUpdate returns the update of the number of child profiles managed by a parent account.
+// Adding child profiles fails the update when the parent would manage more than defs.MaxChildProfiles.
+// It can be run directly or as part of a write transaction.
+func NewChildCountUpdate(parentID string, delta int) *dynamo.Update {
+	update := newUpdate(parentID).Add("childCount", delta)
+	if delta > 0 {
+		update = update.If("attribute_not_exists($) OR $ <= ?", "childCount", "childCount", defs.MaxChildProfiles-delta)
+	}
+	return update
+}
+
+// SetParentalConsentStatus sets the parental consent status of a child account.
+func SetParentalConsentStatus(accountID string, status string) error {
+	return newUpdate(accountID).Set("consentStatus", status).Run()
+}
+
+// GetAgeBand returns the age band of a profile, which is updated from the birth date as the account gets older.
+func GetAgeBand(profile *models.AccountProfile, now time.Time) string {
+	if profile.BirthYear == 0 {
+		return profile.AgeBand
+	}
+	return defs.GetAgeBand(profile.BirthYear, profile.BirthMonth, getAgeBandCountry(profile), now)
+}
+
+// IsChildAccount checks if a profile is the one of a child, either flagged as a child or in the child age band.
+func IsChildAccount(profile *models.AccountProfile, now time.Time) bool {
+	return profile.IsChild || GetAgeBand(profile, now) == defs.AgeBandChild
+}
+
+// GetVisibilitySettings returns the visibility settings of a profile, the profiles of children being at most visible to their organisations.
+func GetVisibilitySettings(profile *models.AccountProfile, now time.Time) visibility.Settings {
+	settings := visibility.Settings(profile.Visibility)
+	if IsChildAccount(profile, now) {
+		return settings.Capped(visibility.LevelOrganization)
+	}
+	return settings
+}
+
+// getAgeBandCountry returns the country of the age band thresholds, which the country corrected by the user overrides.
+func getAgeBandCountry(profile *models.AccountProfile) string {
+	if len(profile.Country) > 0 {
+		return profile.Country
+	}
+	return profile.AgeBandCountry
+}
+
+// SetAgeBand stores the age band of an account from its birth date, in the country of its thresholds.
+func SetAgeBand(accountID string, birthYear int, birthMonth int, countryCode string) error {
+	ageBand := defs.GetAgeBand(birthYear, birthMonth, countryCode, time.Now())
+	if len(ageBand) == 0 {
+		return newUpdate(accountID).Remove("ageBand", "ageBandCountry").Run()
+	}
+	return newUpdate(accountID).Set("ageBand", ageBand).Set("ageBandCountry", countryCode).Run()
+}
+
+// SetBirthDate sets the birth date given at sign-up and stores the age band in the country of the account.
+func SetBirthDate(accountID string, birthYear int, birthMonth int, countryCode string) error {
+	err := EditProfile(accountID, &ProfileEdit{
+		BirthYear:  &birthYear,
+		BirthMonth: birthMonth,
+	})
+	if err != nil {
+		return err
+	}
+	return SetAgeBand(accountID, birthYear, birthMonth, countryCode)
+}
+
+// RefreshAgeBand stores the age band of an account after its birth date or country changed.
+// The country of the account is used when the profile has none.
+func RefreshAgeBand(accountID string) error {
+	profile, err := GetProfile(accountID)
+	if err != nil {
+		return err
+	}
+
+	countryCode := getAgeBandCountry(profile)
+	if len(countryCode) == 0 {
+		accInfo, err := globals.AccountDatabase.GetAccountInfo(accountID)
+		if err != nil {
+			return err
+		} else if accInfo != nil {
+			countryCode = accInfo.Country
+		}
+	}
+	return SetAgeBand(accountID, profile.BirthYear, profile.BirthMonth, countryCode)
+}
+
+// DeleteProfile deletes the profile of an account.
+func DeleteProfile(accountID string) error {
+	return getTable().Delete("accId", accountID).Run()
+}
+
+// SetVisibility sets the visibility levels of some profile fields, keeping the other ones.
+func SetVisibility(accountID string, levels map[string]string) error {
+	entries := make(map[string]interface{}, len(levels))
+	for field, level := range levels {
+		entries[field] = level
+	}
+	return setMapEntries(accountID, "visibility", entries)
+}
+
+// newMapUpdate starts an update of some entries of a map of the profile, which must already exist.
+// Each entry is updated by its own path so the concurrent updates of the other entries are kept.
+func newMapUpdate(accountID string, attribute string, entries map[string]interface{}, removed []string) *dynamo.Update {
+	update := newUpdate(accountID).If("attribute_exists($)", attribute)
+	for key, value := range entries {
+		update.SetExpr("$.$ = ?", attribute, key, value)
+	}
+	for _, key := range removed {
+		update.RemoveExpr("$.$", attribute, key)
+	}
+	return update
+}
+
+// createMap creates a map of the profile, unless it was created in the meantime.
+func createMap(accountID string, attribute string, entries map[string]interface{}) (bool, error) {
+	err := newUpdate(accountID).Set(attribute, entries).If("attribute_not_exists($)", attribute).Run()
+	if isConditionalCheckFailed(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// newNestedMapUpdate starts an update of some entries of a map nested in a map of the profile, which must already exist.
+func newNestedMapUpdate(accountID string, attribute string, key string, entries map[string]interface{}) *dynamo.Update {
+	update := newUpdate(accountID).If("attribute_exists($.$)", attribute, key)
+	for entryKey, value := range entries {
+		update.SetExpr("$.$.$ = ?", attribute, key, entryKey, value)
+	}
+	return update
+}
+
+// setNestedMapEntries sets some entries of a map nested in a map of the profile, keeping the other ones.
+func setNestedMapEntries(accountID string, attribute string, key string, entries map[string]interface{}) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	err := newNestedMapUpdate(accountID, attribute, key, entries).Run()
+	if !isConditionalCheckFailed(err) {
+		return err
+	}
+
+	// The nested map doesn't exist yet, unless it is created concurrently and the entries are set again
+	err = newUpdate(accountID).
+		SetExpr("$.$ = ?", attribute, key, entries).
+		If("attribute_exists($)", attribute).
+		If("attribute_not_exists($.$)", attribute, key).
+		Run()
+	if !isConditionalCheckFailed(err) {
+		return err
+	}
+
+	isCreated, err := createMap(accountID, attribute, map[string]interface{}{key: entries})
+	if err != nil || isCreated {
+		return err
+	}
+	return newNestedMapUpdate(accountID, attribute, key, entries).Run()
+}
+
+// setMapEntries sets some entries of a map of the profile, keeping the other ones.
+func setMapEntries(accountID string, attribute string, entries map[string]interface{}) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	err := newMapUpdate(accountID, attribute, entries, nil).Run()
+	if !isConditionalCheckFailed(err) {
+		return err
+	}
+
+	// The map doesn't exist yet, unless it is created concurrently and the entries are set again
+	isCreated, err := createMap(accountID, attribute, entries)
+	if err != nil || isCreated {
+		return err
+	}
+	return newMapUpdate(accountID, attribute, entries, nil).Run()
+}
+
+func isConditionalCheckFailed(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	return ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
+
+// SetNotifications sets the statuses of some notification categories by channel, keeping the other ones.
+func SetNotifications(accountID string, statuses map[string]map[string]string) error {
+	for category, channels := range statuses {
+		entries := make(map[string]interface{}, len(channels))
+		for channel, status := range channels {
+			entries[channel] = status
+		}
+
+		err := setNestedMapEntries(accountID, "notifications", category, entries)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewNotificationStatusUpdate returns the update of the status of a notification category on a channel, which fails
+// unless the status is still the current one. It can be run directly or as part of a write transaction.
+func NewNotificationStatusUpdate(accountID string, category string, channel string, status string, currentStatus string) *dynamo.Update {
+	return newUpdate(accountID).
+		SetExpr("$.$.$ = ?", "notifications", category, channel, status).
+		If("$.$.$ = ?", "notifications", category, channel, currentStatus)
+}
+
+// GetProfiles returns the profiles of several accounts, mapped by account ID.
+// Accounts that never changed their profile get an empty one.
+func GetProfiles(accountIDs []string) (map[string]*models.AccountProfile, error) {
+	profiles := make(map[string]*models.AccountProfile, len(accountIDs))
+	if len(accountIDs) == 0 {
+		return profiles, nil
+	}
+
+	keys := make([]dynamo.Keyed, len(accountIDs))
+	for i, accountID := range accountIDs {
+		keys[i] = dynamo.Keys{accountID}
+	}
+
+	var items []*models.AccountProfile
+	err := getTable().Batch("accId").Get(keys...).All(&items)
+	if err != nil && err != dynamo.ErrNotFound {
+		return nil, err
+	}
+
+	for _, item := range items {
+		profiles[item.AccountID] = item
+	}
+	for _, accountID := range accountIDs {
+		if _, exists := profiles[accountID]; !exists {
+			profiles[accountID] = &models.AccountProfile{AccountID: accountID}
+		}
+	}
+	return profiles, nil
+}
+
+// SetAcceptedPolicies sets the last accepted versions of some policies, keeping the other ones.
+func SetAcceptedPolicies(accountID string, versions map[string]string) error {
+	entries := make(map[string]interface{}, len(versions))
+	for policy, version := range versions {
+		entries[policy] = version
+	}
+	return setMapEntries(accountID, "acceptedPolicies", entries)
+}
+
+// ProfileEdit are the profile fields to change.
+// The nil fields are left unchanged and the empty ones are removed.
+type ProfileEdit struct {
+	DisplayName *string
+	// BirthYear and BirthMonth are changed together, a zero year removes the birth date
+	BirthYear     *int
+	BirthMonth    int
+	Timezone      *string
+	Country       *string
+	PhoneticNames *models.PhoneticNames
+	// LocalizedNames are the name variants to set by language tag, a nil variant is removed
+	LocalizedNames map[string]*models.LocalizedName
+}
+
+// ErrTooManyLocalizedNames is returned when an account would have more than defs.MaxLocalizedNames name variants.
+var ErrTooManyLocalizedNames = errors.New("Too many localized names")
+
+// maxEditAttempts is how many times a profile edit is tried when its localized names are changed concurrently.
+const maxEditAttempts = 3
+
+// EditProfile changes the profile fields of an account.
+func EditProfile(accountID string, edit *ProfileEdit) error {
+	for attempt := 1; ; attempt++ {
+		update, err := newEditUpdate(accountID, edit)
+		if err != nil {
+			return err
+		}
+
+		// Only the localized names are conditional, so a failed condition means they changed since they were read
+		err = update.Run()
+		if !isConditionalCheckFailed(err) || attempt == maxEditAttempts {
+			return err
+		}
+	}
+}
+
+func newEditUpdate(accountID string, edit *ProfileEdit) (*dynamo.Update, error) {
+	update := newUpdate(accountID)
+	setOrRemoveString(update, "displayName", edit.DisplayName)
+	setOrRemoveString(update, "timezone", edit.Timezone)
+	setOrRemoveString(update, "country", edit.Country)
+
+	if edit.BirthYear != nil {
+		if *edit.BirthYear == 0 {
+			update.Remove("birthYear", "birthMonth")
+		} else if edit.BirthMonth == 0 {
+			update.Set("birthYear", *edit.BirthYear).Remove("birthMonth")
+		} else {
+			update.Set("birthYear", *edit.BirthYear).Set("birthMonth", edit.BirthMonth)
+		}
+	}
+
+	if names := edit.PhoneticNames; names != nil {
+		if len(names.FullName) == 0 && len(names.FirstName) == 0 && len(names.LastName) == 0 {
+			update.Remove("phoneticNames")
+		} else {
+			update.Set("phoneticNames", names)
+		}
+	}
+
+	if len(edit.LocalizedNames) > 0 {
+		profile, err := GetProfile(accountID)
+		if err != nil {
+			return nil, err
+		}
+		if err := editLocalizedNames(update, profile.LocalizedNames, edit.LocalizedNames); err != nil {
+			return nil, err
+		}
+	}
+
+	return update, nil
+}
+
+// editLocalizedNames adds to an update the changes of the localized names, entry by entry so that the concurrent
+// changes of other variants are kept. The update is conditioned on the variants that were read, and on the size
+// of the map so that it can't exceed defs.MaxLocalizedNames.
+func editLocalizedNames(update *dynamo.Update, current map[string]*models.LocalizedName, edits map[string]*models.LocalizedName) error {
+	if current == nil {
+		created := make(map[string]*models.LocalizedName, len(edits))
+		for tag, names := range edits {
+			if names != nil {
+				created[tag] = names
+			}
+		}
+
+		if len(created) > defs.MaxLocalizedNames {
+			return ErrTooManyLocalizedNames
+		} else if len(created) > 0 {
+			update.Set("localizedNames", created).If("attribute_not_exists(localizedNames)")
+		}
+		return nil
+	}
+
+	count, added := len(current), 0
+	for tag, names := range edits {
+		_, exists := current[tag]
+		if names == nil {
+			if exists {
+				update.RemoveExpr("localizedNames.$", tag)
+				count--
+			}
+		} else {
+			update.SetExpr("localizedNames.$ = ?", tag, names)
+			if exists {
+				update.If("attribute_exists(localizedNames.$)", tag)
+			} else {
+				added++
+				count++
+			}
+		}
+	}
+
+	if count > defs.MaxLocalizedNames {
+		return ErrTooManyLocalizedNames
+	} else if added > 0 {
+		update.If("size(localizedNames) <= ?", defs.MaxLocalizedNames-added)
+	}
+	return nil
+}
+
+func setOrRemoveString(update *dynamo.Update, path string, value *string) {
+	if value == nil {
+		return
+	} else if len(*value) == 0 {
+		update.Remove(path)
+	} else {
+		update.Set(path, *value)
+	}
+}
@@ -0,0 +1,127 @@
+package purchaseservice
+
+import (
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/purchaseverification"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/transactionservice"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/calmisland/go-errors"
+	"github.com/guregu/dynamo"
+)
+
+var (
+	// ErrPurchaseOwnedByOtherAccount is returned when a purchase has already been applied to another account.
+	ErrPurchaseOwnedByOtherAccount = errors.New("The purchase belongs to another account")
+)
+
+func getTable() dynamo.Table {
+	return models.GetDB().Table(models.GetTableName(models.TABLE_NAME_STORE_PURCHASES))
+}
+
+// TransactionID returns the ID of the account transaction of a store purchase.
+func TransactionID(store string, storeTransactionID string) string {
+	return store + "-" + storeTransactionID
+}
+
+// NewPurchaseTransaction returns the account transaction of a verified store purchase.
+// The subscriptions are passes and the one-time purchases are products, and a refunded purchase expires when refunded.
+func NewPurchaseTransaction(accountID string, store string, purchase *purchaseverification.Purchase, now int64) *models.AccountTransaction {
+	item := &models.AccountTransactionItem{
+		Price:          purchase.Price,
+		Currency:       purchase.Currency,
+		StartDate:      int(purchase.PurchaseDate),
+		ExpirationDate: int(purchase.ExpirationDate),
+	}
+	if purchase.RefundDate > 0 && (item.ExpirationDate == 0 || int(purchase.RefundDate) < item.ExpirationDate) {
+		item.ExpirationDate = int(purchase.RefundDate)
+		if item.ExpirationDate < item.StartDate {
+			item.ExpirationDate = item.StartDate
+		}
+	}
+
+	transaction := &models.AccountTransaction{
+		AccountID:     accountID,
+		TransactionID: TransactionID(store, purchase.TransactionID),
+		CreatedDate:   int(now),
+		UpdatedDate:   int(now),
+		Sandbox:       purchase.Sandbox,
+	}
+	items := map[string]*models.AccountTransactionItem{
+		purchase.ProductID: item,
+	}
+	if purchase.ExpirationDate > 0 {
+		transaction.Passes = items
+	} else {
+		transaction.Products = items
+	}
+	return transaction
+}
+
+// ApplyPurchases writes the transactions of verified store purchases to an account and returns them.
+// The transactions are identified by their store transaction IDs, so applying the same purchases again updates them,
+// which records the renewals and the refunds.
+func ApplyPurchases(accountID string, store string, purchases []*purchaseverification.Purchase) ([]*models.AccountTransaction, error) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+
+	claimedPurchaseIDs := map[string]bool{}
+	for _, purchase := range purchases {
+		purchaseID := store + ":" + purchase.OriginalTransactionID
+		if claimedPurchaseIDs[purchaseID] {
+			continue
+		}
+
+		err := claimPurchase(accountID, purchaseID, now)
+		if err != nil {
+			return nil, err
+		}
+		claimedPurchaseIDs[purchaseID] = true
+	}
+
+	transactions := make([]*models.AccountTransaction, 0, len(purchases))
+	for _, purchase := range purchases {
+		transaction, err := transactionservice.UpsertTransaction(NewPurchaseTransaction(accountID, store, purchase, now))
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions, nil
+}
+
+// claimPurchase records that a purchase belongs to an account, unless it already belongs to another one.
+func claimPurchase(accountID string, purchaseID string, now int64) error {
+	err := getTable().Update("purchaseId", purchaseID).
+		Set("accId", accountID).
+		SetIfNotExists("createTm", now).
+		If("attribute_not_exists($) OR $ = ?", "purchaseId", "accId", accountID).
+		Run()
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return ErrPurchaseOwnedByOtherAccount
+	}
+	return err
+}
+
+// ReleasePurchases deletes the purchases owned by an account, so their receipts can be applied to another account.
+func ReleasePurchases(accountID string) error {
+	var purchases []models.StorePurchase
+	err := getTable().Get("accId", accountID).Index(models.STORE_PURCHASE_GSI_ACCID).All(&purchases)
+	if err != nil && err != dynamo.ErrNotFound {
+		return err
+	}
+
+	for _, purchase := range purchases {
+		err = getTable().Delete("purchaseId", purchase.PurchaseID).
+			If("$ = ?", "accId", accountID).
+			Run()
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			// The index is eventually consistent, and the purchase was claimed by another account since
+			continue
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
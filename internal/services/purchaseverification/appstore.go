@@ -0,0 +1,179 @@
+package purchaseverification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	appStoreProductionURL = "https://buy.itunes.apple.com/verifyReceipt"
+	appStoreSandboxURL    = "https://sandbox.itunes.apple.com/verifyReceipt"
+
+	// appStoreStatusSandboxReceipt is returned by the production environment for receipts of the sandbox environment.
+	appStoreStatusSandboxReceipt = 21007
+
+	defaultStoreTimeoutSeconds = 10
+	maxStoreResponseSize       = 4 * 1024 * 1024
+)
+
+// The App Store environments that can be configured.
+const (
+	AppStoreEnvironmentProduction = "production"
+	AppStoreEnvironmentSandbox    = "sandbox"
+)
+
+// AppStoreConfig is the configuration of the App Store verifier.
+type AppStoreConfig struct {
+	// BundleID is the bundle ID of the app, the App Store is not supported if not set.
+	BundleID string `env:"AMS_PURCHASE_APPSTORE_BUNDLE_ID"`
+	// SharedSecret is the app-specific shared secret, needed for the receipts with subscriptions.
+	SharedSecret string `env:"AMS_PURCHASE_APPSTORE_SHARED_SECRET"`
+	// TimeoutSeconds is the request timeout.
+	TimeoutSeconds int `env:"AMS_PURCHASE_STORE_TIMEOUT"`
+	// Environment is the environment of the accepted receipts, production by default.
+	// The sandbox environment also accepts the production receipts, and must only be set outside of production.
+	Environment string `env:"AMS_PURCHASE_APPSTORE_ENVIRONMENT"`
+}
+
+type appStoreVerifier struct {
+	bundleID     string
+	sharedSecret string
+	allowSandbox bool
+	client       *http.Client
+}
+
+type appStoreRequest struct {
+	ReceiptData            string `json:"receipt-data"`
+	Password               string `json:"password,omitempty"`
+	ExcludeOldTransactions bool   `json:"exclude-old-transactions"`
+}
+
+type appStoreTransaction struct {
+	TransactionID         string `json:"transaction_id"`
+	OriginalTransactionID string `json:"original_transaction_id"`
+	ProductID             string `json:"product_id"`
+	PurchaseDateMS        string `json:"purchase_date_ms"`
+	ExpiresDateMS         string `json:"expires_date_ms"`
+	CancellationDateMS    string `json:"cancellation_date_ms"`
+}
+
+type appStoreResponse struct {
+	Status  int `json:"status"`
+	Receipt struct {
+		BundleID string                 `json:"bundle_id"`
+		InApp    []*appStoreTransaction `json:"in_app"`
+	} `json:"receipt"`
+	LatestReceiptInfo []*appStoreTransaction `json:"latest_receipt_info"`
+}
+
+// NewAppStoreVerifier creates a verifier of App Store receipts.
+func NewAppStoreVerifier(config AppStoreConfig) Verifier {
+	timeoutSeconds := config.TimeoutSeconds
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = defaultStoreTimeoutSeconds
+	}
+
+	return &appStoreVerifier{
+		bundleID:     config.BundleID,
+		sharedSecret: config.SharedSecret,
+		allowSandbox: config.Environment == AppStoreEnvironmentSandbox,
+		client: &http.Client{
+			Timeout: time.Duration(timeoutSeconds) * time.Second,
+		},
+	}
+}
+
+// Verify returns the purchases of a receipt.
+// Apple recommends verifying with the production environment first, and with the sandbox if the receipt is from there.
+// The sandbox receipts are refused unless the sandbox environment is configured, and their purchases are marked.
+func (verifier *appStoreVerifier) Verify(receipt *Receipt) ([]*Purchase, error) {
+	if len(receipt.Data) == 0 {
+		return nil, ErrInvalidReceipt
+	}
+
+	isSandbox := false
+	resp, err := verifier.post(appStoreProductionURL, receipt.Data)
+	if err != nil {
+		return nil, err
+	} else if resp.Status == appStoreStatusSandboxReceipt {
+		if !verifier.allowSandbox {
+			return nil, ErrSandboxReceipt
+		}
+
+		isSandbox = true
+		resp, err = verifier.post(appStoreSandboxURL, receipt.Data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.Status != 0 || resp.Receipt.BundleID != verifier.bundleID {
+		return nil, ErrInvalidReceipt
+	}
+
+	// The latest receipt info has all the renewals of the subscriptions, but only if a shared secret was sent
+	transactions := resp.LatestReceiptInfo
+	if len(transactions) == 0 {
+		transactions = resp.Receipt.InApp
+	}
+
+	purchases := make([]*Purchase, 0, len(transactions))
+	seenTransactionIDs := map[string]bool{}
+	for _, transaction := range transactions {
+		if seenTransactionIDs[transaction.TransactionID] {
+			continue
+		}
+		seenTransactionIDs[transaction.TransactionID] = true
+
+		purchase := &Purchase{
+			TransactionID:         transaction.TransactionID,
+			OriginalTransactionID: transaction.OriginalTransactionID,
+			ProductID:             transaction.ProductID,
+			PurchaseDate:          parseEpochMS(transaction.PurchaseDateMS),
+			ExpirationDate:        parseEpochMS(transaction.ExpiresDateMS),
+			RefundDate:            parseEpochMS(transaction.CancellationDateMS),
+			Sandbox:               isSandbox,
+		}
+		if len(purchase.OriginalTransactionID) == 0 {
+			purchase.OriginalTransactionID = purchase.TransactionID
+		}
+		purchases = append(purchases, purchase)
+	}
+	return purchases, nil
+}
+
+func (verifier *appStoreVerifier) post(url string, receiptData string) (*appStoreResponse, error) {
+	reqBody, err := json.Marshal(&appStoreRequest{
+		ReceiptData: receiptData,
+		Password:    verifier.sharedSecret,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := verifier.client.Post(url, "application/json", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unexpected status code from the App Store: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxStoreResponseSize))
+	if err != nil {
+		return nil, err
+	}
+
+	storeResp := &appStoreResponse{}
+	err = json.Unmarshal(body, storeResp)
+	if err != nil {
+		return nil, err
+	}
+	return storeResp, nil
+}
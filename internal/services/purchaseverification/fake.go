@@ -0,0 +1,32 @@
+package purchaseverification
+
+import (
+	"encoding/json"
+)
+
+type fakeVerifier struct{}
+
+// NewFakeVerifier creates a verifier for local tests, which trusts any store.
+// It can't be configured, so it's only used by the test setup.
+// The receipt data is the JSON array of the purchases it contains.
+func NewFakeVerifier() Verifier {
+	return &fakeVerifier{}
+}
+
+// Verify returns the purchases of a receipt.
+func (verifier *fakeVerifier) Verify(receipt *Receipt) ([]*Purchase, error) {
+	var purchases []*Purchase
+	err := json.Unmarshal([]byte(receipt.Data), &purchases)
+	if err != nil || len(purchases) == 0 {
+		return nil, ErrInvalidReceipt
+	}
+
+	for _, purchase := range purchases {
+		if purchase == nil || len(purchase.TransactionID) == 0 || len(purchase.ProductID) == 0 {
+			return nil, ErrInvalidReceipt
+		} else if len(purchase.OriginalTransactionID) == 0 {
+			purchase.OriginalTransactionID = purchase.TransactionID
+		}
+	}
+	return purchases, nil
+}
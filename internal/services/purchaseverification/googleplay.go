@@ -0,0 +1,270 @@
+package purchaseverification
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/calmisland/go-errors"
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	googlePlayAPIURL = "https://androidpublisher.googleapis.com/androidpublisher/v3/applications/"
+	googlePlayScope  = "https://www.googleapis.com/auth/androidpublisher"
+
+	googlePlayPurchaseStatePurchased = 0
+	googlePlayPurchaseStateCanceled  = 1
+
+	// googlePlayTokenExpirationMargin renews the access tokens before they expire.
+	googlePlayTokenExpirationMargin = time.Minute
+)
+
+// GooglePlayConfig is the configuration of the Google Play verifier.
+type GooglePlayConfig struct {
+	// PackageName is the package name of the app, Google Play is not supported if not set.
+	PackageName string `env:"AMS_PURCHASE_GOOGLEPLAY_PACKAGE_NAME"`
+	// ServiceAccountKey is the JSON key of the service account that has access to the Google Play Developer API.
+	ServiceAccountKey string `env:"AMS_PURCHASE_GOOGLEPLAY_SERVICE_ACCOUNT_KEY"`
+	// TimeoutSeconds is the request timeout.
+	TimeoutSeconds int `env:"AMS_PURCHASE_STORE_TIMEOUT"`
+}
+
+type googleServiceAccountKey struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+type googleAccessTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+type googlePlaySubscriptionPurchase struct {
+	OrderID           string `json:"orderId"`
+	StartTimeMillis   string `json:"startTimeMillis"`
+	ExpiryTimeMillis  string `json:"expiryTimeMillis"`
+	PriceAmountMicros string `json:"priceAmountMicros"`
+	PriceCurrencyCode string `json:"priceCurrencyCode"`
+}
+
+type googlePlayProductPurchase struct {
+	OrderID            string `json:"orderId"`
+	PurchaseTimeMillis string `json:"purchaseTimeMillis"`
+	PurchaseState      int    `json:"purchaseState"`
+}
+
+type googlePlayVerifier struct {
+	packageName string
+	clientEmail string
+	tokenURI    string
+	privateKey  interface{}
+	client      *http.Client
+
+	tokenMutex           sync.Mutex
+	accessToken          string
+	accessTokenExpiresAt time.Time
+}
+
+// NewGooglePlayVerifier creates a verifier of Google Play purchase tokens.
+func NewGooglePlayVerifier(config GooglePlayConfig) (Verifier, error) {
+	serviceAccountKey := &googleServiceAccountKey{}
+	err := json.Unmarshal([]byte(config.ServiceAccountKey), serviceAccountKey)
+	if err != nil {
+		return nil, errors.New("The Google Play service account key is invalid")
+	}
+
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(serviceAccountKey.PrivateKey))
+	if err != nil {
+		return nil, err
+	}
+
+	timeoutSeconds := config.TimeoutSeconds
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = defaultStoreTimeoutSeconds
+	}
+
+	return &googlePlayVerifier{
+		packageName: config.PackageName,
+		clientEmail: serviceAccountKey.ClientEmail,
+		tokenURI:    serviceAccountKey.TokenURI,
+		privateKey:  privateKey,
+		client: &http.Client{
+			Timeout: time.Duration(timeoutSeconds) * time.Second,
+		},
+	}, nil
+}
+
+// Verify returns the purchase of a purchase token.
+// Google Play only returns the latest renewal of a subscription, and a revoked subscription expires when it was revoked.
+func (verifier *googlePlayVerifier) Verify(receipt *Receipt) ([]*Purchase, error) {
+	if len(receipt.Data) == 0 || len(receipt.ProductID) == 0 {
+		return nil, ErrInvalidReceipt
+	}
+
+	if receipt.Subscription {
+		return verifier.verifySubscription(receipt)
+	}
+	return verifier.verifyProduct(receipt)
+}
+
+func (verifier *googlePlayVerifier) verifySubscription(receipt *Receipt) ([]*Purchase, error) {
+	subscription := &googlePlaySubscriptionPurchase{}
+	err := verifier.get("subscriptions", receipt, subscription)
+	if err != nil {
+		return nil, err
+	}
+
+	priceMicros, _ := strconv.ParseInt(subscription.PriceAmountMicros, 10, 64)
+	purchase := &Purchase{
+		ProductID:      receipt.ProductID,
+		Price:          microsToMinorUnits(priceMicros, subscription.PriceCurrencyCode),
+		Currency:       subscription.PriceCurrencyCode,
+		PurchaseDate:   parseEpochMS(subscription.StartTimeMillis),
+		ExpirationDate: parseEpochMS(subscription.ExpiryTimeMillis),
+	}
+	setGooglePlayTransactionIDs(purchase, subscription.OrderID, receipt.Data)
+	return []*Purchase{purchase}, nil
+}
+
+func (verifier *googlePlayVerifier) verifyProduct(receipt *Receipt) ([]*Purchase, error) {
+	product := &googlePlayProductPurchase{}
+	err := verifier.get("products", receipt, product)
+	if err != nil {
+		return nil, err
+	}
+
+	purchase := &Purchase{
+		ProductID:    receipt.ProductID,
+		PurchaseDate: parseEpochMS(product.PurchaseTimeMillis),
+	}
+	switch product.PurchaseState {
+	case googlePlayPurchaseStatePurchased:
+	case googlePlayPurchaseStateCanceled:
+		// Google Play doesn't tell when a purchase was canceled, so it's refunded from now on
+		purchase.RefundDate = time.Now().UnixNano() / int64(time.Millisecond)
+	default:
+		// The pending purchases haven't been paid yet
+		return nil, ErrInvalidReceipt
+	}
+	setGooglePlayTransactionIDs(purchase, product.OrderID, receipt.Data)
+	return []*Purchase{purchase}, nil
+}
+
+// setGooglePlayTransactionIDs sets the transaction IDs of a purchase from its order ID.
+// The renewals of a subscription have the order ID of the first purchase followed by "..N".
+func setGooglePlayTransactionIDs(purchase *Purchase, orderID string, purchaseToken string) {
+	if len(orderID) == 0 {
+		// The test purchases may have no order ID
+		orderID = purchaseToken
+	}
+
+	purchase.TransactionID = orderID
+	purchase.OriginalTransactionID = strings.SplitN(orderID, "..", 2)[0]
+}
+
+func (verifier *googlePlayVerifier) get(purchaseType string, receipt *Receipt, out interface{}) error {
+	accessToken, err := verifier.getAccessToken()
+	if err != nil {
+		return err
+	}
+
+	reqURL := googlePlayAPIURL + url.PathEscape(verifier.packageName) + "/purchases/" + purchaseType + "/" +
+		url.PathEscape(receipt.ProductID) + "/tokens/" + url.PathEscape(receipt.Data)
+	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := verifier.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusGone:
+		return ErrInvalidReceipt
+	default:
+		return fmt.Errorf("Unexpected status code from Google Play: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxStoreResponseSize))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, out)
+}
+
+// getAccessToken returns an access token of the service account, which is cached until it expires.
+func (verifier *googlePlayVerifier) getAccessToken() (string, error) {
+	verifier.tokenMutex.Lock()
+	defer verifier.tokenMutex.Unlock()
+
+	now := time.Now()
+	if len(verifier.accessToken) > 0 && now.Before(verifier.accessTokenExpiresAt) {
+		return verifier.accessToken, nil
+	}
+
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   verifier.clientEmail,
+		"scope": googlePlayScope,
+		"aud":   verifier.tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(verifier.privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := verifier.client.PostForm(verifier.tokenURI, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Unexpected status code from the Google token endpoint: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxStoreResponseSize))
+	if err != nil {
+		return "", err
+	}
+
+	tokenResp := &googleAccessTokenResponse{}
+	err = json.Unmarshal(body, tokenResp)
+	if err != nil {
+		return "", err
+	}
+
+	verifier.accessToken = tokenResp.AccessToken
+	verifier.accessTokenExpiresAt = now.Add(time.Duration(tokenResp.ExpiresIn)*time.Second - googlePlayTokenExpirationMargin)
+	return verifier.accessToken, nil
+}
+
+// zeroDecimalCurrencies are the currencies without a minor unit.
+var zeroDecimalCurrencies = map[string]bool{
+	"CLP": true, "ISK": true, "JPY": true, "KRW": true, "PYG": true,
+	"UGX": true, "VND": true, "XAF": true, "XOF": true,
+}
+
+// microsToMinorUnits converts a price in millionths of a currency to the smallest unit of the currency.
+func microsToMinorUnits(micros int64, currency string) int {
+	if zeroDecimalCurrencies[strings.ToUpper(currency)] {
+		return int(micros / 1000000)
+	}
+	return int(micros / 10000)
+}
@@ -0,0 +1,111 @@
+package purchaseverification
+
+import (
+	"strconv"
+
+	"github.com/calmisland/go-errors"
+)
+
+// The stores that purchases can be verified with.
+const (
+	StoreAppStore   = "appstore"
+	StoreGooglePlay = "googleplay"
+)
+
+var (
+	// ErrUnsupportedStore is returned when the purchases of a store can't be verified.
+	ErrUnsupportedStore = errors.New("The store is not supported")
+	// ErrInvalidReceipt is returned when the store rejects a receipt.
+	ErrInvalidReceipt = errors.New("The receipt is invalid")
+	// ErrSandboxReceipt is returned when a receipt is from the sandbox environment of a store, which is not allowed.
+	ErrSandboxReceipt = errors.New("The receipt is from the sandbox environment")
+)
+
+// Receipt is a proof of purchase sent by an app.
+type Receipt struct {
+	Store string
+	// Data is the base64 encoded receipt for the App Store, and the purchase token for Google Play.
+	Data string
+	// ProductID is the purchased product, only needed for Google Play.
+	ProductID string
+	// Subscription tells if the product is a subscription, only needed for Google Play.
+	Subscription bool
+}
+
+// Purchase is a purchase verified with a store.
+// A subscription renewal is a purchase of its own, with the original transaction ID of the first purchase.
+type Purchase struct {
+	// TransactionID is the store ID of the purchase, which is unique in its store.
+	TransactionID string `json:"transactionId"`
+	// OriginalTransactionID is the store ID of the first purchase of a subscription, or the purchase itself.
+	OriginalTransactionID string `json:"originalTransactionId"`
+	ProductID             string `json:"productId"`
+	// Price is in the smallest unit of the currency, zero if the store doesn't report it.
+	Price    int    `json:"price"`
+	Currency string `json:"currency"`
+	// PurchaseDate is when the purchase period starts, in epoch milliseconds.
+	PurchaseDate int64 `json:"purchaseTm"`
+	// ExpirationDate is when a subscription period ends, in epoch milliseconds, zero for one-time purchases.
+	ExpirationDate int64 `json:"expirationTm"`
+	// RefundDate is when the purchase was refunded or revoked, in epoch milliseconds, zero if it wasn't.
+	RefundDate int64 `json:"refundTm"`
+	// Sandbox tells that the purchase was made in the sandbox environment of the store, so it wasn't paid.
+	Sandbox bool `json:"sandbox,omitempty"`
+}
+
+// Verifier verifies receipts with their store.
+type Verifier interface {
+	// Verify returns the purchases of a receipt. ErrInvalidReceipt is returned if the store rejects it.
+	Verify(receipt *Receipt) ([]*Purchase, error)
+}
+
+type storeVerifier struct {
+	verifiers map[string]Verifier
+}
+
+// New creates the purchase verifier of the stores, which only supports the stores that are configured.
+func New(appStoreConfig AppStoreConfig, googlePlayConfig GooglePlayConfig) (Verifier, error) {
+	switch appStoreConfig.Environment {
+	case "", AppStoreEnvironmentProduction, AppStoreEnvironmentSandbox:
+	default:
+		return nil, errors.New("Unknown App Store environment: " + appStoreConfig.Environment)
+	}
+
+	verifiers := map[string]Verifier{}
+	if len(appStoreConfig.BundleID) > 0 {
+		verifiers[StoreAppStore] = NewAppStoreVerifier(appStoreConfig)
+	}
+	if len(googlePlayConfig.PackageName) > 0 {
+		googlePlayVerifier, err := NewGooglePlayVerifier(googlePlayConfig)
+		if err != nil {
+			return nil, err
+		}
+		verifiers[StoreGooglePlay] = googlePlayVerifier
+	}
+	return NewStoreVerifier(verifiers), nil
+}
+
+// NewStoreVerifier creates a verifier that verifies each receipt with the verifier of its store.
+func NewStoreVerifier(verifiers map[string]Verifier) Verifier {
+	return &storeVerifier{
+		verifiers: verifiers,
+	}
+}
+
+// Verify returns the purchases of a receipt.
+func (verifier *storeVerifier) Verify(receipt *Receipt) ([]*Purchase, error) {
+	storeVerifier, exists := verifier.verifiers[receipt.Store]
+	if !exists {
+		return nil, ErrUnsupportedStore
+	}
+	return storeVerifier.Verify(receipt)
+}
+
+// parseEpochMS parses the epoch milliseconds that the stores return as strings, zero if not set.
+func parseEpochMS(value string) int64 {
+	date, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return date
+}
@@ -0,0 +1,136 @@
+package transactionservice
+
+import (
+	"strconv"
+	"strings"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/calmisland/go-errors"
+	"github.com/guregu/dynamo"
+)
+
+func getTable() dynamo.Table {
+	return models.GetDB().Table(models.GetTableName(models.TABLE_NAME_ACCOUNT_TRANSACTIONS))
+}
+
+// ErrInvalidCursor is returned when a transactions page cursor is malformed.
+var ErrInvalidCursor = errors.New("Invalid transactions cursor")
+
+// ListTransactions returns a page of the transactions of an account, the most recently created first.
+// The cursor is the one returned with the previous page, and the returned cursor is empty on the last page.
+func ListTransactions(accountID string, limit int, cursor string) ([]*models.AccountTransaction, string, error) {
+	query := getTable().Get("accId", accountID).
+		Index(models.ACCOUNT_TRANSACTION_GSI_CREATETM).
+		Order(dynamo.Descending).
+		Limit(int64(limit))
+	if len(cursor) > 0 {
+		createdDate, transactionID, err := parseCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		query = query.StartFrom(dynamo.PagingKey{
+			"accId":         &dynamodb.AttributeValue{S: aws.String(accountID)},
+			"transactionId": &dynamodb.AttributeValue{S: aws.String(transactionID)},
+			"createTm":      &dynamodb.AttributeValue{N: aws.String(createdDate)},
+		})
+	}
+
+	var transactions []*models.AccountTransaction
+	lastEvaluatedKey, err := query.AllWithLastEvaluatedKey(&transactions)
+	if err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	transactionID, hasTransactionID := lastEvaluatedKey["transactionId"]
+	createdDate, hasCreatedDate := lastEvaluatedKey["createTm"]
+	if hasTransactionID && hasCreatedDate && transactionID.S != nil && createdDate.N != nil {
+		nextCursor = *createdDate.N + cursorSeparator + *transactionID.S
+	}
+	return transactions, nextCursor, nil
+}
+
+// cursorSeparator separates the creation date and the transaction ID of the last transaction of a page in a cursor.
+const cursorSeparator = "."
+
+func parseCursor(cursor string) (string, string, error) {
+	parts := strings.SplitN(cursor, cursorSeparator, 2)
+	if len(parts) != 2 || len(parts[1]) == 0 {
+		return "", "", ErrInvalidCursor
+	} else if _, err := strconv.ParseInt(parts[0], 10, 64); err != nil {
+		return "", "", ErrInvalidCursor
+	}
+	return parts[0], parts[1], nil
+}
+
+// ListAllTransactions returns all the transactions of an account.
+func ListAllTransactions(accountID string) ([]*models.AccountTransaction, error) {
+	var transactions []*models.AccountTransaction
+	err := getTable().Get("accId", accountID).All(&transactions)
+	if err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// GetTransaction returns a transaction of an account, or nil if it doesn't exist.
+func GetTransaction(accountID string, transactionID string) (*models.AccountTransaction, error) {
+	transaction := &models.AccountTransaction{}
+	err := getTable().Get("accId", accountID).Range("transactionId", dynamo.Equal, transactionID).One(transaction)
+	if err == dynamo.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// NewTransactionPut returns the write of a new transaction, which fails if the transaction ID is already used.
+// It can be run directly or as part of a write transaction.
+func NewTransactionPut(transaction *models.AccountTransaction) *dynamo.Put {
+	return getTable().Put(transaction).If("attribute_not_exists($)", "transactionId")
+}
+
+// UpsertTransaction writes a transaction and returns it, replacing the passes and products if it already exists.
+// The creation date of an existing transaction is kept, and so is its sandbox flag once set.
+func UpsertTransaction(transaction *models.AccountTransaction) (*models.AccountTransaction, error) {
+	update := getTable().Update("accId", transaction.AccountID).
+		Range("transactionId", transaction.TransactionID).
+		Set("passes", transaction.Passes).
+		Set("products", transaction.Products).
+		Set("updateTm", transaction.UpdatedDate).
+		SetIfNotExists("createTm", transaction.CreatedDate)
+	if transaction.Sandbox {
+		update.Set("sandbox", true)
+	}
+
+	result := &models.AccountTransaction{}
+	err := update.Value(result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ScanAccountIDs returns the account IDs of a page of transactions and the key of the next page, which is nil on the last page.
+// An account ID is returned once per transaction, so it can be repeated within and across pages.
+func ScanAccountIDs(startFrom dynamo.PagingKey, pageSize int64) ([]string, dynamo.PagingKey, error) {
+	scan := getTable().Scan().Project("accId").SearchLimit(pageSize)
+	if startFrom != nil {
+		scan = scan.StartFrom(startFrom)
+	}
+
+	var transactions []*models.AccountTransaction
+	lastKey, err := scan.AllWithLastEvaluatedKey(&transactions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accountIDs := make([]string, len(transactions))
+	for i, transaction := range transactions {
+		accountIDs[i] = transaction.AccountID
+	}
+	return accountIDs, lastKey, nil
+}
@@ -0,0 +1,93 @@
+package visibility
+
+import (
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/organizationservice"
+	"bitbucket.org/calmisland/go-server-account/accountdatabase"
+)
+
+// Resolver resolves the relationship between two accounts.
+type Resolver interface {
+	// Resolve returns the relationship of the caller to the target account.
+	Resolve(callerAccountID string, targetAccountID string) (Relationship, error)
+	// ResolveMany returns the relationship of the caller to several target accounts, mapped by account ID.
+	ResolveMany(callerAccountID string, targetAccountIDs []string) (map[string]Relationship, error)
+}
+
+type standardResolver struct {
+	accountDatabase accountdatabase.Database
+}
+
+// NewResolver creates a resolver based on the admin roles and the organisation memberships.
+func NewResolver(accountDatabase accountdatabase.Database) Resolver {
+	return &standardResolver{
+		accountDatabase: accountDatabase,
+	}
+}
+
+// Resolve returns the relationship of the caller to the target account.
+func (resolver *standardResolver) Resolve(callerAccountID string, targetAccountID string) (Relationship, error) {
+	if callerAccountID == targetAccountID {
+		return RelationshipSelf, nil
+	}
+
+	// Admins can see all the profiles
+	isAdmin, err := resolver.isAdmin(callerAccountID)
+	if err != nil {
+		return RelationshipNone, err
+	} else if isAdmin {
+		return RelationshipSelf, nil
+	}
+
+	isSharing, err := organizationservice.ShareOrganization(callerAccountID, targetAccountID)
+	if err != nil {
+		return RelationshipNone, err
+	} else if isSharing {
+		return RelationshipOrganization, nil
+	}
+
+	return RelationshipNone, nil
+}
+
+// ResolveMany returns the relationship of the caller to several target accounts, mapped by account ID.
+func (resolver *standardResolver) ResolveMany(callerAccountID string, targetAccountIDs []string) (map[string]Relationship, error) {
+	relationships := make(map[string]Relationship, len(targetAccountIDs))
+
+	isAdmin, err := resolver.isAdmin(callerAccountID)
+	if err != nil {
+		return nil, err
+	} else if isAdmin {
+		for _, targetAccountID := range targetAccountIDs {
+			relationships[targetAccountID] = RelationshipSelf
+		}
+		return relationships, nil
+	}
+
+	organizationIDs, err := organizationservice.GetOrganizationIDs(callerAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := organizationservice.FindMembers(organizationIDs, targetAccountIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, targetAccountID := range targetAccountIDs {
+		if targetAccountID == callerAccountID {
+			relationships[targetAccountID] = RelationshipSelf
+		} else if members[targetAccountID] {
+			relationships[targetAccountID] = RelationshipOrganization
+		} else {
+			relationships[targetAccountID] = RelationshipNone
+		}
+	}
+	return relationships, nil
+}
+
+func (resolver *standardResolver) isAdmin(accountID string) (bool, error) {
+	accountInfo, err := resolver.accountDatabase.GetAccountSignInInfoByID(accountID)
+	if err != nil {
+		return false, err
+	}
+	return accountInfo != nil && accountInfo.AdminRole > 0, nil
+}
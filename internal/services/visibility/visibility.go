@@ -0,0 +1,99 @@
+package visibility
+
+// The visibility levels, which set who can see a profile field.
+const (
+	// LevelPublic fields are visible to every authenticated account.
+	LevelPublic = "public"
+	// LevelOrganization fields are only visible to the members of the same organisations.
+	LevelOrganization = "organization"
+	// LevelPrivate fields are only visible to the account itself.
+	LevelPrivate = "private"
+)
+
+// The profile fields with a visibility setting.
+const (
+	FieldNames  = "names"
+	FieldEmail  = "email"
+	FieldAvatar = "avatar"
+)
+
+// defaultLevels are the visibility levels of the fields that were never set.
+// They are restrictive by default since the accounts can be children.
+var defaultLevels = map[string]string{
+	FieldNames:  LevelOrganization,
+	FieldEmail:  LevelPrivate,
+	FieldAvatar: LevelOrganization,
+}
+
+// levelRanks order the visibility levels from the most restrictive one.
+var levelRanks = map[string]int{
+	LevelPrivate:      0,
+	LevelOrganization: 1,
+	LevelPublic:       2,
+}
+
+// Relationship is the relationship of the caller to the account whose profile is requested.
+type Relationship int
+
+const (
+	// RelationshipNone means the accounts are not related.
+	RelationshipNone Relationship = iota
+	// RelationshipOrganization means the accounts share an organisation.
+	RelationshipOrganization
+	// RelationshipSelf means the caller requested its own profile, or is an admin.
+	RelationshipSelf
+)
+
+// IsValidField checks if a field has a visibility setting.
+func IsValidField(field string) bool {
+	_, exists := defaultLevels[field]
+	return exists
+}
+
+// IsValidLevel checks if a visibility level exists.
+func IsValidLevel(level string) bool {
+	return level == LevelPublic || level == LevelOrganization || level == LevelPrivate
+}
+
+// Settings are the visibility levels of the fields of a profile.
+type Settings map[string]string
+
+// Level returns the visibility level of a field, or its default one if it was never set.
+func (settings Settings) Level(field string) string {
+	if level, exists := settings[field]; exists && IsValidLevel(level) {
+		return level
+	}
+	return defaultLevels[field]
+}
+
+// Effective returns the visibility levels of all the fields, defaults included.
+func (settings Settings) Effective() Settings {
+	effective := make(Settings, len(defaultLevels))
+	for field := range defaultLevels {
+		effective[field] = settings.Level(field)
+	}
+	return effective
+}
+
+// Capped returns the visibility levels of all the fields, restricted to a maximum level such as for the profiles of children.
+func (settings Settings) Capped(maxLevel string) Settings {
+	capped := settings.Effective()
+	for field, level := range capped {
+		if levelRanks[level] > levelRanks[maxLevel] {
+			capped[field] = maxLevel
+		}
+	}
+	return capped
+}
+
+// CanSee checks if a field can be seen with a given relationship.
+func (settings Settings) CanSee(field string, relationship Relationship) bool {
+	switch settings.Level(field) {
+	case LevelPublic:
+		return true
+	case LevelOrganization:
+		return relationship >= RelationshipOrganization
+	default:
+		return relationship >= RelationshipSelf
+	}
+}
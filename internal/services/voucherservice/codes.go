@@ -0,0 +1,54 @@
+package voucherservice
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+const (
+	// codeLength is the number of characters of the codes, which is 60 random bits.
+	codeLength      = 12
+	codeGroupLength = 4
+)
+
+// codeAlphabet are the characters of the codes, without the ones that are easily confused such as O and 0.
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// generateCode generates a random code.
+func generateCode() (string, error) {
+	randomBytes := make([]byte, codeLength)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+
+	code := make([]byte, codeLength)
+	for i, randomByte := range randomBytes {
+		// The alphabet size divides 256, so the characters are uniformly distributed
+		code[i] = codeAlphabet[int(randomByte)%len(codeAlphabet)]
+	}
+	return string(code), nil
+}
+
+// NormalizeCode normalizes a code typed by a user, ignoring the case, the separators and the spaces.
+func NormalizeCode(code string) string {
+	var builder strings.Builder
+	for _, r := range strings.ToUpper(code) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+// FormatCode formats a code in dash separated groups to make it easier to type, such as ABCD-EFGH-JKLM.
+func FormatCode(code string) string {
+	var builder strings.Builder
+	for i, r := range code {
+		if i > 0 && i%codeGroupLength == 0 {
+			builder.WriteByte('-')
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
@@ -0,0 +1,238 @@
+package voucherservice
+
+import (
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/entitlements"
+	"github.com/calmisland/go-errors"
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxBatchSize is the maximum number of codes generated in a single batch.
+	MaxBatchSize = 1000
+
+	dayMilliseconds = int64(24 * time.Hour / time.Millisecond)
+)
+
+var (
+	// ErrVoucherNotFound is returned when a code doesn't exist.
+	ErrVoucherNotFound = errors.New("The voucher code doesn't exist")
+	// ErrVoucherExpired is returned when a code has expired.
+	ErrVoucherExpired = errors.New("The voucher code has expired")
+	// ErrVoucherExhausted is returned when a code has been redeemed by as many accounts as allowed.
+	ErrVoucherExhausted = errors.New("The voucher code can't be redeemed anymore")
+	// ErrVoucherAlreadyRedeemed is returned when an account redeems the same code twice.
+	ErrVoucherAlreadyRedeemed = errors.New("The voucher code has already been redeemed by the account")
+)
+
+// BatchInput describes a batch of codes to generate.
+type BatchInput struct {
+	Name           string
+	Passes         map[string]*models.VoucherItem
+	Products       map[string]*models.VoucherItem
+	MaxRedemptions int
+	ExpirationDate int64
+	Count          int
+	CreatedBy      string
+}
+
+// CreateBatch generates a batch of codes and returns them.
+func CreateBatch(store Store, input *BatchInput) (*models.VoucherBatch, []string, error) {
+	batchUUID, err := uuid.NewRandom()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	batch := &models.VoucherBatch{
+		BatchID:        batchUUID.String(),
+		Name:           input.Name,
+		Passes:         input.Passes,
+		Products:       input.Products,
+		MaxRedemptions: input.MaxRedemptions,
+		ExpirationDate: input.ExpirationDate,
+		CodeCount:      input.Count,
+		CreatedBy:      input.CreatedBy,
+		CreatedDate:    now,
+	}
+
+	// The batch is saved first so the codes never refer to a missing batch
+	err = store.PutBatch(batch)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	codes := make([]string, 0, input.Count)
+	seenCodes := make(map[string]bool, input.Count)
+	vouchers := make([]*models.Voucher, 0, input.Count)
+	for len(codes) < input.Count {
+		code, err := generateCode()
+		if err != nil {
+			return nil, nil, err
+		} else if seenCodes[code] {
+			continue
+		}
+
+		seenCodes[code] = true
+		codes = append(codes, code)
+		vouchers = append(vouchers, &models.Voucher{
+			Code:           code,
+			BatchID:        batch.BatchID,
+			MaxRedemptions: input.MaxRedemptions,
+			ExpirationDate: input.ExpirationDate,
+			CreatedDate:    now,
+		})
+	}
+
+	err = store.PutVouchers(vouchers)
+	if err != nil {
+		return nil, nil, err
+	}
+	return batch, codes, nil
+}
+
+// GetBatch returns a batch with its codes, or nil if it doesn't exist.
+func GetBatch(store Store, batchID string) (*models.VoucherBatch, []*models.Voucher, error) {
+	batch, err := store.GetBatch(batchID)
+	if err != nil || batch == nil {
+		return nil, nil, err
+	}
+
+	vouchers, err := store.GetBatchVouchers(batchID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return batch, vouchers, nil
+}
+
+func getVoucher(store Store, code string) (*models.Voucher, error) {
+	voucher, err := store.GetVoucher(code)
+	if err != nil {
+		return nil, err
+	} else if voucher == nil {
+		return nil, ErrVoucherNotFound
+	}
+	return voucher, nil
+}
+
+func checkVoucher(voucher *models.Voucher, now int64) error {
+	if voucher.ExpirationDate != 0 && voucher.ExpirationDate <= now {
+		return ErrVoucherExpired
+	} else if voucher.Redemptions >= voucher.MaxRedemptions {
+		return ErrVoucherExhausted
+	}
+	return nil
+}
+
+// redemptionTransactionID returns the ID of the transaction of a code redemption.
+// It's the same for every redemption of a code, so an account can't write it twice.
+func redemptionTransactionID(code string) string {
+	return "voucher-" + code
+}
+
+// Redeem redeems a code for an account and returns the transaction granting its passes and products.
+// The redemption count and the transaction are written atomically, so concurrent redemptions can't exceed the
+// redemption limit, and an account can never redeem the same code twice.
+func Redeem(store Store, accountID string, code string) (*models.AccountTransaction, error) {
+	code = NormalizeCode(code)
+	if len(code) != codeLength {
+		return nil, ErrVoucherNotFound
+	}
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	voucher, err := getVoucher(store, code)
+	if err != nil {
+		return nil, err
+	} else if err = checkVoucher(voucher, now); err != nil {
+		return nil, err
+	}
+
+	batch, err := store.GetBatch(voucher.BatchID)
+	if err != nil {
+		return nil, err
+	} else if batch == nil {
+		return nil, ErrVoucherNotFound
+	}
+
+	transactionID := redemptionTransactionID(code)
+	existingTransactions, err := store.ListTransactions(accountID)
+	if err != nil {
+		return nil, err
+	}
+	for _, existingTransaction := range existingTransactions {
+		if existingTransaction.TransactionID == transactionID {
+			return nil, ErrVoucherAlreadyRedeemed
+		}
+	}
+
+	// The passes that are still active are extended from their expiration date
+	active := entitlements.Compute(existingTransactions, now)
+	transaction := &models.AccountTransaction{
+		AccountID:     accountID,
+		TransactionID: transactionID,
+		Passes:        newTransactionItems(batch.Passes, active.Passes, now),
+		Products:      newTransactionItems(batch.Products, active.Products, now),
+		CreatedDate:   int(now),
+		UpdatedDate:   int(now),
+	}
+
+	err = store.WriteRedemption(code, now, transaction)
+	if err == ErrRedemptionCanceled {
+		return nil, getRedemptionFailure(store, accountID, code, now, err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+func newTransactionItems(items map[string]*models.VoucherItem, active []*entitlements.Entitlement, now int64) map[string]*models.AccountTransactionItem {
+	if len(items) == 0 {
+		return nil
+	}
+
+	activeExpirationDates := make(map[string]int64, len(active))
+	for _, entitlement := range active {
+		activeExpirationDates[entitlement.ID] = entitlement.ExpirationDate
+	}
+
+	transactionItems := make(map[string]*models.AccountTransactionItem, len(items))
+	for itemID, item := range items {
+		startDate := now
+		if expirationDate := activeExpirationDates[itemID]; expirationDate > now {
+			startDate = expirationDate
+		}
+
+		transactionItem := &models.AccountTransactionItem{
+			StartDate: int(startDate),
+			Shareable: item.Shareable,
+		}
+		if item.DurationDays > 0 {
+			transactionItem.ExpirationDate = int(startDate + int64(item.DurationDays)*dayMilliseconds)
+		}
+		transactionItems[itemID] = transactionItem
+	}
+	return transactionItems
+}
+
+// getRedemptionFailure finds out which condition failed when a redemption was canceled by a concurrent one.
+// The cancellation error is returned if no condition failed, which means the redemption conflicted with another one
+// and can be retried.
+func getRedemptionFailure(store Store, accountID string, code string, now int64, cancelErr error) error {
+	transaction, err := store.GetTransaction(accountID, redemptionTransactionID(code))
+	if err != nil {
+		return err
+	} else if transaction != nil {
+		return ErrVoucherAlreadyRedeemed
+	}
+
+	voucher, err := getVoucher(store, code)
+	if err != nil {
+		return err
+	} else if err = checkVoucher(voucher, now); err != nil {
+		return err
+	}
+	return cancelErr
+}
@@ -0,0 +1,124 @@
+package voucherservice
+
+import (
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/transactionservice"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/calmisland/go-errors"
+	"github.com/guregu/dynamo"
+)
+
+// ErrRedemptionCanceled is returned by a store when a redemption write is canceled, because a condition failed
+// or because it conflicted with a concurrent write.
+var ErrRedemptionCanceled = errors.New("The voucher redemption was canceled")
+
+// Store reads and writes the vouchers and the transactions of their redemptions.
+type Store interface {
+	// PutBatch writes a batch of codes.
+	PutBatch(batch *models.VoucherBatch) error
+	// PutVouchers writes the codes of a batch.
+	PutVouchers(vouchers []*models.Voucher) error
+	// GetBatch returns a batch, nil if it doesn't exist.
+	GetBatch(batchID string) (*models.VoucherBatch, error)
+	// GetBatchVouchers returns the codes of a batch.
+	GetBatchVouchers(batchID string) ([]*models.Voucher, error)
+	// GetVoucher returns a code with a consistent read, nil if it doesn't exist.
+	GetVoucher(code string) (*models.Voucher, error)
+	// ListTransactions returns all the transactions of an account.
+	ListTransactions(accountID string) ([]*models.AccountTransaction, error)
+	// GetTransaction returns a transaction of an account, nil if it doesn't exist.
+	GetTransaction(accountID string, transactionID string) (*models.AccountTransaction, error)
+	// WriteRedemption atomically counts a redemption of a code and puts its transaction. It fails with
+	// ErrRedemptionCanceled unless the code is under its redemption limit, unexpired at the date in epoch
+	// milliseconds, and the transaction ID is unused.
+	WriteRedemption(code string, now int64, transaction *models.AccountTransaction) error
+}
+
+type standardStore struct{}
+
+// NewStore creates the store of the vouchers tables and the transactions table.
+func NewStore() Store {
+	return &standardStore{}
+}
+
+func getBatchTable() dynamo.Table {
+	return models.GetDB().Table(models.GetTableName(models.TABLE_NAME_VOUCHER_BATCHES))
+}
+
+func getVoucherTable() dynamo.Table {
+	return models.GetDB().Table(models.GetTableName(models.TABLE_NAME_VOUCHERS))
+}
+
+func (store *standardStore) PutBatch(batch *models.VoucherBatch) error {
+	return getBatchTable().Put(batch).Run()
+}
+
+func (store *standardStore) PutVouchers(vouchers []*models.Voucher) error {
+	items := make([]interface{}, len(vouchers))
+	for i, voucher := range vouchers {
+		items[i] = voucher
+	}
+	_, err := getVoucherTable().Batch().Write().Put(items...).Run()
+	return err
+}
+
+func (store *standardStore) GetBatch(batchID string) (*models.VoucherBatch, error) {
+	batch := &models.VoucherBatch{}
+	err := getBatchTable().Get("batchId", batchID).One(batch)
+	if err == dynamo.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+func (store *standardStore) GetBatchVouchers(batchID string) ([]*models.Voucher, error) {
+	var vouchers []*models.Voucher
+	err := getVoucherTable().Get("batchId", batchID).Index(models.VOUCHER_GSI_BATCHID).All(&vouchers)
+	if err != nil && err != dynamo.ErrNotFound {
+		return nil, err
+	}
+	return vouchers, nil
+}
+
+func (store *standardStore) GetVoucher(code string) (*models.Voucher, error) {
+	voucher := &models.Voucher{}
+	err := getVoucherTable().Get("code", code).Consistent(true).One(voucher)
+	if err == dynamo.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return voucher, nil
+}
+
+func (store *standardStore) ListTransactions(accountID string) ([]*models.AccountTransaction, error) {
+	return transactionservice.ListAllTransactions(accountID)
+}
+
+func (store *standardStore) GetTransaction(accountID string, transactionID string) (*models.AccountTransaction, error) {
+	return transactionservice.GetTransaction(accountID, transactionID)
+}
+
+func (store *standardStore) WriteRedemption(code string, now int64, transaction *models.AccountTransaction) error {
+	redemptionUpdate := getVoucherTable().Update("code", code).
+		Add("redemptions", 1).
+		If("$ < $", "redemptions", "maxRedemptions").
+		If("($ = ? OR $ > ?)", "expirationTm", 0, "expirationTm", now)
+
+	err := models.GetDB().WriteTx().
+		Update(redemptionUpdate).
+		Put(transactionservice.NewTransactionPut(transaction)).
+		Run()
+	if isTransactionCanceled(err) {
+		return ErrRedemptionCanceled
+	}
+	return err
+}
+
+func isTransactionCanceled(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	return ok && awsErr.Code() == dynamodb.ErrCodeTransactionCanceledException
+}
@@ -3,8 +3,14 @@ package globalsetup
 import (
 	"fmt"
 
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/accountverificationservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/avatarmoderation"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/namefilter"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/policies"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/purchaseverification"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/visibility"
 	"bitbucket.org/calmisland/go-server-account/accountdatabase/accountdynamodb"
 	"bitbucket.org/calmisland/go-server-account/avatars"
 	"bitbucket.org/calmisland/go-server-aws/awsdynamodb"
@@ -30,7 +36,12 @@ func Setup() {
 	setupMessageQueue()
 	setupGeoIP()
 	setupAvatarStorage()
+	setupAvatarModerator()
 	setupAccountVerificationService()
+	setupRelationshipResolver()
+	setupNameFilter()
+	setupPurchaseVerifier()
+	setupRequiredPolicyVersions()
 
 	globals.Verify()
 }
@@ -164,6 +175,109 @@ func setupAvatarStorage() {
 	if err != nil {
 		panic(err)
 	}
+
+	globals.AvatarSizeStorages = map[int]avatars.Storage{}
+	for _, size := range defs.AvatarSizes {
+		globals.AvatarSizeStorages[size], err = avatars.NewStorage(avatars.StorageConfig{
+			Storage:    avatarStorageConfig.Storage,
+			AvatarPath: defs.AvatarSizePath(avatarStorageConfig.AvatarPath, size),
+		})
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	globals.AvatarPresetStorage, err = avatars.NewStorage(avatars.StorageConfig{
+		Storage:    avatarStorageConfig.Storage,
+		AvatarPath: defs.AvatarPresetPath(avatarStorageConfig.AvatarPath),
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	globals.AvatarQuarantineStorage, err = avatars.NewStorage(avatars.StorageConfig{
+		Storage:    avatarStorageConfig.Storage,
+		AvatarPath: defs.AvatarQuarantinePath(avatarStorageConfig.AvatarPath),
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func setupAvatarModerator() {
+	var moderatorConfig avatarmoderation.Config
+	err := configs.ReadEnvConfig(&moderatorConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	var rulesConfig avatarmoderation.RulesConfig
+	err = configs.ReadEnvConfig(&rulesConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	var httpConfig avatarmoderation.HTTPConfig
+	err = configs.ReadEnvConfig(&httpConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	globals.AvatarModerator, err = avatarmoderation.New(moderatorConfig, rulesConfig, httpConfig)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func setupRelationshipResolver() {
+	globals.RelationshipResolver = visibility.NewResolver(globals.AccountDatabase)
+}
+
+func setupNameFilter() {
+	var nameFilterConfig namefilter.Config
+	err := configs.ReadEnvConfig(&nameFilterConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	globals.NameFilter = namefilter.NewStoredFilter(nameFilterConfig)
+}
+
+func setupPurchaseVerifier() {
+	var appStoreConfig purchaseverification.AppStoreConfig
+	err := configs.ReadEnvConfig(&appStoreConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	var googlePlayConfig purchaseverification.GooglePlayConfig
+	err = configs.ReadEnvConfig(&googlePlayConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	globals.PurchaseVerifier, err = purchaseverification.New(appStoreConfig, googlePlayConfig)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func setupRequiredPolicyVersions() {
+	var policiesConfig policies.Config
+	err := configs.ReadEnvConfig(&policiesConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	globals.RequiredPolicyVersions, err = policies.NewRequiredVersions(policiesConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	globals.PolicyConsentGraceEndDate, err = policies.NewConsentGraceEndDate(policiesConfig)
+	if err != nil {
+		panic(err)
+	}
 }
 
 func setupAccountVerificationService() {
@@ -1,8 +1,14 @@
 package testsetup
 
 import (
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
 	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/accountverificationservice/accountverificationservicemock"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/avatarmoderation"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/namefilter"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/policies"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/purchaseverification"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/visibility"
 	"bitbucket.org/calmisland/go-server-account/accountdatabase/accountmemorydb"
 	"bitbucket.org/calmisland/go-server-account/avatars"
 	"bitbucket.org/calmisland/go-server-cloud/cloudstorage/memorystorage"
@@ -28,7 +34,12 @@ func Setup() {
 	setupEmailQueue()
 	setupGeoIP()
 	setupAvatarStorage()
+	setupAvatarModerator()
 	setupAccountVerificationService()
+	setupRelationshipResolver()
+	setupNameFilter()
+	setupPurchaseVerifier()
+	setupRequiredPolicyVersions()
 
 	globals.Verify()
 }
@@ -95,10 +106,61 @@ func setupAvatarStorage() {
 	if err != nil {
 		panic(err)
 	}
+
+	globals.AvatarSizeStorages = map[int]avatars.Storage{}
+	for _, size := range defs.AvatarSizes {
+		globals.AvatarSizeStorages[size], err = avatars.NewStorage(avatars.StorageConfig{
+			Storage:    avatarMemoryStorage,
+			AvatarPath: defs.AvatarSizePath(avatarStorageConfig.AvatarPath, size),
+		})
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	globals.AvatarPresetStorage, err = avatars.NewStorage(avatars.StorageConfig{
+		Storage:    avatarMemoryStorage,
+		AvatarPath: defs.AvatarPresetPath(avatarStorageConfig.AvatarPath),
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	globals.AvatarQuarantineStorage, err = avatars.NewStorage(avatars.StorageConfig{
+		Storage:    avatarMemoryStorage,
+		AvatarPath: defs.AvatarQuarantinePath(avatarStorageConfig.AvatarPath),
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func setupAvatarModerator() {
+	globals.AvatarModerator = avatarmoderation.NewRulesModerator(avatarmoderation.RulesConfig{})
+}
+
+func setupRelationshipResolver() {
+	globals.RelationshipResolver = visibility.NewResolver(globals.AccountDatabase)
+}
+
+func setupNameFilter() {
+	globals.NameFilter = namefilter.NewStaticFilter(nil)
+}
+
+func setupPurchaseVerifier() {
+	globals.PurchaseVerifier = purchaseverification.NewFakeVerifier()
+}
+
+func setupRequiredPolicyVersions() {
+	globals.RequiredPolicyVersions, _ = policies.NewRequiredVersions(policies.Config{
+		TermsOfServiceVersion: "test",
+		PrivacyPolicyVersion:  "test",
+	})
 }
 
 func setupAccountVerificationService() {
 	verificationService := &accountverificationservicemock.MockService{}
 	verificationService.On("GetVerificationLink", mock.Anything, mock.Anything, mock.Anything).Return("http://localhost:9999/verify")
+	verificationService.On("GetParentalConsentLink", mock.Anything, mock.Anything, mock.Anything).Return("http://localhost:9999/consent")
 	globals.AccountVerificationService = verificationService
 }
@@ -0,0 +1,129 @@
+package test_test
+
+import (
+	"testing"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/accountdeletionservice"
+	"github.com/calmisland/go-testify/assert"
+)
+
+// accountDeletionStore is an in-memory store of the accounts, linking the parent accounts to their child profiles.
+type accountDeletionStore struct {
+	// parents are the parent accounts by child profile, including the links without a parent on the profile
+	parents      map[string]string
+	profileLinks map[string]bool
+	data         map[string]bool
+	records      map[string]bool
+	// deleted are the deleted accounts, in order
+	deleted []string
+}
+
+func newAccountDeletionStore(accountIDs ...string) *accountDeletionStore {
+	store := &accountDeletionStore{
+		parents:      map[string]string{},
+		profileLinks: map[string]bool{},
+		data:         map[string]bool{},
+		records:      map[string]bool{},
+	}
+	for _, accountID := range accountIDs {
+		store.data[accountID] = true
+		store.records[accountID] = true
+	}
+	return store
+}
+
+func (store *accountDeletionStore) addChild(parentID string, childID string) {
+	store.parents[childID] = parentID
+	store.profileLinks[childID] = true
+	store.data[childID] = true
+	store.records[childID] = true
+}
+
+func (store *accountDeletionStore) GetParentID(accountID string) (string, error) {
+	if !store.profileLinks[accountID] {
+		return "", nil
+	}
+	return store.parents[accountID], nil
+}
+
+func (store *accountDeletionStore) ListChildIDs(accountID string) ([]string, error) {
+	var childIDs []string
+	for childID, parentID := range store.parents {
+		if parentID == accountID {
+			childIDs = append(childIDs, childID)
+		}
+	}
+	return childIDs, nil
+}
+
+func (store *accountDeletionStore) IsParentOf(parentID string, childID string) (bool, error) {
+	return store.parents[childID] == parentID, nil
+}
+
+func (store *accountDeletionStore) UnlinkChild(parentID string, childID string) error {
+	if store.parents[childID] == parentID {
+		delete(store.parents, childID)
+	}
+	return nil
+}
+
+func (store *accountDeletionStore) DeleteData(accountID string) error {
+	delete(store.data, accountID)
+	delete(store.profileLinks, accountID)
+	return nil
+}
+
+func (store *accountDeletionStore) DeleteRecords(accountID string) error {
+	if !store.records[accountID] {
+		return accountdeletionservice.ErrAccountNotFound
+	}
+	delete(store.records, accountID)
+	store.deleted = append(store.deleted, accountID)
+	return nil
+}
+
+func TestDeleteAccountCascade(t *testing.T) {
+	store := newAccountDeletionStore("PARENT", "OTHER")
+	store.addChild("PARENT", "CHILD-1")
+	store.addChild("PARENT", "CHILD-2")
+	store.addChild("OTHER", "OTHER-CHILD")
+
+	err := accountdeletionservice.DeleteAccount(store, "PARENT")
+	assert.NoError(t, err)
+
+	// The child profiles are deleted and unlinked before their parent
+	assert.ElementsMatch(t, []string{"CHILD-1", "CHILD-2"}, store.deleted[:2])
+	assert.Equal(t, "PARENT", store.deleted[2])
+	assert.Equal(t, map[string]string{"OTHER-CHILD": "OTHER"}, store.parents)
+	assert.Equal(t, map[string]bool{"OTHER": true, "OTHER-CHILD": true}, store.data)
+	assert.Equal(t, map[string]bool{"OTHER": true, "OTHER-CHILD": true}, store.records)
+
+	err = accountdeletionservice.DeleteAccount(store, "PARENT")
+	assert.Equal(t, accountdeletionservice.ErrAccountNotFound, err)
+}
+
+func TestDeleteChild(t *testing.T) {
+	store := newAccountDeletionStore("PARENT", "OTHER")
+	store.addChild("PARENT", "CHILD")
+
+	// Only the parent can delete its child profile
+	err := accountdeletionservice.DeleteChild(store, "OTHER", "CHILD")
+	assert.Equal(t, accountdeletionservice.ErrChildNotFound, err)
+	assert.True(t, store.records["CHILD"])
+
+	err = accountdeletionservice.DeleteChild(store, "PARENT", "CHILD")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"CHILD"}, store.deleted)
+	assert.Empty(t, store.parents)
+	assert.True(t, store.records["PARENT"])
+}
+
+func TestDeleteInterruptedChild(t *testing.T) {
+	store := newAccountDeletionStore("PARENT")
+	// A child profile whose creation was interrupted after linking it, with no account nor parent on its profile
+	store.parents["CHILD"] = "PARENT"
+
+	err := accountdeletionservice.DeleteChild(store, "PARENT", "CHILD")
+	assert.NoError(t, err)
+	assert.Empty(t, store.parents)
+}
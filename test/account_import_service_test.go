@@ -0,0 +1,38 @@
+package test_test
+
+import (
+	"strings"
+	"testing"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/accountimportservice"
+	"github.com/calmisland/go-testify/assert"
+)
+
+func TestParseAccountImportCSV(t *testing.T) {
+	data := "\ufeffEmail,Phone,First Name,lastName,lang,country,notes\n" +
+		"teacher@example.com,,Jane,Doe,en_US,us,ignored\n" +
+		"\n" +
+		",,,,,,\n" +
+		", +82 10 1234 1234,Minji,Kim,ko,KR\n"
+
+	rows, err := accountimportservice.ParseCSV(strings.NewReader(data))
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+
+	assert.Equal(t, 2, rows[0].Line)
+	assert.Equal(t, "teacher@example.com", rows[0].Email)
+	assert.Equal(t, "Doe", rows[0].LastName)
+	assert.Equal(t, "us", rows[0].Country)
+
+	assert.Equal(t, 5, rows[1].Line)
+	assert.Equal(t, "+82 10 1234 1234", rows[1].PhoneNumber)
+	assert.Equal(t, "ko", rows[1].Language)
+}
+
+func TestParseAccountImportCSVWithoutContactColumn(t *testing.T) {
+	_, err := accountimportservice.ParseCSV(strings.NewReader("firstName,lastName\nJane,Doe\n"))
+	assert.Error(t, err)
+
+	_, err = accountimportservice.ParseCSV(strings.NewReader(""))
+	assert.Error(t, err)
+}
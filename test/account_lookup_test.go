@@ -0,0 +1,138 @@
+package test_test
+
+import (
+	"strconv"
+	"testing"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/accountlookup"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/visibility"
+	"bitbucket.org/calmisland/go-server-account/accountdatabase"
+	"github.com/calmisland/go-testify/assert"
+)
+
+// accountLookupStore is an in-memory store of the accounts to look up.
+type accountLookupStore struct {
+	accounts      map[string]*accountdatabase.AccountInfo
+	profiles      map[string]*models.AccountProfile
+	relationships map[string]visibility.Relationship
+	// reads counts the profile reads, since the accounts are read concurrently
+	reads map[string]int
+}
+
+func newAccountLookupStore() *accountLookupStore {
+	return &accountLookupStore{
+		accounts:      map[string]*accountdatabase.AccountInfo{},
+		profiles:      map[string]*models.AccountProfile{},
+		relationships: map[string]visibility.Relationship{},
+		reads:         map[string]int{},
+	}
+}
+
+func (store *accountLookupStore) GetAccountInfo(accountID string) (*accountdatabase.AccountInfo, error) {
+	return store.accounts[accountID], nil
+}
+
+func (store *accountLookupStore) GetProfiles(accountIDs []string) (map[string]*models.AccountProfile, error) {
+	profiles := map[string]*models.AccountProfile{}
+	for _, accountID := range accountIDs {
+		store.reads[accountID]++
+		if profile, exists := store.profiles[accountID]; exists {
+			profiles[accountID] = profile
+		}
+	}
+	return profiles, nil
+}
+
+func (store *accountLookupStore) ResolveMany(callerAccountID string, accountIDs []string) (map[string]visibility.Relationship, error) {
+	relationships := map[string]visibility.Relationship{}
+	for _, accountID := range accountIDs {
+		relationships[accountID] = store.relationships[accountID]
+	}
+	return relationships, nil
+}
+
+func (store *accountLookupStore) addAccount(accountID string, levels map[string]string, relationship visibility.Relationship) {
+	store.accounts[accountID] = &accountdatabase.AccountInfo{
+		FullName: "Name of " + accountID,
+		Email:    accountID + "@example.com",
+	}
+	store.profiles[accountID] = &models.AccountProfile{
+		AccountID:  accountID,
+		Visibility: levels,
+	}
+	store.relationships[accountID] = relationship
+}
+
+func TestAccountLookupDedupe(t *testing.T) {
+	store := newAccountLookupStore()
+	store.addAccount("a", map[string]string{visibility.FieldNames: visibility.LevelPublic}, visibility.RelationshipNone)
+	store.addAccount("b", map[string]string{visibility.FieldNames: visibility.LevelPublic}, visibility.RelationshipNone)
+
+	accounts, err := accountlookup.Lookup(store, "caller", []string{"b", "", "a", "b", "a"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(accounts))
+	assert.Equal(t, "b", accounts[0].AccountID)
+	assert.Equal(t, "a", accounts[1].AccountID)
+
+	// The duplicates are only read once
+	assert.Equal(t, 1, store.reads["a"])
+	assert.Equal(t, 1, store.reads["b"])
+
+	_, err = accountlookup.Lookup(store, "caller", []string{"", ""})
+	assert.Equal(t, accountlookup.ErrNoAccountIDs, err)
+}
+
+func TestAccountLookupLimit(t *testing.T) {
+	store := newAccountLookupStore()
+
+	accountIDs := make([]string, 0, accountlookup.MaxAccountIDs+1)
+	for i := 0; i < accountlookup.MaxAccountIDs; i++ {
+		accountIDs = append(accountIDs, "account-"+strconv.Itoa(i))
+	}
+
+	// The limit applies once the duplicates are removed
+	accounts, err := accountlookup.Lookup(store, "caller", append(accountIDs, accountIDs[:50]...))
+	assert.NoError(t, err)
+	assert.Equal(t, accountlookup.MaxAccountIDs, len(accounts))
+
+	_, err = accountlookup.Lookup(store, "caller", append(accountIDs, "one-too-many"))
+	assert.Equal(t, accountlookup.ErrTooManyAccountIDs, err)
+}
+
+func TestAccountLookupVisibility(t *testing.T) {
+	store := newAccountLookupStore()
+	store.addAccount("public", map[string]string{
+		visibility.FieldNames: visibility.LevelPublic,
+		visibility.FieldEmail: visibility.LevelPrivate,
+	}, visibility.RelationshipNone)
+	store.addAccount("classmate", nil, visibility.RelationshipOrganization)
+	store.addAccount("stranger", nil, visibility.RelationshipNone)
+	store.addAccount("child", map[string]string{visibility.FieldNames: visibility.LevelPublic}, visibility.RelationshipNone)
+	store.profiles["child"].IsChild = true
+
+	accounts, err := accountlookup.Lookup(store, "caller", []string{"public", "classmate", "stranger", "child", "unknown"})
+	assert.NoError(t, err)
+
+	public := accounts[0]
+	assert.Equal(t, accountlookup.StatusFound, public.Status)
+	assert.True(t, public.CanSeeNames)
+	assert.False(t, public.CanSeeEmail)
+	assert.Equal(t, "Name of public", public.Info.FullName)
+	assert.Equal(t, "", public.Info.Email)
+
+	// The default levels let the organisation members see the names and avatar
+	classmate := accounts[1]
+	assert.Equal(t, accountlookup.StatusFound, classmate.Status)
+	assert.True(t, classmate.CanSeeNames)
+	assert.True(t, classmate.CanSeeAvatar)
+	assert.False(t, classmate.CanSeeEmail)
+
+	// The hidden accounts can't be told apart from the ones that don't exist
+	for _, account := range accounts[2:] {
+		assert.Equal(t, accountlookup.StatusNotFound, account.Status)
+		assert.Nil(t, account.Info)
+		assert.Nil(t, account.Profile)
+		assert.False(t, account.CanSeeNames)
+	}
+}
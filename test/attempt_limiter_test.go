@@ -0,0 +1,24 @@
+package test_test
+
+import (
+	"testing"
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/attemptlimiter"
+	"github.com/calmisland/go-testify/assert"
+)
+
+func TestAttemptLimiterCounterKey(t *testing.T) {
+	limit := attemptlimiter.New("test", 5, 15*time.Minute)
+	start := time.Date(2021, 3, 1, 10, 15, 0, 0, time.UTC)
+
+	// The attempts of the same window share a counter
+	key := limit.CounterKey("user@example.com", start)
+	assert.Equal(t, key, limit.CounterKey("user@example.com", start.Add(14*time.Minute)))
+	assert.NotEqual(t, key, limit.CounterKey("user@example.com", start.Add(15*time.Minute)))
+	assert.NotEqual(t, key, limit.CounterKey("other@example.com", start))
+
+	// The scopes don't share their counters
+	otherLimit := attemptlimiter.New("other", 5, 15*time.Minute)
+	assert.NotEqual(t, key, otherLimit.CounterKey("user@example.com", start))
+}
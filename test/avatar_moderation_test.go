@@ -0,0 +1,63 @@
+package test_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/avatarmoderation"
+	"github.com/calmisland/go-testify/assert"
+)
+
+func TestRulesAvatarModerator(t *testing.T) {
+	blockedData := []byte("blocked upload")
+	blockedHash := sha256.Sum256(blockedData)
+	moderator := avatarmoderation.NewRulesModerator(avatarmoderation.RulesConfig{
+		BlockedHashes: " " + hex.EncodeToString(blockedHash[:]) + " ,",
+	})
+
+	decision, err := moderator.Moderate(&avatarmoderation.Avatar{Width: 400, Height: 300, Data: []byte("upload")})
+	assert.NoError(t, err)
+	assert.True(t, decision.Approved)
+
+	decision, err = moderator.Moderate(&avatarmoderation.Avatar{Width: 400, Height: 32})
+	assert.NoError(t, err)
+	assert.False(t, decision.Approved)
+	assert.Equal(t, avatarmoderation.ReasonTooSmall, decision.Reason)
+
+	decision, err = moderator.Moderate(&avatarmoderation.Avatar{Width: 2000, Height: 100})
+	assert.NoError(t, err)
+	assert.Equal(t, avatarmoderation.ReasonBadAspectRatio, decision.Reason)
+
+	decision, err = moderator.Moderate(&avatarmoderation.Avatar{Width: 400, Height: 400, Data: blockedData})
+	assert.NoError(t, err)
+	assert.Equal(t, avatarmoderation.ReasonBlocked, decision.Reason)
+}
+
+func TestHTTPAvatarModerator(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "image/jpeg", r.Header.Get("Content-Type"))
+		w.Write([]byte(`{"approved": false, "reason": "nudity"}`))
+	}))
+	defer server.Close()
+
+	moderator, err := avatarmoderation.NewHTTPModerator(avatarmoderation.HTTPConfig{
+		URL:    server.URL,
+		APIKey: "secret",
+	})
+	assert.NoError(t, err)
+
+	decision, err := moderator.Moderate(&avatarmoderation.Avatar{
+		ImageData:        []byte("image"),
+		ImageContentType: "image/jpeg",
+	})
+	assert.NoError(t, err)
+	assert.False(t, decision.Approved)
+	assert.Equal(t, "nudity", decision.Reason)
+
+	_, err = avatarmoderation.NewHTTPModerator(avatarmoderation.HTTPConfig{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,36 @@
+package test_test
+
+import (
+	"testing"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/avatarpresets"
+	"github.com/calmisland/go-testify/assert"
+)
+
+func TestAvatarPresetCatalog(t *testing.T) {
+	presets := avatarpresets.List()
+	assert.NotEmpty(t, presets)
+
+	for _, preset := range presets {
+		assert.NotEmpty(t, preset.ID)
+		assert.NotEmpty(t, preset.Category)
+		assert.NotEmpty(t, preset.Names[defs.DefaultLanguageCode], preset.ID)
+
+		found, exists := avatarpresets.Get(preset.ID)
+		assert.True(t, exists)
+		assert.Equal(t, preset, found)
+	}
+
+	_, exists := avatarpresets.Get("unknown")
+	assert.False(t, exists)
+}
+
+func TestAvatarPresetLocalizedName(t *testing.T) {
+	preset, exists := avatarpresets.Get("animal-cat")
+	assert.True(t, exists)
+
+	assert.Equal(t, "고양이", preset.LocalizedName("ko_KR"))
+	assert.Equal(t, "고양이", preset.LocalizedName("ko"))
+	assert.Equal(t, "Cat", preset.LocalizedName("fr_FR"))
+}
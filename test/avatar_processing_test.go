@@ -0,0 +1,57 @@
+package test_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/avatarservice"
+	"github.com/calmisland/go-testify/assert"
+)
+
+func TestProcessAvatarImage(t *testing.T) {
+	source := image.NewNRGBA(image.Rect(0, 0, 300, 200))
+	source.Set(10, 10, color.NRGBA{R: 255, A: 255})
+
+	var buffer bytes.Buffer
+	assert.NoError(t, png.Encode(&buffer, source))
+
+	processed, err := avatarservice.ProcessImage(buffer.Bytes(), []int{64, 128})
+	assert.NoError(t, err)
+	assert.Equal(t, 300, processed.Width)
+	assert.Equal(t, 200, processed.Height)
+	assert.Len(t, processed.Data, 2)
+
+	for size, data := range processed.Data {
+		config, err := jpeg.DecodeConfig(bytes.NewReader(data))
+		assert.NoError(t, err)
+		assert.Equal(t, size, config.Width)
+		assert.Equal(t, size, config.Height)
+	}
+}
+
+func TestProcessAvatarImageStripsExif(t *testing.T) {
+	var buffer bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buffer, image.NewRGBA(image.Rect(0, 0, 32, 32)), nil))
+
+	// Insert an APP1 EXIF segment right after the start of image marker
+	exif := []byte("Exif\x00\x00GPS-DATA")
+	segment := append([]byte{0xFF, 0xE1, 0x00, byte(len(exif) + 2)}, exif...)
+	data := append(append(append([]byte{}, buffer.Bytes()[:2]...), segment...), buffer.Bytes()[2:]...)
+
+	processed, err := avatarservice.ProcessImage(data, []int{64})
+	assert.NoError(t, err)
+	assert.False(t, bytes.Contains(processed.Data[64], []byte("Exif")))
+	assert.False(t, bytes.Contains(processed.Data[64], []byte("GPS-DATA")))
+}
+
+func TestProcessAvatarImageInvalid(t *testing.T) {
+	_, err := avatarservice.ProcessImage([]byte("GIF89a not an avatar"), []int{64})
+	assert.Equal(t, avatarservice.ErrInvalidImage, err)
+
+	assert.True(t, avatarservice.IsSupportedContentType("image/webp"))
+	assert.False(t, avatarservice.IsSupportedContentType("image/gif"))
+}
@@ -0,0 +1,254 @@
+package test_test
+
+import (
+	"errors"
+	"testing"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/childservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
+	"bitbucket.org/calmisland/go-server-account/accountdatabase"
+	"github.com/calmisland/go-testify/assert"
+	"github.com/google/uuid"
+)
+
+func TestChildDeviceCredential(t *testing.T) {
+	credential := childservice.FormatDeviceCredential("TEST-CREDENTIAL", "c2VjcmV0.c2VjcmV0")
+	credentialID, secret, err := childservice.ParseDeviceCredential(credential)
+	assert.NoError(t, err)
+	assert.Equal(t, "TEST-CREDENTIAL", credentialID)
+	assert.Equal(t, "c2VjcmV0.c2VjcmV0", secret)
+
+	for _, invalidCredential := range []string{"", "TEST-CREDENTIAL", "TEST-CREDENTIAL.", ".c2VjcmV0"} {
+		_, _, err = childservice.ParseDeviceCredential(invalidCredential)
+		assert.Equal(t, childservice.ErrInvalidDeviceCredential, err, invalidCredential)
+	}
+}
+
+// childStore is an in-memory store of the child profiles, whose profiles only record their parent.
+type childStore struct {
+	links       map[string]*models.AccountChild
+	accounts    map[string]*accountdatabase.CreateAccountInfo
+	parents     map[string]string
+	credentials map[string]*models.ChildDeviceCredential
+	profileErr  error
+}
+
+func newChildStore() *childStore {
+	return &childStore{
+		links:       map[string]*models.AccountChild{},
+		accounts:    map[string]*accountdatabase.CreateAccountInfo{},
+		parents:     map[string]string{},
+		credentials: map[string]*models.ChildDeviceCredential{},
+	}
+}
+
+func (store *childStore) LinkChild(link *models.AccountChild) error {
+	children, _ := store.ListChildren(link.ParentID)
+	if len(children) >= defs.MaxChildProfiles {
+		return childservice.ErrTooManyChildren
+	}
+	store.links[link.ParentID+"/"+link.ChildID] = link
+	return nil
+}
+
+func (store *childStore) UnlinkChild(parentID string, childID string) error {
+	delete(store.links, parentID+"/"+childID)
+	return nil
+}
+
+func (store *childStore) ListChildren(parentID string) ([]models.AccountChild, error) {
+	var children []models.AccountChild
+	for _, link := range store.links {
+		if link.ParentID == parentID {
+			children = append(children, *link)
+		}
+	}
+	return children, nil
+}
+
+func (store *childStore) GetChild(parentID string, childID string) (*models.AccountChild, error) {
+	return store.links[parentID+"/"+childID], nil
+}
+
+func (store *childStore) CreateAccount(info *accountdatabase.CreateAccountInfo, names *accountdatabase.AccountNameInfo) error {
+	store.accounts[info.ID] = info
+	return nil
+}
+
+func (store *childStore) DeleteAccount(childID string) error {
+	delete(store.accounts, childID)
+	return nil
+}
+
+func (store *childStore) SetProfile(childID string, parentID string, edit *profileservice.ProfileEdit) error {
+	if store.profileErr != nil {
+		return store.profileErr
+	}
+	store.parents[childID] = parentID
+	return nil
+}
+
+func (store *childStore) DeleteProfile(childID string) error {
+	delete(store.parents, childID)
+	return nil
+}
+
+func (store *childStore) PutDeviceCredential(credential *models.ChildDeviceCredential) error {
+	store.credentials[credential.CredentialID] = credential
+	return nil
+}
+
+func (store *childStore) GetDeviceCredential(credentialID string) (*models.ChildDeviceCredential, error) {
+	return store.credentials[credentialID], nil
+}
+
+func (store *childStore) ListDeviceCredentials(childID string) ([]models.ChildDeviceCredential, error) {
+	var credentials []models.ChildDeviceCredential
+	for _, credential := range store.credentials {
+		if credential.ChildID == childID {
+			credentials = append(credentials, *credential)
+		}
+	}
+	return credentials, nil
+}
+
+func (store *childStore) SetDeviceCredentialUsed(credentialID string, usedDate int64) error {
+	store.credentials[credentialID].LastUsedDate = usedDate
+	return nil
+}
+
+func (store *childStore) DeleteDeviceCredential(credentialID string) error {
+	delete(store.credentials, credentialID)
+	return nil
+}
+
+func createTestChild(t *testing.T, store childservice.Store, parentID string) string {
+	childID, err := childservice.CreateChild(store, parentID, &childservice.NewChild{
+		FullName:  "Test Child",
+		FirstName: "Test",
+		LastName:  "Child",
+	})
+	assert.NoError(t, err)
+	return childID
+}
+
+func TestCreateChild(t *testing.T) {
+	store := newChildStore()
+	childID := createTestChild(t, store, "PARENT")
+
+	assert.Contains(t, store.accounts, childID)
+	assert.Equal(t, "PARENT", store.parents[childID])
+
+	childIDs, err := childservice.GetChildIDs(store, "PARENT")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{childID}, childIDs)
+
+	// Only the parent manages the child profile
+	isParent, err := childservice.IsParentOf(store, "PARENT", childID)
+	assert.NoError(t, err)
+	assert.True(t, isParent)
+	isParent, err = childservice.IsParentOf(store, "OTHER", childID)
+	assert.NoError(t, err)
+	assert.False(t, isParent)
+}
+
+func TestCreateChildLimit(t *testing.T) {
+	store := newChildStore()
+	for i := 0; i < defs.MaxChildProfiles; i++ {
+		createTestChild(t, store, "PARENT")
+	}
+
+	_, err := childservice.CreateChild(store, "PARENT", &childservice.NewChild{FullName: "Test Child"})
+	assert.Equal(t, childservice.ErrTooManyChildren, err)
+	assert.Len(t, store.accounts, defs.MaxChildProfiles)
+
+	// The limit is by parent
+	createTestChild(t, store, "OTHER")
+}
+
+func TestCreateChildRollback(t *testing.T) {
+	store := newChildStore()
+	store.profileErr = errors.New("Failed to write the profile")
+
+	_, err := childservice.CreateChild(store, "PARENT", &childservice.NewChild{FullName: "Test Child"})
+	assert.Equal(t, store.profileErr, err)
+	assert.Empty(t, store.links)
+	assert.Empty(t, store.accounts)
+	assert.Empty(t, store.parents)
+}
+
+func TestChildDeviceCredentialVerification(t *testing.T) {
+	setupPasswordHasher(t)
+	store := newChildStore()
+	childID := createTestChild(t, store, "PARENT")
+
+	credential, deviceCredential, err := childservice.CreateDeviceCredential(store, "PARENT", childID, "Tablet")
+	assert.NoError(t, err)
+
+	verifiedID, err := childservice.VerifyDeviceCredential(store, deviceCredential)
+	assert.NoError(t, err)
+	assert.Equal(t, childID, verifiedID)
+	assert.NotZero(t, store.credentials[credential.CredentialID].LastUsedDate)
+
+	_, err = childservice.VerifyDeviceCredential(store, childservice.FormatDeviceCredential(credential.CredentialID, "d3Jvbmc"))
+	assert.Equal(t, childservice.ErrInvalidDeviceCredential, err)
+
+	// The credential can only be revoked through its child profile
+	err = childservice.DeleteDeviceCredential(store, "OTHER-CHILD", credential.CredentialID)
+	assert.Equal(t, childservice.ErrDeviceCredentialNotFound, err)
+
+	err = childservice.DeleteDeviceCredential(store, childID, credential.CredentialID)
+	assert.NoError(t, err)
+	_, err = childservice.VerifyDeviceCredential(store, deviceCredential)
+	assert.Equal(t, childservice.ErrInvalidDeviceCredential, err)
+}
+
+func TestChildDeviceCredentialLimit(t *testing.T) {
+	setupPasswordHasher(t)
+	store := newChildStore()
+	childID := createTestChild(t, store, "PARENT")
+
+	for i := 0; i < defs.MaxChildDeviceCredentials; i++ {
+		_, _, err := childservice.CreateDeviceCredential(store, "PARENT", childID, "Tablet")
+		assert.NoError(t, err)
+	}
+	_, _, err := childservice.CreateDeviceCredential(store, "PARENT", childID, "Tablet")
+	assert.Equal(t, childservice.ErrTooManyDeviceCredentials, err)
+
+	err = childservice.DeleteDeviceCredentials(store, childID)
+	assert.NoError(t, err)
+	assert.Empty(t, store.credentials)
+}
+
+func TestChildStoreLimit(t *testing.T) {
+	setupDynamoDBLocal(t, map[string]interface{}{
+		models.TABLE_NAME_ACCOUNT_CHILDREN: models.AccountChild{},
+		models.TABLE_NAME_ACCOUNT_PROFILES: models.AccountProfile{},
+	})
+	store := childservice.NewStore()
+	parentID := uuid.New().String()
+
+	for i := 0; i < defs.MaxChildProfiles; i++ {
+		err := store.LinkChild(&models.AccountChild{ParentID: parentID, ChildID: uuid.New().String()})
+		assert.NoError(t, err)
+	}
+	err := store.LinkChild(&models.AccountChild{ParentID: parentID, ChildID: "CHILD"})
+	assert.Equal(t, childservice.ErrTooManyChildren, err)
+
+	children, err := store.ListChildren(parentID)
+	assert.NoError(t, err)
+	assert.Len(t, children, defs.MaxChildProfiles)
+
+	// Unlinking a child profile frees its place, once only
+	err = store.UnlinkChild(parentID, children[0].ChildID)
+	assert.NoError(t, err)
+	err = store.UnlinkChild(parentID, children[0].ChildID)
+	assert.NoError(t, err)
+
+	err = store.LinkChild(&models.AccountChild{ParentID: parentID, ChildID: "CHILD"})
+	assert.NoError(t, err)
+	err = store.LinkChild(&models.AccountChild{ParentID: parentID, ChildID: uuid.New().String()})
+	assert.Equal(t, childservice.ErrTooManyChildren, err)
+}
@@ -0,0 +1,88 @@
+package test_test
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/defaultavatars"
+	"github.com/calmisland/go-testify/assert"
+)
+
+func TestDefaultAvatarInitials(t *testing.T) {
+	assert.Equal(t, "JD", defaultavatars.Initials("", "jane", "doe"))
+	assert.Equal(t, "JD", defaultavatars.Initials("Jane Mary Doe", "", ""))
+	assert.Equal(t, "김", defaultavatars.Initials("김민지", "", ""))
+	assert.Equal(t, "", defaultavatars.Initials("", "", ""))
+}
+
+func TestDefaultAvatarRender(t *testing.T) {
+	options := defaultavatars.Options{
+		AccountID: "3b7c2f42-4d1e-4a6c-9d3a-0f1e2d3c4b5a",
+		Initials:  "<&",
+		Size:      64,
+	}
+
+	avatar, err := defaultavatars.Render(options)
+	assert.NoError(t, err)
+	assert.Equal(t, defaultavatars.ContentTypeSVG, avatar.ContentType)
+	assert.True(t, strings.Contains(string(avatar.Data), "&lt;&amp;"))
+
+	// The rendering and the ETag are deterministic
+	sameAvatar, err := defaultavatars.Render(options)
+	assert.NoError(t, err)
+	assert.Equal(t, avatar.Data, sameAvatar.Data)
+	assert.Equal(t, defaultavatars.ETag(options), defaultavatars.ETag(options))
+
+	options.Format = defaultavatars.FormatPNG
+	assert.NotEqual(t, defaultavatars.ETag(defaultavatars.Options{AccountID: options.AccountID, Initials: "<&", Size: 64}), defaultavatars.ETag(options))
+
+	avatar, err = defaultavatars.Render(options)
+	assert.NoError(t, err)
+	assert.Equal(t, defaultavatars.ContentTypePNG, avatar.ContentType)
+
+	config, err := png.DecodeConfig(bytes.NewReader(avatar.Data))
+	assert.NoError(t, err)
+	assert.Equal(t, 64, config.Width)
+	assert.Equal(t, 64, config.Height)
+
+	// Initials are ignored by identicons, so name changes don't invalidate them
+	options.Initials = "AB"
+	assert.Equal(t, defaultavatars.ETag(defaultavatars.Options{AccountID: options.AccountID, Format: defaultavatars.FormatPNG, Size: 64}), defaultavatars.ETag(options))
+}
+
+func TestDefaultAvatarSupportedOptions(t *testing.T) {
+	assert.True(t, defaultavatars.IsSupported("", ""))
+	assert.True(t, defaultavatars.IsSupported(defaultavatars.StyleInitials, defaultavatars.FormatSVG))
+	assert.True(t, defaultavatars.IsSupported(defaultavatars.StyleIdenticon, defaultavatars.FormatPNG))
+	assert.True(t, defaultavatars.IsSupported("", defaultavatars.FormatPNG))
+
+	// The initials can't be rendered as PNG
+	assert.False(t, defaultavatars.IsSupported(defaultavatars.StyleInitials, defaultavatars.FormatPNG))
+	assert.False(t, defaultavatars.IsSupported("robot", ""))
+	assert.False(t, defaultavatars.IsSupported("", "gif"))
+}
+
+func TestDefaultAvatarLastModified(t *testing.T) {
+	options := defaultavatars.Options{
+		AccountID: "3b7c2f42-4d1e-4a6c-9d3a-0f1e2d3c4b5a",
+		Initials:  "JD",
+		Size:      64,
+	}
+	namesDate := time.Date(2022, 3, 1, 10, 15, 30, 500, time.UTC)
+
+	// The initials change with the names
+	assert.Equal(t, namesDate.Truncate(time.Second), defaultavatars.LastModified(options, namesDate))
+
+	// The identicons only change with the rendering
+	options.Style = defaultavatars.StyleIdenticon
+	identiconDate := defaultavatars.LastModified(options, namesDate)
+	assert.True(t, identiconDate.Before(namesDate))
+	assert.Equal(t, identiconDate, defaultavatars.LastModified(options, namesDate.AddDate(1, 0, 0)))
+
+	// Names edited before the rendering changed don't matter
+	options.Style = defaultavatars.StyleInitials
+	assert.Equal(t, identiconDate, defaultavatars.LastModified(options, time.Time{}))
+}
@@ -0,0 +1,28 @@
+package test_test
+
+import (
+	"os"
+	"testing"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/calmisland/go-testify/assert"
+)
+
+// setupDynamoDBLocal creates the tables of the stores tested against DynamoDB Local, by table name with their item model.
+// The tests are skipped unless DYNAMODB_ENDPOINT is set, as by `make test-dynamodb-local` with the docker-compose service.
+func setupDynamoDBLocal(t *testing.T, tables map[string]interface{}) {
+	if len(os.Getenv("DYNAMODB_ENDPOINT")) == 0 {
+		t.Skip("DYNAMODB_ENDPOINT is not set")
+	}
+
+	for tableName, model := range tables {
+		err := models.GetDB().CreateTable(models.GetTableName(tableName), model).OnDemand(true).Run()
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dynamodb.ErrCodeResourceInUseException {
+			// The table was created by another test
+			err = nil
+		}
+		assert.NoError(t, err)
+	}
+}
@@ -0,0 +1,131 @@
+package test_test
+
+import (
+	"testing"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/entitlements"
+	"github.com/calmisland/go-testify/assert"
+)
+
+const testDay = 24 * 60 * 60 * 1000
+
+func newTestPassTransaction(transactionID string, passID string, startDate int, expirationDate int) *models.AccountTransaction {
+	return &models.AccountTransaction{
+		AccountID:     "TEST-ACCOUNT",
+		TransactionID: transactionID,
+		Passes: map[string]*models.AccountTransactionItem{
+			passID: {StartDate: startDate, ExpirationDate: expirationDate},
+		},
+	}
+}
+
+func TestEntitlementsActivePass(t *testing.T) {
+	now := int64(100 * testDay)
+	result := entitlements.Compute([]*models.AccountTransaction{
+		newTestPassTransaction("t1", "premium", 90*testDay, 120*testDay),
+		newTestPassTransaction("t2", "expired", 10*testDay, 40*testDay),
+		newTestPassTransaction("t3", "upcoming", 110*testDay, 140*testDay),
+	}, now)
+
+	assert.Len(t, result.Passes, 1)
+	assert.Equal(t, "premium", result.Passes[0].ID)
+	assert.Equal(t, int64(90*testDay), result.Passes[0].StartDate)
+	assert.Equal(t, int64(120*testDay), result.Passes[0].ExpirationDate)
+	assert.Empty(t, result.Products)
+}
+
+func TestEntitlementsMergesExtensions(t *testing.T) {
+	now := int64(100 * testDay)
+	result := entitlements.Compute([]*models.AccountTransaction{
+		// The initial pass, an overlapping one, and a renewal bought in advance starting at the expiration
+		newTestPassTransaction("t1", "premium", 70*testDay, 101*testDay),
+		newTestPassTransaction("t2", "premium", 80*testDay, 95*testDay),
+		newTestPassTransaction("t3", "premium", 101*testDay, 131*testDay),
+		// A later period that doesn't follow the others
+		newTestPassTransaction("t4", "premium", 200*testDay, 230*testDay),
+	}, now)
+
+	assert.Len(t, result.Passes, 1)
+	assert.Equal(t, int64(70*testDay), result.Passes[0].StartDate)
+	assert.Equal(t, int64(131*testDay), result.Passes[0].ExpirationDate)
+	assert.Equal(t, []string{"t1", "t2", "t3"}, result.Passes[0].TransactionIDs)
+}
+
+func TestEntitlementsGapAndPermanentProducts(t *testing.T) {
+	now := int64(100 * testDay)
+	result := entitlements.Compute([]*models.AccountTransaction{
+		newTestPassTransaction("t1", "premium", 10*testDay, 40*testDay),
+		newTestPassTransaction("t2", "premium", 95*testDay, 125*testDay),
+		{
+			TransactionID: "t3",
+			Products: map[string]*models.AccountTransactionItem{
+				"book-1": {StartDate: 20 * testDay},
+			},
+		},
+	}, now)
+
+	assert.Len(t, result.Passes, 1)
+	assert.Equal(t, int64(95*testDay), result.Passes[0].StartDate)
+	assert.Equal(t, []string{"t2"}, result.Passes[0].TransactionIDs)
+
+	assert.Len(t, result.Products, 1)
+	assert.Equal(t, "book-1", result.Products[0].ID)
+	assert.Equal(t, int64(0), result.Products[0].ExpirationDate)
+}
+
+func TestEntitlementsRevocation(t *testing.T) {
+	now := int64(100 * testDay)
+	granted := newTestPassTransaction("t1", "premium", 90*testDay, 120*testDay)
+	granted.CreatedDate = 90 * testDay
+	extended := newTestPassTransaction("t2", "premium", 120*testDay, 150*testDay)
+	extended.CreatedDate = 91 * testDay
+	revoked := &models.AccountTransaction{
+		AccountID:     "TEST-ACCOUNT",
+		TransactionID: "t3",
+		Passes: map[string]*models.AccountTransactionItem{
+			"premium": {StartDate: 95 * testDay, Revoked: true},
+		},
+		CreatedDate: 95 * testDay,
+	}
+
+	result := entitlements.Compute([]*models.AccountTransaction{granted, extended, revoked}, now)
+	assert.Empty(t, result.Passes)
+
+	// The passes granted after the revocation are not revoked
+	regranted := newTestPassTransaction("t4", "premium", 96*testDay, 110*testDay)
+	regranted.CreatedDate = 96 * testDay
+	result = entitlements.Compute([]*models.AccountTransaction{granted, extended, revoked, regranted}, now)
+	assert.Len(t, result.Passes, 1)
+	assert.Equal(t, int64(110*testDay), result.Passes[0].ExpirationDate)
+	assert.Equal(t, int64(96*testDay), result.Passes[0].StartDate)
+	assert.Equal(t, []string{"t4"}, result.Passes[0].TransactionIDs)
+}
+
+func TestEntitlementsSharedByFamily(t *testing.T) {
+	now := int64(100 * testDay)
+	shareable := newTestPassTransaction("o1", "premium", 90*testDay, 120*testDay)
+	shareable.Passes["premium"].Shareable = true
+	ownerTransactions := []*models.AccountTransaction{
+		shareable,
+		newTestPassTransaction("o2", "private", 90*testDay, 120*testDay),
+	}
+
+	shared := entitlements.ComputeShared("OWNER-ACCOUNT", ownerTransactions, now)
+	assert.Len(t, shared.Passes, 1)
+	assert.Equal(t, "premium", shared.Passes[0].ID)
+	assert.Equal(t, "OWNER-ACCOUNT", shared.Passes[0].SharedBy)
+
+	own := entitlements.Compute([]*models.AccountTransaction{
+		newTestPassTransaction("m1", "premium", 95*testDay, 110*testDay),
+		newTestPassTransaction("m2", "extra", 95*testDay, 110*testDay),
+	}, now)
+	merged := entitlements.Merge(own, shared)
+	assert.Len(t, merged.Passes, 2)
+	assert.Equal(t, "extra", merged.Passes[0].ID)
+	assert.Equal(t, "premium", merged.Passes[1].ID)
+	assert.Equal(t, "", merged.Passes[1].SharedBy)
+	assert.Equal(t, int64(90*testDay), merged.Passes[1].StartDate)
+	assert.Equal(t, int64(120*testDay), merged.Passes[1].ExpirationDate)
+	assert.Equal(t, []string{"m1", "o1"}, merged.Passes[1].TransactionIDs)
+}
@@ -0,0 +1,47 @@
+package test_test
+
+import (
+	"testing"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/expiryreminders"
+	"github.com/calmisland/go-testify/assert"
+)
+
+func TestExpiryReminderWindows(t *testing.T) {
+	windows, err := expiryreminders.ParseWindows("")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 7}, windows)
+
+	windows, err = expiryreminders.ParseWindows(" 30, 3 ")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3, 30}, windows)
+
+	_, err = expiryreminders.ParseWindows("7,0")
+	assert.Error(t, err)
+}
+
+func TestDueExpiryReminders(t *testing.T) {
+	windows := []int{1, 7}
+	now := int64(100 * testDay)
+	transactions := []*models.AccountTransaction{
+		newTestPassTransaction("t1", "soon", 90*testDay, 105*testDay),
+		newTestPassTransaction("t2", "tomorrow", 90*testDay, 100*testDay+testDay/2),
+		newTestPassTransaction("t3", "later", 90*testDay, 120*testDay),
+		// The extension moves the expiration date of the pass out of the windows
+		newTestPassTransaction("t4", "extended", 90*testDay, 102*testDay),
+		newTestPassTransaction("t5", "extended", 102*testDay, 130*testDay),
+	}
+
+	reminders := expiryreminders.DueReminders("TEST-ACCOUNT", transactions, windows, now)
+	assert.Len(t, reminders, 2)
+
+	windowsByPass := map[string]int{}
+	for _, reminder := range reminders {
+		windowsByPass[reminder.PassID] = reminder.WindowDays
+	}
+	assert.Equal(t, map[string]int{"soon": 7, "tomorrow": 1}, windowsByPass)
+
+	reminder := &expiryreminders.Reminder{PassID: "soon", ExpirationDate: 105 * testDay, WindowDays: 7}
+	assert.Equal(t, "soon#9072000000#7", reminder.ID())
+}
@@ -0,0 +1,95 @@
+package test_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/kl15migrationservice"
+	"bitbucket.org/calmisland/go-server-account/accountdatabase"
+	"github.com/calmisland/go-testify/assert"
+	"github.com/google/uuid"
+)
+
+func TestKl15MigrationReportRange(t *testing.T) {
+	fallback := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	day, err := kl15migrationservice.ParseReportDay("", fallback)
+	assert.NoError(t, err)
+	assert.Equal(t, fallback, day)
+
+	from, err := kl15migrationservice.ParseReportDay("2021-01-01", fallback)
+	assert.NoError(t, err)
+	_, err = kl15migrationservice.ParseReportDay("01/01/2021", fallback)
+	assert.Error(t, err)
+
+	assert.True(t, kl15migrationservice.IsValidReportRange(from, from))
+	assert.True(t, kl15migrationservice.IsValidReportRange(from, from.AddDate(0, 0, kl15migrationservice.MaxReportDays-1)))
+	assert.False(t, kl15migrationservice.IsValidReportRange(from, from.AddDate(0, 0, kl15migrationservice.MaxReportDays)))
+	assert.False(t, kl15migrationservice.IsValidReportRange(from, from.AddDate(0, 0, -1)))
+
+	defaultFrom, defaultTo := kl15migrationservice.DefaultReportRange()
+	assert.True(t, kl15migrationservice.IsValidReportRange(defaultFrom, defaultTo))
+}
+
+func TestKl15MigrationFailureReasons(t *testing.T) {
+	assert.True(t, kl15migrationservice.IsMigrationFailure(kl15migrationservice.FailureReasonEmailAlreadyUsed))
+	assert.True(t, kl15migrationservice.IsMigrationFailure(kl15migrationservice.FailureReasonPhoneNumberAlreadyUsed))
+	assert.True(t, kl15migrationservice.IsMigrationFailure(kl15migrationservice.FailureReasonInternalError))
+
+	// Rejected sign-in attempts are not failures of the migration
+	assert.False(t, kl15migrationservice.IsMigrationFailure(kl15migrationservice.FailureReasonInvalidPassword))
+	assert.False(t, kl15migrationservice.IsMigrationFailure(kl15migrationservice.FailureReasonAccountNotFound))
+}
+
+func TestKl15MigrationStatusCounts(t *testing.T) {
+	setupDynamoDBLocal(t, map[string]interface{}{
+		models.TABLE_NAME_ACCOUNTS_MIGRATION_KL1DOT5:        models.AccountMigrationKl1dot5{},
+		models.TABLE_NAME_ACCOUNTS_MIGRATION_KL1DOT5_EVENTS: models.AccountMigrationKl1dot5Event{},
+	})
+	record := &models.AccountMigrationKl1dot5{Email: uuid.New().String() + "@example.com"}
+	table := models.GetDB().Table(models.GetTableName(models.TABLE_NAME_ACCOUNTS_MIGRATION_KL1DOT5))
+	assert.NoError(t, table.Put(record).Run())
+
+	initial, err := kl15migrationservice.RecountStatuses()
+	assert.NoError(t, err)
+
+	err = kl15migrationservice.SetRecordMigrationStatus(record, kl15migrationservice.MigrationStatusFailed)
+	assert.NoError(t, err)
+	// The record is read again since its status changed after it was read
+	err = kl15migrationservice.SetRecordMigrationStatus(record, accountdatabase.AccountsKl1dot5MigrationStatusDone)
+	assert.NoError(t, err)
+
+	counts, err := kl15migrationservice.GetStatusCounts()
+	assert.NoError(t, err)
+	assert.Equal(t, initial.Done+1, counts.Done)
+	assert.Equal(t, initial.Failed, counts.Failed)
+
+	recounted, err := kl15migrationservice.RecountStatuses()
+	assert.NoError(t, err)
+	assert.Equal(t, counts.Done, recounted.Done)
+	assert.Equal(t, counts.Failed, recounted.Failed)
+}
+
+func TestKl15MigrationPhoneNumberRecord(t *testing.T) {
+	setupDynamoDBLocal(t, map[string]interface{}{
+		models.TABLE_NAME_ACCOUNTS_MIGRATION_KL1DOT5: models.AccountMigrationKl1dot5{},
+	})
+	// Phone-only records are keyed by their legacy login ID
+	loginID := uuid.New().String()
+	phoneNumber := fmt.Sprintf("+8210%08d", time.Now().UnixNano()%100000000)
+	record := &models.AccountMigrationKl1dot5{Email: loginID, PhoneNumber: phoneNumber}
+	table := models.GetDB().Table(models.GetTableName(models.TABLE_NAME_ACCOUNTS_MIGRATION_KL1DOT5))
+	assert.NoError(t, table.Put(record).Run())
+
+	found, ok, err := kl15migrationservice.GetRecord("", phoneNumber)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, loginID, found.Email)
+
+	found, ok, err = kl15migrationservice.GetRecordByKey(loginID)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, phoneNumber, found.PhoneNumber)
+}
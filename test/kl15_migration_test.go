@@ -0,0 +1,71 @@
+package test_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/kl15migrationservice"
+	"bitbucket.org/calmisland/go-server-account/accountdatabase"
+	"github.com/calmisland/go-testify/assert"
+)
+
+func TestKl15MigrationCheckpoint(t *testing.T) {
+	checkpoint := kl15migrationservice.NewCheckpoint()
+	failedRecord := &models.AccountMigrationKl1dot5{Email: "failed@example.com"}
+
+	checkpoint.RecordFailure(failedRecord)
+	checkpoint.RecordFailure(failedRecord)
+	checkpoint.RecordOutcome(&models.AccountMigrationKl1dot5{Email: "created@example.com"}, kl15migrationservice.OutcomeCreated)
+	assert.Equal(t, []string{"failed@example.com"}, checkpoint.FailedKeys)
+
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	assert.NoError(t, checkpoint.Save(path))
+
+	// The failed records are kept across runs until they are retried successfully
+	loaded, err := kl15migrationservice.LoadCheckpoint(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"failed@example.com"}, loaded.FailedKeys)
+	assert.Equal(t, 1, loaded.Outcomes[kl15migrationservice.OutcomeCreated])
+
+	loaded.RecordOutcome(failedRecord, kl15migrationservice.OutcomeCreated)
+	assert.Empty(t, loaded.FailedKeys)
+	assert.Equal(t, 2, loaded.Outcomes[kl15migrationservice.OutcomeCreated])
+
+	// Restarting starts the scan again but keeps the failed records to retry
+	loaded.RecordFailure(&models.AccountMigrationKl1dot5{Email: "failed-again@example.com"})
+	loaded.Done = true
+	restarted := loaded.Restart()
+	assert.False(t, restarted.Done)
+	assert.Nil(t, restarted.LastKey)
+	assert.Equal(t, 0, restarted.Outcomes[kl15migrationservice.OutcomeCreated])
+	assert.Equal(t, []string{"failed-again@example.com"}, restarted.FailedKeys)
+
+	missing, err := kl15migrationservice.LoadCheckpoint(filepath.Join(t.TempDir(), "missing.json"))
+	assert.NoError(t, err)
+	assert.False(t, missing.Done)
+	assert.NotNil(t, missing.Outcomes)
+}
+
+func TestKl15PrecreateAccountSkippedRecords(t *testing.T) {
+	outcome, err := kl15migrationservice.PrecreateAccount(&models.AccountMigrationKl1dot5{
+		Email:           "migrated@example.com",
+		MigrationStatus: accountdatabase.AccountsKl1dot5MigrationStatusDone,
+	}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, kl15migrationservice.OutcomeAlreadyMigrated, outcome)
+
+	// Phone-only records keep their legacy login ID as the key, which is not an email
+	outcome, err = kl15migrationservice.PrecreateAccount(&models.AccountMigrationKl1dot5{
+		Email:       "legacy-login",
+		PhoneNumber: "not a phone number",
+	}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, kl15migrationservice.OutcomeInvalid, outcome)
+
+	outcome, err = kl15migrationservice.PrecreateAccount(&models.AccountMigrationKl1dot5{
+		Email: "legacy-login",
+	}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, kl15migrationservice.OutcomeInvalid, outcome)
+}
@@ -0,0 +1,170 @@
+package test_test
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/legacypasswords"
+	"bitbucket.org/calmisland/go-server-account/accountdatabase"
+	"bitbucket.org/calmisland/go-server-account/accounts"
+	"bitbucket.org/calmisland/go-server-security/passwords"
+	"github.com/calmisland/go-testify/assert"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+func TestLegacyPasswordPbkdf2(t *testing.T) {
+	key := pbkdf2.Key([]byte("p4ssword"), []byte("salt"), 1000, 32, sha256.New)
+	hash := &legacypasswords.Hash{
+		Algorithm:  legacypasswords.AlgorithmPbkdf2Sha256,
+		Hash:       base64.StdEncoding.EncodeToString(key),
+		Salt:       "salt",
+		Iterations: 1000,
+	}
+
+	isPassed, err := legacypasswords.Verify("p4ssword", hash)
+	assert.NoError(t, err)
+	assert.True(t, isPassed)
+
+	isPassed, err = legacypasswords.Verify("password", hash)
+	assert.NoError(t, err)
+	assert.False(t, isPassed)
+
+	hash.Iterations = 0
+	_, err = legacypasswords.Verify("p4ssword", hash)
+	assert.Equal(t, legacypasswords.ErrInvalidHash, err)
+}
+
+func TestLegacyPasswordSaltedMd5(t *testing.T) {
+	sum := md5.Sum([]byte("saltp4ssword"))
+	hash := &legacypasswords.Hash{
+		Algorithm: legacypasswords.AlgorithmSaltedMd5,
+		Hash:      hex.EncodeToString(sum[:]),
+		Salt:      "salt",
+	}
+
+	isPassed, err := legacypasswords.Verify("p4ssword", hash)
+	assert.NoError(t, err)
+	assert.True(t, isPassed)
+
+	isPassed, err = legacypasswords.Verify("password", hash)
+	assert.NoError(t, err)
+	assert.False(t, isPassed)
+}
+
+func TestLegacyPasswordUnknownAlgorithm(t *testing.T) {
+	assert.False(t, legacypasswords.IsRegistered("bcrypt"))
+
+	_, err := legacypasswords.Verify("p4ssword", &legacypasswords.Hash{Algorithm: "bcrypt"})
+	assert.Equal(t, legacypasswords.ErrUnknownAlgorithm, err)
+}
+
+// setupPasswordHasher sets a fast password hasher.
+func setupPasswordHasher(t *testing.T) {
+	var err error
+	globals.PasswordHasher, err = passwords.NewPasswordHasher(passwords.PasswordHashConfig{
+		DefaultCost: 5,
+		SecureCost:  5,
+	})
+	assert.NoError(t, err)
+}
+
+// legacyPasswordStore is an in-memory store of the legacy password hashes.
+type legacyPasswordStore struct {
+	hashes map[string]*models.AccountLegacyPassword
+}
+
+func (store *legacyPasswordStore) PutHash(hash *models.AccountLegacyPassword) error {
+	store.hashes[hash.AccountID] = hash
+	return nil
+}
+
+func (store *legacyPasswordStore) GetHash(accountID string) (*models.AccountLegacyPassword, error) {
+	return store.hashes[accountID], nil
+}
+
+func (store *legacyPasswordStore) DeleteHash(accountID string) error {
+	delete(store.hashes, accountID)
+	return nil
+}
+
+// accountFlagsDatabase is an account database with the password hashes and the flags of the accounts.
+type accountFlagsDatabase struct {
+	accountdatabase.Database
+	passwordHashes map[string]string
+	flags          map[string]int32
+}
+
+func newAccountFlagsDatabase() *accountFlagsDatabase {
+	return &accountFlagsDatabase{
+		passwordHashes: map[string]string{},
+		flags:          map[string]int32{},
+	}
+}
+
+func (db *accountFlagsDatabase) EditAccount(accountID string, info *accountdatabase.AccountEditInfo) error {
+	if info.PasswordHash != nil {
+		db.passwordHashes[accountID] = *info.PasswordHash
+	}
+	return nil
+}
+
+func (db *accountFlagsDatabase) SetAccountFlags(accountID string, flags int32) error {
+	db.flags[accountID] |= flags
+	return nil
+}
+
+func (db *accountFlagsDatabase) RemoveAccountFlags(accountID string, flags int32) error {
+	db.flags[accountID] &^= flags
+	return nil
+}
+
+func TestMigrateLegacyPassword(t *testing.T) {
+	setupPasswordHasher(t)
+	db := newAccountFlagsDatabase()
+	globals.AccountDatabase = db
+
+	sum := md5.Sum([]byte("saltp4ssword"))
+	hash := &legacypasswords.Hash{
+		Algorithm: legacypasswords.AlgorithmSaltedMd5,
+		Hash:      hex.EncodeToString(sum[:]),
+		Salt:      "salt",
+	}
+	store := &legacyPasswordStore{hashes: map[string]*models.AccountLegacyPassword{}}
+	for _, accountID := range []string{"EMAIL", "PHONE"} {
+		assert.NoError(t, legacypasswords.Save(store, accountID, hash, "legacy"))
+		db.flags[accountID] = accounts.MustSetPasswordFlag
+	}
+
+	isMigrated, err := legacypasswords.MigratePassword(store, "EMAIL", "password", true)
+	assert.NoError(t, err)
+	assert.False(t, isMigrated)
+	assert.Equal(t, int32(accounts.MustSetPasswordFlag), db.flags["EMAIL"])
+
+	// The login ID used to sign in is verified
+	isMigrated, err = legacypasswords.MigratePassword(store, "EMAIL", "p4ssword", true)
+	assert.NoError(t, err)
+	assert.True(t, isMigrated)
+	assert.True(t, accounts.IsAccountVerified(db.flags["EMAIL"]))
+	assert.True(t, accounts.IsAccountEmailVerified(db.flags["EMAIL"]))
+	assert.False(t, accounts.IsAccountPhoneNumberVerified(db.flags["EMAIL"]))
+	assert.False(t, accounts.AccountMustSetPassword(db.flags["EMAIL"]))
+	assert.True(t, globals.PasswordHasher.VerifyPasswordHash("p4ssword", db.passwordHashes["EMAIL"]))
+
+	isMigrated, err = legacypasswords.MigratePassword(store, "PHONE", "p4ssword", false)
+	assert.NoError(t, err)
+	assert.True(t, isMigrated)
+	assert.True(t, accounts.IsAccountVerified(db.flags["PHONE"]))
+	assert.True(t, accounts.IsAccountPhoneNumberVerified(db.flags["PHONE"]))
+	assert.False(t, accounts.IsAccountEmailVerified(db.flags["PHONE"]))
+
+	// The legacy hash is only used once
+	assert.Empty(t, store.hashes)
+	isMigrated, err = legacypasswords.MigratePassword(store, "EMAIL", "p4ssword", true)
+	assert.NoError(t, err)
+	assert.False(t, isMigrated)
+}
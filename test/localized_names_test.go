@@ -0,0 +1,41 @@
+package test_test
+
+import (
+	"testing"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/localizednames"
+	"github.com/calmisland/go-testify/assert"
+)
+
+func TestNameNormalization(t *testing.T) {
+	// The same names typed as decomposed characters and as precomposed ones
+	assert.Equal(t, "\uac00", defs.SanitizeName("\u1100\u1161"))
+	assert.Equal(t, "Ren\u00e9", defs.SanitizeName("Rene\u0301"))
+
+	assert.Equal(t, 3, defs.NameLength("김민지"))
+	field, _ := defs.CheckNameLengths("", "가나다라마바사아자차카타파하가나다라마바사아자차카타파하가나다라", "")
+	assert.Equal(t, "", field)
+	field, maxLength := defs.CheckNameLengths("", "가나다라마바사아자차카타파하가나다라마바사아자차카타파하가나다라마", "")
+	assert.Equal(t, "firstName", field)
+	assert.Equal(t, defs.MaxPartNameLength, maxLength)
+}
+
+func TestLocalizedNameTags(t *testing.T) {
+	tag, err := localizednames.ParseTag("ja-latn")
+	assert.NoError(t, err)
+	assert.Equal(t, "ja-Latn", tag)
+
+	_, err = localizednames.ParseTag("not a tag")
+	assert.Error(t, err)
+}
+
+func TestLocalizedNameSelection(t *testing.T) {
+	variants := []string{"ja-Latn", "ko"}
+
+	assert.Equal(t, "", localizednames.Select("ja_JP", variants, localizednames.ParsePreferences("", "ja-JP,ja;q=0.9")))
+	assert.Equal(t, "ko", localizednames.Select("ja_JP", variants, localizednames.ParsePreferences("ko_KR", "ja")))
+	assert.Equal(t, "ja-Latn", localizednames.Select("ja_JP", variants, localizednames.ParsePreferences("", "en-US,en;q=0.9")))
+	assert.Equal(t, "", localizednames.Select("ja_JP", nil, localizednames.ParsePreferences("", "en-US")))
+	assert.Equal(t, "", localizednames.Select("ja_JP", variants, nil))
+}
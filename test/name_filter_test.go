@@ -0,0 +1,56 @@
+package test_test
+
+import (
+	"testing"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/namefilter"
+	"github.com/calmisland/go-testify/assert"
+)
+
+func newTestNameFilter() namefilter.Filter {
+	return namefilter.NewStaticFilter([]*models.NameFilterWord{
+		{Language: "en", Word: "badword", Match: namefilter.MatchWord},
+		{Language: "en", Word: "boob", Match: namefilter.MatchWord},
+		{Language: "ja", Word: "ばか", Match: namefilter.MatchSubstring},
+	})
+}
+
+func TestNameFilterBlocksVariants(t *testing.T) {
+	filter := newTestNameFilter()
+
+	for _, name := range []string{
+		"badword",
+		"John Badword",
+		"BADWORD",
+		"b4dw0rd",
+		"b@dword!",
+		"badword123",
+		"b a d w o r d",
+		"baaadwoooord",
+		"bádwörd",
+		"bаdwоrd", // Cyrillic a and o
+		"ｂａｄｗｏｒｄ",
+		"あのばかやろう",
+	} {
+		isBlocked, err := filter.IsBlocked(name)
+		assert.NoError(t, err)
+		assert.True(t, isBlocked, name)
+	}
+}
+
+func TestNameFilterAllowsInnocentNames(t *testing.T) {
+	filter := newTestNameFilter()
+
+	for _, name := range []string{
+		"John Doe",
+		"Bob",
+		"Badwordsworth",
+		"김민지",
+		"やまだ たろう",
+	} {
+		isBlocked, err := filter.IsBlocked(name)
+		assert.NoError(t, err)
+		assert.False(t, isBlocked, name)
+	}
+}
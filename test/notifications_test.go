@@ -0,0 +1,365 @@
+package test_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v1 "bitbucket.org/calmisland/account-lambda-funcs/internal/controllers/v1"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/messagetemplates"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notifications"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/notificationservice"
+	"bitbucket.org/calmisland/go-server-account/accountdatabase"
+	"bitbucket.org/calmisland/go-server-account/accounts"
+	"bitbucket.org/calmisland/go-server-messages/messages"
+	"github.com/calmisland/go-testify/assert"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+func TestNotificationPreferences(t *testing.T) {
+	preferences := notifications.Preferences{
+		notifications.CategorySecurity:  {notifications.ChannelEmail: notifications.StatusDisabled},
+		notifications.CategoryMarketing: {notifications.ChannelEmail: notifications.StatusPending, notifications.ChannelPush: notifications.StatusEnabled},
+		notifications.CategoryReminders: {notifications.ChannelSMS: notifications.StatusDisabled},
+	}
+
+	// The security and account notifications are mandatory
+	assert.True(t, preferences.Allows(notifications.CategorySecurity, notifications.ChannelEmail))
+	assert.True(t, notifications.IsMandatory(notifications.CategoryAccount))
+	assert.False(t, notifications.IsMandatory(notifications.CategoryProductNews))
+	// The marketing notifications wait for the double opt-in
+	assert.False(t, preferences.Allows(notifications.CategoryMarketing, notifications.ChannelEmail))
+	assert.True(t, preferences.Allows(notifications.CategoryMarketing, notifications.ChannelPush))
+	assert.False(t, preferences.Allows(notifications.CategoryMarketing, notifications.ChannelSMS))
+	assert.False(t, preferences.Allows(notifications.CategoryReminders, notifications.ChannelSMS))
+	assert.True(t, preferences.Allows(notifications.CategoryReminders, notifications.ChannelEmail))
+
+	// The accounts that never set their preferences only get the marketing notifications after opting in
+	var defaults notifications.Preferences
+	assert.True(t, defaults.Allows(notifications.CategoryProductNews, notifications.ChannelEmail))
+	assert.False(t, defaults.Allows(notifications.CategoryMarketing, notifications.ChannelEmail))
+
+	all := preferences.All()
+	assert.Len(t, all, 5)
+	assert.Equal(t, notifications.StatusEnabled, all[notifications.CategoryAccount][notifications.ChannelEmail])
+	assert.Equal(t, notifications.StatusEnabled, all[notifications.CategorySecurity][notifications.ChannelEmail])
+	assert.Equal(t, notifications.StatusPending, all[notifications.CategoryMarketing][notifications.ChannelEmail])
+	assert.Equal(t, notifications.StatusDisabled, all[notifications.CategoryMarketing][notifications.ChannelSMS])
+}
+
+func TestNotificationDoubleOptIn(t *testing.T) {
+	assert.True(t, notifications.RequiresDoubleOptIn(notifications.CategoryMarketing, notifications.ChannelEmail))
+	assert.True(t, notifications.RequiresDoubleOptIn(notifications.CategoryMarketing, notifications.ChannelSMS))
+	assert.False(t, notifications.RequiresDoubleOptIn(notifications.CategoryMarketing, notifications.ChannelPush))
+	assert.False(t, notifications.RequiresDoubleOptIn(notifications.CategoryReminders, notifications.ChannelEmail))
+
+	assert.Equal(t, notifications.ChannelEmail, notifications.GetChannel(messages.MessageTypeEmail))
+	assert.Equal(t, notifications.ChannelSMS, notifications.GetChannel(messages.MessageTypeSMS))
+}
+
+// optInStore is an in-memory store of the marketing opt-ins and the profiles. The write conditions of the real store
+// are checked against DynamoDB Local by TestNotificationStoreOptInConditions.
+type optInStore struct {
+	profiles map[string]*models.AccountProfile
+	optIns   map[string]*models.MarketingOptIn
+}
+
+func newOptInStore() *optInStore {
+	return &optInStore{
+		profiles: map[string]*models.AccountProfile{},
+		optIns:   map[string]*models.MarketingOptIn{},
+	}
+}
+
+func (store *optInStore) GetProfile(accountID string) (*models.AccountProfile, error) {
+	profile, ok := store.profiles[accountID]
+	if !ok {
+		profile = &models.AccountProfile{AccountID: accountID}
+		store.profiles[accountID] = profile
+	}
+	return profile, nil
+}
+
+func (store *optInStore) SetNotifications(accountID string, statuses map[string]map[string]string) error {
+	profile, _ := store.GetProfile(accountID)
+	if profile.Notifications == nil {
+		profile.Notifications = map[string]map[string]string{}
+	}
+	for category, channels := range statuses {
+		if profile.Notifications[category] == nil {
+			profile.Notifications[category] = map[string]string{}
+		}
+		for channel, status := range channels {
+			profile.Notifications[category][channel] = status
+		}
+	}
+	return nil
+}
+
+func (store *optInStore) PutOptIn(optIn *models.MarketingOptIn) error {
+	store.optIns[optIn.AccountID+"/"+optIn.Channel] = optIn
+	return nil
+}
+
+func (store *optInStore) GetOptIn(accountID string, channel string) (*models.MarketingOptIn, error) {
+	return store.optIns[accountID+"/"+channel], nil
+}
+
+func (store *optInStore) ConfirmOptIn(accountID string, channel string, confirmedDate int64, ipAddress string) error {
+	optIn := store.optIns[accountID+"/"+channel]
+	profile, _ := store.GetProfile(accountID)
+	if optIn == nil || optIn.Status != models.MarketingOptInStatusPending ||
+		profile.Notifications[notifications.CategoryMarketing][channel] != notifications.StatusPending {
+		return notificationservice.ErrOptInNotFound
+	}
+
+	optIn.Status = models.MarketingOptInStatusConfirmed
+	optIn.ConfirmedDate = confirmedDate
+	optIn.ConfirmIP = ipAddress
+	return store.SetNotifications(accountID, map[string]map[string]string{
+		notifications.CategoryMarketing: {channel: notifications.StatusEnabled},
+	})
+}
+
+func (store *optInStore) WithdrawOptIn(accountID string, channel string, withdrawnDate int64) error {
+	optIn := store.optIns[accountID+"/"+channel]
+	if optIn == nil {
+		return nil
+	} else if optIn.Status == models.MarketingOptInStatusConfirmed {
+		optIn.Status = models.MarketingOptInStatusWithdrawn
+		optIn.WithdrawnDate = withdrawnDate
+	} else if optIn.Status == models.MarketingOptInStatusPending {
+		delete(store.optIns, accountID+"/"+channel)
+	}
+	return nil
+}
+
+func (store *optInStore) ListOptIns(accountID string) ([]*models.MarketingOptIn, error) {
+	var optIns []*models.MarketingOptIn
+	for _, optIn := range store.optIns {
+		if optIn.AccountID == accountID {
+			optIns = append(optIns, optIn)
+		}
+	}
+	return optIns, nil
+}
+
+func (store *optInStore) DeleteOptIn(accountID string, channel string) error {
+	delete(store.optIns, accountID+"/"+channel)
+	return nil
+}
+
+// signInInfoDatabase is an account database with the sign-in info of the accounts.
+type signInInfoDatabase struct {
+	accountdatabase.Database
+	accounts map[string]*accountdatabase.AccountSignInInfo
+}
+
+func (db *signInInfoDatabase) GetAccountSignInInfoByID(accountID string) (*accountdatabase.AccountSignInInfo, error) {
+	return db.accounts[accountID], nil
+}
+
+// setupNotificationGlobals sets the globals used by the notification service, returning the message queue.
+func setupNotificationGlobals(t *testing.T) *messageQueue {
+	queue := setupParentalConsentGlobals(t)
+	globals.AccountDatabase = &signInInfoDatabase{
+		accounts: map[string]*accountdatabase.AccountSignInInfo{
+			"ACCOUNT": {
+				ID:       "ACCOUNT",
+				Email:    "user@example.com",
+				Language: "en_US",
+				Flags:    accounts.IsAccountEmailVerifiedFlag,
+			},
+		},
+	}
+	return queue
+}
+
+// enableMarketing enables the marketing notifications by email, returning the code of the opt-in.
+func enableMarketing(t *testing.T, store *optInStore, queue *messageQueue) string {
+	err := notificationservice.SetPreferences(store, "ACCOUNT", map[string]map[string]bool{
+		notifications.CategoryMarketing: {notifications.ChannelEmail: true},
+	}, notificationservice.Origin{IPAddress: "127.0.0.1"})
+	assert.NoError(t, err)
+
+	assert.NotEmpty(t, queue.messages)
+	message := queue.messages[len(queue.messages)-1]
+	assert.Equal(t, "user@example.com", message.Recipient)
+	template, ok := message.Template.(*messagetemplates.MarketingOptInTemplate)
+	assert.True(t, ok)
+	return template.Code
+}
+
+func setMarketing(t *testing.T, store *optInStore, enabled bool) {
+	err := notificationservice.SetPreferences(store, "ACCOUNT", map[string]map[string]bool{
+		notifications.CategoryMarketing: {notifications.ChannelEmail: enabled},
+	}, notificationservice.Origin{IPAddress: "127.0.0.1"})
+	assert.NoError(t, err)
+}
+
+func TestMarketingOptIn(t *testing.T) {
+	queue := setupNotificationGlobals(t)
+	store := newOptInStore()
+	origin := notificationservice.Origin{IPAddress: "127.0.0.1"}
+
+	code := enableMarketing(t, store, queue)
+	assert.Equal(t, notifications.StatusPending, store.profiles["ACCOUNT"].Notifications[notifications.CategoryMarketing][notifications.ChannelEmail])
+	assert.Equal(t, models.MarketingOptInStatusPending, store.optIns["ACCOUNT/email"].Status)
+
+	err := notificationservice.ConfirmOptIn(store, "ACCOUNT", notifications.ChannelEmail, "WRONG", origin)
+	assert.Equal(t, notificationservice.ErrInvalidOptInCode, err)
+
+	err = notificationservice.ConfirmOptIn(store, "ACCOUNT", notifications.ChannelEmail, code, origin)
+	assert.NoError(t, err)
+	assert.Equal(t, notifications.StatusEnabled, store.profiles["ACCOUNT"].Notifications[notifications.CategoryMarketing][notifications.ChannelEmail])
+	assert.Equal(t, models.MarketingOptInStatusConfirmed, store.optIns["ACCOUNT/email"].Status)
+	assert.Equal(t, "127.0.0.1", store.optIns["ACCOUNT/email"].ConfirmIP)
+
+	// A confirmed opt-in can't be confirmed again
+	err = notificationservice.ConfirmOptIn(store, "ACCOUNT", notifications.ChannelEmail, code, origin)
+	assert.Equal(t, notificationservice.ErrOptInNotFound, err)
+
+	// The confirmed opt-in is kept as the proof of the consent when withdrawn
+	setMarketing(t, store, false)
+	assert.Equal(t, notifications.StatusDisabled, store.profiles["ACCOUNT"].Notifications[notifications.CategoryMarketing][notifications.ChannelEmail])
+	assert.Equal(t, models.MarketingOptInStatusWithdrawn, store.optIns["ACCOUNT/email"].Status)
+	assert.True(t, store.optIns["ACCOUNT/email"].WithdrawnDate > 0)
+}
+
+func TestDisablePendingMarketingOptIn(t *testing.T) {
+	queue := setupNotificationGlobals(t)
+	store := newOptInStore()
+	origin := notificationservice.Origin{IPAddress: "127.0.0.1"}
+
+	code := enableMarketing(t, store, queue)
+	setMarketing(t, store, false)
+	assert.Equal(t, notifications.StatusDisabled, store.profiles["ACCOUNT"].Notifications[notifications.CategoryMarketing][notifications.ChannelEmail])
+	assert.Nil(t, store.optIns["ACCOUNT/email"])
+
+	// The code of the disabled opt-in can't enable the marketing notifications anymore
+	err := notificationservice.ConfirmOptIn(store, "ACCOUNT", notifications.ChannelEmail, code, origin)
+	assert.Equal(t, notificationservice.ErrOptInNotFound, err)
+	assert.Equal(t, notifications.StatusDisabled, store.profiles["ACCOUNT"].Notifications[notifications.CategoryMarketing][notifications.ChannelEmail])
+}
+
+func TestConfirmOptInRequiresPendingStatus(t *testing.T) {
+	queue := setupNotificationGlobals(t)
+	store := newOptInStore()
+
+	code := enableMarketing(t, store, queue)
+	// The status was disabled without withdrawing the opt-in
+	store.profiles["ACCOUNT"].Notifications[notifications.CategoryMarketing][notifications.ChannelEmail] = notifications.StatusDisabled
+
+	err := notificationservice.ConfirmOptIn(store, "ACCOUNT", notifications.ChannelEmail, code, notificationservice.Origin{})
+	assert.Equal(t, notificationservice.ErrOptInNotFound, err)
+	assert.Equal(t, models.MarketingOptInStatusPending, store.optIns["ACCOUNT/email"].Status)
+}
+
+func TestMarketingOptInRestrictions(t *testing.T) {
+	setupNotificationGlobals(t)
+	store := newOptInStore()
+	store.profiles["CHILD"] = &models.AccountProfile{AccountID: "CHILD", IsChild: true}
+
+	err := notificationservice.SetPreferences(store, "CHILD", map[string]map[string]bool{
+		notifications.CategoryMarketing: {notifications.ChannelPush: true},
+	}, notificationservice.Origin{})
+	assert.Equal(t, notificationservice.ErrChildAccount, err)
+
+	err = notificationservice.SetPreferences(store, "ACCOUNT", map[string]map[string]bool{
+		notifications.CategoryMarketing: {notifications.ChannelSMS: true},
+	}, notificationservice.Origin{})
+	assert.Equal(t, notificationservice.ErrNoRecipient, err)
+	assert.Empty(t, store.optIns)
+
+	// The push notifications don't need a double opt-in
+	err = notificationservice.SetPreferences(store, "ACCOUNT", map[string]map[string]bool{
+		notifications.CategoryMarketing: {notifications.ChannelPush: true},
+	}, notificationservice.Origin{})
+	assert.NoError(t, err)
+	assert.Equal(t, notifications.StatusEnabled, store.profiles["ACCOUNT"].Notifications[notifications.CategoryMarketing][notifications.ChannelPush])
+}
+
+func TestDeleteOptIns(t *testing.T) {
+	store := newOptInStore()
+	store.optIns["ACCOUNT/email"] = &models.MarketingOptIn{AccountID: "ACCOUNT", Channel: notifications.ChannelEmail}
+	store.optIns["ACCOUNT/sms"] = &models.MarketingOptIn{AccountID: "ACCOUNT", Channel: notifications.ChannelSMS}
+	store.optIns["OTHER/email"] = &models.MarketingOptIn{AccountID: "OTHER", Channel: notifications.ChannelEmail}
+
+	assert.NoError(t, notificationservice.DeleteOptIns(store, "ACCOUNT"))
+	assert.Len(t, store.optIns, 1)
+	assert.NotNil(t, store.optIns["OTHER/email"])
+}
+
+func TestNotificationHandlerRequests(t *testing.T) {
+	e := echo.New()
+	requests := map[string]echo.HandlerFunc{
+		`not json`: v1.HandleEditSelfNotifications,
+		`{"preferences": {"unknown": {"email": true}}}`:   v1.HandleEditSelfNotifications,
+		`{"preferences": {"marketing": {"fax": true}}}`:   v1.HandleEditSelfNotifications,
+		`{"preferences": {"security": {"email": false}}}`: v1.HandleEditSelfNotifications,
+		`{"preferences": {"account": {"sms": false}}}`:    v1.HandleEditSelfNotifications,
+		`{"channel": "push", "code": "CODE"}`:             v1.HandleConfirmSelfNotifications,
+		`{"channel": "email"}`:                            v1.HandleConfirmSelfNotifications,
+	}
+
+	for body, handler := range requests {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		assert.NoError(t, handler(e.NewContext(req, rec)))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+}
+
+func TestNotificationStoreOptInConditions(t *testing.T) {
+	setupDynamoDBLocal(t, map[string]interface{}{
+		models.TABLE_NAME_MARKETING_OPT_INS: models.MarketingOptIn{},
+		models.TABLE_NAME_ACCOUNT_PROFILES:  models.AccountProfile{},
+	})
+	store := notificationservice.NewStore()
+	accountID := uuid.New().String()
+	channel := notifications.ChannelEmail
+
+	err := store.PutOptIn(&models.MarketingOptIn{AccountID: accountID, Channel: channel, Status: models.MarketingOptInStatusPending})
+	assert.NoError(t, err)
+	// The opt-in can't be confirmed while the marketing notifications of the channel aren't pending
+	err = store.ConfirmOptIn(accountID, channel, 1000, "127.0.0.1")
+	assert.Equal(t, notificationservice.ErrOptInNotFound, err)
+
+	err = store.SetNotifications(accountID, map[string]map[string]string{
+		notifications.CategoryMarketing: {channel: notifications.StatusPending},
+	})
+	assert.NoError(t, err)
+	err = store.ConfirmOptIn(accountID, channel, 1000, "127.0.0.1")
+	assert.NoError(t, err)
+	err = store.ConfirmOptIn(accountID, channel, 2000, "127.0.0.1")
+	assert.Equal(t, notificationservice.ErrOptInNotFound, err)
+
+	profile, err := store.GetProfile(accountID)
+	assert.NoError(t, err)
+	assert.Equal(t, notifications.StatusEnabled, profile.Notifications[notifications.CategoryMarketing][channel])
+
+	// A confirmed opt-in is kept as withdrawn, a pending one is deleted
+	err = store.WithdrawOptIn(accountID, channel, 3000)
+	assert.NoError(t, err)
+	optIn, err := store.GetOptIn(accountID, channel)
+	assert.NoError(t, err)
+	assert.Equal(t, models.MarketingOptInStatusWithdrawn, optIn.Status)
+
+	err = store.PutOptIn(&models.MarketingOptIn{AccountID: accountID, Channel: notifications.ChannelSMS, Status: models.MarketingOptInStatusPending})
+	assert.NoError(t, err)
+	err = store.WithdrawOptIn(accountID, notifications.ChannelSMS, 3000)
+	assert.NoError(t, err)
+	optIn, err = store.GetOptIn(accountID, notifications.ChannelSMS)
+	assert.NoError(t, err)
+	assert.Nil(t, optIn)
+
+	// Withdrawing without an opt-in does nothing
+	err = store.WithdrawOptIn(accountID, notifications.ChannelPush, 3000)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,393 @@
+package test_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "bitbucket.org/calmisland/account-lambda-funcs/internal/controllers/v1"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/messagetemplates"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/accountverificationservice/accountverificationservicemock"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/parentalconsentservice"
+	"bitbucket.org/calmisland/go-server-messages/messages"
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+	"github.com/calmisland/go-testify/assert"
+	"github.com/calmisland/go-testify/mock"
+	"github.com/labstack/echo/v4"
+)
+
+func TestAge(t *testing.T) {
+	now := time.Date(2021, time.June, 15, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 11, defs.GetAge(2010, 1, now))
+	assert.Equal(t, 10, defs.GetAge(2010, 12, now))
+	// The youngest possible age is used when the birthday may not have passed yet
+	assert.Equal(t, 10, defs.GetAge(2010, 6, now))
+	assert.Equal(t, 10, defs.GetAge(2010, 0, now))
+	assert.Equal(t, 0, defs.GetAge(2021, 0, now))
+}
+
+func TestRequiresParentalConsent(t *testing.T) {
+	now := time.Date(2021, time.June, 15, 0, 0, 0, 0, time.UTC)
+
+	assert.True(t, defs.RequiresParentalConsent(2008, 12, "US", now))
+	assert.False(t, defs.RequiresParentalConsent(2008, 1, "US", now))
+	assert.True(t, defs.RequiresParentalConsent(2008, 0, "US", now))
+	assert.False(t, defs.RequiresParentalConsent(2007, 0, "US", now))
+
+	// The Korean PIPA requires the consent under 14
+	assert.True(t, defs.RequiresParentalConsent(2008, 1, "KR", now))
+	assert.False(t, defs.RequiresParentalConsent(2006, 0, "KR", now))
+}
+
+func TestValidateSignUpBirthDate(t *testing.T) {
+	now := time.Date(2021, time.June, 15, 0, 0, 0, 0, time.UTC)
+
+	parentEmail, apiErr := defs.ValidateSignUpBirthDate(0, 0, "", "child@example.com", "US", now)
+	assert.Nil(t, apiErr)
+	assert.Empty(t, parentEmail)
+	_, apiErr = defs.ValidateSignUpBirthDate(0, 5, "", "child@example.com", "US", now)
+	assert.Equal(t, apierrors.ErrorInvalidParameters.WithField("birthMonth"), apiErr)
+	_, apiErr = defs.ValidateSignUpBirthDate(2022, 0, "", "child@example.com", "US", now)
+	assert.Equal(t, apierrors.ErrorInvalidParameters.WithField("birthYear"), apiErr)
+
+	// The parent email is only needed under the parental consent age of the country
+	parentEmail, apiErr = defs.ValidateSignUpBirthDate(2007, 0, " parent@example.com ", "child@example.com", "US", now)
+	assert.Nil(t, apiErr)
+	assert.Empty(t, parentEmail)
+	_, apiErr = defs.ValidateSignUpBirthDate(2007, 0, "", "child@example.com", "KR", now)
+	assert.Equal(t, apierrors.ErrorInvalidParameters.WithField("parentEmail"), apiErr)
+
+	parentEmail, apiErr = defs.ValidateSignUpBirthDate(2010, 0, " parent@example.com ", "child@example.com", "US", now)
+	assert.Nil(t, apiErr)
+	assert.Equal(t, "parent@example.com", parentEmail)
+	_, apiErr = defs.ValidateSignUpBirthDate(2010, 0, "Child@Example.com", "child@example.com", "US", now)
+	assert.Equal(t, apierrors.ErrorInputInvalidFormat.WithField("parentEmail"), apiErr)
+	_, apiErr = defs.ValidateSignUpBirthDate(2010, 0, "parent", "child@example.com", "US", now)
+	assert.Equal(t, apierrors.ErrorInputInvalidFormat.WithField("parentEmail"), apiErr)
+}
+
+func TestAgeBands(t *testing.T) {
+	now := time.Date(2021, time.June, 15, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, "", defs.GetAgeBand(0, 0, "US", now))
+	assert.Equal(t, defs.AgeBandChild, defs.GetAgeBand(2010, 1, "US", now))
+	assert.Equal(t, defs.AgeBandTeen, defs.GetAgeBand(2005, 1, "US", now))
+	assert.Equal(t, defs.AgeBandAdult, defs.GetAgeBand(2003, 1, "US", now))
+
+	// The thresholds depend on the country
+	assert.Equal(t, defs.AgeBandChild, defs.GetAgeBand(2008, 1, "KR", now))
+	assert.Equal(t, defs.AgeBandTeen, defs.GetAgeBand(2003, 1, "KR", now))
+	assert.Equal(t, defs.AgeBandAdult, defs.GetAgeBand(2002, 1, "KR", now))
+}
+
+// consentStore is an in-memory store of the parental consents.
+type consentStore struct {
+	consents map[string]*models.ParentalConsent
+	events   []models.ParentalConsentEvent
+	statuses map[string]string
+}
+
+func newConsentStore() *consentStore {
+	return &consentStore{
+		consents: map[string]*models.ParentalConsent{},
+		statuses: map[string]string{},
+	}
+}
+
+func (store *consentStore) PutConsent(consent *models.ParentalConsent) error {
+	store.consents[consent.ConsentID] = consent
+	return nil
+}
+
+func (store *consentStore) GetConsent(consentID string) (*models.ParentalConsent, error) {
+	consent, ok := store.consents[consentID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *consent
+	return &copied, nil
+}
+
+func (store *consentStore) ListConsents(accountID string) ([]*models.ParentalConsent, error) {
+	var consents []*models.ParentalConsent
+	for _, consent := range store.consents {
+		if consent.AccountID == accountID {
+			consents = append(consents, consent)
+		}
+	}
+	return consents, nil
+}
+
+func (store *consentStore) GrantConsent(consentID string, grantedDate int64) error {
+	consent := store.consents[consentID]
+	consent.Status = models.ParentalConsentStatusGranted
+	consent.GrantedDate = grantedDate
+	return nil
+}
+
+func (store *consentStore) DeleteConsent(consentID string) error {
+	delete(store.consents, consentID)
+	return nil
+}
+
+func (store *consentStore) PutEvent(event *models.ParentalConsentEvent) error {
+	store.events = append(store.events, *event)
+	return nil
+}
+
+func (store *consentStore) ListEvents(accountID string) ([]models.ParentalConsentEvent, error) {
+	var events []models.ParentalConsentEvent
+	for i := len(store.events) - 1; i >= 0; i-- {
+		if store.events[i].AccountID == accountID {
+			events = append(events, store.events[i])
+		}
+	}
+	return events, nil
+}
+
+func (store *consentStore) SetConsentStatus(accountID string, status string) error {
+	store.statuses[accountID] = status
+	return nil
+}
+
+// putTestConsent puts a consent request with a known code.
+func putTestConsent(t *testing.T, store *consentStore, consentID string, code string, expirationDate int64) {
+	codeHash, err := globals.PasswordHasher.GeneratePasswordHash(code, false)
+	assert.NoError(t, err)
+
+	store.consents[consentID] = &models.ParentalConsent{
+		ConsentID:      consentID,
+		AccountID:      "CHILD",
+		ParentEmail:    "parent@example.com",
+		CodeHash:       codeHash,
+		Status:         models.ParentalConsentStatusPending,
+		ExpirationDate: expirationDate,
+	}
+	store.statuses["CHILD"] = models.ParentalConsentStatusPending
+}
+
+// messageQueue records the enqueued messages.
+type messageQueue struct {
+	messages []*messages.Message
+}
+
+func (queue *messageQueue) EnqueueMessage(message *messages.Message) error {
+	queue.messages = append(queue.messages, message)
+	return nil
+}
+
+// setupParentalConsentGlobals sets the globals used by the parental consent service, returning the message queue.
+func setupParentalConsentGlobals(t *testing.T) *messageQueue {
+	setupPasswordHasher(t)
+
+	verificationService := &accountverificationservicemock.MockService{}
+	verificationService.On("GetParentalConsentLink", mock.Anything, mock.Anything, mock.Anything).Return("http://localhost:9999/consent")
+	globals.AccountVerificationService = verificationService
+
+	queue := &messageQueue{}
+	globals.MessageSendQueue = queue
+	return queue
+}
+
+func TestRequestParentalConsent(t *testing.T) {
+	queue := setupParentalConsentGlobals(t)
+	store := newConsentStore()
+	origin := parentalconsentservice.Origin{IPAddress: "127.0.0.1", UserAgent: "test"}
+
+	consent, err := parentalconsentservice.RequestConsent(store, "CHILD", "parent@example.com", "en_US", origin)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ParentalConsentStatusPending, consent.Status)
+	assert.True(t, consent.ExpirationDate > consent.CreatedDate)
+	assert.NotNil(t, store.consents[consent.ConsentID])
+	assert.Equal(t, models.ParentalConsentStatusPending, store.statuses["CHILD"])
+
+	// The request is recorded for audit
+	assert.Len(t, store.events, 1)
+	assert.Equal(t, models.ParentalConsentEventRequested, store.events[0].Event)
+	assert.Equal(t, models.ParentalConsentMethodEmail, store.events[0].Method)
+	assert.Equal(t, "127.0.0.1", store.events[0].IPAddress)
+
+	// The parent is asked to consent by email, whatever the preferences of the child account
+	assert.Len(t, queue.messages, 1)
+	assert.Equal(t, "parent@example.com", queue.messages[0].Recipient)
+	assert.Equal(t, messages.MessageTypeEmail, queue.messages[0].MessageType)
+	template, ok := queue.messages[0].Template.(*messagetemplates.ParentalConsentTemplate)
+	assert.True(t, ok)
+	assert.Equal(t, consent.ExpirationDate, template.ExpirationDate)
+	assert.NotEmpty(t, template.Link)
+}
+
+func TestResendParentalConsent(t *testing.T) {
+	queue := setupParentalConsentGlobals(t)
+	store := newConsentStore()
+	origin := parentalconsentservice.Origin{IPAddress: "127.0.0.1"}
+
+	// An account that never requested a consent has no parent to send it to
+	_, err := parentalconsentservice.ResendConsent(store, "CHILD", "en_US", origin)
+	assert.Equal(t, parentalconsentservice.ErrConsentNotFound, err)
+	assert.Empty(t, queue.messages)
+
+	earlier := time.Now().Add(-time.Hour).UnixNano() / int64(time.Millisecond)
+	putTestConsent(t, store, "EXPIRED", "CODE", earlier)
+
+	consent, err := parentalconsentservice.ResendConsent(store, "CHILD", "en_US", origin)
+	assert.NoError(t, err)
+	assert.Equal(t, "parent@example.com", consent.ParentEmail)
+	assert.True(t, consent.ExpirationDate > consent.CreatedDate)
+	assert.Equal(t, models.ParentalConsentStatusPending, store.statuses["CHILD"])
+
+	// Only the new request can be granted
+	assert.Nil(t, store.consents["EXPIRED"])
+	assert.Len(t, store.consents, 1)
+	assert.NotNil(t, store.consents[consent.ConsentID])
+	assert.Len(t, queue.messages, 1)
+	assert.Equal(t, "parent@example.com", queue.messages[0].Recipient)
+
+	// The last request is resent again
+	resent, err := parentalconsentservice.ResendConsent(store, "CHILD", "en_US", origin)
+	assert.NoError(t, err)
+	assert.NotEqual(t, consent.ConsentID, resent.ConsentID)
+	assert.Len(t, store.consents, 1)
+	assert.Len(t, queue.messages, 2)
+	assert.Len(t, store.events, 2)
+}
+
+func TestGrantParentalConsent(t *testing.T) {
+	setupParentalConsentGlobals(t)
+	store := newConsentStore()
+	origin := parentalconsentservice.Origin{IPAddress: "127.0.0.1"}
+	later := time.Now().Add(time.Hour).UnixNano() / int64(time.Millisecond)
+	putTestConsent(t, store, "CONSENT", "CODE", later)
+
+	_, err := parentalconsentservice.GrantConsent(store, "UNKNOWN", "CODE", origin)
+	assert.Equal(t, parentalconsentservice.ErrConsentNotFound, err)
+	_, err = parentalconsentservice.GrantConsent(store, "CONSENT", "WRONG", origin)
+	assert.Equal(t, parentalconsentservice.ErrInvalidConsentCode, err)
+	assert.Equal(t, models.ParentalConsentStatusPending, store.statuses["CHILD"])
+	assert.Empty(t, store.events)
+
+	consent, err := parentalconsentservice.GrantConsent(store, "CONSENT", "CODE", origin)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ParentalConsentStatusGranted, consent.Status)
+	assert.Equal(t, models.ParentalConsentStatusGranted, store.consents["CONSENT"].Status)
+	assert.Equal(t, models.ParentalConsentStatusGranted, store.statuses["CHILD"])
+	assert.Len(t, store.events, 1)
+	assert.Equal(t, models.ParentalConsentEventGranted, store.events[0].Event)
+
+	// Granting again doesn't record another consent
+	_, err = parentalconsentservice.GrantConsent(store, "CONSENT", "CODE", origin)
+	assert.NoError(t, err)
+	assert.Len(t, store.events, 1)
+}
+
+func TestGrantExpiredParentalConsent(t *testing.T) {
+	setupParentalConsentGlobals(t)
+	store := newConsentStore()
+	earlier := time.Now().Add(-time.Hour).UnixNano() / int64(time.Millisecond)
+	putTestConsent(t, store, "CONSENT", "CODE", earlier)
+
+	_, err := parentalconsentservice.GrantConsent(store, "CONSENT", "CODE", parentalconsentservice.Origin{})
+	assert.Equal(t, parentalconsentservice.ErrConsentNotFound, err)
+	assert.Equal(t, models.ParentalConsentStatusPending, store.statuses["CHILD"])
+}
+
+func TestWithdrawParentalConsent(t *testing.T) {
+	setupParentalConsentGlobals(t)
+	store := newConsentStore()
+	origin := parentalconsentservice.Origin{IPAddress: "127.0.0.1"}
+	earlier := time.Now().Add(-time.Hour).UnixNano() / int64(time.Millisecond)
+	putTestConsent(t, store, "CONSENT", "CODE", earlier)
+
+	_, err := parentalconsentservice.WithdrawConsent(store, "CONSENT", "WRONG", origin)
+	assert.Equal(t, parentalconsentservice.ErrInvalidConsentCode, err)
+
+	// An expired request can still be refused
+	consent, err := parentalconsentservice.WithdrawConsent(store, "CONSENT", "CODE", origin)
+	assert.NoError(t, err)
+	assert.Equal(t, "CHILD", consent.AccountID)
+	assert.Len(t, store.events, 1)
+	assert.Equal(t, models.ParentalConsentEventWithdrawn, store.events[0].Event)
+	assert.Equal(t, "parent@example.com", store.events[0].ParentEmail)
+}
+
+func TestParentAccountConsentEvents(t *testing.T) {
+	store := newConsentStore()
+	origin := parentalconsentservice.Origin{IPAddress: "127.0.0.1"}
+
+	err := parentalconsentservice.RecordParentAccountConsent(store, "CHILD", "PARENT", origin)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ParentalConsentStatusGranted, store.statuses["CHILD"])
+	err = parentalconsentservice.RecordParentAccountWithdrawal(store, "CHILD", "PARENT", origin)
+	assert.NoError(t, err)
+	store.events[0].CreatedDate = 1
+	store.events[1].CreatedDate = 2
+
+	events, err := parentalconsentservice.GetEvents(store, "CHILD")
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+	assert.Equal(t, models.ParentalConsentEventGranted, events[0].Event)
+	assert.Equal(t, models.ParentalConsentEventWithdrawn, events[1].Event)
+	assert.Equal(t, models.ParentalConsentMethodParentAccount, events[1].Method)
+	assert.Equal(t, "PARENT", events[1].ParentID)
+}
+
+func TestDeleteParentalConsents(t *testing.T) {
+	setupParentalConsentGlobals(t)
+	store := newConsentStore()
+	putTestConsent(t, store, "CONSENT", "CODE", 0)
+	store.events = append(store.events, models.ParentalConsentEvent{AccountID: "CHILD", Event: models.ParentalConsentEventRequested})
+
+	err := parentalconsentservice.DeleteConsents(store, "CHILD")
+	assert.NoError(t, err)
+	assert.Empty(t, store.consents)
+	// The events are kept for audit
+	assert.Len(t, store.events, 1)
+}
+
+func TestParentalConsentMiddleware(t *testing.T) {
+	e := echo.New()
+	handler := helpers.ParentalConsentMiddleware(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	for _, status := range []string{"", models.ParentalConsentStatusGranted} {
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+		c.Set(helpers.ProfileContextKey, &models.AccountProfile{ParentalConsentStatus: status})
+
+		assert.NoError(t, handler(c))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	c.Set(helpers.ProfileContextKey, &models.AccountProfile{ParentalConsentStatus: models.ParentalConsentStatusPending})
+
+	err := handler(c)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+}
+
+func TestParentalConsentHandlerRequests(t *testing.T) {
+	e := echo.New()
+	handlers := []echo.HandlerFunc{v1.HandleGrantParentalConsent, v1.HandleWithdrawParentalConsent}
+	bodies := []string{`not json`, `{"consentId": "CONSENT"}`, `{"code": "CODE"}`}
+
+	for _, handler := range handlers {
+		for _, body := range bodies {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			assert.NoError(t, handler(e.NewContext(req, rec)))
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
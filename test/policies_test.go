@@ -0,0 +1,261 @@
+package test_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v1 "bitbucket.org/calmisland/account-lambda-funcs/internal/controllers/v1"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/globals"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/helpers"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/policies"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/policyconsentservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/profileservice"
+	"bitbucket.org/calmisland/go-server-requests/apierrors"
+	"github.com/calmisland/go-testify/assert"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+func TestRequiredPolicyVersions(t *testing.T) {
+	_, err := policies.NewRequiredVersions(policies.Config{PrivacyPolicyVersion: "2021-06-01"})
+	assert.Error(t, err)
+	_, err = policies.NewRequiredVersions(policies.Config{TermsOfServiceVersion: "2021-06-01"})
+	assert.Error(t, err)
+
+	required, err := policies.NewRequiredVersions(policies.Config{
+		TermsOfServiceVersion: "2021-06-01",
+		PrivacyPolicyVersion:  "2021-07-01",
+	})
+	assert.NoError(t, err)
+
+	assert.True(t, required.AreCurrent(nil))
+	assert.True(t, required.AreCurrent(map[string]string{models.PolicyTermsOfService: "2021-06-01"}))
+	assert.False(t, required.AreCurrent(map[string]string{models.PolicyTermsOfService: "2021-01-01"}))
+	assert.False(t, required.AreCurrent(map[string]string{"cookies": "2021-06-01"}))
+
+	assert.Nil(t, required.Missing(map[string]string{
+		models.PolicyTermsOfService: "2021-06-01",
+		models.PolicyPrivacy:        "2021-07-01",
+	}))
+	// A newly published version must be accepted again
+	assert.Equal(t, policies.Versions{models.PolicyPrivacy: "2021-07-01"}, required.Missing(map[string]string{
+		models.PolicyTermsOfService: "2021-06-01",
+		models.PolicyPrivacy:        "2021-06-01",
+	}))
+	assert.Equal(t, required, required.Missing(nil))
+}
+
+func TestPolicyConsentGracePeriod(t *testing.T) {
+	// The grace end date must be set at release time
+	_, err := policies.NewConsentGraceEndDate(policies.Config{})
+	assert.Error(t, err)
+	_, err = policies.NewConsentGraceEndDate(policies.Config{ConsentGraceEndDate: "01/09/2021"})
+	assert.Error(t, err)
+
+	graceEndDate, err := policies.NewConsentGraceEndDate(policies.Config{ConsentGraceEndDate: "2021-09-01"})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2021, time.September, 1, 0, 0, 0, 0, time.UTC), graceEndDate)
+
+	before := time.Date(2021, time.August, 31, 0, 0, 0, 0, time.UTC)
+	assert.True(t, policies.IsInGracePeriod(nil, graceEndDate, before))
+	assert.False(t, policies.IsInGracePeriod(nil, graceEndDate, graceEndDate))
+	assert.False(t, policies.IsInGracePeriod(nil, time.Time{}, before))
+	// The accounts that accepted a previous version must accept the new one
+	assert.False(t, policies.IsInGracePeriod(map[string]string{models.PolicyTermsOfService: "2021-01-01"}, graceEndDate, before))
+}
+
+// runPolicyConsentMiddleware runs the policy consent middleware for an account with accepted policy versions.
+func runPolicyConsentMiddleware(t *testing.T, accepted map[string]string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+	c.Set(helpers.ProfileContextKey, &models.AccountProfile{AcceptedPolicies: accepted})
+
+	handler := helpers.PolicyConsentMiddleware(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	assert.NoError(t, handler(c))
+	return rec
+}
+
+func TestPolicyConsentMiddleware(t *testing.T) {
+	globals.RequiredPolicyVersions = policies.Versions{
+		models.PolicyTermsOfService: "2021-06-01",
+		models.PolicyPrivacy:        "2021-07-01",
+	}
+	globals.PolicyConsentGraceEndDate = time.Time{}
+
+	rec := runPolicyConsentMiddleware(t, map[string]string{
+		models.PolicyTermsOfService: "2021-06-01",
+		models.PolicyPrivacy:        "2021-07-01",
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = runPolicyConsentMiddleware(t, map[string]string{
+		models.PolicyTermsOfService: "2021-06-01",
+		models.PolicyPrivacy:        "2021-06-01",
+	})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	var body apierrors.APIError
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, helpers.ErrorPolicyConsentRequired.ErrorCode, body.ErrorCode)
+	assert.Equal(t, helpers.ErrorPolicyConsentRequired.ErrorName, body.ErrorName)
+
+	rec = runPolicyConsentMiddleware(t, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// The accounts that never accepted any policy are let through during the grace period
+	globals.PolicyConsentGraceEndDate = time.Now().Add(time.Hour)
+	rec = runPolicyConsentMiddleware(t, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = runPolicyConsentMiddleware(t, map[string]string{models.PolicyTermsOfService: "2021-01-01"})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	globals.PolicyConsentGraceEndDate = time.Time{}
+}
+
+func TestConsentMiddlewaresShareProfile(t *testing.T) {
+	globals.RequiredPolicyVersions = policies.Versions{models.PolicyTermsOfService: "2021-06-01"}
+
+	// The profile is only read from the request context, which has no signed in account to load it
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+	c.Set(helpers.ProfileContextKey, &models.AccountProfile{
+		AcceptedPolicies: map[string]string{models.PolicyTermsOfService: "2021-06-01"},
+	})
+
+	handler := helpers.PolicyConsentMiddleware(helpers.ParentalConsentMiddleware(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}))
+	assert.NoError(t, handler(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+// policyConsentStore is an in-memory store of the policy consents, the real store being tested by TestPolicyConsentStore.
+type policyConsentStore struct {
+	consents map[string][]models.AccountPolicyConsent
+	accepted map[string]map[string]string
+}
+
+func newPolicyConsentStore() *policyConsentStore {
+	return &policyConsentStore{
+		consents: map[string][]models.AccountPolicyConsent{},
+		accepted: map[string]map[string]string{},
+	}
+}
+
+func (store *policyConsentStore) PutConsent(consent *models.AccountPolicyConsent) error {
+	store.consents[consent.AccountID] = append(store.consents[consent.AccountID], *consent)
+	return nil
+}
+
+func (store *policyConsentStore) ListConsents(accountID string) ([]models.AccountPolicyConsent, error) {
+	return append([]models.AccountPolicyConsent{}, store.consents[accountID]...), nil
+}
+
+func (store *policyConsentStore) DeleteConsent(accountID string, consentID string) error {
+	var kept []models.AccountPolicyConsent
+	for _, consent := range store.consents[accountID] {
+		if consent.ConsentID != consentID {
+			kept = append(kept, consent)
+		}
+	}
+	store.consents[accountID] = kept
+	return nil
+}
+
+func (store *policyConsentStore) SetAcceptedPolicies(accountID string, versions map[string]string) error {
+	if store.accepted[accountID] == nil {
+		store.accepted[accountID] = map[string]string{}
+	}
+	for policy, version := range versions {
+		store.accepted[accountID][policy] = version
+	}
+	return nil
+}
+
+func TestAcceptPolicies(t *testing.T) {
+	store := newPolicyConsentStore()
+	origin := policyconsentservice.Origin{IPAddress: "127.0.0.1", Language: "en_US"}
+
+	err := policyconsentservice.Accept(store, "ACCOUNT", policies.Versions{
+		models.PolicyTermsOfService: "2021-06-01",
+		models.PolicyPrivacy:        "2021-06-01",
+	}, origin)
+	assert.NoError(t, err)
+	err = policyconsentservice.Accept(store, "ACCOUNT", policies.Versions{models.PolicyPrivacy: "2021-07-01"}, origin)
+	assert.NoError(t, err)
+
+	// The last accepted versions are kept by policy
+	assert.Equal(t, map[string]string{
+		models.PolicyTermsOfService: "2021-06-01",
+		models.PolicyPrivacy:        "2021-07-01",
+	}, store.accepted["ACCOUNT"])
+
+	// Each accepted version is recorded with where it was accepted from
+	consents, err := policyconsentservice.GetConsents(store, "ACCOUNT")
+	assert.NoError(t, err)
+	assert.Len(t, consents, 3)
+	for _, consent := range consents {
+		assert.Equal(t, consent.Policy+"#"+consent.Version, consent.ConsentID)
+		assert.Equal(t, "127.0.0.1", consent.IPAddress)
+		assert.Equal(t, "en_US", consent.Language)
+		assert.True(t, consent.AcceptedDate > 0)
+	}
+	assert.True(t, consents[0].AcceptedDate <= consents[2].AcceptedDate)
+
+	err = policyconsentservice.DeleteConsents(store, "ACCOUNT")
+	assert.NoError(t, err)
+	consents, err = policyconsentservice.GetConsents(store, "ACCOUNT")
+	assert.NoError(t, err)
+	assert.Empty(t, consents)
+}
+
+func TestGetPoliciesHandler(t *testing.T) {
+	globals.RequiredPolicyVersions = policies.Versions{
+		models.PolicyTermsOfService: "2021-06-01",
+		models.PolicyPrivacy:        "2021-07-01",
+	}
+
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/v1/policies", nil), rec)
+	assert.NoError(t, v1.HandleGetPolicies(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var body struct {
+		Versions map[string]string `json:"versions"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, map[string]string(globals.RequiredPolicyVersions), body.Versions)
+}
+
+func TestPolicyConsentStore(t *testing.T) {
+	setupDynamoDBLocal(t, map[string]interface{}{
+		models.TABLE_NAME_ACCOUNT_POLICY_CONSENTS: models.AccountPolicyConsent{},
+		models.TABLE_NAME_ACCOUNT_PROFILES:        models.AccountProfile{},
+	})
+	store := policyconsentservice.NewStore()
+	accountID := uuid.New().String()
+
+	err := policyconsentservice.Accept(store, accountID, policies.Versions{"terms": "2021-01", "privacy": "2021-01"}, policyconsentservice.Origin{})
+	assert.NoError(t, err)
+	// Accepting a new version of one policy keeps the accepted version of the other
+	err = policyconsentservice.Accept(store, accountID, policies.Versions{"terms": "2021-06"}, policyconsentservice.Origin{})
+	assert.NoError(t, err)
+
+	consents, err := store.ListConsents(accountID)
+	assert.NoError(t, err)
+	assert.Len(t, consents, 3)
+
+	profile, err := profileservice.GetProfile(accountID)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"terms": "2021-06", "privacy": "2021-01"}, profile.AcceptedPolicies)
+
+	err = policyconsentservice.DeleteConsents(store, accountID)
+	assert.NoError(t, err)
+	consents, err = store.ListConsents(accountID)
+	assert.NoError(t, err)
+	assert.Empty(t, consents)
+}
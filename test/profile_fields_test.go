@@ -0,0 +1,48 @@
+package test_test
+
+import (
+	"testing"
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/defs"
+	"github.com/calmisland/go-testify/assert"
+)
+
+func TestCountryCodeValidation(t *testing.T) {
+	assert.True(t, defs.IsValidCountryCode("KR"))
+	assert.True(t, defs.IsValidCountryCode("US"))
+	assert.False(t, defs.IsValidCountryCode("XX"))
+	assert.False(t, defs.IsValidCountryCode("kr"))
+	assert.False(t, defs.IsValidCountryCode("KOR"))
+}
+
+func TestTimezoneValidation(t *testing.T) {
+	assert.True(t, defs.IsValidTimezone("Asia/Seoul"))
+	assert.True(t, defs.IsValidTimezone("UTC"))
+	assert.False(t, defs.IsValidTimezone(""))
+	assert.False(t, defs.IsValidTimezone("Local"))
+	assert.False(t, defs.IsValidTimezone("Mars/Olympus_Mons"))
+
+	assert.Equal(t, "Asia/Seoul", defs.GetCountryDefaultTimezone("KR"))
+	assert.Equal(t, "America/New_York", defs.GetCountryDefaultTimezone("US"))
+	assert.Equal(t, "Australia/Sydney", defs.GetCountryDefaultTimezone("AU"))
+	assert.Equal(t, "Europe/Madrid", defs.GetCountryDefaultTimezone("ES"))
+	assert.Equal(t, "Europe/Copenhagen", defs.GetCountryDefaultTimezone("DK"))
+	assert.Equal(t, defs.DefaultTimezone, defs.GetCountryDefaultTimezone("XX"))
+	assert.Equal(t, defs.DefaultTimezone, defs.GetCountryDefaultTimezone(defs.DefaultCountryCode))
+	for _, countryCode := range []string{"US", "GB", "JP", "UA"} {
+		assert.True(t, defs.IsValidTimezone(defs.GetCountryDefaultTimezone(countryCode)), countryCode)
+	}
+}
+
+func TestBirthDateValidation(t *testing.T) {
+	now := time.Date(2021, time.June, 15, 0, 0, 0, 0, time.UTC)
+
+	assert.True(t, defs.IsValidBirthDate(2015, 0, now))
+	assert.True(t, defs.IsValidBirthDate(2015, 12, now))
+	assert.True(t, defs.IsValidBirthDate(2021, 6, now))
+	assert.False(t, defs.IsValidBirthDate(2021, 7, now))
+	assert.False(t, defs.IsValidBirthDate(2022, 0, now))
+	assert.False(t, defs.IsValidBirthDate(1850, 0, now))
+	assert.False(t, defs.IsValidBirthDate(2015, 13, now))
+}
@@ -0,0 +1,60 @@
+package test_test
+
+import (
+	"testing"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/visibility"
+	"github.com/calmisland/go-testify/assert"
+)
+
+func TestProfileVisibilityDefaults(t *testing.T) {
+	var settings visibility.Settings
+
+	assert.Equal(t, visibility.LevelOrganization, settings.Level(visibility.FieldNames))
+	assert.Equal(t, visibility.LevelPrivate, settings.Level(visibility.FieldEmail))
+	assert.Equal(t, visibility.LevelOrganization, settings.Level(visibility.FieldAvatar))
+
+	effective := visibility.Settings{visibility.FieldEmail: visibility.LevelPublic, visibility.FieldAvatar: "unknown"}.Effective()
+	assert.Equal(t, visibility.Settings{
+		visibility.FieldNames:  visibility.LevelOrganization,
+		visibility.FieldEmail:  visibility.LevelPublic,
+		visibility.FieldAvatar: visibility.LevelOrganization,
+	}, effective)
+}
+
+func TestProfileVisibilityCanSee(t *testing.T) {
+	settings := visibility.Settings{
+		visibility.FieldNames:  visibility.LevelPublic,
+		visibility.FieldEmail:  visibility.LevelPrivate,
+		visibility.FieldAvatar: visibility.LevelOrganization,
+	}
+
+	assert.True(t, settings.CanSee(visibility.FieldNames, visibility.RelationshipNone))
+	assert.False(t, settings.CanSee(visibility.FieldAvatar, visibility.RelationshipNone))
+	assert.True(t, settings.CanSee(visibility.FieldAvatar, visibility.RelationshipOrganization))
+	assert.False(t, settings.CanSee(visibility.FieldEmail, visibility.RelationshipOrganization))
+	assert.True(t, settings.CanSee(visibility.FieldEmail, visibility.RelationshipSelf))
+}
+
+func TestProfileVisibilityCapped(t *testing.T) {
+	settings := visibility.Settings{
+		visibility.FieldNames: visibility.LevelPublic,
+		visibility.FieldEmail: visibility.LevelPrivate,
+	}
+
+	capped := settings.Capped(visibility.LevelOrganization)
+	assert.Equal(t, visibility.Settings{
+		visibility.FieldNames:  visibility.LevelOrganization,
+		visibility.FieldEmail:  visibility.LevelPrivate,
+		visibility.FieldAvatar: visibility.LevelOrganization,
+	}, capped)
+	assert.False(t, capped.CanSee(visibility.FieldNames, visibility.RelationshipNone))
+	assert.Equal(t, visibility.LevelPublic, settings.Level(visibility.FieldNames))
+}
+
+func TestProfileVisibilityValidation(t *testing.T) {
+	assert.True(t, visibility.IsValidField(visibility.FieldNames))
+	assert.False(t, visibility.IsValidField("country"))
+	assert.True(t, visibility.IsValidLevel(visibility.LevelPrivate))
+	assert.False(t, visibility.IsValidLevel("friends"))
+}
@@ -0,0 +1,86 @@
+package test_test
+
+import (
+	"testing"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/purchaseservice"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/purchaseverification"
+	"github.com/calmisland/go-testify/assert"
+)
+
+func TestFakePurchaseVerifier(t *testing.T) {
+	verifier := purchaseverification.NewFakeVerifier()
+
+	purchases, err := verifier.Verify(&purchaseverification.Receipt{
+		Store: purchaseverification.StoreAppStore,
+		Data:  `[{"transactionId": "1001", "productId": "premium", "purchaseTm": 1000, "expirationTm": 2000}]`,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, purchases, 1)
+	assert.Equal(t, "1001", purchases[0].OriginalTransactionID)
+	assert.Equal(t, int64(2000), purchases[0].ExpirationDate)
+
+	_, err = verifier.Verify(&purchaseverification.Receipt{Store: purchaseverification.StoreAppStore, Data: "not json"})
+	assert.Equal(t, purchaseverification.ErrInvalidReceipt, err)
+	_, err = verifier.Verify(&purchaseverification.Receipt{Store: purchaseverification.StoreAppStore, Data: `[{"productId": "premium"}]`})
+	assert.Equal(t, purchaseverification.ErrInvalidReceipt, err)
+}
+
+func TestStorePurchaseVerifierUnsupportedStore(t *testing.T) {
+	verifier := purchaseverification.NewStoreVerifier(map[string]purchaseverification.Verifier{
+		purchaseverification.StoreAppStore: purchaseverification.NewFakeVerifier(),
+	})
+
+	_, err := verifier.Verify(&purchaseverification.Receipt{Store: purchaseverification.StoreGooglePlay, Data: "token"})
+	assert.Equal(t, purchaseverification.ErrUnsupportedStore, err)
+}
+
+func TestPurchaseVerifierConfig(t *testing.T) {
+	_, err := purchaseverification.New(purchaseverification.AppStoreConfig{Environment: "staging"}, purchaseverification.GooglePlayConfig{})
+	assert.Error(t, err)
+
+	verifier, err := purchaseverification.New(purchaseverification.AppStoreConfig{}, purchaseverification.GooglePlayConfig{})
+	assert.NoError(t, err)
+	_, err = verifier.Verify(&purchaseverification.Receipt{Store: purchaseverification.StoreAppStore, Data: "receipt"})
+	assert.Equal(t, purchaseverification.ErrUnsupportedStore, err)
+}
+
+func TestSandboxPurchaseTransactions(t *testing.T) {
+	transaction := purchaseservice.NewPurchaseTransaction("TEST-ACCOUNT", purchaseverification.StoreAppStore, &purchaseverification.Purchase{
+		TransactionID: "1004",
+		ProductID:     "stickers",
+		PurchaseDate:  1000,
+		Sandbox:       true,
+	}, 1500)
+	assert.True(t, transaction.Sandbox)
+}
+
+func TestPurchaseTransactions(t *testing.T) {
+	subscription := purchaseservice.NewPurchaseTransaction("TEST-ACCOUNT", purchaseverification.StoreAppStore, &purchaseverification.Purchase{
+		TransactionID:  "1002",
+		ProductID:      "premium",
+		PurchaseDate:   1000,
+		ExpirationDate: 2000,
+	}, 1500)
+	assert.Equal(t, "appstore-1002", subscription.TransactionID)
+	assert.Empty(t, subscription.Products)
+	assert.Equal(t, 2000, subscription.Passes["premium"].ExpirationDate)
+
+	refundedProduct := purchaseservice.NewPurchaseTransaction("TEST-ACCOUNT", purchaseverification.StoreGooglePlay, &purchaseverification.Purchase{
+		TransactionID: "GPA.1",
+		ProductID:     "stickers",
+		PurchaseDate:  1000,
+		RefundDate:    1200,
+	}, 1500)
+	assert.Empty(t, refundedProduct.Passes)
+	assert.Equal(t, 1200, refundedProduct.Products["stickers"].ExpirationDate)
+
+	refundedSubscription := purchaseservice.NewPurchaseTransaction("TEST-ACCOUNT", purchaseverification.StoreAppStore, &purchaseverification.Purchase{
+		TransactionID:  "1003",
+		ProductID:      "premium",
+		PurchaseDate:   1000,
+		ExpirationDate: 2000,
+		RefundDate:     500,
+	}, 1500)
+	assert.Equal(t, 1000, refundedSubscription.Passes["premium"].ExpirationDate)
+}
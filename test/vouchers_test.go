@@ -0,0 +1,240 @@
+package test_test
+
+import (
+	"testing"
+	"time"
+
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/models"
+	"bitbucket.org/calmisland/account-lambda-funcs/internal/services/voucherservice"
+	"github.com/calmisland/go-testify/assert"
+	"github.com/google/uuid"
+)
+
+func TestVoucherCodeFormatting(t *testing.T) {
+	assert.Equal(t, "ABCD-EFGH-JKLM", voucherservice.FormatCode("ABCDEFGHJKLM"))
+	assert.Equal(t, "ABCDEFGHJKLM", voucherservice.NormalizeCode("abcd-efgh-jklm"))
+	assert.Equal(t, "ABCDEFGHJKLM", voucherservice.NormalizeCode(" ABCD EFGH\tJKLM "))
+}
+
+// voucherStore is an in-memory store of the vouchers, checking the redemption write conditions like the transaction
+// of the real store, which TestVoucherStoreRedemptionConditions runs against DynamoDB Local.
+type voucherStore struct {
+	batches      map[string]*models.VoucherBatch
+	vouchers     map[string]*models.Voucher
+	transactions map[string][]*models.AccountTransaction
+	// hiddenTransactions are not listed before a redemption, like a concurrent redemption that wasn't read yet
+	hiddenTransactions bool
+}
+
+func newVoucherStore() *voucherStore {
+	return &voucherStore{
+		batches:      map[string]*models.VoucherBatch{},
+		vouchers:     map[string]*models.Voucher{},
+		transactions: map[string][]*models.AccountTransaction{},
+	}
+}
+
+func (store *voucherStore) PutBatch(batch *models.VoucherBatch) error {
+	store.batches[batch.BatchID] = batch
+	return nil
+}
+
+func (store *voucherStore) PutVouchers(vouchers []*models.Voucher) error {
+	for _, voucher := range vouchers {
+		store.vouchers[voucher.Code] = voucher
+	}
+	return nil
+}
+
+func (store *voucherStore) GetBatch(batchID string) (*models.VoucherBatch, error) {
+	return store.batches[batchID], nil
+}
+
+func (store *voucherStore) GetBatchVouchers(batchID string) ([]*models.Voucher, error) {
+	var vouchers []*models.Voucher
+	for _, voucher := range store.vouchers {
+		if voucher.BatchID == batchID {
+			vouchers = append(vouchers, voucher)
+		}
+	}
+	return vouchers, nil
+}
+
+func (store *voucherStore) GetVoucher(code string) (*models.Voucher, error) {
+	if voucher, exists := store.vouchers[code]; exists {
+		copied := *voucher
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (store *voucherStore) ListTransactions(accountID string) ([]*models.AccountTransaction, error) {
+	if store.hiddenTransactions {
+		return nil, nil
+	}
+	return store.transactions[accountID], nil
+}
+
+func (store *voucherStore) GetTransaction(accountID string, transactionID string) (*models.AccountTransaction, error) {
+	for _, transaction := range store.transactions[accountID] {
+		if transaction.TransactionID == transactionID {
+			return transaction, nil
+		}
+	}
+	return nil, nil
+}
+
+func (store *voucherStore) WriteRedemption(code string, now int64, transaction *models.AccountTransaction) error {
+	voucher := store.vouchers[code]
+	existingTransaction, _ := store.GetTransaction(transaction.AccountID, transaction.TransactionID)
+	if voucher == nil || voucher.Redemptions >= voucher.MaxRedemptions ||
+		(voucher.ExpirationDate != 0 && voucher.ExpirationDate <= now) || existingTransaction != nil {
+		return voucherservice.ErrRedemptionCanceled
+	}
+
+	voucher.Redemptions++
+	store.transactions[transaction.AccountID] = append(store.transactions[transaction.AccountID], transaction)
+	return nil
+}
+
+func (store *voucherStore) addVoucher(code string, maxRedemptions int, expirationDate int64) {
+	store.batches["batch"] = &models.VoucherBatch{
+		BatchID:        "batch",
+		Passes:         map[string]*models.VoucherItem{"pass": {DurationDays: 30}},
+		MaxRedemptions: maxRedemptions,
+		ExpirationDate: expirationDate,
+	}
+	store.vouchers[code] = &models.Voucher{
+		Code:           code,
+		BatchID:        "batch",
+		MaxRedemptions: maxRedemptions,
+		ExpirationDate: expirationDate,
+	}
+}
+
+func TestVoucherRedemption(t *testing.T) {
+	store := newVoucherStore()
+	store.addVoucher("ABCDEFGHJKLM", 1, 0)
+
+	transaction, err := voucherservice.Redeem(store, "account", "abcd-efgh-jklm")
+	assert.NoError(t, err)
+	assert.Equal(t, "voucher-ABCDEFGHJKLM", transaction.TransactionID)
+	assert.Equal(t, "account", transaction.AccountID)
+	assert.Equal(t, 30*24*60*60*1000, transaction.Passes["pass"].ExpirationDate-transaction.Passes["pass"].StartDate)
+	assert.Equal(t, 1, store.vouchers["ABCDEFGHJKLM"].Redemptions)
+
+	_, err = voucherservice.Redeem(store, "account", "ABCDEFGHJKLN")
+	assert.Equal(t, voucherservice.ErrVoucherNotFound, err)
+	_, err = voucherservice.Redeem(store, "account", "ABCD")
+	assert.Equal(t, voucherservice.ErrVoucherNotFound, err)
+}
+
+func TestVoucherRedemptionLimit(t *testing.T) {
+	store := newVoucherStore()
+	store.addVoucher("ABCDEFGHJKLM", 2, 0)
+
+	_, err := voucherservice.Redeem(store, "first", "ABCDEFGHJKLM")
+	assert.NoError(t, err)
+	_, err = voucherservice.Redeem(store, "second", "ABCDEFGHJKLM")
+	assert.NoError(t, err)
+	_, err = voucherservice.Redeem(store, "third", "ABCDEFGHJKLM")
+	assert.Equal(t, voucherservice.ErrVoucherExhausted, err)
+	assert.Equal(t, 2, store.vouchers["ABCDEFGHJKLM"].Redemptions)
+	assert.Equal(t, 0, len(store.transactions["third"]))
+}
+
+func TestVoucherExpiry(t *testing.T) {
+	store := newVoucherStore()
+	store.addVoucher("ABCDEFGHJKLM", 10, time.Now().Add(-time.Minute).UnixNano()/int64(time.Millisecond))
+
+	_, err := voucherservice.Redeem(store, "account", "ABCDEFGHJKLM")
+	assert.Equal(t, voucherservice.ErrVoucherExpired, err)
+	assert.Equal(t, 0, store.vouchers["ABCDEFGHJKLM"].Redemptions)
+}
+
+func TestVoucherDoubleRedemption(t *testing.T) {
+	store := newVoucherStore()
+	store.addVoucher("ABCDEFGHJKLM", 10, 0)
+
+	_, err := voucherservice.Redeem(store, "account", "ABCDEFGHJKLM")
+	assert.NoError(t, err)
+	_, err = voucherservice.Redeem(store, "account", "ABCDEFGHJKLM")
+	assert.Equal(t, voucherservice.ErrVoucherAlreadyRedeemed, err)
+
+	// A concurrent redemption that wasn't listed is caught by the transaction ID condition
+	store.hiddenTransactions = true
+	_, err = voucherservice.Redeem(store, "account", "ABCDEFGHJKLM")
+	assert.Equal(t, voucherservice.ErrVoucherAlreadyRedeemed, err)
+	assert.Equal(t, 1, store.vouchers["ABCDEFGHJKLM"].Redemptions)
+	assert.Equal(t, 1, len(store.transactions["account"]))
+}
+
+func TestVoucherBatchCreation(t *testing.T) {
+	store := newVoucherStore()
+	batch, codes, err := voucherservice.CreateBatch(store, &voucherservice.BatchInput{
+		Name:           "Launch",
+		Passes:         map[string]*models.VoucherItem{"pass": {DurationDays: 7}},
+		MaxRedemptions: 1,
+		Count:          50,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 50, len(codes))
+
+	storedBatch, vouchers, err := voucherservice.GetBatch(store, batch.BatchID)
+	assert.NoError(t, err)
+	assert.Equal(t, batch, storedBatch)
+	assert.Equal(t, 50, len(vouchers))
+	for _, code := range codes {
+		assert.Equal(t, code, voucherservice.NormalizeCode(voucherservice.FormatCode(code)))
+	}
+}
+
+func TestVoucherStoreRedemptionConditions(t *testing.T) {
+	setupDynamoDBLocal(t, map[string]interface{}{
+		models.TABLE_NAME_VOUCHER_BATCHES:      models.VoucherBatch{},
+		models.TABLE_NAME_VOUCHERS:             models.Voucher{},
+		models.TABLE_NAME_ACCOUNT_TRANSACTIONS: models.AccountTransaction{},
+	})
+	store := voucherservice.NewStore()
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	code, expiredCode := uuid.New().String(), uuid.New().String()
+	err := store.PutVouchers([]*models.Voucher{
+		{Code: code, BatchID: "batch", MaxRedemptions: 2},
+		{Code: expiredCode, BatchID: "batch", MaxRedemptions: 2, ExpirationDate: now - 1000},
+	})
+	assert.NoError(t, err)
+
+	newTransaction := func() *models.AccountTransaction {
+		return &models.AccountTransaction{
+			AccountID:     uuid.New().String(),
+			TransactionID: "voucher-" + code,
+			CreatedDate:   int(now),
+		}
+	}
+
+	first := newTransaction()
+	err = store.WriteRedemption(code, now, first)
+	assert.NoError(t, err)
+	// The same account can't redeem the code twice
+	err = store.WriteRedemption(code, now, first)
+	assert.Equal(t, voucherservice.ErrRedemptionCanceled, err)
+
+	err = store.WriteRedemption(code, now, newTransaction())
+	assert.NoError(t, err)
+	// The redemption limit is reached
+	third := newTransaction()
+	err = store.WriteRedemption(code, now, third)
+	assert.Equal(t, voucherservice.ErrRedemptionCanceled, err)
+
+	voucher, err := store.GetVoucher(code)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, voucher.Redemptions)
+	transaction, err := store.GetTransaction(third.AccountID, third.TransactionID)
+	assert.NoError(t, err)
+	assert.Nil(t, transaction)
+
+	err = store.WriteRedemption(expiredCode, now, newTransaction())
+	assert.Equal(t, voucherservice.ErrRedemptionCanceled, err)
+	err = store.WriteRedemption(uuid.New().String(), now, newTransaction())
+	assert.Equal(t, voucherservice.ErrRedemptionCanceled, err)
+}